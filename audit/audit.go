@@ -0,0 +1,206 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-23 16:10:05
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-23 16:10:05
+ * @FilePath: \zabbix-mcp-go\audit\audit.go
+ * @Description: 工具调用审计日志（追加写入的 JSONL 文件，与 zap 日志分离）
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 工具调用结果状态
+const (
+	StatusOK        = "ok"         // 调用成功
+	StatusToolError = "tool_error" // 工具返回 isError 结果
+	StatusError     = "error"      // 处理器返回 Go error
+)
+
+// redactedValue 替换敏感参数的取值
+const redactedValue = "******"
+
+// sensitiveKeys 参数名包含以下任意片段时视为敏感信息
+var sensitiveKeys = []string{"passwd", "password", "token", "secret"}
+
+// Entry 一次工具调用的审计记录
+type Entry struct {
+	Time       time.Time              `json:"time"`
+	Caller     string                 `json:"caller"`    // 调用方身份（HTTP 头/远端地址，stdio 下为系统用户）
+	Client     string                 `json:"client"`    // MCP 客户端名称及版本
+	Session    string                 `json:"session"`   // MCP 会话ID
+	Transport  string                 `json:"transport"` // stdio / sse
+	Tool       string                 `json:"tool"`      // 工具名称
+	Instance   string                 `json:"instance"`  // 目标 Zabbix 实例
+	Arguments  map[string]interface{} `json:"arguments"` // 已脱敏的调用参数
+	Methods    []string               `json:"methods"`   // 实际调用的 Zabbix API 方法
+	Status     string                 `json:"status"`    // ok / tool_error / error
+	Error      string                 `json:"error,omitempty"`
+	DurationMs int64                  `json:"duration_ms"`
+}
+
+// Filter 审计日志查询条件，零值字段表示不限制
+type Filter struct {
+	Since    time.Time
+	Until    time.Time
+	Tool     string
+	Instance string
+	Limit    int // 只返回最新的 Limit 条，0 表示不限制
+}
+
+func (f Filter) match(e Entry) bool {
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && e.Time.After(f.Until) {
+		return false
+	}
+	if f.Tool != "" && e.Tool != f.Tool {
+		return false
+	}
+	if f.Instance != "" && e.Instance != f.Instance {
+		return false
+	}
+	return true
+}
+
+// Log 追加写入的审计日志文件
+type Log struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+}
+
+// Open 以追加方式打开（必要时创建）审计日志文件
+func Open(path string) (*Log, error) {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("创建审计日志目录失败: %w", err)
+		}
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("打开审计日志失败: %w", err)
+	}
+	return &Log{path: path, file: f}, nil
+}
+
+// Path 返回审计日志文件路径
+func (l *Log) Path() string {
+	return l.path
+}
+
+// Write 追加一条审计记录，参数会在写入前脱敏
+func (l *Log) Write(e Entry) error {
+	e.Arguments = Redact(e.Arguments)
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return os.ErrClosed
+	}
+	if _, err := l.file.Write(data); err != nil {
+		return err
+	}
+	return l.file.Sync()
+}
+
+// Query 按条件读取审计记录，按时间先后顺序返回
+func (l *Log) Query(f Filter) ([]Entry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	file, err := os.Open(l.path)
+	if err != nil {
+		return nil, fmt.Errorf("读取审计日志失败: %w", err)
+	}
+	defer file.Close()
+
+	out := []Entry{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue // 跳过损坏的行，不影响其余记录
+		}
+		if f.match(e) {
+			out = append(out, e)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取审计日志失败: %w", err)
+	}
+	if f.Limit > 0 && len(out) > f.Limit {
+		out = out[len(out)-f.Limit:]
+	}
+	return out, nil
+}
+
+// Close 关闭审计日志文件
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// Redact 返回参数的深拷贝，敏感字段被替换为 ******
+func Redact(args map[string]interface{}) map[string]interface{} {
+	if args == nil {
+		return nil
+	}
+	out := make(map[string]interface{}, len(args))
+	for k, v := range args {
+		if isSensitive(k) {
+			out[k] = redactedValue
+			continue
+		}
+		out[k] = redactValue(v)
+	}
+	return out
+}
+
+func redactValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		return Redact(val)
+	case []interface{}:
+		items := make([]interface{}, len(val))
+		for i, item := range val {
+			items[i] = redactValue(item)
+		}
+		return items
+	default:
+		return v
+	}
+}
+
+func isSensitive(key string) bool {
+	lower := strings.ToLower(key)
+	for _, s := range sensitiveKeys {
+		if strings.Contains(lower, s) {
+			return true
+		}
+	}
+	return false
+}
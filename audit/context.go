@@ -0,0 +1,72 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-23 16:32:47
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-23 16:32:47
+ * @FilePath: \zabbix-mcp-go\audit\context.go
+ * @Description: 通过 context 传递调用方身份以及本次工具调用触发的 Zabbix 方法
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+package audit
+
+import (
+	"context"
+	"sync"
+)
+
+type callerKey struct{}
+type recorderKey struct{}
+
+// Caller 调用方身份，由传输层在建立请求上下文时写入
+type Caller struct {
+	Identity  string
+	Transport string
+}
+
+// WithCaller 将调用方身份写入 context
+func WithCaller(ctx context.Context, identity, transport string) context.Context {
+	return context.WithValue(ctx, callerKey{}, Caller{Identity: identity, Transport: transport})
+}
+
+// CallerFromContext 读取调用方身份
+func CallerFromContext(ctx context.Context) (Caller, bool) {
+	c, ok := ctx.Value(callerKey{}).(Caller)
+	return c, ok
+}
+
+// APICall 一次 Zabbix API 调用
+type APICall struct {
+	Instance string
+	Method   string
+}
+
+// callRecorder 收集一次工具调用期间发出的 Zabbix API 调用
+type callRecorder struct {
+	mu    sync.Mutex
+	calls []APICall
+}
+
+func (r *callRecorder) snapshot() []APICall {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]APICall(nil), r.calls...)
+}
+
+func withRecorder(ctx context.Context) (context.Context, *callRecorder) {
+	r := &callRecorder{}
+	return context.WithValue(ctx, recorderKey{}, r), r
+}
+
+// RecordCall 记录一次 Zabbix API 调用；context 中没有审计记录器时忽略
+func RecordCall(ctx context.Context, instance, method string) {
+	if ctx == nil {
+		return
+	}
+	r, ok := ctx.Value(recorderKey{}).(*callRecorder)
+	if !ok {
+		return
+	}
+	r.mu.Lock()
+	r.calls = append(r.calls, APICall{Instance: instance, Method: method})
+	r.mu.Unlock()
+}
@@ -0,0 +1,78 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-23 16:45:30
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-23 16:45:30
+ * @FilePath: \zabbix-mcp-go\audit\middleware.go
+ * @Description: MCP 工具调用审计中间件
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+package audit
+
+import (
+	"context"
+	"time"
+
+	"zabbixMcp/logger"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Middleware 返回记录每一次工具调用的中间件，log 为 nil 时不做任何处理
+func Middleware(log *Log) server.ToolHandlerMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		if log == nil {
+			return next
+		}
+		return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			ctx, recorder := withRecorder(ctx)
+			start := time.Now()
+			result, err := next(ctx, req)
+
+			args := req.GetArguments()
+			entry := Entry{
+				Time:       start,
+				Tool:       req.Params.Name,
+				Arguments:  args,
+				Status:     StatusOK,
+				DurationMs: time.Since(start).Milliseconds(),
+			}
+			if caller, ok := CallerFromContext(ctx); ok {
+				entry.Caller = caller.Identity
+				entry.Transport = caller.Transport
+			}
+			if session := server.ClientSessionFromContext(ctx); session != nil {
+				entry.Session = session.SessionID()
+				if withInfo, ok := session.(server.SessionWithClientInfo); ok {
+					info := withInfo.GetClientInfo()
+					entry.Client = info.Name
+					if info.Version != "" {
+						entry.Client += "/" + info.Version
+					}
+				}
+			}
+			if v, ok := args["instance"].(string); ok {
+				entry.Instance = v
+			}
+			entry.Methods = []string{}
+			for _, call := range recorder.snapshot() {
+				entry.Methods = append(entry.Methods, call.Method)
+				if entry.Instance == "" {
+					entry.Instance = call.Instance
+				}
+			}
+			switch {
+			case err != nil:
+				entry.Status = StatusError
+				entry.Error = err.Error()
+			case result != nil && result.IsError:
+				entry.Status = StatusToolError
+			}
+			if werr := log.Write(entry); werr != nil {
+				logger.L().Errorf("写入审计日志失败: %v", werr)
+			}
+			return result, err
+		}
+	}
+}
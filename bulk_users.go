@@ -0,0 +1,145 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-31 16:02:37
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-31 16:02:37
+ * @FilePath: \zabbix-mcp-go\bulk_users.go
+ * @Description: bulk-users 子命令：不启动 MCP 服务，直接按 CSV/JSON 文件批量开通用户
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"zabbixMcp/credential"
+	lg "zabbixMcp/logger"
+	"zabbixMcp/server"
+)
+
+// runBulkUsers 执行 bulk-users 子命令，返回进程退出码：0 全部成功，1 有行失败，2 参数或连接错误
+func runBulkUsers(argv []string) int {
+	fs := flag.NewFlagSet("bulk-users", flag.ContinueOnError)
+	var (
+		instance       = fs.String("instance", "", "目标 Zabbix 实例名称（必填）")
+		file           = fs.String("file", "", "CSV 或 JSON 文件路径，- 表示从标准输入读取（必填）")
+		dataFormat     = fs.String("data-format", "auto", "文件格式: auto、csv、json，auto 按扩展名与首个字符判断")
+		mode           = fs.String("mode", server.BulkModeSkip, "用户已存在时的处理方式: skip、update、fail")
+		emailMediaType = fs.String("email-media-type", "Email", "email 列使用的媒介类型名称或ID")
+		dryRun         = fs.Bool("dry-run", false, "只校验并输出计划，不创建或更新用户")
+		output         = fs.String("output", "-", "处理报告（JSON）的输出文件，- 表示标准输出")
+		level          = fs.String("loglevel", "warn", "日志等级 (debug, info, warn, error)")
+	)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "用法: %s bulk-users -instance <实例> -file <users.csv|users.json|-> [选项]\n", filepath.Base(os.Args[0]))
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(argv); err != nil {
+		return 2
+	}
+	if *instance == "" || *file == "" {
+		fs.Usage()
+		return 2
+	}
+	switch *mode {
+	case server.BulkModeSkip, server.BulkModeUpdate, server.BulkModeFail:
+	default:
+		fmt.Fprintf(os.Stderr, "不支持的 -mode %q，可选 skip、update、fail\n", *mode)
+		return 2
+	}
+
+	lg.SetLogLevel(*level)
+	if err := lg.InitLogger(); err != nil {
+		fmt.Fprintf(os.Stderr, "初始化日志失败: %v\n", err)
+		return 2
+	}
+	defer lg.Sync()
+
+	data, err := readBulkFile(*file)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "读取 %s 失败: %v\n", *file, err)
+		return 2
+	}
+	if *dataFormat == "auto" {
+		switch strings.ToLower(filepath.Ext(*file)) {
+		case ".csv":
+			*dataFormat = "csv"
+		case ".json":
+			*dataFormat = "json"
+		}
+	}
+	rows, err := server.ParseBulkUsers(string(data), *dataFormat)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	if err := LoadConfig(); err != nil {
+		fmt.Fprintf(os.Stderr, "加载配置失败: %v\n", err)
+		return 2
+	}
+	provider, err := InitPoolsFromConfig("")
+	if err != nil || provider == nil {
+		fmt.Fprintf(os.Stderr, "初始化 Zabbix 客户端池失败: %v\n", err)
+		return 2
+	}
+
+	// 子命令没有 HTTP 服务，link 方式改为写入本地加密文件
+	credCfg := AppConfig.Credentials
+	if credCfg.Delivery == credential.MethodLink {
+		fmt.Fprintf(os.Stderr, "bulk-users 不提供 HTTP 服务，密码改为写入加密文件 %s\n", credCfg.File.Path)
+		credCfg.Delivery = credential.MethodFile
+	}
+	deliverer, _, err := newCredentialDeliverer(credCfg, provider, 0, false)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "初始化密码投递失败: %v\n", err)
+		return 2
+	}
+
+	ctx := context.Background()
+	batch, err := server.PrepareBulkUsers(ctx, provider, *instance, rows, server.BulkUserOptions{Mode: *mode, EmailMediaType: *emailMediaType})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	report := batch.Report(true)
+	if !*dryRun {
+		report = server.ExecuteBulkUsers(ctx, provider, batch, deliverer)
+	}
+
+	out, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	out = append(out, '\n')
+	if *output == "-" {
+		_, err = os.Stdout.Write(out)
+	} else {
+		err = os.WriteFile(*output, out, 0600)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "写入处理报告失败: %v\n", err)
+		return 2
+	}
+	fmt.Fprintf(os.Stderr, "共 %d 行: 创建 %d，更新 %d，跳过 %d，失败 %d\n",
+		report.Summary.Total, report.Summary.Created, report.Summary.Updated, report.Summary.Skipped, report.Summary.Failed)
+	if report.Summary.Failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+func readBulkFile(name string) ([]byte, error) {
+	if name == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(name)
+}
@@ -18,7 +18,17 @@ import (
 
 // Config 多实例配置
 type Config struct {
-	Instances []ZabbixInstance `yaml:"instances"`
+	Instances    []ZabbixInstance   `yaml:"instances"`
+	Confirmation ConfirmationConfig `yaml:"confirmation,omitempty"`
+	Audit        AuditConfig        `yaml:"audit,omitempty"`
+	Tracing      TracingConfig      `yaml:"tracing,omitempty"`
+	APICall      APICallConfig      `yaml:"api_call,omitempty"`
+	Pagination   PaginationConfig   `yaml:"pagination,omitempty"`
+	Output       OutputConfig       `yaml:"output,omitempty"`
+	Cache        CacheConfig        `yaml:"cache,omitempty"`
+	Resources    ResourcesConfig    `yaml:"resources,omitempty"`
+	ProblemFeed  ProblemFeedConfig  `yaml:"problem_feed,omitempty"`
+	Credentials  CredentialsConfig  `yaml:"credentials,omitempty"`
 }
 
 // ZabbixInstance Zabbix实例配置
@@ -30,19 +40,192 @@ type ZabbixInstance struct {
 	Token    string `yaml:"token,omitempty"`
 	AuthType string `yaml:"auth_type,omitempty"` // "password" 或 "token"
 	Default  bool   `yaml:"default,omitempty"`
+	ServerTZ string `yaml:"server_tz,omitempty"` // Zabbix 服务器所在时区（IANA 名称，如 Asia/Shanghai），空则使用本地时区
+}
+
+// ConfirmationConfig 破坏性操作的二次确认配置
+type ConfirmationConfig struct {
+	Enabled     bool     `yaml:"enabled"`
+	Tools       []string `yaml:"tools,omitempty"`       // 始终需要确认的工具
+	MaxObjects  int      `yaml:"max_objects,omitempty"` // 单次影响对象数超过该值时需要确认，0 表示不按数量判断
+	TokenTTL    int      `yaml:"token_ttl,omitempty"`   // 确认令牌有效期（秒）
+	Elicitation bool     `yaml:"elicitation"`           // 客户端支持 elicitation 时直接询问用户
+}
+
+// AuditConfig 工具调用审计日志配置
+type AuditConfig struct {
+	Enabled        bool     `yaml:"enabled"`
+	Path           string   `yaml:"path,omitempty"`            // JSONL 审计文件路径
+	TrustedProxies []string `yaml:"trusted_proxies,omitempty"` // 可信反向代理的 IP 或 CIDR，只有来自这些地址的请求才采信 X-Forwarded-User/X-User
+}
+
+// TracingConfig OpenTelemetry 链路追踪配置（OTLP/HTTP）
+type TracingConfig struct {
+	Enabled     bool    `yaml:"enabled"`
+	Endpoint    string  `yaml:"endpoint,omitempty"`     // 例如 localhost:4318 或 http://collector:4318/v1/traces
+	Insecure    bool    `yaml:"insecure,omitempty"`     // endpoint 不带协议时使用 http
+	ServiceName string  `yaml:"service_name,omitempty"` // 上报的 service.name
+	SampleRatio float64 `yaml:"sample_ratio,omitempty"` // 采样比例 (0,1]，0 表示全部采样
+}
+
+// APICallConfig 通用 api_call 工具的方法放行配置
+type APICallConfig struct {
+	Enabled        bool     `yaml:"enabled"`
+	Allow          []string `yaml:"allow,omitempty"`            // 允许的方法，支持通配符，例如 "*.get"
+	Deny           []string `yaml:"deny,omitempty"`             // 禁止的方法，优先于 allow
+	MaxParamsBytes int      `yaml:"max_params_bytes,omitempty"` // 参数序列化后的最大字节数，0 表示不限制
+}
+
+// PaginationConfig *.get 类工具的分页配置
+type PaginationConfig struct {
+	DefaultLimit int            `yaml:"default_limit"`   // 未指定 limit 时的每页条数
+	MaxLimit     int            `yaml:"max_limit"`       // limit 上限，0 表示不限制
+	Tools        map[string]int `yaml:"tools,omitempty"` // 按工具覆盖默认条数，例如 get_hosts: 50
+}
+
+// OutputConfig 工具输出的默认预算
+type OutputConfig struct {
+	MaxTokens int `yaml:"max_tokens"` // 单次结果的大小预算（约 4 字节 1 个 token），0 表示不限制
+}
+
+// CacheConfig 读多写少的元数据响应缓存配置（每个实例独立缓存）
+type CacheConfig struct {
+	Enabled    bool           `yaml:"enabled"`
+	TTL        int            `yaml:"ttl,omitempty"`         // 默认有效期（秒）
+	MaxEntries int            `yaml:"max_entries,omitempty"` // 每个实例最多缓存的响应数，0 表示不限制
+	Methods    map[string]int `yaml:"methods,omitempty"`     // 缓存的 *.get 方法及有效期（秒），0 表示使用 ttl
+}
+
+// ResourcesConfig MCP 资源配置
+type ResourcesConfig struct {
+	ProblemLimit     int  `yaml:"problem_limit"`           // zabbix://{instance}/problems 最多返回的问题数，0 表示不限制
+	Subscribe        bool `yaml:"subscribe"`               // 是否支持 resources/subscribe
+	PollInterval     int  `yaml:"poll_interval,omitempty"` // 重新读取已订阅资源的间隔（秒）
+	MaxSubscriptions int  `yaml:"max_subscriptions"`       // 每个会话最多订阅的资源数，0 表示不限制
+}
+
+// ProblemFeedConfig 实时问题推送配置
+type ProblemFeedConfig struct {
+	Enabled  bool `yaml:"enabled"`
+	Interval int  `yaml:"interval,omitempty"` // 每个实例的轮询间隔（秒）
+}
+
+// CredentialsConfig 生成密码（create_user、update_user 重置、bulk_create_users）的投递配置
+type CredentialsConfig struct {
+	Delivery string               `yaml:"delivery"` // file、smtp、link、inline
+	File     CredentialFileConfig `yaml:"file,omitempty"`
+	SMTP     CredentialSMTPConfig `yaml:"smtp,omitempty"`
+	Link     CredentialLinkConfig `yaml:"link,omitempty"`
+}
+
+// CredentialFileConfig 本地加密文件
+type CredentialFileConfig struct {
+	Path    string `yaml:"path,omitempty"`     // 加密后的密码文件（JSONL）
+	KeyEnv  string `yaml:"key_env,omitempty"`  // 保存密钥的环境变量（32 字节 base64 或口令），优先于 key_file
+	KeyFile string `yaml:"key_file,omitempty"` // 未设置环境变量时使用的密钥文件，不存在时自动生成
+}
+
+// CredentialSMTPConfig 借用 Zabbix 邮件媒介类型的 SMTP 设置直接发送邮件（不经过 Zabbix）
+type CredentialSMTPConfig struct {
+	MediaType         string `yaml:"media_type,omitempty"`          // 邮件媒介类型名称或ID
+	SMTPPasswordEnv   string `yaml:"smtp_password_env,omitempty"`   // 媒介类型启用 SMTP 认证时，保存 SMTP 密码的环境变量
+	AllowInsecureAuth bool   `yaml:"allow_insecure_auth,omitempty"` // 媒介类型未加密却启用了认证时仍以明文发送 SMTP 密码，默认拒绝发送
+}
+
+// CredentialLinkConfig 一次性取回链接
+type CredentialLinkConfig struct {
+	BaseURL string `yaml:"base_url,omitempty"` // 用户浏览器访问 HTTP 服务的地址，空则使用 http://localhost:{port}
+	TTL     int    `yaml:"ttl,omitempty"`      // 链接有效期（秒）
 }
 
 var AppConfig Config
 
+// defaultConfig 返回未在 config.yml 中显式配置时使用的默认值
+func defaultConfig() Config {
+	return Config{
+		Confirmation: ConfirmationConfig{
+			Enabled:     true,
+			Tools:       []string{"delete_user", "offboard_user", "api_call"},
+			MaxObjects:  5,
+			TokenTTL:    300,
+			Elicitation: true,
+		},
+		Audit: AuditConfig{
+			Enabled: true,
+			Path:    "logs/audit.jsonl",
+		},
+		Tracing: TracingConfig{
+			Endpoint:    "localhost:4318",
+			Insecure:    true,
+			ServiceName: "zabbix-mcp-server",
+		},
+		Output: OutputConfig{MaxTokens: 20000},
+		Resources: ResourcesConfig{
+			ProblemLimit:     200,
+			Subscribe:        true,
+			PollInterval:     60,
+			MaxSubscriptions: 50,
+		},
+		ProblemFeed: ProblemFeedConfig{
+			Enabled:  true,
+			Interval: 30,
+		},
+		Credentials: CredentialsConfig{
+			Delivery: "file",
+			File: CredentialFileConfig{
+				Path:    "secrets/credentials.jsonl",
+				KeyEnv:  "ZABBIX_MCP_CREDENTIAL_KEY",
+				KeyFile: "secrets/credentials.key",
+			},
+			SMTP: CredentialSMTPConfig{
+				MediaType:       "Email",
+				SMTPPasswordEnv: "ZABBIX_MCP_SMTP_PASSWORD",
+			},
+			Link: CredentialLinkConfig{TTL: 900},
+		},
+		Cache: CacheConfig{
+			Enabled:    true,
+			TTL:        300,
+			MaxEntries: 1000,
+			Methods: map[string]int{
+				"hostgroup.get":     0,
+				"templategroup.get": 0,
+				"template.get":      0,
+				"usergroup.get":     0,
+				"role.get":          0,
+				"mediatype.get":     0,
+				"valuemap.get":      0,
+			},
+		},
+		Pagination: PaginationConfig{
+			DefaultLimit: 100,
+			MaxLimit:     1000,
+			Tools:        map[string]int{"get_hosts": 50},
+		},
+		APICall: APICallConfig{
+			Enabled:        true,
+			Allow:          []string{"*.get", "apiinfo.version"},
+			Deny:           []string{"user.login", "user.logout", "user.checkAuthentication"},
+			MaxParamsBytes: 64 * 1024,
+		},
+	}
+}
+
 func LoadConfig() error {
 	data, err := os.ReadFile("config.yml")
 	if err != nil {
 		return fmt.Errorf("读取配置文件失败: %w", err)
 	}
 
+	AppConfig = defaultConfig()
+
 	if err := yaml.Unmarshal(data, &AppConfig); err != nil {
 		return fmt.Errorf("解析配置文件失败: %w", err)
 	}
+	// 每页条数必须为正数，显式配置为 0 时沿用默认值
+	if AppConfig.Pagination.DefaultLimit <= 0 {
+		AppConfig.Pagination.DefaultLimit = defaultConfig().Pagination.DefaultLimit
+	}
 
 	return nil
 }
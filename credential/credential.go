@@ -0,0 +1,99 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2026-01-05 10:21:36
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2026-01-05 10:21:36
+ * @FilePath: \zabbix-mcp-go\credential\credential.go
+ * @Description: 生成密码的投递：写入本地加密文件、通过 Zabbix 邮件媒介发送给用户或生成一次性取回链接，工具输出只说明密码去了哪里
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+package credential
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+
+	"zabbixMcp/models"
+)
+
+// 投递方式
+const (
+	MethodFile   = "file"   // 追加写入本地加密文件
+	MethodSMTP   = "smtp"   // 借用 Zabbix 邮件媒介类型的 SMTP 设置，直接连接 SMTP 服务器发送到用户邮箱
+	MethodLink   = "link"   // HTTP 服务上的一次性取回链接
+	MethodInline = "inline" // 直接写在工具结果中（不推荐，仅用于兼容）
+)
+
+// 生成密码的原因
+const (
+	ReasonCreate = "create" // 新建用户的初始密码
+	ReasonReset  = "reset"  // 重置后的密码
+)
+
+// Credential 需要投递的一个账号密码
+type Credential struct {
+	Instance string
+	UserID   string
+	Username string
+	Password string
+	Reason   string
+}
+
+// Receipt 投递回执，代替密码出现在工具结果中
+type Receipt struct {
+	Method    string           `json:"method,omitempty" jsonschema_description:"file 本地加密文件 smtp 邮件 link 一次性链接 inline 直接返回"`
+	Location  string           `json:"location,omitempty"` // 文件路径与条目ID、收件地址（已打码）或取回链接
+	ExpiresAt models.Timestamp `json:"expires_at,omitempty"`
+	Passwd    string           `json:"passwd,omitempty"` // 仅 inline 方式
+	Error     string           `json:"error,omitempty"`  // 投递失败的原因，密码已丢弃，需要重新重置
+}
+
+// Deliverer 投递方式
+type Deliverer interface {
+	Method() string
+	Deliver(ctx context.Context, c Credential) (*Receipt, error)
+}
+
+// Deliver 投递密码；d 为 nil 或投递失败时返回带错误说明的回执，调用方据此提示重新重置密码，不会回退为明文返回
+func Deliver(ctx context.Context, d Deliverer, c Credential) *Receipt {
+	if d == nil {
+		return &Receipt{Error: "未配置密码投递方式（credentials.delivery），密码已丢弃，请配置后用 update_user 的 updatePasswd 重新生成"}
+	}
+	receipt, err := d.Deliver(ctx, c)
+	if err != nil {
+		return &Receipt{Method: d.Method(), Error: fmt.Sprintf("%v；密码已丢弃，请用 update_user 的 updatePasswd 重新生成", err)}
+	}
+	return receipt
+}
+
+// Inline 把密码直接写在工具结果中，密码会出现在对话记录与日志里
+type Inline struct{}
+
+// Method 投递方式名称
+func (Inline) Method() string { return MethodInline }
+
+// Deliver 返回带明文密码的回执
+func (Inline) Deliver(_ context.Context, c Credential) (*Receipt, error) {
+	return &Receipt{Method: MethodInline, Passwd: c.Password}, nil
+}
+
+// randomID 随机的十六进制ID
+func randomID(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// randomToken 随机的 URL 安全令牌
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,197 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2026-01-05 10:48:12
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2026-01-05 10:48:12
+ * @FilePath: \zabbix-mcp-go\credential\file.go
+ * @Description: 本地加密文件：每个密码用 AES-256-GCM 加密后作为一行 JSON 追加写入
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+package credential
+
+import (
+	"bufio"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// keyLength AES-256 密钥长度
+const keyLength = 32
+
+// Entry 加密文件中的一条记录，Password 只在读取时解密填充
+type Entry struct {
+	ID       string    `json:"id"`
+	Time     time.Time `json:"time"`
+	Instance string    `json:"instance"`
+	UserID   string    `json:"userid"`
+	Username string    `json:"username"`
+	Reason   string    `json:"reason"`
+	Nonce    []byte    `json:"nonce"`
+	Secret   []byte    `json:"secret"` // 加密后的密码
+	Password string    `json:"-"`
+}
+
+// aad 把记录的元数据绑定到密文上，篡改用户名等字段后无法解密
+func (e Entry) aad() []byte {
+	return []byte(strings.Join([]string{e.ID, e.Instance, e.UserID, e.Username, e.Reason}, "\x00"))
+}
+
+// FileStore 追加写入的加密密码文件
+type FileStore struct {
+	mu   sync.Mutex
+	path string
+	aead cipher.AEAD
+}
+
+// OpenFile 打开加密密码文件。密钥优先取环境变量 keyEnv 的值（32 字节的 base64 或任意口令），
+// 未设置时读取 keyFile，keyFile 不存在则生成随机密钥并以 0600 权限写入
+func OpenFile(path, keyEnv, keyFile string) (*FileStore, error) {
+	key, err := LoadKey(keyEnv, keyFile, true)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return nil, fmt.Errorf("创建密码文件目录失败: %w", err)
+		}
+	}
+	return &FileStore{path: path, aead: aead}, nil
+}
+
+// LoadKey 读取加密密钥，create 为 true 且密钥文件不存在时生成新密钥
+func LoadKey(keyEnv, keyFile string, create bool) ([]byte, error) {
+	if keyEnv != "" {
+		if v := strings.TrimSpace(os.Getenv(keyEnv)); v != "" {
+			return parseKey(v), nil
+		}
+	}
+	if keyFile == "" {
+		return nil, fmt.Errorf("未设置环境变量 %s，也没有配置密钥文件", keyEnv)
+	}
+	data, err := os.ReadFile(keyFile)
+	if err == nil {
+		return parseKey(strings.TrimSpace(string(data))), nil
+	}
+	if !errors.Is(err, os.ErrNotExist) || !create {
+		return nil, fmt.Errorf("读取密钥文件失败: %w", err)
+	}
+	key := make([]byte, keyLength)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	if dir := filepath.Dir(keyFile); dir != "" {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return nil, fmt.Errorf("创建密钥目录失败: %w", err)
+		}
+	}
+	if err := os.WriteFile(keyFile, []byte(base64.StdEncoding.EncodeToString(key)+"\n"), 0600); err != nil {
+		return nil, fmt.Errorf("写入密钥文件失败: %w", err)
+	}
+	return key, nil
+}
+
+// parseKey 32 字节的 base64 直接作为密钥，其他内容视为口令取 SHA-256
+func parseKey(v string) []byte {
+	if key, err := base64.StdEncoding.DecodeString(v); err == nil && len(key) == keyLength {
+		return key
+	}
+	sum := sha256.Sum256([]byte(v))
+	return sum[:]
+}
+
+// Path 密码文件路径
+func (s *FileStore) Path() string {
+	return s.path
+}
+
+// Method 投递方式名称
+func (s *FileStore) Method() string { return MethodFile }
+
+// Deliver 加密后追加写入一条记录，回执中给出文件路径与记录ID
+func (s *FileStore) Deliver(_ context.Context, c Credential) (*Receipt, error) {
+	id, err := randomID(8)
+	if err != nil {
+		return nil, err
+	}
+	e := Entry{ID: id, Time: time.Now(), Instance: c.Instance, UserID: c.UserID, Username: c.Username, Reason: c.Reason}
+	e.Nonce = make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(e.Nonce); err != nil {
+		return nil, err
+	}
+	e.Secret = s.aead.Seal(nil, e.Nonce, []byte(c.Password), e.aad())
+	line, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("打开密码文件失败: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return nil, fmt.Errorf("写入密码文件失败: %w", err)
+	}
+	return &Receipt{Method: MethodFile, Location: fmt.Sprintf("%s#%s", s.path, id)}, nil
+}
+
+// ReadFile 读取并解密密码文件中的记录，match 为 nil 时返回全部记录
+func ReadFile(path string, key []byte, match func(Entry) bool) ([]Entry, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("第 %d 行格式错误: %w", line, err)
+		}
+		if match != nil && !match(e) {
+			continue
+		}
+		plain, err := aead.Open(nil, e.Nonce, e.Secret, e.aad())
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 行（%s）解密失败，密钥不正确或记录被篡改", line, e.ID)
+		}
+		e.Password = string(plain)
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
@@ -0,0 +1,183 @@
+package credential
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileStoreRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "secrets", "passwords.jsonl")
+	keyFile := filepath.Join(dir, "keys", "passwords.key")
+	store, err := OpenFile(path, "", keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	creds := []Credential{
+		{Instance: "prod", UserID: "1001", Username: "zhangsan", Password: "Init-pass-1", Reason: ReasonCreate},
+		{Instance: "prod", UserID: "1002", Username: "lisi", Password: "Reset-pass-2", Reason: ReasonReset},
+	}
+	var ids []string
+	for _, c := range creds {
+		receipt, err := store.Deliver(ctx, c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		loc, id, ok := strings.Cut(receipt.Location, "#")
+		if receipt.Method != MethodFile || loc != path || !ok || receipt.Passwd != "" {
+			t.Fatalf("回执 = %+v", receipt)
+		}
+		ids = append(ids, id)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range creds {
+		if bytes.Contains(raw, []byte(c.Password)) {
+			t.Errorf("密码文件中出现明文密码 %s", c.Password)
+		}
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("密码文件权限 = %v, %v，期望 0600", info.Mode().Perm(), err)
+	}
+
+	key, err := LoadKey("", keyFile, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := ReadFile(path, key, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != len(creds) {
+		t.Fatalf("读取到 %d 条记录，期望 %d", len(entries), len(creds))
+	}
+	for i, e := range entries {
+		c := creds[i]
+		if e.ID != ids[i] || e.Instance != c.Instance || e.UserID != c.UserID || e.Username != c.Username || e.Reason != c.Reason || e.Password != c.Password {
+			t.Errorf("第 %d 条记录 = %+v，期望 %+v", i+1, e, c)
+		}
+	}
+
+	entries, err = ReadFile(path, key, func(e Entry) bool { return e.Username == "lisi" })
+	if err != nil || len(entries) != 1 || entries[0].Password != "Reset-pass-2" {
+		t.Errorf("按用户名过滤 = %+v, %v", entries, err)
+	}
+
+	other := sha256.Sum256([]byte("wrong key"))
+	if _, err := ReadFile(path, other[:], nil); err == nil {
+		t.Error("密钥不正确时应解密失败")
+	}
+}
+
+// TestFileStoreTamper 记录的元数据绑定在密文上，改动后无法解密
+func TestFileStoreTamper(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "passwords.jsonl")
+	keyFile := filepath.Join(dir, "passwords.key")
+	store, err := OpenFile(path, "", keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Deliver(context.Background(), Credential{Instance: "prod", UserID: "1001", Username: "zhangsan", Password: "Init-pass-1", Reason: ReasonCreate}); err != nil {
+		t.Fatal(err)
+	}
+	key, err := LoadKey("", keyFile, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, edit := range map[string][2]string{
+		"用户名":  {`"username":"zhangsan"`, `"username":"admin"`},
+		"用户ID": {`"userid":"1001"`, `"userid":"1"`},
+		"实例":   {`"instance":"prod"`, `"instance":"test"`},
+		"原因":   {`"reason":"create"`, `"reason":"reset"`},
+	} {
+		tampered := bytes.Replace(raw, []byte(edit[0]), []byte(edit[1]), 1)
+		if bytes.Equal(tampered, raw) {
+			t.Fatalf("%s: 记录中没有 %s", name, edit[0])
+		}
+		if err := os.WriteFile(path, tampered, 0600); err != nil {
+			t.Fatal(err)
+		}
+		if entries, err := ReadFile(path, key, nil); err == nil {
+			t.Errorf("篡改%s后应解密失败: %+v", name, entries)
+		}
+	}
+}
+
+func TestLoadKey(t *testing.T) {
+	dir := t.TempDir()
+	const env = "ZABBIX_MCP_TEST_CREDENTIAL_KEY"
+	raw := bytes.Repeat([]byte{7}, keyLength)
+	passphrase := sha256.Sum256([]byte("correct horse battery staple"))
+	short := base64.StdEncoding.EncodeToString([]byte("short"))
+	shortSum := sha256.Sum256([]byte(short)) // 长度不是 32 字节的 base64 也按口令处理
+
+	t.Run("环境变量", func(t *testing.T) {
+		keyFile := filepath.Join(dir, "unused.key")
+		for value, want := range map[string][]byte{
+			base64.StdEncoding.EncodeToString(raw): raw,
+			"  correct horse battery staple \n":    passphrase[:],
+			short:                                  shortSum[:],
+		} {
+			t.Setenv(env, value)
+			key, err := LoadKey(env, keyFile, true)
+			if err != nil || !bytes.Equal(key, want) {
+				t.Errorf("环境变量 %q 的密钥 = %x, %v，期望 %x", value, key, err, want)
+			}
+		}
+		if _, err := os.Stat(keyFile); !os.IsNotExist(err) {
+			t.Error("使用环境变量时不应生成密钥文件")
+		}
+	})
+
+	t.Run("密钥文件", func(t *testing.T) {
+		t.Setenv(env, " ")
+		keyFile := filepath.Join(dir, "existing.key")
+		if err := os.WriteFile(keyFile, []byte(base64.StdEncoding.EncodeToString(raw)+"\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		key, err := LoadKey(env, keyFile, false)
+		if err != nil || !bytes.Equal(key, raw) {
+			t.Errorf("密钥文件的密钥 = %x, %v，期望 %x", key, err, raw)
+		}
+	})
+
+	t.Run("生成", func(t *testing.T) {
+		keyFile := filepath.Join(dir, "generated", "passwords.key")
+		if _, err := LoadKey("", keyFile, false); err == nil {
+			t.Error("create 为 false 时密钥文件不存在应返回错误")
+		}
+		key, err := LoadKey("", keyFile, true)
+		if err != nil || len(key) != keyLength {
+			t.Fatalf("生成的密钥 = %x, %v", key, err)
+		}
+		info, err := os.Stat(keyFile)
+		if err != nil || info.Mode().Perm() != 0600 {
+			t.Fatalf("密钥文件权限 = %v, %v，期望 0600", info, err)
+		}
+		again, err := LoadKey("", keyFile, true)
+		if err != nil || !bytes.Equal(again, key) {
+			t.Errorf("再次读取的密钥 = %x, %v，期望与生成的相同", again, err)
+		}
+	})
+
+	if _, err := LoadKey(env, "", true); err == nil {
+		t.Error("未设置环境变量也没有密钥文件时应返回错误")
+	}
+}
@@ -0,0 +1,136 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2026-01-05 11:30:04
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2026-01-05 11:30:04
+ * @FilePath: \zabbix-mcp-go\credential\link.go
+ * @Description: 一次性取回链接：密码只保存在内存中，取回一次或过期后即删除
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+package credential
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"zabbixMcp/logger"
+	"zabbixMcp/models"
+)
+
+// LinkPath 取回链接在 HTTP 服务上的路径前缀
+const LinkPath = "/credentials/"
+
+// defaultLinkTTL 未配置时取回链接的有效期
+const defaultLinkTTL = 15 * time.Minute
+
+type linkEntry struct {
+	cred    Credential
+	expires time.Time
+}
+
+// LinkStore 一次性取回链接，同时作为 LinkPath 下的 HTTP 处理器
+type LinkStore struct {
+	mu      sync.Mutex
+	baseURL string
+	ttl     time.Duration
+	entries map[string]linkEntry
+}
+
+// NewLinkStore 创建取回链接存储，baseURL 为用户浏览器可以访问到的 HTTP 服务地址
+func NewLinkStore(baseURL string, ttl time.Duration) *LinkStore {
+	if ttl <= 0 {
+		ttl = defaultLinkTTL
+	}
+	return &LinkStore{baseURL: strings.TrimRight(baseURL, "/"), ttl: ttl, entries: map[string]linkEntry{}}
+}
+
+// Method 投递方式名称
+func (s *LinkStore) Method() string { return MethodLink }
+
+// Deliver 保存密码并返回取回链接
+func (s *LinkStore) Deliver(_ context.Context, c Credential) (*Receipt, error) {
+	token, err := randomToken(24)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	expires := now.Add(s.ttl)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for t, e := range s.entries {
+		if now.After(e.expires) {
+			delete(s.entries, t)
+		}
+	}
+	s.entries[token] = linkEntry{cred: c, expires: expires}
+	return &Receipt{Method: MethodLink, Location: s.baseURL + LinkPath + token, ExpiresAt: models.Timestamp(expires.Unix())}, nil
+}
+
+// take 取出并删除令牌对应的密码
+func (s *LinkStore) take(token string) (Credential, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[token]
+	if !ok {
+		return Credential{}, false
+	}
+	delete(s.entries, token)
+	if time.Now().After(e.expires) {
+		return Credential{}, false
+	}
+	return e.cred, true
+}
+
+// exists 令牌是否仍然有效
+func (s *LinkStore) exists(token string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[token]
+	return ok && time.Now().Before(e.expires)
+}
+
+var linkPage = template.Must(template.New("credential").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><meta name="robots" content="noindex"><title>Zabbix 账号密码</title></head>
+<body>
+{{if .Password}}<p>Zabbix 实例 {{.Instance}} 上账号 <b>{{.Username}}</b> 的密码：</p>
+<pre>{{.Password}}</pre>
+<p>该链接已失效，请立即登录并修改密码。</p>
+{{else if .Valid}}<form method="post"><p>该链接只能查看一次。</p><button type="submit">显示密码</button></form>
+{{else}}<p>链接无效、已过期或已被使用。</p>{{end}}
+</body></html>`))
+
+// ServeHTTP GET 只显示确认按钮（避免聊天客户端的链接预览把密码取走），POST 显示密码并使链接失效
+func (s *LinkStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimPrefix(r.URL.Path, LinkPath)
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	data := struct {
+		Valid                        bool
+		Instance, Username, Password string
+	}{}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		data.Valid = s.exists(token)
+	case http.MethodPost:
+		if c, ok := s.take(token); ok {
+			data.Instance, data.Username, data.Password = c.Instance, c.Username, c.Password
+			logger.L().Infof("密码取回链接已使用: 实例 %s 用户 %s，来自 %s", c.Instance, c.Username, r.RemoteAddr)
+		}
+	default:
+		http.Error(w, fmt.Sprintf("不支持的方法 %s", r.Method), http.StatusMethodNotAllowed)
+		return
+	}
+	if !data.Valid && data.Password == "" {
+		w.WriteHeader(http.StatusNotFound)
+	}
+	if err := linkPage.Execute(w, data); err != nil {
+		logger.L().Warnf("输出密码取回页面失败: %v", err)
+	}
+}
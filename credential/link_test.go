@@ -0,0 +1,101 @@
+package credential
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func serveLink(s *LinkStore, method, location string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(method, location, nil))
+	return rec
+}
+
+// TestLinkStore GET 只显示确认按钮不消耗链接，POST 显示密码并使链接失效
+func TestLinkStore(t *testing.T) {
+	s := NewLinkStore("https://mcp.example.com/", 0)
+	c := Credential{Instance: "prod", UserID: "1001", Username: "zhangsan", Password: "Init-pass-1", Reason: ReasonCreate}
+	receipt, err := s.Deliver(context.Background(), c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if receipt.Method != MethodLink || !strings.HasPrefix(receipt.Location, "https://mcp.example.com"+LinkPath) || receipt.Passwd != "" {
+		t.Fatalf("回执 = %+v", receipt)
+	}
+	if ttl := time.Until(receipt.ExpiresAt.Time()); ttl <= defaultLinkTTL-time.Minute || ttl > defaultLinkTTL {
+		t.Errorf("有效期 = %v，期望 %v", ttl, defaultLinkTTL)
+	}
+
+	for i := 0; i < 2; i++ {
+		rec := serveLink(s, http.MethodGet, receipt.Location)
+		if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), c.Password) || !strings.Contains(rec.Body.String(), `method="post"`) {
+			t.Fatalf("第 %d 次 GET = %d %s", i+1, rec.Code, rec.Body)
+		}
+		if rec.Header().Get("Cache-Control") != "no-store" || rec.Header().Get("Referrer-Policy") != "no-referrer" {
+			t.Errorf("响应头 = %v", rec.Header())
+		}
+	}
+
+	rec := serveLink(s, http.MethodPost, receipt.Location)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), c.Password) || !strings.Contains(rec.Body.String(), c.Username) {
+		t.Fatalf("POST = %d %s", rec.Code, rec.Body)
+	}
+	for _, method := range []string{http.MethodPost, http.MethodGet} {
+		if rec := serveLink(s, method, receipt.Location); rec.Code != http.StatusNotFound || strings.Contains(rec.Body.String(), c.Password) {
+			t.Errorf("使用后 %s = %d %s，期望 404", method, rec.Code, rec.Body)
+		}
+	}
+
+	if rec := serveLink(s, http.MethodPost, "/credentials/nosuch"); rec.Code != http.StatusNotFound {
+		t.Errorf("未知令牌 = %d，期望 404", rec.Code)
+	}
+	if rec := serveLink(s, http.MethodDelete, receipt.Location); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("DELETE = %d，期望 405", rec.Code)
+	}
+}
+
+// TestLinkStoreExpired 过期的链接返回 404，下次投递时清理
+func TestLinkStoreExpired(t *testing.T) {
+	s := NewLinkStore("http://127.0.0.1:8080", time.Hour)
+	ctx := context.Background()
+	receipt, err := s.Deliver(ctx, Credential{Instance: "prod", Username: "zhangsan", Password: "Init-pass-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := strings.TrimPrefix(receipt.Location, "http://127.0.0.1:8080"+LinkPath)
+	s.mu.Lock()
+	e := s.entries[token]
+	e.expires = time.Now().Add(-time.Second)
+	s.entries[token] = e
+	s.mu.Unlock()
+
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		if rec := serveLink(s, method, receipt.Location); rec.Code != http.StatusNotFound || strings.Contains(rec.Body.String(), "Init-pass-1") {
+			t.Errorf("过期后 %s = %d %s，期望 404", method, rec.Code, rec.Body)
+		}
+	}
+
+	expired, err := s.Deliver(ctx, Credential{Instance: "prod", Username: "lisi", Password: "Init-pass-2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.mu.Lock()
+	for tok, e := range s.entries {
+		e.expires = time.Now().Add(-time.Second)
+		s.entries[tok] = e
+	}
+	s.mu.Unlock()
+	if _, err := s.Deliver(ctx, Credential{Instance: "prod", Username: "wangwu", Password: "Init-pass-3"}); err != nil {
+		t.Fatal(err)
+	}
+	s.mu.Lock()
+	n := len(s.entries)
+	s.mu.Unlock()
+	if n != 1 {
+		t.Errorf("投递后剩余 %d 个链接，期望过期的 %s 已清理", n, expired.Location)
+	}
+}
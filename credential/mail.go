@@ -0,0 +1,242 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2026-01-05 14:02:51
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2026-01-05 14:02:51
+ * @FilePath: \zabbix-mcp-go\credential\mail.go
+ * @Description: 借用 Zabbix 邮件媒介类型的 SMTP 设置，直接连接 SMTP 服务器把密码发送到用户的邮箱
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+package credential
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Zabbix 邮件媒介类型的 smtp_security
+const (
+	SMTPSecurityNone     = 0
+	SMTPSecuritySTARTTLS = 1
+	SMTPSecuritySSL      = 2
+)
+
+// smtpTimeout 连接 SMTP 服务器的超时时间
+const smtpTimeout = 15 * time.Second
+
+// SMTPSettings 邮件媒介类型的 SMTP 设置（Zabbix 不回传媒介类型的密码，需要单独配置）
+type SMTPSettings struct {
+	Server     string
+	Port       int
+	HELO       string
+	From       string
+	Security   int
+	VerifyPeer bool // smtp_verify_peer：校验服务器证书链
+	VerifyHost bool // smtp_verify_host：校验证书中的主机名
+	Auth       bool
+	Username   string
+}
+
+// MediaLookup 查找实例中邮件媒介类型的 SMTP 设置，以及用户在该媒介类型下的收件地址
+type MediaLookup func(ctx context.Context, instance, userID string) (*SMTPSettings, []string, error)
+
+// SMTPMailer 直接连接 SMTP 服务器发送密码。邮件不经过 Zabbix（Zabbix API 没有发送任意邮件的方法），
+// 只是借用邮件媒介类型的服务器、端口、发件人、加密与认证设置，以及用户在该媒介类型下的收件地址；
+// 因此本服务所在主机需要能访问该 SMTP 服务器，发送记录也不会出现在 Zabbix 的告警历史中
+type SMTPMailer struct {
+	Lookup            MediaLookup
+	SMTPPassword      string // smtp_authentication 为用户名密码时使用
+	AllowInsecureAuth bool   // 媒介类型未加密（smtp_security 为 None）却启用了认证时，仍以明文发送 SMTP 密码
+
+	rootCAs *x509.CertPool // 校验服务器证书的根证书，nil 使用系统根证书
+}
+
+// Method 投递方式名称
+func (m *SMTPMailer) Method() string { return MethodSMTP }
+
+// Deliver 按用户在该媒介类型下的收件地址发送邮件，回执中只给出打码后的地址
+func (m *SMTPMailer) Deliver(ctx context.Context, c Credential) (*Receipt, error) {
+	settings, recipients, err := m.Lookup(ctx, c.Instance, c.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("用户 %s 没有配置该邮件媒介的收件地址", c.Username)
+	}
+	if err := m.send(ctx, settings, recipients, c); err != nil {
+		return nil, fmt.Errorf("发送邮件失败: %w", err)
+	}
+	masked := make([]string, len(recipients))
+	for i, r := range recipients {
+		masked[i] = MaskEmail(r)
+	}
+	return &Receipt{Method: MethodSMTP, Location: strings.Join(masked, ", ")}, nil
+}
+
+func (m *SMTPMailer) send(ctx context.Context, s *SMTPSettings, recipients []string, c Credential) error {
+	if s == nil || s.Server == "" {
+		return fmt.Errorf("邮件媒介类型没有配置 SMTP 服务器")
+	}
+	from, err := mail.ParseAddress(s.From)
+	if err != nil {
+		return fmt.Errorf("发件地址 %q 无效: %w", s.From, err)
+	}
+	if s.Auth && s.Security == SMTPSecurityNone && !m.AllowInsecureAuth {
+		return fmt.Errorf("邮件媒介类型启用了 SMTP 认证但未加密，SMTP 密码会以明文传输；请将媒介类型的连接安全改为 STARTTLS 或 SSL/TLS，或在 credentials.smtp 中设置 allow_insecure_auth: true")
+	}
+	port := s.Port
+	if port == 0 {
+		port = 25
+	}
+	addr := net.JoinHostPort(s.Server, strconv.Itoa(port))
+	ctx, cancel := context.WithTimeout(ctx, smtpTimeout)
+	defer cancel()
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	tlsConfig := m.tlsConfig(s)
+	if s.Security == SMTPSecuritySSL {
+		conn = tls.Client(conn, tlsConfig)
+	}
+	client, err := smtp.NewClient(conn, s.Server)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+	if s.HELO != "" {
+		if err := client.Hello(s.HELO); err != nil {
+			return err
+		}
+	}
+	if s.Security == SMTPSecuritySTARTTLS {
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+	if s.Auth {
+		// smtp.PlainAuth 拒绝在未加密的连接上发送密码，未加密时只有显式开启 allow_insecure_auth 才会走到这里
+		var auth smtp.Auth = insecurePlainAuth{username: s.Username, password: m.SMTPPassword}
+		if s.Security != SMTPSecurityNone {
+			auth = smtp.PlainAuth("", s.Username, m.SMTPPassword, s.Server)
+		}
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(from.Address); err != nil {
+		return err
+	}
+	for _, r := range recipients {
+		if err := client.Rcpt(r); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(message(from.String(), recipients, c)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// tlsConfig 按媒介类型的 smtp_verify_peer/smtp_verify_host 校验服务器证书，与 Zabbix 发送告警邮件时一致：
+// verify_peer 校验证书链，verify_host 校验证书中的主机名，两者可以分别关闭
+func (m *SMTPMailer) tlsConfig(s *SMTPSettings) *tls.Config {
+	cfg := &tls.Config{ServerName: s.Server, RootCAs: m.rootCAs}
+	if s.VerifyPeer && s.VerifyHost {
+		return cfg
+	}
+	cfg.InsecureSkipVerify = true
+	if !s.VerifyPeer && !s.VerifyHost {
+		return cfg
+	}
+	cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+		leaf := cs.PeerCertificates[0]
+		if s.VerifyHost {
+			return leaf.VerifyHostname(s.Server)
+		}
+		opts := x509.VerifyOptions{Roots: m.rootCAs, Intermediates: x509.NewCertPool()}
+		for _, c := range cs.PeerCertificates[1:] {
+			opts.Intermediates.AddCert(c)
+		}
+		_, err := leaf.Verify(opts)
+		return err
+	}
+	return cfg
+}
+
+// insecurePlainAuth 不检查连接是否加密的 PLAIN 认证，仅用于 allow_insecure_auth
+type insecurePlainAuth struct {
+	username, password string
+}
+
+func (a insecurePlainAuth) Start(*smtp.ServerInfo) (string, []byte, error) {
+	return "PLAIN", []byte("\x00" + a.username + "\x00" + a.password), nil
+}
+
+func (a insecurePlainAuth) Next(_ []byte, more bool) ([]byte, error) {
+	if more {
+		return nil, errors.New("SMTP 服务器返回了意外的认证质询")
+	}
+	return nil, nil
+}
+
+// message 组装邮件，正文使用 base64 编码
+func message(from string, recipients []string, c Credential) []byte {
+	subject := fmt.Sprintf("Zabbix 账号 %s 的密码", c.Username)
+	body := fmt.Sprintf("您好，\r\n\r\n您在 Zabbix 实例 %s 上的账号 %s 的%s密码为：\r\n\r\n    %s\r\n\r\n请尽快登录并修改密码，不要转发本邮件。\r\n",
+		c.Instance, c.Username, reasonText(c.Reason), c.Password)
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(recipients, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.BEncoding.Encode("UTF-8", subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=UTF-8\r\nContent-Transfer-Encoding: base64\r\n\r\n")
+	encoded := base64.StdEncoding.EncodeToString([]byte(body))
+	for len(encoded) > 76 {
+		buf.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	buf.WriteString(encoded + "\r\n")
+	return buf.Bytes()
+}
+
+func reasonText(reason string) string {
+	if reason == ReasonReset {
+		return "新"
+	}
+	return "初始"
+}
+
+// MaskEmail 邮箱地址打码，只保留首字母与域名：z***@example.com
+func MaskEmail(addr string) string {
+	local, domain, ok := strings.Cut(addr, "@")
+	if !ok || local == "" {
+		return "***"
+	}
+	r := []rune(local)
+	return string(r[0]) + "***@" + domain
+}
@@ -0,0 +1,274 @@
+package credential
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"math/big"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// smtpSink 最小的 SMTP 服务器，记录收到的认证信息与邮件
+type smtpSink struct {
+	ln       net.Listener
+	tls      *tls.Config // 非 nil 时支持 STARTTLS，implicit 时连接即握手
+	implicit bool
+
+	mu    sync.Mutex
+	conns int
+	auth  string
+	rcpts []string
+	data  string
+}
+
+func newSMTPSink(t *testing.T, cfg *tls.Config, implicit bool) *smtpSink {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &smtpSink{ln: ln, tls: cfg, implicit: implicit}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.conns++
+			s.mu.Unlock()
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *smtpSink) port() int {
+	return s.ln.Addr().(*net.TCPAddr).Port
+}
+
+func (s *smtpSink) serve(conn net.Conn) {
+	defer func() { conn.Close() }()
+	secure := false
+	if s.implicit {
+		conn = tls.Server(conn, s.tls)
+		secure = true
+	}
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 sink ESMTP")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			if s.tls != nil && !secure {
+				tp.PrintfLine("250-sink")
+				tp.PrintfLine("250-STARTTLS")
+			} else {
+				tp.PrintfLine("250-sink")
+			}
+			tp.PrintfLine("250 AUTH PLAIN")
+		case "STARTTLS":
+			tp.PrintfLine("220 go ahead")
+			tlsConn := tls.Server(conn, s.tls)
+			if tlsConn.Handshake() != nil {
+				return
+			}
+			conn, secure = tlsConn, true
+			tp = textproto.NewConn(conn)
+		case "AUTH":
+			_, resp, _ := strings.Cut(arg, " ")
+			decoded, _ := base64.StdEncoding.DecodeString(resp)
+			s.mu.Lock()
+			s.auth = string(decoded)
+			s.mu.Unlock()
+			tp.PrintfLine("235 ok")
+		case "RCPT":
+			s.mu.Lock()
+			s.rcpts = append(s.rcpts, strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>"))
+			s.mu.Unlock()
+			tp.PrintfLine("250 ok")
+		case "DATA":
+			tp.PrintfLine("354 go ahead")
+			var b strings.Builder
+			r := bufio.NewScanner(tp.DotReader())
+			for r.Scan() {
+				b.WriteString(r.Text() + "\n")
+			}
+			s.mu.Lock()
+			s.data = b.String()
+			s.mu.Unlock()
+			tp.PrintfLine("250 queued")
+		case "QUIT":
+			tp.PrintfLine("221 bye")
+			return
+		default:
+			tp.PrintfLine("250 ok")
+		}
+	}
+}
+
+// selfSigned 生成用于 host 的自签名证书及信任它的根证书池
+func selfSigned(t *testing.T, host string) (*tls.Config, *x509.CertPool) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: host},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		tmpl.IPAddresses = []net.IP{ip}
+	} else {
+		tmpl.DNSNames = []string{host}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}, pool
+}
+
+func testMailer(settings SMTPSettings) *SMTPMailer {
+	return &SMTPMailer{
+		Lookup: func(context.Context, string, string) (*SMTPSettings, []string, error) {
+			return &settings, []string{"lisi@example.com"}, nil
+		},
+		SMTPPassword: "smtp-secret",
+	}
+}
+
+var testCredential = Credential{Instance: "prod", UserID: "42", Username: "lisi", Password: "S3cret-pass", Reason: ReasonCreate}
+
+func TestSMTPMailerPlaintext(t *testing.T) {
+	sink := newSMTPSink(t, nil, false)
+	m := testMailer(SMTPSettings{Server: "127.0.0.1", Port: sink.port(), From: "zabbix@example.com"})
+	receipt, err := m.Deliver(context.Background(), testCredential)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if receipt.Method != MethodSMTP || receipt.Location != "l***@example.com" {
+		t.Errorf("回执 = %+v", receipt)
+	}
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	if len(sink.rcpts) != 1 || sink.rcpts[0] != "lisi@example.com" {
+		t.Errorf("收件人 = %v", sink.rcpts)
+	}
+	_, body, _ := strings.Cut(sink.data, "\n\n")
+	decoded, _ := base64.StdEncoding.DecodeString(strings.ReplaceAll(body, "\n", ""))
+	if !strings.Contains(string(decoded), testCredential.Password) {
+		t.Errorf("邮件正文中没有密码: %q", decoded)
+	}
+}
+
+func TestSMTPMailerRefusesPlaintextAuth(t *testing.T) {
+	sink := newSMTPSink(t, nil, false)
+	m := testMailer(SMTPSettings{Server: "127.0.0.1", Port: sink.port(), From: "zabbix@example.com", Auth: true, Username: "zabbix"})
+	_, err := m.Deliver(context.Background(), testCredential)
+	if err == nil || !strings.Contains(err.Error(), "allow_insecure_auth") {
+		t.Fatalf("未加密的认证: err = %v，期望提示 allow_insecure_auth", err)
+	}
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	if sink.conns != 0 {
+		t.Errorf("拒绝发送前不应连接 SMTP 服务器，连接数 = %d", sink.conns)
+	}
+}
+
+func TestSMTPMailerInsecureAuthOptIn(t *testing.T) {
+	sink := newSMTPSink(t, nil, false)
+	m := testMailer(SMTPSettings{Server: "127.0.0.1", Port: sink.port(), From: "zabbix@example.com", Auth: true, Username: "zabbix"})
+	m.AllowInsecureAuth = true
+	if _, err := m.Deliver(context.Background(), testCredential); err != nil {
+		t.Fatal(err)
+	}
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	if sink.auth != "\x00zabbix\x00smtp-secret" {
+		t.Errorf("PLAIN 认证 = %q", sink.auth)
+	}
+}
+
+// TestSMTPMailerVerification smtp_verify_peer 校验证书链，smtp_verify_host 校验主机名
+func TestSMTPMailerVerification(t *testing.T) {
+	cases := []struct {
+		name                   string
+		certHost               string
+		trusted                bool
+		verifyPeer, verifyHost bool
+		ok                     bool
+	}{
+		{"全部校验", "127.0.0.1", true, true, true, true},
+		{"证书链不可信", "127.0.0.1", false, true, true, false},
+		{"主机名不符", "mail.example.com", true, true, true, false},
+		{"只校验证书链", "mail.example.com", true, true, false, true},
+		{"只校验证书链且不可信", "mail.example.com", false, true, false, false},
+		{"只校验主机名", "127.0.0.1", false, false, true, true},
+		{"只校验主机名且不符", "mail.example.com", false, false, true, false},
+		{"都不校验", "mail.example.com", false, false, false, true},
+	}
+	for _, security := range []int{SMTPSecuritySTARTTLS, SMTPSecuritySSL} {
+		for _, c := range cases {
+			name := c.name
+			if security == SMTPSecuritySSL {
+				name = "SSL/" + name
+			} else {
+				name = "STARTTLS/" + name
+			}
+			t.Run(name, func(t *testing.T) {
+				cfg, pool := selfSigned(t, c.certHost)
+				sink := newSMTPSink(t, cfg, security == SMTPSecuritySSL)
+				m := testMailer(SMTPSettings{
+					Server: "127.0.0.1", Port: sink.port(), From: "zabbix@example.com",
+					Security: security, VerifyPeer: c.verifyPeer, VerifyHost: c.verifyHost,
+					Auth: true, Username: "zabbix",
+				})
+				if c.trusted {
+					m.rootCAs = pool
+				}
+				_, err := m.Deliver(context.Background(), testCredential)
+				if c.ok != (err == nil) {
+					t.Fatalf("err = %v，期望成功 = %v", err, c.ok)
+				}
+				sink.mu.Lock()
+				defer sink.mu.Unlock()
+				if c.ok && sink.auth != "\x00zabbix\x00smtp-secret" {
+					t.Errorf("加密连接上的 PLAIN 认证 = %q", sink.auth)
+				}
+				if !c.ok && sink.auth != "" {
+					t.Errorf("证书校验失败后不应发送 SMTP 密码，收到 %q", sink.auth)
+				}
+			})
+		}
+	}
+}
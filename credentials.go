@@ -0,0 +1,79 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2026-01-05 17:12:30
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2026-01-05 17:12:30
+ * @FilePath: \zabbix-mcp-go\credentials.go
+ * @Description: credentials 子命令：解密并查看 credentials.delivery 为 file 时写入的密码
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"zabbixMcp/credential"
+)
+
+// runCredentials 执行 credentials 子命令，返回进程退出码：0 成功，1 没有匹配的记录，2 参数或读取错误
+func runCredentials(argv []string) int {
+	fs := flag.NewFlagSet("credentials", flag.ContinueOnError)
+	var (
+		id       = fs.String("id", "", "记录ID（工具结果中 location 的 # 之后部分）")
+		instance = fs.String("instance", "", "只显示该实例的记录")
+		username = fs.String("username", "", "只显示该用户的记录")
+	)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "用法: %s credentials [-id <记录ID>] [-instance <实例>] [-username <用户名>]\n", filepath.Base(os.Args[0]))
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(argv); err != nil {
+		return 2
+	}
+	if err := LoadConfig(); err != nil {
+		fmt.Fprintf(os.Stderr, "加载配置失败: %v\n", err)
+		return 2
+	}
+	cfg := AppConfig.Credentials.File
+	key, err := credential.LoadKey(cfg.KeyEnv, cfg.KeyFile, false)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	entries, err := credential.ReadFile(cfg.Path, key, func(e credential.Entry) bool {
+		return (*id == "" || e.ID == *id) && (*instance == "" || e.Instance == *instance) && (*username == "" || e.Username == *username)
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "读取 %s 失败: %v\n", cfg.Path, err)
+		return 2
+	}
+
+	type record struct {
+		ID       string    `json:"id"`
+		Time     time.Time `json:"time"`
+		Instance string    `json:"instance"`
+		UserID   string    `json:"userid"`
+		Username string    `json:"username"`
+		Reason   string    `json:"reason"`
+		Passwd   string    `json:"passwd"`
+	}
+	records := make([]record, len(entries))
+	for i, e := range entries {
+		records[i] = record{ID: e.ID, Time: e.Time, Instance: e.Instance, UserID: e.UserID, Username: e.Username, Reason: e.Reason, Passwd: e.Password}
+	}
+	out, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	fmt.Println(string(out))
+	if len(records) == 0 {
+		return 1
+	}
+	return 0
+}
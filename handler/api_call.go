@@ -0,0 +1,113 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-27 15:20:51
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-27 15:20:51
+ * @FilePath: \zabbix-mcp-go\handler\api_call.go
+ * @Description: 通用 API 调用工具：按允许/禁止列表放行方法
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"zabbixMcp/models"
+	"zabbixMcp/server"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// APICallPolicy 描述 api_call 工具可以调用哪些方法
+type APICallPolicy struct {
+	Enabled        bool
+	Allow          []string // 允许的方法，支持通配符，例如 *.get
+	Deny           []string // 禁止的方法，优先于 Allow
+	MaxParamsBytes int      // 参数序列化后的最大字节数，0 表示不限制
+}
+
+// allows 判断方法是否允许调用，不允许时返回原因
+func (p APICallPolicy) allows(method string) error {
+	if !p.Enabled {
+		return models.Errorf(models.CodePermissionDenied, "api_call 工具未启用").
+			WithHint("在 config.yml 中设置 api_call.enabled: true 后重启服务，或改用专用工具")
+	}
+	for _, pattern := range p.Deny {
+		if matchMethod(pattern, method) {
+			return models.Errorf(models.CodePermissionDenied, "方法 %s 被 api_call 禁止列表 %s 拒绝", method, pattern).
+				WithHint("该方法不能通过 api_call 调用，请改用专用工具")
+		}
+	}
+	for _, pattern := range p.Allow {
+		if matchMethod(pattern, method) {
+			return nil
+		}
+	}
+	return models.Errorf(models.CodePermissionDenied, "方法 %s 不在 api_call 允许列表中", method).
+		WithHint(fmt.Sprintf("允许的方法: %s；如需调用其它方法请在 config.yml 的 api_call.allow 中添加", strings.Join(p.Allow, ", ")))
+}
+
+// matchMethod 方法名不区分大小写按通配符匹配
+func matchMethod(pattern, method string) bool {
+	ok, err := path.Match(strings.ToLower(pattern), strings.ToLower(method))
+	return err == nil && ok
+}
+
+// readOnlyMethod 只读方法不需要二次确认
+func readOnlyMethod(method string) bool {
+	return strings.HasSuffix(method, ".get") || method == "apiinfo.version"
+}
+
+// apiCallPolicy 由 main 按 config.yml 的 api_call 段（缺省值见 defaultConfig）注入，未注入时 api_call 不可用
+var apiCallPolicy APICallPolicy
+
+// SetAPICallPolicy 注入 api_call 方法放行策略
+func SetAPICallPolicy(p APICallPolicy) {
+	apiCallPolicy = p
+}
+
+// APICallHandler 调用任意允许的 Zabbix API 方法，作为尚无专用工具时的兜底
+func APICallHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var args models.APICallArgs
+	if err := bindArgs(req, &args); err != nil {
+		return nil, err
+	}
+	policy := apiCallPolicy
+	if err := policy.allows(args.Method); err != nil {
+		return nil, err
+	}
+	if policy.MaxParamsBytes > 0 {
+		data, _ := json.Marshal(args.Params)
+		if len(data) > policy.MaxParamsBytes {
+			return nil, models.ArgsError{{Arg: "params", Problem: fmt.Sprintf("大小 %d 字节超过上限 %d 字节，请拆分为多次调用", len(data), policy.MaxParamsBytes)}}
+		}
+	}
+	ctx = withRawOption(ctx, args.Raw)
+	ctx = withCacheOption(ctx, args.Cache)
+	if clientPool == nil {
+		return mcp.NewToolResultStructuredOnly(makeResult(nil)), nil
+	}
+	if args.DryRun {
+		plan, err := server.PlanAPICall(ctx, clientPool, args.Instance, args.Method, args.Params)
+		if err != nil {
+			return nil, fmt.Errorf("预览 %s 失败: %w", args.Method, err)
+		}
+		return mcp.NewToolResultStructuredOnly(makeResult(plan)), nil
+	}
+	if !readOnlyMethod(args.Method) {
+		if res, err := confirmMutation(ctx, req, "api_call", 1, func() (*models.MutationPlan, error) {
+			return server.PlanAPICall(ctx, clientPool, args.Instance, args.Method, args.Params)
+		}); res != nil || err != nil {
+			return res, err
+		}
+	}
+	result, err := server.CallAPI(ctx, clientPool, args.Instance, args.Method, args.Params)
+	if err != nil {
+		return nil, fmt.Errorf("调用 %s 失败: %w", args.Method, err)
+	}
+	return mcp.NewToolResultStructuredOnly(makeResult(result)), nil
+}
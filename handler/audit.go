@@ -0,0 +1,47 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-23 17:20:03
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-23 17:20:03
+ * @FilePath: \zabbix-mcp-go\handler\audit.go
+ * @Description: 审计日志查询
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+package handler
+
+import (
+	"context"
+	"fmt"
+
+	"zabbixMcp/audit"
+	"zabbixMcp/models"
+	"zabbixMcp/server"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// 审计日志引用，main 初始化后通过 SetAuditLog 注入（可为 nil，表示未启用）
+var auditLog *audit.Log
+
+// SetAuditLog 注入审计日志
+func SetAuditLog(l *audit.Log) {
+	auditLog = l
+}
+
+// GetAuditLogHandler 按时间范围、工具与实例查询审计记录
+func GetAuditLogHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var args models.GetAuditLogArgs
+	if err := bindArgs(req, &args); err != nil {
+		return nil, err
+	}
+	filter := audit.Filter{Tool: args.Tool, Instance: args.Instance, Limit: args.Limit}
+	var err error
+	if filter.Since, filter.Until, err = parseTimeRange(args.Instance, "since", args.Since, "until", args.Until); err != nil {
+		return nil, err
+	}
+	entries, err := server.QueryAuditLog(ctx, auditLog, filter)
+	if err != nil {
+		return nil, fmt.Errorf("查询审计日志失败: %w", err)
+	}
+	return mcp.NewToolResultStructuredOnly(makeResult(entries)), nil
+}
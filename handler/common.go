@@ -1,6 +1,13 @@
 package handler
 
-import "zabbixMcp/zabbix"
+import (
+	"context"
+
+	"zabbixMcp/models"
+	"zabbixMcp/zabbix"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
 
 // 持有可选的客户端池引用，main 初始化后会调用 SetClientPool 注入
 // 现在使用 zabbix.ClientProvider 接口，隐藏底层具体类型
@@ -21,3 +28,43 @@ func makeResult(data interface{}) map[string]interface{} {
 		"data": data,
 	}
 }
+
+// ToolResult 与 makeResult 结构一致的类型化结果，用于通过 mcp.WithOutputSchema 为工具声明 outputSchema
+type ToolResult[T any] struct {
+	OK   bool                     `json:"ok"`
+	Data T                        `json:"data"`
+	Raw  []map[string]interface{} `json:"raw,omitempty" jsonschema_description:"raw=true 时返回的 Zabbix 原始结构 此时 data 为空"`
+	Page *models.PageInfo         `json:"page,omitempty" jsonschema_description:"分页信息 has_more 为 true 时用 next_cursor 获取下一页"`
+	// 以下字段由输出层（fields/max_items/max_tokens/summary 参数）填充
+	Items     []map[string]interface{} `json:"items,omitempty" jsonschema_description:"指定 fields 时按字段裁剪后的结果 此时 data 为空"`
+	Summary   map[string]interface{}   `json:"summary,omitempty" jsonschema_description:"按常用维度分组计数的摘要"`
+	Truncated *models.Truncation       `json:"truncated,omitempty" jsonschema_description:"结果被截断时给出省略的条数"`
+}
+
+// makeRawResult raw 模式的返回：原始结构不符合类型化的 data，放在 raw 字段中
+func makeRawResult(records []map[string]interface{}) map[string]interface{} {
+	result := makeResult([]interface{}{})
+	result["raw"] = records
+	return result
+}
+
+// bindArgs 按 models 中的参数结构体解码并校验工具参数，错误中逐个列出有问题的参数
+func bindArgs(req mcp.CallToolRequest, out interface{}) error {
+	return models.DecodeArgs(req.GetArguments(), out)
+}
+
+// withRawOption 参数 raw 为 true 时跳过响应归一化，直接返回 Zabbix 原始结构
+func withRawOption(ctx context.Context, raw bool) context.Context {
+	if raw {
+		return zabbix.WithRawResponse(ctx, true)
+	}
+	return ctx
+}
+
+// withCacheOption 参数 cache 为 bypass 时本次调用不读取响应缓存
+func withCacheOption(ctx context.Context, cache string) context.Context {
+	if cache == "bypass" {
+		return zabbix.WithCacheBypass(ctx)
+	}
+	return ctx
+}
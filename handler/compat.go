@@ -0,0 +1,34 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-26 10:18:02
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-26 10:18:02
+ * @FilePath: \zabbix-mcp-go\handler\compat.go
+ * @Description: 版本兼容规则工具处理器
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+package handler
+
+import (
+	"context"
+
+	"zabbixMcp/logger"
+	"zabbixMcp/models"
+	"zabbixMcp/server"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// GetAPICompatHandler 说明 AdaptAPIParams 在指定实例上会做哪些参数调整
+func GetAPICompatHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var args models.GetAPICompatArgs
+	if err := bindArgs(req, &args); err != nil {
+		return nil, err
+	}
+	report, err := server.GetAPICompat(ctx, clientPool, args.Instance, args.Method, args.All)
+	if err != nil {
+		logger.L().Errorf("获取版本兼容规则失败: %v", err)
+		return nil, err
+	}
+	return mcp.NewToolResultStructuredOnly(makeResult(report)), nil
+}
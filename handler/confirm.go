@@ -0,0 +1,259 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-23 14:02:18
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-23 14:02:18
+ * @FilePath: \zabbix-mcp-go\handler\confirm.go
+ * @Description: 破坏性操作的二次确认（确认令牌 / MCP elicitation）
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+package handler
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"zabbixMcp/logger"
+	"zabbixMcp/models"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	mcpserver "github.com/mark3labs/mcp-go/server"
+)
+
+// ConfirmationPolicy 描述哪些调用需要二次确认
+type ConfirmationPolicy struct {
+	Enabled     bool
+	Tools       []string      // 始终需要确认的工具
+	MaxObjects  int           // 影响对象数超过该值时需要确认，0 表示不按数量判断
+	TokenTTL    time.Duration // 确认令牌有效期
+	Elicitation bool          // 客户端支持时通过 elicitation 直接询问用户
+}
+
+// requires 判断指定工具在影响 objects 个对象时是否需要确认
+func (p ConfirmationPolicy) requires(tool string, objects int) bool {
+	if !p.Enabled {
+		return false
+	}
+	for _, t := range p.Tools {
+		if t == tool {
+			return true
+		}
+	}
+	return p.MaxObjects > 0 && objects > p.MaxObjects
+}
+
+// 确认令牌参数名；计算调用指纹时需要排除
+const confirmTokenArg = "confirm_token"
+
+var (
+	confirmPolicy = ConfirmationPolicy{Enabled: true, Tools: []string{"delete_user", "offboard_user", "api_call"}, MaxObjects: 5, TokenTTL: 5 * time.Minute, Elicitation: true}
+	confirmations = newConfirmationStore()
+)
+
+// SetConfirmationPolicy 注入二次确认策略
+func SetConfirmationPolicy(p ConfirmationPolicy) {
+	if p.TokenTTL <= 0 {
+		p.TokenTTL = 5 * time.Minute
+	}
+	confirmPolicy = p
+}
+
+type pendingConfirmation struct {
+	session     string // 签发令牌的 MCP 会话，令牌只能在同一会话中使用
+	tool        string
+	fingerprint string
+	targets     string // 预览时解析出的目标对象指纹，为空表示不校验
+	expiresAt   time.Time
+}
+
+// confirmationStore 保存尚未使用的确认令牌，令牌只能使用一次
+type confirmationStore struct {
+	mu      sync.Mutex
+	pending map[string]pendingConfirmation
+}
+
+func newConfirmationStore() *confirmationStore {
+	return &confirmationStore{pending: make(map[string]pendingConfirmation)}
+}
+
+func (s *confirmationStore) issue(session, tool, fingerprint, targets string, ttl time.Duration) (string, time.Time, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", time.Time{}, err
+	}
+	token := hex.EncodeToString(buf)
+	expiresAt := time.Now().Add(ttl)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.purgeLocked()
+	s.pending[token] = pendingConfirmation{session: session, tool: tool, fingerprint: fingerprint, targets: targets, expiresAt: expiresAt}
+	return token, expiresAt, nil
+}
+
+// consume 校验并作废令牌；令牌必须由同一会话、同一工具、同一组参数签发且未过期，签发时记录了目标对象的还要求目标不变。
+// 其他会话提交的令牌按不存在处理，也不会被作废
+func (s *confirmationStore) consume(token, session, tool, fingerprint, targets string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.purgeLocked()
+	p, ok := s.pending[token]
+	if !ok || p.session != session {
+		return models.ArgsError{{Arg: confirmTokenArg, Problem: "无效或已过期，请不带 confirm_token 重新发起调用获取新令牌"}}
+	}
+	if p.tool != tool || p.fingerprint != fingerprint {
+		return models.ArgsError{{Arg: confirmTokenArg, Problem: "与本次调用的工具或参数不匹配，请使用首次调用时完全相同的参数"}}
+	}
+	delete(s.pending, token)
+	if p.targets != targets {
+		return models.Errorf(models.CodeConflict, "预览之后 %s 匹配的对象已发生变化，未执行任何变更", tool).
+			WithHint("不带 confirm_token 重新调用以查看新的变更预览，确认后使用新令牌")
+	}
+	return nil
+}
+
+func (s *confirmationStore) purgeLocked() {
+	now := time.Now()
+	for k, p := range s.pending {
+		if now.After(p.expiresAt) {
+			delete(s.pending, k)
+		}
+	}
+}
+
+// argsFingerprint 计算除确认令牌与 dry_run 外全部参数的指纹，保证确认的正是预览的那次调用
+func argsFingerprint(args map[string]interface{}) string {
+	filtered := make(map[string]interface{}, len(args))
+	for k, v := range args {
+		if k == confirmTokenArg || k == "dry_run" {
+			continue
+		}
+		filtered[k] = v
+	}
+	data, _ := json.Marshal(filtered) // map 按 key 排序序列化，结果稳定
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// targetsFingerprint 计算目标对象的指纹，targets 为 nil 时返回空串
+func targetsFingerprint(targets interface{}) string {
+	if targets == nil {
+		return ""
+	}
+	data, _ := json.Marshal(targets)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// confirmMutation 在执行破坏性操作前进行确认。
+// 返回非 nil 的结果时，调用方应直接将其返回给客户端而不执行变更；
+// 返回 (nil, nil) 表示已确认（或无需确认），可以继续执行。
+// plan 仅在需要确认时才会被调用，用于向用户展示将要发生的变更。
+func confirmMutation(ctx context.Context, req mcp.CallToolRequest, tool string, objects int, plan func() (*models.MutationPlan, error)) (*mcp.CallToolResult, error) {
+	return confirmMutationFor(ctx, req, tool, objects, nil, plan)
+}
+
+// confirmMutationFor 同 confirmMutation，参数之外还把按参数解析出的目标对象 targets 记入令牌：
+// 参数相同但预览与确认之间匹配到的对象不同（例如按姓名或邮箱查找账号）时拒绝执行
+func confirmMutationFor(ctx context.Context, req mcp.CallToolRequest, tool string, objects int, targets interface{}, plan func() (*models.MutationPlan, error)) (*mcp.CallToolResult, error) {
+	policy := confirmPolicy
+	if !policy.requires(tool, objects) {
+		return nil, nil
+	}
+	args := req.GetArguments()
+	session := sessionID(ctx)
+	fingerprint := argsFingerprint(args)
+	targetPrint := targetsFingerprint(targets)
+	if token, _ := args[confirmTokenArg].(string); token != "" {
+		if err := confirmations.consume(token, session, tool, fingerprint, targetPrint); err != nil {
+			return nil, err
+		}
+		logger.L().Infof("工具 %s 已通过确认令牌确认", tool)
+		return nil, nil
+	}
+
+	summary, err := plan()
+	if err != nil {
+		return nil, fmt.Errorf("生成变更预览失败: %w", err)
+	}
+
+	if policy.Elicitation {
+		confirmed, err := elicitConfirmation(ctx, tool, summary)
+		switch {
+		case err == nil && confirmed:
+			logger.L().Infof("工具 %s 已通过 elicitation 确认", tool)
+			return nil, nil
+		case err == nil:
+			return mcp.NewToolResultStructuredOnly(makeResult(map[string]interface{}{
+				"cancelled": true,
+				"message":   "用户拒绝了该操作，未执行任何变更",
+				"plan":      summary,
+			})), nil
+		case errors.Is(err, mcpserver.ErrElicitationNotSupported), errors.Is(err, mcpserver.ErrNoActiveSession):
+			// 客户端不支持 elicitation，退回令牌方式
+		default:
+			logger.L().Warnf("elicitation 请求失败，退回确认令牌方式: %v", err)
+		}
+	}
+
+	token, expiresAt, err := confirmations.issue(session, tool, fingerprint, targetPrint, policy.TokenTTL)
+	if err != nil {
+		return nil, fmt.Errorf("生成确认令牌失败: %w", err)
+	}
+	return mcp.NewToolResultStructuredOnly(makeResult(map[string]interface{}{
+		"confirmation_required": true,
+		"confirm_token":         token,
+		"expires_at":            expiresAt,
+		"message":               fmt.Sprintf("%s 为破坏性操作，尚未执行。请向用户确认以下变更后，使用相同参数并附带 confirm_token 再次调用", tool),
+		"plan":                  summary,
+	})), nil
+}
+
+// sessionID 返回当前 MCP 会话ID，没有会话（例如直接调用处理器）时为空串
+func sessionID(ctx context.Context) string {
+	if session := mcpserver.ClientSessionFromContext(ctx); session != nil {
+		return session.SessionID()
+	}
+	return ""
+}
+
+// elicitConfirmation 通过 MCP elicitation 直接询问用户是否继续
+func elicitConfirmation(ctx context.Context, tool string, plan *models.MutationPlan) (bool, error) {
+	srv := mcpserver.ServerFromContext(ctx)
+	if srv == nil {
+		return false, mcpserver.ErrNoActiveSession
+	}
+	detail, _ := json.MarshalIndent(plan, "", "  ")
+	result, err := srv.RequestElicitation(ctx, mcp.ElicitationRequest{
+		Params: mcp.ElicitationParams{
+			Message: fmt.Sprintf("即将在实例 %s 上执行 %s（%s），影响 %d 个对象：\n%s\n是否继续？",
+				plan.Instance, tool, plan.Method, len(plan.Affected), detail),
+			RequestedSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"confirm": map[string]interface{}{
+						"type":        "boolean",
+						"description": "确认执行该操作",
+					},
+				},
+				"required": []string{"confirm"},
+			},
+		},
+	})
+	if err != nil {
+		return false, err
+	}
+	if result.Action != mcp.ElicitationResponseActionAccept {
+		return false, nil
+	}
+	content, _ := result.Content.(map[string]interface{})
+	confirmed, _ := content["confirm"].(bool)
+	return confirmed, nil
+}
@@ -0,0 +1,232 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"zabbixMcp/handler"
+	"zabbixMcp/register"
+	"zabbixMcp/zabbix/zabbixtest"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	mcpserver "github.com/mark3labs/mcp-go/server"
+)
+
+// plainSession 不支持 elicitation 的客户端会话
+type plainSession struct {
+	id string
+	ch chan mcp.JSONRPCNotification
+}
+
+func (s *plainSession) SessionID() string                                   { return s.id }
+func (s *plainSession) NotificationChannel() chan<- mcp.JSONRPCNotification { return s.ch }
+func (s *plainSession) Initialize()                                         {}
+func (s *plainSession) Initialized() bool                                   { return true }
+
+func newPlainSession(id string) *plainSession {
+	return &plainSession{id: id, ch: make(chan mcp.JSONRPCNotification, 10)}
+}
+
+// elicitAnswer 以固定的回答响应 elicitation 请求，并记录被询问的次数
+type elicitAnswer struct {
+	action  mcp.ElicitationResponseAction
+	confirm bool
+	asked   int
+}
+
+func (a *elicitAnswer) Elicit(context.Context, mcp.ElicitationRequest) (*mcp.ElicitationResult, error) {
+	a.asked++
+	return &mcp.ElicitationResult{ElicitationResponse: mcp.ElicitationResponse{
+		Action:  a.action,
+		Content: map[string]interface{}{"confirm": a.confirm},
+	}}, nil
+}
+
+// confirmTester 通过 MCP 服务器以指定会话调用工具
+type confirmTester struct {
+	t   *testing.T
+	s   *mcpserver.MCPServer
+	srv *zabbixtest.Server
+	n   int
+}
+
+func newConfirmTester(t *testing.T, policy handler.ConfirmationPolicy) *confirmTester {
+	t.Helper()
+	srv := testServer(t)
+	handler.SetConfirmationPolicy(policy)
+	s := mcpserver.NewMCPServer("test", "1.0.0", mcpserver.WithElicitation())
+	register.Registers(s)
+	return &confirmTester{t: t, s: s, srv: srv}
+}
+
+// call 以 session 身份调用工具，返回结构化结果中的 data 与 JSON-RPC 错误信息
+func (ct *confirmTester) call(session mcpserver.ClientSession, tool string, args map[string]interface{}) (map[string]interface{}, string) {
+	ct.t.Helper()
+	ct.n++
+	msg, _ := json.Marshal(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      ct.n,
+		"method":  mcp.MethodToolsCall,
+		"params":  map[string]any{"name": tool, "arguments": args},
+	})
+	switch resp := ct.s.HandleMessage(ct.s.WithContext(context.Background(), session), msg).(type) {
+	case mcp.JSONRPCResponse:
+		raw, _ := json.Marshal(resp.Result)
+		var result struct {
+			IsError           bool `json:"isError"`
+			StructuredContent struct {
+				Data map[string]interface{} `json:"data"`
+			} `json:"structuredContent"`
+		}
+		if err := json.Unmarshal(raw, &result); err != nil {
+			ct.t.Fatal(err)
+		}
+		if result.IsError {
+			return nil, string(raw)
+		}
+		return result.StructuredContent.Data, ""
+	case mcp.JSONRPCError:
+		return nil, resp.Error.Message
+	default:
+		ct.t.Fatalf("%s: 意外的响应 %#v", tool, resp)
+		return nil, ""
+	}
+}
+
+func (ct *confirmTester) addUser(username string) string {
+	return ct.srv.Store().AddUser(zabbixtest.User{Username: username, RoleID: "1", GroupIDs: []string{"8"}})
+}
+
+func (ct *confirmTester) userExists(id string) bool {
+	_, ok := ct.srv.Store().User(id)
+	return ok
+}
+
+// TestConfirmTokenFlow 令牌只能由签发它的会话、以相同参数使用一次
+func TestConfirmTokenFlow(t *testing.T) {
+	ct := newConfirmTester(t, handler.ConfirmationPolicy{Enabled: true, Tools: []string{"delete_user"}, TokenTTL: time.Minute})
+	a, b := newPlainSession("confirm-a"), newPlainSession("confirm-b")
+	userID := ct.addUser("token-flow")
+	other := ct.addUser("token-flow-other")
+	args := map[string]interface{}{"instance": "zbx", "userids": []interface{}{userID}}
+
+	preview, msg := ct.call(a, "delete_user", args)
+	if msg != "" {
+		t.Fatal(msg)
+	}
+	token, _ := preview["confirm_token"].(string)
+	if token == "" || preview["confirmation_required"] != true {
+		t.Fatalf("首次调用应返回确认令牌: %v", preview)
+	}
+	if !ct.userExists(userID) {
+		t.Fatal("确认之前不应执行删除")
+	}
+
+	confirmed := map[string]interface{}{"instance": "zbx", "userids": []interface{}{userID}, "confirm_token": token}
+	if _, msg := ct.call(b, "delete_user", confirmed); !strings.Contains(msg, "无效或已过期") {
+		t.Errorf("其他会话使用令牌: 错误 %q，期望拒绝", msg)
+	}
+	changed := map[string]interface{}{"instance": "zbx", "userids": []interface{}{other}, "confirm_token": token}
+	if _, msg := ct.call(a, "delete_user", changed); !strings.Contains(msg, "不匹配") {
+		t.Errorf("更换参数后使用令牌: 错误 %q，期望拒绝", msg)
+	}
+	if !ct.userExists(userID) || !ct.userExists(other) {
+		t.Fatal("被拒绝的确认不应执行删除")
+	}
+
+	if _, msg := ct.call(a, "delete_user", confirmed); msg != "" {
+		t.Fatalf("签发会话使用令牌确认失败: %s", msg)
+	}
+	if ct.userExists(userID) {
+		t.Error("确认后用户应已删除")
+	}
+	if _, msg := ct.call(a, "delete_user", confirmed); !strings.Contains(msg, "无效或已过期") {
+		t.Errorf("重复使用令牌: 错误 %q，期望拒绝", msg)
+	}
+}
+
+func TestConfirmTokenExpires(t *testing.T) {
+	ct := newConfirmTester(t, handler.ConfirmationPolicy{Enabled: true, Tools: []string{"delete_user"}, TokenTTL: 10 * time.Millisecond})
+	session := newPlainSession("confirm-expire")
+	userID := ct.addUser("token-expire")
+	args := map[string]interface{}{"instance": "zbx", "userids": []interface{}{userID}}
+
+	preview, msg := ct.call(session, "delete_user", args)
+	if msg != "" {
+		t.Fatal(msg)
+	}
+	time.Sleep(20 * time.Millisecond)
+	args["confirm_token"] = preview["confirm_token"]
+	if _, msg := ct.call(session, "delete_user", args); !strings.Contains(msg, "无效或已过期") {
+		t.Errorf("过期令牌: 错误 %q，期望拒绝", msg)
+	}
+	if !ct.userExists(userID) {
+		t.Error("过期令牌不应执行删除")
+	}
+}
+
+// TestConfirmElicitation 支持 elicitation 的客户端直接询问用户，不支持时退回确认令牌
+func TestConfirmElicitation(t *testing.T) {
+	ct := newConfirmTester(t, handler.ConfirmationPolicy{Enabled: true, Tools: []string{"disable_user"}, TokenTTL: time.Minute, Elicitation: true})
+	disabled := func(id string) bool {
+		u, _ := ct.srv.Store().User(id)
+		return slices.Contains(u.GroupIDs, "9")
+	}
+
+	for _, c := range []struct {
+		name   string
+		answer elicitAnswer
+		done   bool
+	}{
+		{"同意", elicitAnswer{action: mcp.ElicitationResponseActionAccept, confirm: true}, true},
+		{"未勾选确认", elicitAnswer{action: mcp.ElicitationResponseActionAccept, confirm: false}, false},
+		{"拒绝", elicitAnswer{action: mcp.ElicitationResponseActionDecline}, false},
+		{"取消", elicitAnswer{action: mcp.ElicitationResponseActionCancel}, false},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			answer := c.answer
+			session := mcpserver.NewInProcessSessionWithHandlers("confirm-elicit-"+c.name, nil, &answer, nil)
+			userID := ct.addUser("elicit-" + string(answer.action) + "-" + c.name)
+			data, msg := ct.call(session, "disable_user", map[string]interface{}{"instance": "zbx", "userid": userID})
+			if msg != "" {
+				t.Fatal(msg)
+			}
+			if answer.asked != 1 {
+				t.Errorf("elicitation 询问次数 = %d，期望 1", answer.asked)
+			}
+			if _, ok := data["confirm_token"]; ok {
+				t.Errorf("支持 elicitation 时不应签发确认令牌: %v", data)
+			}
+			if disabled(userID) != c.done {
+				t.Errorf("用户已禁用 = %v，期望 %v", disabled(userID), c.done)
+			}
+			if !c.done && data["cancelled"] != true {
+				t.Errorf("用户未确认时应返回 cancelled: %v", data)
+			}
+		})
+	}
+
+	t.Run("不支持时退回令牌", func(t *testing.T) {
+		session := newPlainSession("confirm-elicit-fallback")
+		userID := ct.addUser("elicit-fallback")
+		args := map[string]interface{}{"instance": "zbx", "userid": userID}
+		preview, msg := ct.call(session, "disable_user", args)
+		if msg != "" {
+			t.Fatal(msg)
+		}
+		if preview["confirm_token"] == nil || disabled(userID) {
+			t.Fatalf("不支持 elicitation 时应返回确认令牌且不执行: %v", preview)
+		}
+		args["confirm_token"] = preview["confirm_token"]
+		if _, msg := ct.call(session, "disable_user", args); msg != "" {
+			t.Fatalf("使用令牌确认失败: %s", msg)
+		}
+		if !disabled(userID) {
+			t.Error("确认后用户应已禁用")
+		}
+	})
+}
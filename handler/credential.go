@@ -0,0 +1,42 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2026-01-05 16:05:43
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2026-01-05 16:05:43
+ * @FilePath: \zabbix-mcp-go\handler\credential.go
+ * @Description: 生成密码的投递策略：工具结果中只返回投递回执
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+package handler
+
+import (
+	"context"
+
+	"zabbixMcp/credential"
+	"zabbixMcp/logger"
+)
+
+// CredentialPolicy 生成密码的投递策略
+type CredentialPolicy struct {
+	Deliverer credential.Deliverer // 为 nil 时密码被丢弃，回执中说明原因
+	MediaType string               // create_user 的 email 参数使用的邮件媒介类型名称或ID
+}
+
+// credentialPolicy 由 main 按 config.yml 的 credentials 段（缺省值见 defaultConfig）注入，未注入时密码被丢弃
+var credentialPolicy CredentialPolicy
+
+// SetCredentialPolicy 注入密码投递策略
+func SetCredentialPolicy(p CredentialPolicy) {
+	credentialPolicy = p
+}
+
+// deliverPassword 按策略投递密码，返回放入工具结果的回执
+func deliverPassword(ctx context.Context, c credential.Credential) *credential.Receipt {
+	receipt := credential.Deliver(ctx, credentialPolicy.Deliverer, c)
+	if receipt.Error != "" {
+		logger.L().Warnf("投递实例 %s 用户 %s 的密码失败: %s", c.Instance, c.Username, receipt.Error)
+	} else {
+		logger.L().Infof("已投递实例 %s 用户 %s 的密码: %s", c.Instance, c.Username, receipt.Method)
+	}
+	return receipt
+}
@@ -0,0 +1,53 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-27 11:26:03
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-27 11:26:03
+ * @FilePath: \zabbix-mcp-go\handler\errors.go
+ * @Description: 将处理器返回的错误转换为结构化的 isError 工具结果
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+package handler
+
+import (
+	"context"
+	"fmt"
+
+	"zabbixMcp/logger"
+	"zabbixMcp/zabbix"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	mcpserver "github.com/mark3labs/mcp-go/server"
+)
+
+// ErrorMiddleware 将处理器返回的 error 转换为 isError 的工具结果，
+// 结构化内容为 {"ok": false, "error": models.Error}，文本内容附带修正建议。
+// 应注册为最外层中间件，内层的追踪、审计、指标中间件仍能看到原始 error。
+func ErrorMiddleware() mcpserver.ToolHandlerMiddleware {
+	return func(next mcpserver.ToolHandlerFunc) mcpserver.ToolHandlerFunc {
+		return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			result, err := next(ctx, req)
+			if err == nil {
+				return result, nil
+			}
+			return toolErrorResult(req, err), nil
+		}
+	}
+}
+
+func toolErrorResult(req mcp.CallToolRequest, err error) *mcp.CallToolResult {
+	e := zabbix.ClassifyError(err)
+	if e.Instance == "" {
+		e.Instance, _ = req.GetArguments()["instance"].(string)
+	}
+	logger.L().Warnf("工具 %s 调用失败 [%s]: %v", req.Params.Name, e.Code, err)
+	text := fmt.Sprintf("[%s] %s", e.Code, e.Message)
+	if e.Hint != "" {
+		text += "\n提示: " + e.Hint
+	}
+	return &mcp.CallToolResult{
+		Content:           []mcp.Content{mcp.NewTextContent(text)},
+		StructuredContent: map[string]interface{}{"ok": false, "error": e},
+		IsError:           true,
+	}
+}
@@ -0,0 +1,29 @@
+package handler_test
+
+import (
+	"sync"
+	"testing"
+
+	"zabbixMcp/handler"
+	"zabbixMcp/zabbix/zabbixtest"
+)
+
+var (
+	sharedOnce   sync.Once
+	sharedServer *zabbixtest.Server
+)
+
+// testServer 返回所有处理器测试共用的模拟服务器（实例名 zbx）；客户端池只注入一次，
+// 订阅等后台任务可能在测试结束后仍会读取它
+func testServer(t *testing.T) *zabbixtest.Server {
+	t.Helper()
+	sharedOnce.Do(func() {
+		sharedServer = zabbixtest.NewServer(zabbixtest.Options{Instance: "zbx"})
+		provider, err := zabbixtest.NewProvider(sharedServer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		handler.SetClientPool(provider)
+	})
+	return sharedServer
+}
@@ -11,25 +11,28 @@ package handler
 
 import (
 	"context"
-	"fmt"
 
 	"zabbixMcp/models"
-	"zabbixMcp/server"
 
 	"github.com/mark3labs/mcp-go/mcp"
 )
 
-// GetHostsHandler 通过注入的 ClientProvider 调用 host.get 并返回结果
+// GetHostsHandler 通过注入的 ClientProvider 分页调用 host.get 并返回结果
 func GetHostsHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var args models.GetHostsArgs
+	if err := bindArgs(req, &args); err != nil {
+		return nil, err
+	}
+	ctx = withRawOption(ctx, args.Raw)
+	ctx = withCacheOption(ctx, args.Cache)
 	if clientPool == nil {
 		return mcp.NewToolResultStructuredOnly(makeResult([]map[string]interface{}{})), nil
 	}
-	spec := models.HostGetParams{Output: "extend"}
-	hosts, err := server.GetHosts(ctx, clientPool, spec)
-	if err != nil {
-		return nil, fmt.Errorf("调用 host.get 失败: %w", err)
+	spec := models.HostGetParams{Output: "extend", GroupIDs: args.GroupIDs}
+	if args.Name != "" {
+		spec.Search = map[string]interface{}{"name": args.Name}
 	}
-	return mcp.NewToolResultStructuredOnly(makeResult(hosts)), nil
+	return getPaged[models.Host](ctx, "get_hosts", args.Instance, "host.get", spec, args.PageArgs, args.Raw)
 }
 
 // 通过主机组查询
@@ -13,6 +13,7 @@ import (
 	"context"
 
 	"zabbixMcp/logger"
+	"zabbixMcp/models"
 	"zabbixMcp/server"
 	"zabbixMcp/zabbix"
 
@@ -22,19 +23,16 @@ import (
 // GetInstancesInfoHandler 返回池中所有实例的信息
 // 签名为 mcp 工具处理器，返回可序列化的结构或错误
 func GetInstancesInfoHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	instanceName := ""
-	// req.Params.Arguments may be typed as interface{}; try to assert to a map first
-	if args, ok := req.Params.Arguments.(map[string]interface{}); ok {
-		if v, ok2 := args["instance"].(string); ok2 {
-			instanceName = v
-		}
+	var args models.GetInstancesInfoArgs
+	if err := bindArgs(req, &args); err != nil {
+		return nil, err
 	}
 	if clientPool == nil {
 		// 返回统一包装的空列表，便于客户端解析
 		logger.Info("GetInstancesInfoHandler: clientPool is nil", nil, nil)
 		return mcp.NewToolResultStructuredOnly(makeResult([]zabbix.ClientInfo{})), nil
 	}
-	infos, err := server.GetInstancesInfo(ctx, clientPool, instanceName)
+	infos, err := server.GetInstancesInfo(ctx, clientPool, args.Instance)
 	if err != nil {
 		logger.L().Errorf("获取实例信息失败: %v", err)
 		return nil, err
@@ -0,0 +1,437 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-28 14:20:37
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-28 14:20:37
+ * @FilePath: \zabbix-mcp-go\handler\output.go
+ * @Description: 所有工具共用的输出层：字段裁剪、表格格式、条数/大小预算与摘要
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"zabbixMcp/models"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	mcpserver "github.com/mark3labs/mcp-go/server"
+)
+
+// OutputPolicy 输出层的默认预算
+type OutputPolicy struct {
+	MaxTokens int // 未指定 max_tokens 时的大小预算，0 表示不限制
+}
+
+// outputPolicy 由 main 按 config.yml 的 output 段（缺省值见 defaultConfig）注入
+var outputPolicy OutputPolicy
+
+// SetOutputPolicy 注入输出预算
+func SetOutputPolicy(p OutputPolicy) {
+	outputPolicy = p
+}
+
+// bytesPerToken 估算 token 数时每个 token 对应的字节数
+const bytesPerToken = 4
+
+// maxCellRunes 表格单元格的最大字符数，超出部分以省略号代替
+const maxCellRunes = 80
+
+type outputKey struct{}
+
+// outputState 输出中间件与处理器之间共享的信息
+type outputState struct {
+	args models.OutputArgs
+	// resume 分页结果在第 index 条之后被截断时，返回从该条之后继续翻页的游标
+	resume func(index int) string
+	// loc 结果中时间的 iso 换算到的时区（instance 参数对应实例的 server_tz）
+	loc *time.Location
+}
+
+func outputStateFrom(ctx context.Context) *outputState {
+	st, _ := ctx.Value(outputKey{}).(*outputState)
+	return st
+}
+
+// OutputMiddleware 从工具参数中取出 fields/format/max_items/max_tokens/summary（处理器看不到这些参数），
+// 并按它们裁剪处理器返回的结构化结果。应注册在 ErrorMiddleware 之内，参数错误同样返回 isError 结果。
+func OutputMiddleware() mcpserver.ToolHandlerMiddleware {
+	props, _, err := models.ArgsSchema(models.OutputArgs{})
+	if err != nil {
+		panic(fmt.Sprintf("生成输出参数 schema 失败: %v", err))
+	}
+	return func(next mcpserver.ToolHandlerFunc) mcpserver.ToolHandlerFunc {
+		return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			picked := map[string]interface{}{}
+			rest := map[string]interface{}{}
+			for k, v := range req.GetArguments() {
+				if _, ok := props[k]; ok {
+					picked[k] = v
+				} else {
+					rest[k] = v
+				}
+			}
+			var args models.OutputArgs
+			if err := models.DecodeArgs(picked, &args); err != nil {
+				return nil, err
+			}
+			if args.MaxItems < 0 {
+				return nil, models.ArgsError{{Arg: "max_items", Problem: "不能为负数"}}
+			}
+			if args.MaxTokens < 0 {
+				return nil, models.ArgsError{{Arg: "max_tokens", Problem: "不能为负数"}}
+			}
+			if args.MaxTokens == 0 {
+				args.MaxTokens = outputPolicy.MaxTokens
+			}
+			req.Params.Arguments = rest
+			instance, _ := rest["instance"].(string)
+			st := &outputState{args: args, loc: instanceLocation(instance)}
+			result, err := next(context.WithValue(ctx, outputKey{}, st), req)
+			if err != nil || result == nil || result.IsError {
+				return result, err
+			}
+			return shapeResult(req.Params.Name, result, st), nil
+		}
+	}
+}
+
+// shapeResult 按输出参数改写结果并把时间换算到实例时区；未要求裁剪、未超出预算且时区为本地时区时原样返回
+func shapeResult(tool string, result *mcp.CallToolResult, st *outputState) *mcp.CallToolResult {
+	if result.StructuredContent == nil {
+		return result
+	}
+	data, err := json.Marshal(result.StructuredContent)
+	if err != nil {
+		return result
+	}
+	args := st.args
+	budget := args.MaxTokens * bytesPerToken
+	if len(args.Fields) == 0 && args.Format == "json" && !args.Summary && args.MaxItems == 0 &&
+		(budget == 0 || len(data) <= budget) && (st.loc == nil || st.loc == time.Local) {
+		return result
+	}
+	var m map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&m); err != nil {
+		return result
+	}
+	localize := st.loc != nil && st.loc != time.Local
+	if localize {
+		localizeTimes(m, st.loc)
+	}
+
+	// 结果列表：data，raw 模式下为 raw；对象结果按单条处理
+	key := "data"
+	items, isList := m["data"].([]interface{})
+	if raw, ok := m["raw"].([]interface{}); ok && len(items) == 0 {
+		key, items, isList = "raw", raw, true
+	}
+	if !isList {
+		if m["data"] == nil {
+			if localize {
+				return structuredResult(result, m)
+			}
+			return result
+		}
+		items = []interface{}{m["data"]}
+	}
+	total := len(items)
+
+	shaped := items
+	if len(args.Fields) > 0 {
+		shaped = make([]interface{}, len(items))
+		for i, item := range items {
+			shaped[i] = projectFields(item, args.Fields)
+		}
+	}
+
+	// 先按条数，再按大小预算确定保留的条数，至少保留一条
+	keep := total
+	if args.MaxItems > 0 && keep > args.MaxItems {
+		keep = args.MaxItems
+	}
+	if budget > 0 {
+		overhead := len(data)
+		for _, item := range items {
+			size, _ := json.Marshal(item)
+			overhead -= len(size) + 1
+		}
+		used := overhead
+		for i := 0; i < keep; i++ {
+			size, _ := json.Marshal(shaped[i])
+			used += len(size) + 1
+			if used > budget && i > 0 {
+				keep = i
+				break
+			}
+		}
+	}
+
+	if args.Summary || keep < total {
+		if summary := summarize(tool, items); summary != nil && isList {
+			m["summary"] = summary
+		}
+	}
+	kept := shaped[:keep]
+	switch {
+	case len(args.Fields) > 0:
+		m["items"] = kept
+		m[key] = []interface{}{}
+	case isList:
+		m[key] = kept
+	}
+	if keep < total {
+		truncation := models.Truncation{Omitted: total - keep, Total: total}
+		if page, ok := m["page"].(map[string]interface{}); ok && st.resume != nil {
+			page["has_more"] = true
+			page["next_cursor"] = st.resume(keep - 1)
+			page["returned"] = keep
+			truncation.Message = fmt.Sprintf("还有 %d 条已省略，使用 page.next_cursor 继续获取", total-keep)
+		} else {
+			truncation.Message = fmt.Sprintf("还有 %d 条已省略，请缩小查询范围、用 fields 只取需要的字段或提高 max_items/max_tokens", total-keep)
+		}
+		m["truncated"] = truncation
+	}
+
+	if args.Format != "table" && args.Format != "markdown" {
+		return structuredResult(result, m)
+	}
+	return &mcp.CallToolResult{
+		Result:            result.Result,
+		Content:           []mcp.Content{mcp.NewTextContent(renderText(m, kept, args))},
+		StructuredContent: m,
+	}
+}
+
+// structuredResult 以改写后的结构化结果替换原结果
+func structuredResult(result *mcp.CallToolResult, m map[string]interface{}) *mcp.CallToolResult {
+	out, err := json.Marshal(m)
+	if err != nil {
+		return result
+	}
+	return &mcp.CallToolResult{
+		Result:            result.Result,
+		Content:           []mcp.Content{mcp.NewTextContent(string(out))},
+		StructuredContent: m,
+	}
+}
+
+// projectFields 只保留 fields 指定的字段；a.b 途经数组时对每个元素生效
+func projectFields(item interface{}, fields []string) interface{} {
+	out := map[string]interface{}{}
+	for _, f := range fields {
+		if v, ok := pickPath(item, strings.Split(f, ".")); ok {
+			if picked, ok := v.(map[string]interface{}); ok {
+				mergeInto(out, picked)
+			}
+		}
+	}
+	return out
+}
+
+// pickPath 返回只包含 path 的裁剪结构（顶层为对象）
+func pickPath(v interface{}, path []string) (interface{}, bool) {
+	if len(path) == 0 {
+		return v, true
+	}
+	switch val := v.(type) {
+	case map[string]interface{}:
+		child, ok := val[path[0]]
+		if !ok {
+			return nil, false
+		}
+		picked, ok := pickPath(child, path[1:])
+		if !ok {
+			return nil, false
+		}
+		return map[string]interface{}{path[0]: picked}, true
+	case []interface{}:
+		out := make([]interface{}, 0, len(val))
+		for _, elem := range val {
+			if picked, ok := pickPath(elem, path); ok {
+				out = append(out, picked)
+			} else {
+				out = append(out, map[string]interface{}{})
+			}
+		}
+		return out, true
+	}
+	return nil, false
+}
+
+// mergeInto 合并两个裁剪结构，使 a.b 与 a.c 落在同一个对象（或同一数组的对应元素）中
+func mergeInto(dst, src map[string]interface{}) {
+	for k, v := range src {
+		existing, ok := dst[k]
+		if !ok {
+			dst[k] = v
+			continue
+		}
+		switch ev := existing.(type) {
+		case map[string]interface{}:
+			if sv, ok := v.(map[string]interface{}); ok {
+				mergeInto(ev, sv)
+			}
+		case []interface{}:
+			if sv, ok := v.([]interface{}); ok && len(sv) == len(ev) {
+				for i := range ev {
+					em, ok1 := ev[i].(map[string]interface{})
+					sm, ok2 := sv[i].(map[string]interface{})
+					if ok1 && ok2 {
+						mergeInto(em, sm)
+					}
+				}
+			}
+		}
+	}
+}
+
+// valuesAt 取 path 对应的所有取值，途经数组时展开
+func valuesAt(v interface{}, path []string) []interface{} {
+	if len(path) == 0 {
+		if list, ok := v.([]interface{}); ok {
+			return list
+		}
+		return []interface{}{v}
+	}
+	switch val := v.(type) {
+	case map[string]interface{}:
+		child, ok := val[path[0]]
+		if !ok {
+			return nil
+		}
+		return valuesAt(child, path[1:])
+	case []interface{}:
+		var out []interface{}
+		for _, elem := range val {
+			out = append(out, valuesAt(elem, path)...)
+		}
+		return out
+	}
+	return nil
+}
+
+// renderText 以表格输出本页条目，并附上截断说明、摘要与翻页游标
+func renderText(m map[string]interface{}, rows []interface{}, args models.OutputArgs) string {
+	columns := args.Fields
+	if len(columns) == 0 {
+		columns = columnsOf(rows)
+	}
+	cells := make([][]string, len(rows))
+	for i, row := range rows {
+		cells[i] = make([]string, len(columns))
+		for j, col := range columns {
+			cells[i][j] = cellText(valuesAt(row, strings.Split(col, ".")))
+		}
+	}
+
+	var b strings.Builder
+	switch {
+	case len(columns) == 0:
+	case args.Format == "markdown":
+		b.WriteString("| " + strings.Join(escapeMarkdown(columns), " | ") + " |\n")
+		b.WriteString("|" + strings.Repeat(" --- |", len(columns)) + "\n")
+		for _, row := range cells {
+			b.WriteString("| " + strings.Join(escapeMarkdown(row), " | ") + " |\n")
+		}
+	default:
+		b.WriteString(strings.Join(columns, "\t") + "\n")
+		for _, row := range cells {
+			b.WriteString(strings.Join(row, "\t") + "\n")
+		}
+	}
+	if t, ok := m["truncated"].(models.Truncation); ok {
+		fmt.Fprintf(&b, "... %s\n", t.Message)
+	}
+	if summary, ok := m["summary"]; ok {
+		data, _ := json.Marshal(summary)
+		fmt.Fprintf(&b, "摘要: %s\n", data)
+	}
+	if page, ok := m["page"].(map[string]interface{}); ok {
+		if cursor, _ := page["next_cursor"].(string); cursor != "" {
+			fmt.Fprintf(&b, "共 %v 条，下一页 cursor: %s\n", page["total"], cursor)
+		} else if page["total"] != nil {
+			fmt.Fprintf(&b, "共 %v 条\n", page["total"])
+		}
+	}
+	return b.String()
+}
+
+// columnsOf 未指定 fields 时使用所有条目的字段并集：ID 字段在前，其余按字母排序
+func columnsOf(rows []interface{}) []string {
+	seen := map[string]bool{}
+	var ids, others []string
+	for _, row := range rows {
+		obj, ok := row.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for k := range obj {
+			if seen[k] {
+				continue
+			}
+			seen[k] = true
+			if strings.HasSuffix(k, "id") {
+				ids = append(ids, k)
+			} else {
+				others = append(others, k)
+			}
+		}
+	}
+	sort.Strings(ids)
+	sort.Strings(others)
+	return append(ids, others...)
+}
+
+// cellText 将取值压缩为单元格文本：对象数组优先显示名称，时间显示 iso，其余以紧凑 JSON 显示
+func cellText(values []interface{}) string {
+	parts := make([]string, 0, len(values))
+	for _, v := range values {
+		switch val := v.(type) {
+		case nil:
+			continue
+		case string:
+			parts = append(parts, val)
+		case map[string]interface{}:
+			if name := displayName(val); name != "" {
+				parts = append(parts, name)
+				continue
+			}
+			data, _ := json.Marshal(val)
+			parts = append(parts, string(data))
+		default:
+			parts = append(parts, fmt.Sprint(val))
+		}
+	}
+	text := strings.Join(parts, ",")
+	text = strings.NewReplacer("\t", " ", "\n", " ", "\r", " ").Replace(text)
+	if r := []rune(text); len(r) > maxCellRunes {
+		text = string(r[:maxCellRunes-1]) + "…"
+	}
+	return text
+}
+
+func displayName(obj map[string]interface{}) string {
+	for _, key := range []string{"name", "username", "host", "tag", "iso"} {
+		if s, ok := obj[key].(string); ok && s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+func escapeMarkdown(cells []string) []string {
+	out := make([]string, len(cells))
+	for i, c := range cells {
+		out[i] = strings.ReplaceAll(c, "|", `\|`)
+	}
+	return out
+}
@@ -0,0 +1,108 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-28 10:25:47
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-28 10:25:47
+ * @FilePath: \zabbix-mcp-go\handler\page.go
+ * @Description: *.get 类工具的分页与计数
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"zabbixMcp/models"
+	"zabbixMcp/server"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// PaginationPolicy 每页条数的默认值与上限
+type PaginationPolicy struct {
+	DefaultLimit int
+	MaxLimit     int
+	Tools        map[string]int // 按工具覆盖默认条数
+}
+
+// paginationPolicy 由 main 按 config.yml 的 pagination 段（缺省值见 defaultConfig）注入
+var paginationPolicy PaginationPolicy
+
+// SetPaginationPolicy 注入分页策略
+func SetPaginationPolicy(p PaginationPolicy) {
+	paginationPolicy = p
+}
+
+// pageRequest 按工具的默认条数与上限解析分页参数
+func pageRequest(tool string, args models.PageArgs) (models.PageRequest, error) {
+	policy := paginationPolicy
+	limit := args.Limit
+	switch {
+	case limit < 0:
+		return models.PageRequest{}, models.ArgsError{{Arg: "limit", Problem: "不能为负数"}}
+	case limit == 0:
+		limit = policy.DefaultLimit
+		if n, ok := policy.Tools[tool]; ok && n > 0 {
+			limit = n
+		}
+	case policy.MaxLimit > 0 && limit > policy.MaxLimit:
+		return models.PageRequest{}, models.ArgsError{{Arg: "limit", Problem: fmt.Sprintf("不能超过 %d，更多结果请通过 cursor 翻页", policy.MaxLimit)}}
+	}
+	return models.PageRequest{Limit: limit, Cursor: args.Cursor}, nil
+}
+
+// getPaged *.get 类工具的公共流程：count_only 只返回数量，否则返回一页结果（raw 模式放在 raw 字段）与分页信息
+func getPaged[T any](ctx context.Context, tool, instance, method string, spec models.ParamSpec, args models.PageArgs, raw bool) (*mcp.CallToolResult, error) {
+	page, err := pageRequest(tool, args)
+	if err != nil {
+		return nil, err
+	}
+	// max_items 小于每页条数时直接少取，避免截断
+	st := outputStateFrom(ctx)
+	if st != nil && st.args.MaxItems > 0 && st.args.MaxItems < page.Limit {
+		page.Limit = st.args.MaxItems
+	}
+	if args.CountOnly {
+		total, err := server.Count(ctx, clientPool, instance, method, spec)
+		if err != nil {
+			return nil, fmt.Errorf("调用 %s 失败: %w", method, err)
+		}
+		return mcp.NewToolResultStructuredOnly(withPage(makeResult([]T{}), &models.PageInfo{Total: total})), nil
+	}
+	if raw {
+		records, info, err := server.GetPage[map[string]interface{}](ctx, clientPool, instance, method, spec, page)
+		if err != nil {
+			return nil, fmt.Errorf("调用 %s 失败: %w", method, err)
+		}
+		setResume(st, method, info, records)
+		return mcp.NewToolResultStructuredOnly(withPage(makeRawResult(records), info)), nil
+	}
+	items, info, err := server.GetPage[T](ctx, clientPool, instance, method, spec, page)
+	if err != nil {
+		return nil, fmt.Errorf("调用 %s 失败: %w", method, err)
+	}
+	setResume(st, method, info, items)
+	return mcp.NewToolResultStructuredOnly(withPage(makeResult(items), info)), nil
+}
+
+// setResume 告诉输出层本页在截断后如何继续翻页：从保留的最后一条之后开始
+func setResume[T any](st *outputState, method string, info *models.PageInfo, items []T) {
+	if st == nil {
+		return
+	}
+	idField := server.PageIDField(method)
+	st.resume = func(index int) string {
+		var obj map[string]interface{}
+		data, _ := json.Marshal(items[index])
+		_ = json.Unmarshal(data, &obj)
+		return server.ResumeCursor(info, fmt.Sprint(obj[idField]))
+	}
+}
+
+// withPage 在结果中附加分页信息
+func withPage(result map[string]interface{}, page *models.PageInfo) map[string]interface{} {
+	result["page"] = page
+	return result
+}
@@ -0,0 +1,333 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-30 10:52:37
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-30 10:52:37
+ * @FilePath: \zabbix-mcp-go\handler\problem_feed.go
+ * @Description: 实时问题推送：每个实例一个轮询协程跟踪新问题与恢复，按订阅的过滤条件以日志通知推送给会话
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"zabbixMcp/logger"
+	"zabbixMcp/models"
+	"zabbixMcp/server"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	mcpserver "github.com/mark3labs/mcp-go/server"
+)
+
+// ProblemLogger 问题推送使用的日志通知 logger 名称
+const ProblemLogger = "zabbix.problems"
+
+// ProblemFeedPolicy 实时问题推送配置
+type ProblemFeedPolicy struct {
+	Enabled  bool
+	Interval time.Duration // 每个实例的轮询间隔
+}
+
+// problemFeedPolicy 由 main 按 config.yml 的 problem_feed 段（缺省值见 defaultConfig）注入，未注入时不推送
+var problemFeedPolicy ProblemFeedPolicy
+
+// SetProblemFeedPolicy 设置实时问题推送配置
+func SetProblemFeedPolicy(p ProblemFeedPolicy) {
+	// 显式配置 interval: 0 时兜底，time.NewTicker 不接受非正数
+	if p.Interval <= 0 {
+		p.Interval = 30 * time.Second
+	}
+	problemFeedPolicy = p
+}
+
+// problemSubscription 一个会话的问题订阅及其过滤条件
+type problemSubscription struct {
+	ID          string      `json:"subscription_id"`
+	SessionID   string      `json:"-"`
+	Instance    string      `json:"instance,omitempty"`
+	MinSeverity int         `json:"min_severity"`
+	HostGroups  []string    `json:"hostgroups,omitempty"`
+	Tags        []tagFilter `json:"tags,omitempty"`
+}
+
+// tagFilter 标签过滤条件，Value 为空时只要求存在该标签
+type tagFilter struct {
+	Tag   string `json:"tag"`
+	Value string `json:"value,omitempty"`
+}
+
+// problemNotification 推送给会话的日志通知内容
+type problemNotification struct {
+	SubscriptionID string `json:"subscription_id"`
+	server.ProblemChange
+}
+
+// problemFeed 全部会话的问题订阅
+type problemFeed struct {
+	mu     sync.Mutex
+	nextID int
+	subs   map[string]*problemSubscription // 订阅ID -> 订阅
+	wake   map[string]chan struct{}        // 实例 -> 唤醒轮询协程，新订阅后立即建立基准
+}
+
+var feed = &problemFeed{subs: make(map[string]*problemSubscription), wake: make(map[string]chan struct{})}
+
+// SubscribeProblemsHandler 为当前会话订阅实时问题推送
+func SubscribeProblemsHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var args models.SubscribeProblemsArgs
+	if err := bindArgs(req, &args); err != nil {
+		return nil, err
+	}
+	if !problemFeedPolicy.Enabled {
+		return nil, models.Errorf(models.CodePermissionDenied, "实时问题推送未开启").WithHint("在 config.yml 中设置 problem_feed.enabled: true")
+	}
+	session := mcpserver.ClientSessionFromContext(ctx)
+	if session == nil {
+		return nil, models.Errorf(models.CodeInvalidParams, "当前连接不支持服务端推送").WithHint("通过 stdio 或 HTTP/SSE 连接后再订阅")
+	}
+	if args.Instance != "" && (clientPool == nil || len(clientPool.Info(args.Instance)) == 0) {
+		return nil, models.Errorf(models.CodeInstanceNotFound, "实例 %s 不存在", args.Instance).WithHint("调用 get_instances_info 查看可用的实例名称后重试")
+	}
+	sub := &problemSubscription{
+		SessionID:   session.SessionID(),
+		Instance:    args.Instance,
+		MinSeverity: args.MinSeverity,
+	}
+	for _, g := range args.HostGroups {
+		if g = strings.TrimSpace(g); g != "" {
+			sub.HostGroups = append(sub.HostGroups, g)
+		}
+	}
+	for _, t := range args.Tags {
+		tag, value, _ := strings.Cut(t, "=")
+		if tag = strings.TrimSpace(tag); tag == "" {
+			return nil, models.ArgsError{{Arg: "tags", Problem: fmt.Sprintf("标签 %q 格式错误，应为 tag 或 tag=value", t)}}
+		}
+		sub.Tags = append(sub.Tags, tagFilter{Tag: tag, Value: strings.TrimSpace(value)})
+	}
+	feed.add(sub)
+	logger.L().Infof("会话 %s 订阅问题推送 %s", sub.SessionID, sub.ID)
+	return mcp.NewToolResultStructuredOnly(makeResult(map[string]interface{}{
+		"subscription": sub,
+		"interval":     problemFeedPolicy.Interval.String(),
+		"logger":       ProblemLogger,
+	})), nil
+}
+
+// UnsubscribeProblemsHandler 取消当前会话的问题订阅
+func UnsubscribeProblemsHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var args models.UnsubscribeProblemsArgs
+	if err := bindArgs(req, &args); err != nil {
+		return nil, err
+	}
+	session := mcpserver.ClientSessionFromContext(ctx)
+	if session == nil {
+		return mcp.NewToolResultStructuredOnly(makeResult(map[string]interface{}{"removed": 0})), nil
+	}
+	removed := feed.remove(session.SessionID(), args.SubscriptionID)
+	if args.SubscriptionID != "" && removed == 0 {
+		return nil, models.Errorf(models.CodeNotFound, "当前会话没有订阅 %s", args.SubscriptionID)
+	}
+	return mcp.NewToolResultStructuredOnly(makeResult(map[string]interface{}{"removed": removed})), nil
+}
+
+// WatchProblems 为每个实例启动一个轮询协程，有会话订阅该实例的问题（或订阅了 zabbix://{instance}/problems 资源）时
+// 跟踪新问题与恢复并推送；阻塞直到 ctx 结束，未开启时立即返回
+func WatchProblems(ctx context.Context, s *mcpserver.MCPServer) {
+	if !problemFeedPolicy.Enabled || clientPool == nil {
+		return
+	}
+	var wg sync.WaitGroup
+	for _, info := range clientPool.Info("") {
+		wake := make(chan struct{}, 1)
+		feed.mu.Lock()
+		feed.wake[info.Instance] = wake
+		feed.mu.Unlock()
+		wg.Add(1)
+		go func(instance string) {
+			defer wg.Done()
+			watchInstanceProblems(ctx, s, instance, wake)
+		}(info.Instance)
+	}
+	wg.Wait()
+}
+
+func watchInstanceProblems(ctx context.Context, s *mcpserver.MCPServer, instance string, wake <-chan struct{}) {
+	uri := ResourceScheme + instance + "/problems"
+	ticker := time.NewTicker(problemFeedPolicy.Interval)
+	defer ticker.Stop()
+	var tracker *server.ProblemTracker
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-wake:
+		}
+		// 没有订阅者时丢弃跟踪状态，下次有订阅时以当时的状态为基准，不补发期间的问题
+		if !feed.watching(instance) && !watcher.subscribed(uri) {
+			tracker = nil
+			continue
+		}
+		pollCtx, cancel := context.WithTimeout(ctx, problemFeedPolicy.Interval)
+		if tracker == nil {
+			t, err := server.NewProblemTracker(pollCtx, clientPool, instance)
+			cancel()
+			if err != nil {
+				logger.L().Warnf("初始化实例 %s 的问题跟踪失败: %v", instance, err)
+				continue
+			}
+			tracker = t
+			continue
+		}
+		changes, err := tracker.Poll(pollCtx, clientPool)
+		cancel()
+		if err != nil {
+			logger.L().Warnf("轮询实例 %s 的问题失败: %v", instance, err)
+			continue
+		}
+		if len(changes) == 0 {
+			continue
+		}
+		feed.dispatch(s, changes)
+		watcher.refresh(ctx, s, uri)
+	}
+}
+
+func (f *problemFeed) add(sub *problemSubscription) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.nextID++
+	sub.ID = fmt.Sprintf("problems-%d", f.nextID)
+	f.subs[sub.ID] = sub
+	for instance, wake := range f.wake {
+		if sub.Instance == "" || sub.Instance == instance {
+			select {
+			case wake <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// remove 删除会话的指定订阅，id 为空时删除会话的全部订阅，返回删除的数量
+func (f *problemFeed) remove(sessionID, id string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := 0
+	for subID, sub := range f.subs {
+		if sub.SessionID == sessionID && (id == "" || id == subID) {
+			delete(f.subs, subID)
+			n++
+		}
+	}
+	return n
+}
+
+func (f *problemFeed) dropSession(sessionID string) {
+	f.remove(sessionID, "")
+}
+
+// watching 是否有订阅覆盖该实例
+func (f *problemFeed) watching(instance string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, sub := range f.subs {
+		if sub.Instance == "" || sub.Instance == instance {
+			return true
+		}
+	}
+	return false
+}
+
+// dispatch 把问题动态推送给过滤条件匹配的订阅；会话已断开时清除其订阅
+func (f *problemFeed) dispatch(s *mcpserver.MCPServer, changes []server.ProblemChange) {
+	f.mu.Lock()
+	subs := make([]problemSubscription, 0, len(f.subs))
+	for _, sub := range f.subs {
+		subs = append(subs, *sub)
+	}
+	f.mu.Unlock()
+
+	gone := make(map[string]bool)
+	for _, c := range changes {
+		level := severityLevel(int(c.Severity))
+		loc := instanceLocation(c.Instance)
+		for _, sub := range subs {
+			if gone[sub.SessionID] || !sub.match(c) {
+				continue
+			}
+			data, err := localized(problemNotification{SubscriptionID: sub.ID, ProblemChange: c}, loc)
+			if err != nil {
+				logger.L().Warnf("序列化问题 %s 失败: %v", c.EventID, err)
+				continue
+			}
+			n := mcp.NewLoggingMessageNotification(level, ProblemLogger, data)
+			err = s.SendLogMessageToSpecificClient(sub.SessionID, n)
+			if errors.Is(err, mcpserver.ErrSessionNotFound) {
+				gone[sub.SessionID] = true
+				f.dropSession(sub.SessionID)
+			} else if err != nil {
+				logger.L().Warnf("向会话 %s 推送问题 %s 失败: %v", sub.SessionID, c.EventID, err)
+			}
+		}
+	}
+}
+
+// match 判断问题动态是否满足订阅的过滤条件：主机组任一匹配（ID或名称），标签全部匹配
+func (sub problemSubscription) match(c server.ProblemChange) bool {
+	if sub.Instance != "" && sub.Instance != c.Instance || int(c.Severity) < sub.MinSeverity {
+		return false
+	}
+	if len(sub.HostGroups) > 0 {
+		found := false
+		for _, g := range c.HostGroups {
+			for _, want := range sub.HostGroups {
+				if want == g.GroupID || want == g.Name {
+					found = true
+				}
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for _, want := range sub.Tags {
+		found := false
+		for _, t := range c.Tags {
+			if t.Tag == want.Tag && (want.Value == "" || t.Value == want.Value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// severityLevel 问题严重级别对应的日志级别；恢复动态沿用问题的级别，
+// 客户端用 logging/setLevel 调整接收的最低级别（默认 error，即一般严重及以上）
+func severityLevel(severity int) mcp.LoggingLevel {
+	switch severity {
+	case 5:
+		return mcp.LoggingLevelAlert
+	case 4:
+		return mcp.LoggingLevelCritical
+	case 3:
+		return mcp.LoggingLevelError
+	case 2:
+		return mcp.LoggingLevelWarning
+	case 1:
+		return mcp.LoggingLevelNotice
+	}
+	return mcp.LoggingLevelInfo
+}
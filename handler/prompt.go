@@ -0,0 +1,236 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-29 16:12:45
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-29 16:12:45
+ * @FilePath: \zabbix-mcp-go\handler\prompt.go
+ * @Description: 常用运维流程的 MCP 提示词：预先读取相关数据嵌入提示词，保证每次排查的起点一致
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"zabbixMcp/models"
+	"zabbixMcp/server"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	// unreachableEventWindow 排查主机不可达时读取的事件时间范围
+	unreachableEventWindow = 24 * time.Hour
+	// noiseDefaultDays review_template_noise 默认统计的天数
+	noiseDefaultDays = 7
+	// noiseMaxEvents 统计模板触发器噪音时最多读取的事件数
+	noiseMaxEvents = 10000
+)
+
+// promptSection 嵌入提示词的一份数据
+type promptSection struct {
+	title string
+	data  interface{}
+}
+
+// TriageProblemsPrompt 分诊实例的当前问题
+func TriageProblemsPrompt(ctx context.Context, req mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	args, err := promptArgs(req, "instance")
+	if err != nil {
+		return nil, err
+	}
+	instance := args["instance"]
+	loc := instanceLocation(instance)
+	problems, err := server.GetProblemsWithHosts(ctx, clientPool, instance, nil, resourcePolicy.ProblemLimit)
+	if err != nil {
+		return nil, err
+	}
+	return promptResult(loc,
+		fmt.Sprintf("分诊 %s 的当前问题", instance),
+		fmt.Sprintf(`请对 Zabbix 实例 %s 的当前问题做分诊：
+1. 按严重级别（5灾难 → 0未分类）和影响的主机归类，指出可能同源的问题（同一主机、同一时间段、相同标签）。
+2. 列出最需要优先处理的问题及理由，区分已确认（acknowledged）与未确认的问题。
+3. 对每个优先问题给出下一步排查建议，需要更多信息时使用 get_hosts、api_call 等工具或读取 zabbix://%s/hosts/{host} 资源。
+以下数据读取于 %s，共 %d 个问题，最新的在前（最多读取 resources.problem_limit 个）。`,
+			instance, instance, nowString(loc), len(problems)),
+		promptSection{"当前问题", problems},
+	)
+}
+
+// DiagnoseHostPrompt 排查主机不可达的原因
+func DiagnoseHostPrompt(ctx context.Context, req mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	args, err := promptArgs(req, "instance", "host")
+	if err != nil {
+		return nil, err
+	}
+	instance := args["instance"]
+	loc := instanceLocation(instance)
+	hostID, err := server.ResolveID(ctx, clientPool, instance, server.KindHost, "host", args["host"])
+	if err != nil {
+		return nil, err
+	}
+	diagnosis, err := server.DiagnoseHost(ctx, clientPool, instance, hostID, time.Now().Add(-unreachableEventWindow))
+	if err != nil {
+		return nil, err
+	}
+	return promptResult(loc,
+		fmt.Sprintf("排查 %s 上的主机 %s 为何不可达", instance, args["host"]),
+		fmt.Sprintf(`请分析 Zabbix 实例 %s 上的主机 %s（hostid %s）为何不可达：
+1. 检查主机状态（是否禁用、是否处于维护）与各接口的 available/error（5.2 之前可用性在主机上，5.4 起在接口上）。
+2. 结合 agent.ping、icmpping 等可用性监控项的最新值判断是网络不通、Agent 停止还是 Zabbix 服务器/代理本身的问题；有 proxyid 时考虑代理是否正常。
+3. 根据最近 24 小时的事件判断不可达从何时开始、是否反复出现。
+4. 给出最可能的原因与具体的验证步骤（例如在服务器或代理上执行的检查命令）。
+以下数据读取于 %s。`,
+			instance, args["host"], hostID, nowString(loc)),
+		promptSection{"主机配置与接口", diagnosis.Host},
+		promptSection{"主机上的当前问题", diagnosis.Problems},
+		promptSection{"可用性监控项", diagnosis.Availability},
+		promptSection{"最近 24 小时的事件", diagnosis.RecentEvents},
+	)
+}
+
+// OnboardUserPrompt 将新用户加入用户组
+func OnboardUserPrompt(ctx context.Context, req mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	args, err := promptArgs(req, "instance", "username", "group")
+	if err != nil {
+		return nil, err
+	}
+	instance, username := args["instance"], args["username"]
+	loc := instanceLocation(instance)
+	groupID, err := server.ResolveID(ctx, clientPool, instance, server.KindUserGroup, "group", args["group"])
+	if err != nil {
+		return nil, err
+	}
+	oc, err := server.GetOnboardingContext(ctx, clientPool, instance, username, groupID)
+	if err != nil {
+		return nil, err
+	}
+	return promptResult(loc,
+		fmt.Sprintf("在 %s 中为 %s 开通账号并加入 %s", instance, username, args["group"]),
+		fmt.Sprintf(`请在 Zabbix 实例 %s 中为新用户 %s 开通账号并加入用户组 %s（usrgrpid %s）：
+1. 先确认是否已有同名用户；已存在时改为用 update_user 调整其用户组，不要重复创建。
+2. 检查用户组是否启用（users_status）以及前端访问方式（gui_access），组被禁用或禁止前端访问时先向我确认。
+3. 根据用户组的用途从可选角色中挑选合适的角色，说明理由。
+4. 先以 dry_run: true 调用 create_user 展示将要执行的变更，经我确认后再正式创建；初始密码不会出现在结果中，按结果里的 credential 告诉我密码投递到了哪里（加密文件、邮箱或一次性链接），投递失败时提示我用 update_user 的 updatePasswd 重新生成。
+以下数据读取于 %s。`,
+			instance, username, oc.Group.Name, groupID, nowString(loc)),
+		promptSection{"目标用户组", oc.Group},
+		promptSection{"同名的已有用户", oc.ExistingUsers},
+		promptSection{"可选角色", oc.Roles},
+	)
+}
+
+// OffboardUserPrompt 在全部实例中为离职用户收回访问权限
+func OffboardUserPrompt(ctx context.Context, req mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	args, err := promptArgs(req, "username")
+	if err != nil {
+		return nil, err
+	}
+	username := args["username"]
+	presence, err := server.FindUserAcrossInstances(ctx, clientPool, username)
+	if err != nil {
+		return nil, err
+	}
+	return promptResult(time.Local,
+		fmt.Sprintf("在全部实例中为 %s 办理离职", username),
+		fmt.Sprintf(`用户 %s 已离职，请在全部 Zabbix 实例中收回其访问权限：
+1. 汇总该用户在哪些实例中有账号、所属用户组以及配置的告警媒介；查询失败的实例单独列出，稍后重试。
+2. 提醒我处理其告警媒介：该用户是某些告警的唯一接收人时，需要先把告警转交给其他人，离职处理会移除其全部媒介。
+3. 以 query: %s、dry_run: true 调用 offboard_user 展示变更（按用户名、姓名或邮箱匹配的账号将移入 "No access to the frontend" 与禁用用户组、重置密码、移除媒介并吊销 API 令牌），核对匹配到的账号确实属于该用户，经我确认后再正式执行；只需处理个别实例时用 instances 限定。
+4. 执行后把返回的处理报告整理为合规工单可用的说明，列出失败或跳过的账号及原因。
+5. 除非我明确要求，不要删除账号（delete_user），以保留审计记录。
+以下数据读取于 %s。`,
+			username, username, nowString(time.Local)),
+		promptSection{"各实例中的账号", presence},
+	)
+}
+
+// ReviewTemplatePrompt 评估模板中告警过于频繁的触发器
+func ReviewTemplatePrompt(ctx context.Context, req mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	args, err := promptArgs(req, "instance", "name")
+	if err != nil {
+		return nil, err
+	}
+	instance := args["instance"]
+	loc := instanceLocation(instance)
+	days := noiseDefaultDays
+	if v := args["days"]; v != "" {
+		if days, err = strconv.Atoi(v); err != nil || days <= 0 {
+			return nil, models.ArgsError{{Arg: "days", Problem: "必须是正整数"}}
+		}
+	}
+	since := time.Now().AddDate(0, 0, -days)
+	period := fmt.Sprintf("最近 %d 天", days)
+	if v := args["since"]; v != "" {
+		if since, _, err = parseTimeRange(instance, "since", v, "", ""); err != nil {
+			return nil, err
+		}
+		period = fmt.Sprintf("自 %s 以来", since.In(loc).Format(time.RFC3339))
+	}
+	templateID, err := server.ResolveID(ctx, clientPool, instance, server.KindTemplate, "name", args["name"])
+	if err != nil {
+		return nil, err
+	}
+	noise, err := server.GetTemplateTriggerNoise(ctx, clientPool, instance, templateID, since, noiseMaxEvents)
+	if err != nil {
+		return nil, err
+	}
+	return promptResult(loc,
+		fmt.Sprintf("评估 %s 中模板 %s 的噪音触发器", instance, args["name"]),
+		fmt.Sprintf(`请评估 Zabbix 实例 %s 中模板 %s（templateid %s）的触发器是否过于嘈杂：
+1. 根据%s每个触发器产生的问题事件数、涉及的主机数与继承的主机数，找出告警最频繁的触发器；只在个别主机上频繁告警时，指出可能是主机本身的问题而非阈值问题。
+2. 结合表达式与严重级别判断原因：阈值过低、没有使用 avg/min 等函数平滑、缺少恢复表达式导致反复触发、严重级别过高等。
+3. 为每个需要调整的触发器给出具体的修改建议（新的表达式、恢复表达式或严重级别），并说明预期效果。
+4. 不要直接修改模板；需要查看完整配置时读取 zabbix://%s/templates/%s/export 资源。
+以下数据读取于 %s。`,
+			instance, args["name"], templateID, period, instance, templateID, nowString(loc)),
+		promptSection{"触发器告警统计（按问题事件数从多到少）", noise},
+	)
+}
+
+// promptArgs 读取提示词参数并检查必填参数
+func promptArgs(req mcp.GetPromptRequest, required ...string) (map[string]string, error) {
+	args := make(map[string]string, len(req.Params.Arguments))
+	for k, v := range req.Params.Arguments {
+		args[k] = strings.TrimSpace(v)
+	}
+	var problems models.ArgsError
+	for _, name := range required {
+		if args[name] == "" {
+			problems = append(problems, models.ArgError{Arg: name, Problem: "为必填参数，不能为空"})
+		}
+	}
+	if len(problems) > 0 {
+		return nil, problems
+	}
+	return args, nil
+}
+
+// promptResult 组装提示词：第一条消息为任务说明，之后每份预取的数据各占一条消息，时间换算到 loc
+func promptResult(loc *time.Location, description, instruction string, sections ...promptSection) (*mcp.GetPromptResult, error) {
+	messages := []mcp.PromptMessage{mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent(instruction))}
+	for _, s := range sections {
+		v, err := localized(s.data, loc)
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		text := fmt.Sprintf("%s：\n```json\n%s\n```", s.title, data)
+		messages = append(messages, mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent(text)))
+	}
+	return mcp.NewGetPromptResult(description, messages), nil
+}
+
+// nowString 当前时间在 loc 中的 RFC3339 表示
+func nowString(loc *time.Location) string {
+	return time.Now().In(loc).Format(time.RFC3339)
+}
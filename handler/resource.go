@@ -0,0 +1,173 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-29 10:18:52
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-29 10:18:52
+ * @FilePath: \zabbix-mcp-go\handler\resource.go
+ * @Description: zabbix:// 资源：实例列表、主机配置、当前问题与模板导出
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"time"
+
+	"zabbixMcp/models"
+	"zabbixMcp/server"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ResourceScheme 资源 URI 的协议前缀
+const ResourceScheme = "zabbix://"
+
+// ResourcePolicy 资源读取与订阅配置
+type ResourcePolicy struct {
+	ProblemLimit int // zabbix://{instance}/problems 最多返回的问题数，0 表示不限制
+}
+
+// resourcePolicy 由 main 按 config.yml 的 resources 段（缺省值见 defaultConfig）注入
+var resourcePolicy ResourcePolicy
+
+// SetResourcePolicy 设置资源读取配置
+func SetResourcePolicy(p ResourcePolicy) {
+	resourcePolicy = p
+}
+
+// instanceResource zabbix://instances 中的实例信息；不含 in_use 等瞬时状态，订阅时只在配置或连接状态变化时推送
+type instanceResource struct {
+	Instance  string `json:"instance"`
+	URL       string `json:"url"`
+	AuthType  string `json:"auth_type"`
+	ServerTZ  string `json:"server_tz"`
+	Connected bool   `json:"connected"`
+	Version   string `json:"version"`
+}
+
+// ResourceHandler 资源与资源模板共用的读取处理器
+func ResourceHandler(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	return ReadResource(ctx, req.Params.URI)
+}
+
+// ReadResource 按 URI 读取资源，订阅轮询也通过它重新读取内容：
+//
+//	zabbix://instances
+//	zabbix://{instance}/problems
+//	zabbix://{instance}/hosts/{host}
+//	zabbix://{instance}/templates/{name}/export
+//
+// host 与 name 既可以是ID也可以是名称，路径段按 URL 编码
+func ReadResource(ctx context.Context, uri string) ([]mcp.ResourceContents, error) {
+	segments, err := matchResource(uri)
+	if err != nil {
+		return nil, err
+	}
+	if len(segments) == 1 && segments[0] == "instances" {
+		infos, err := server.GetInstancesInfo(ctx, clientPool, "")
+		if err != nil {
+			return nil, err
+		}
+		out := make([]instanceResource, 0, len(infos))
+		for _, info := range infos {
+			out = append(out, instanceResource{
+				Instance:  info.Instance,
+				URL:       info.URL,
+				AuthType:  info.AuthType,
+				ServerTZ:  info.ServerTZ,
+				Connected: info.Connected,
+				Version:   info.Version,
+			})
+		}
+		return jsonResource(uri, time.Local, out)
+	}
+	if clientPool == nil {
+		return nil, models.Errorf(models.CodeInstanceUnavailable, "没有可用的 Zabbix 客户端")
+	}
+	instance := segments[0]
+	switch {
+	case len(segments) == 2 && segments[1] == "problems":
+		problems, err := server.GetCurrentProblems(ctx, clientPool, instance, resourcePolicy.ProblemLimit)
+		if err != nil {
+			return nil, err
+		}
+		return jsonResource(uri, instanceLocation(instance), problems)
+	case len(segments) == 3 && segments[1] == "hosts":
+		hostID, err := server.ResolveID(ctx, clientPool, instance, server.KindHost, "host", segments[2])
+		if err != nil {
+			return nil, err
+		}
+		host, err := server.GetHostDetail(ctx, clientPool, instance, hostID)
+		if err != nil {
+			return nil, err
+		}
+		return jsonResource(uri, instanceLocation(instance), host)
+	case len(segments) == 4 && segments[1] == "templates" && segments[3] == "export":
+		templateID, err := server.ResolveID(ctx, clientPool, instance, server.KindTemplate, "name", segments[2])
+		if err != nil {
+			return nil, err
+		}
+		exported, err := server.ExportTemplate(ctx, clientPool, instance, templateID)
+		if err != nil {
+			return nil, err
+		}
+		return []mcp.ResourceContents{mcp.TextResourceContents{URI: uri, MIMEType: "application/json", Text: exported}}, nil
+	}
+	return nil, unknownResource(uri)
+}
+
+// matchResource 校验 URI 是否对应已注册的资源或资源模板，返回解码后的路径段
+func matchResource(uri string) ([]string, error) {
+	segments, err := resourcePath(uri)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case len(segments) == 1 && segments[0] == "instances":
+	case len(segments) == 2 && segments[1] == "problems":
+	case len(segments) == 3 && segments[1] == "hosts":
+	case len(segments) == 4 && segments[1] == "templates" && segments[3] == "export":
+	default:
+		return nil, unknownResource(uri)
+	}
+	return segments, nil
+}
+
+// resourcePath 拆分并解码 zabbix:// 之后的路径段
+func resourcePath(uri string) ([]string, error) {
+	rest, ok := strings.CutPrefix(uri, ResourceScheme)
+	if !ok || rest == "" {
+		return nil, unknownResource(uri)
+	}
+	parts := strings.Split(strings.TrimSuffix(rest, "/"), "/")
+	for i, p := range parts {
+		decoded, err := url.PathUnescape(p)
+		if err != nil || decoded == "" {
+			return nil, unknownResource(uri)
+		}
+		parts[i] = decoded
+	}
+	return parts, nil
+}
+
+func unknownResource(uri string) error {
+	return models.Errorf(models.CodeNotFound, "未知的资源 %s", uri).
+		WithHint("可用资源: zabbix://instances、zabbix://{instance}/problems、zabbix://{instance}/hosts/{host}、zabbix://{instance}/templates/{name}/export")
+}
+
+// jsonResource 以 JSON 返回资源内容，时间换算到 loc
+func jsonResource(uri string, loc *time.Location, v interface{}) ([]mcp.ResourceContents, error) {
+	v, err := localized(v, loc)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return []mcp.ResourceContents{mcp.TextResourceContents{URI: uri, MIMEType: "application/json", Text: string(data)}}, nil
+}
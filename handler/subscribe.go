@@ -0,0 +1,261 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-29 11:02:14
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-29 11:02:14
+ * @FilePath: \zabbix-mcp-go\handler\subscribe.go
+ * @Description: 资源订阅：定期重新读取已订阅的资源，内容变化时推送 notifications/resources/updated
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"zabbixMcp/logger"
+	"zabbixMcp/models"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	mcpserver "github.com/mark3labs/mcp-go/server"
+)
+
+// SubscriptionPolicy 资源订阅配置
+type SubscriptionPolicy struct {
+	Enabled       bool
+	Interval      time.Duration // 重新读取已订阅资源的间隔
+	MaxPerSession int           // 每个会话最多订阅的资源数，0 表示不限制
+}
+
+// subscriptionPolicy 由 main 按 config.yml 的 resources 段（缺省值见 defaultConfig）注入，未注入时不登记订阅
+var subscriptionPolicy SubscriptionPolicy
+
+// SetSubscriptionPolicy 设置资源订阅配置
+func SetSubscriptionPolicy(p SubscriptionPolicy) {
+	// 显式配置 poll_interval: 0 时兜底，time.NewTicker 不接受非正数
+	if p.Interval <= 0 {
+		p.Interval = time.Minute
+	}
+	subscriptionPolicy = p
+}
+
+// resourceWatcher 记录每个资源的订阅会话与上次读取内容的摘要
+type resourceWatcher struct {
+	mu       sync.Mutex
+	sessions map[string]map[string]bool // URI -> 会话ID
+	digests  map[string]uint64          // URI -> 内容摘要
+}
+
+var watcher = &resourceWatcher{
+	sessions: make(map[string]map[string]bool),
+	digests:  make(map[string]uint64),
+}
+
+// ResourceHooks 记录 resources/subscribe 与 resources/unsubscribe 的钩子，会话结束时清除其资源订阅与问题订阅；
+// 创建 MCP 服务器时通过 server.WithHooks 注册。
+// 订阅在请求初始化钩子中登记：只有它能拒绝请求，After/BeforeSubscribe 钩子无法返回错误
+func ResourceHooks() *mcpserver.Hooks {
+	hooks := &mcpserver.Hooks{}
+	hooks.AddOnRequestInitialization(func(ctx context.Context, _ any, message any) error {
+		raw, ok := message.(json.RawMessage)
+		if !ok || !subscriptionPolicy.Enabled {
+			return nil
+		}
+		var req struct {
+			Method string `json:"method"`
+			Params struct {
+				URI string `json:"uri"`
+			} `json:"params"`
+		}
+		if json.Unmarshal(raw, &req) != nil || req.Method != string(mcp.MethodResourcesSubscribe) {
+			return nil
+		}
+		session := mcpserver.ClientSessionFromContext(ctx)
+		if session == nil {
+			return nil
+		}
+		return watcher.subscribe(session.SessionID(), req.Params.URI)
+	})
+	hooks.AddAfterUnsubscribe(func(ctx context.Context, _ any, message *mcp.UnsubscribeRequest, _ *mcp.EmptyResult) {
+		if session := mcpserver.ClientSessionFromContext(ctx); session != nil {
+			watcher.unsubscribe(session.SessionID(), message.Params.URI)
+		}
+	})
+	hooks.AddOnUnregisterSession(func(_ context.Context, session mcpserver.ClientSession) {
+		watcher.dropSession(session.SessionID())
+		feed.dropSession(session.SessionID())
+	})
+	return hooks
+}
+
+// WatchResources 按配置的间隔重新读取已订阅的资源，内容变化时向订阅的会话推送通知；阻塞直到 ctx 结束，
+// 未开启订阅时立即返回
+func WatchResources(ctx context.Context, s *mcpserver.MCPServer) {
+	if !subscriptionPolicy.Enabled {
+		return
+	}
+	ticker := time.NewTicker(subscriptionPolicy.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			watcher.poll(ctx, s)
+		}
+	}
+}
+
+// subscribe 记录订阅；URI 不对应已注册的资源或资源模板、实例不存在或超出会话订阅上限时拒绝，
+// 资源第一次被订阅时在后台读取一次作为比较基准
+func (w *resourceWatcher) subscribe(sessionID, uri string) error {
+	segments, err := matchResource(uri)
+	if err != nil {
+		return err
+	}
+	if len(segments) > 1 && clientPool != nil && len(clientPool.Info(segments[0])) == 0 {
+		return models.Errorf(models.CodeInstanceNotFound, "实例 %s 不存在", segments[0])
+	}
+	w.mu.Lock()
+	if w.sessions[uri][sessionID] {
+		w.mu.Unlock()
+		return nil
+	}
+	if limit := subscriptionPolicy.MaxPerSession; limit > 0 && w.countLocked(sessionID) >= limit {
+		w.mu.Unlock()
+		return models.Errorf(models.CodeInvalidParams, "每个会话最多订阅 %d 个资源", limit).WithHint("先 resources/unsubscribe 不再需要的资源，或调大 resources.max_subscriptions")
+	}
+	first := len(w.sessions[uri]) == 0
+	if first {
+		w.sessions[uri] = make(map[string]bool)
+	}
+	w.sessions[uri][sessionID] = true
+	w.mu.Unlock()
+	logger.L().Infof("会话 %s 订阅资源 %s", sessionID, uri)
+	if first {
+		timeout := subscriptionPolicy.Interval
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			if digest, err := readDigest(ctx, uri); err == nil {
+				w.mu.Lock()
+				if _, seen := w.digests[uri]; !seen && len(w.sessions[uri]) > 0 {
+					w.digests[uri] = digest
+				}
+				w.mu.Unlock()
+			} else {
+				logger.L().Warnf("读取订阅的资源 %s 失败: %v", uri, err)
+			}
+		}()
+	}
+	return nil
+}
+
+// countLocked 会话当前订阅的资源数
+func (w *resourceWatcher) countLocked(sessionID string) int {
+	n := 0
+	for _, sessions := range w.sessions {
+		if sessions[sessionID] {
+			n++
+		}
+	}
+	return n
+}
+
+func (w *resourceWatcher) unsubscribe(sessionID, uri string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.removeLocked(sessionID, uri)
+}
+
+func (w *resourceWatcher) dropSession(sessionID string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for uri := range w.sessions {
+		w.removeLocked(sessionID, uri)
+	}
+}
+
+// removeLocked 删除一个订阅，资源不再有订阅者时清除其摘要
+func (w *resourceWatcher) removeLocked(sessionID, uri string) {
+	delete(w.sessions[uri], sessionID)
+	if len(w.sessions[uri]) == 0 {
+		delete(w.sessions, uri)
+		delete(w.digests, uri)
+	}
+}
+
+// poll 重新读取全部已订阅的资源，与上次摘要不同时通知订阅的会话
+func (w *resourceWatcher) poll(ctx context.Context, s *mcpserver.MCPServer) {
+	w.mu.Lock()
+	uris := make([]string, 0, len(w.sessions))
+	for uri := range w.sessions {
+		uris = append(uris, uri)
+	}
+	w.mu.Unlock()
+
+	for _, uri := range uris {
+		w.refresh(ctx, s, uri)
+	}
+}
+
+// refresh 重新读取一个资源，内容与上次摘要不同时通知订阅的会话；资源没有订阅者时不读取
+func (w *resourceWatcher) refresh(ctx context.Context, s *mcpserver.MCPServer, uri string) {
+	if !w.subscribed(uri) {
+		return
+	}
+	readCtx, cancel := context.WithTimeout(ctx, subscriptionPolicy.Interval)
+	digest, err := readDigest(readCtx, uri)
+	cancel()
+	if err != nil {
+		logger.L().Warnf("读取订阅的资源 %s 失败: %v", uri, err)
+		return
+	}
+	w.mu.Lock()
+	prev, seen := w.digests[uri]
+	var targets []string
+	if _, subscribed := w.sessions[uri]; subscribed {
+		w.digests[uri] = digest
+		if seen && prev != digest {
+			for id := range w.sessions[uri] {
+				targets = append(targets, id)
+			}
+		}
+	}
+	w.mu.Unlock()
+	for _, id := range targets {
+		err := s.SendNotificationToSpecificClient(id, mcp.MethodNotificationResourceUpdated, map[string]any{"uri": uri})
+		if errors.Is(err, mcpserver.ErrSessionNotFound) {
+			w.dropSession(id)
+		} else if err != nil {
+			logger.L().Warnf("向会话 %s 推送资源 %s 更新失败: %v", id, uri, err)
+		}
+	}
+}
+
+// subscribed 资源是否有会话订阅
+func (w *resourceWatcher) subscribed(uri string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.sessions[uri]) > 0
+}
+
+// readDigest 读取资源并计算内容摘要
+func readDigest(ctx context.Context, uri string) (uint64, error) {
+	contents, err := ReadResource(ctx, uri)
+	if err != nil {
+		return 0, err
+	}
+	h := fnv.New64a()
+	for _, c := range contents {
+		if text, ok := c.(mcp.TextResourceContents); ok {
+			h.Write([]byte(text.Text))
+		}
+	}
+	return h.Sum64(), nil
+}
@@ -0,0 +1,126 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"zabbixMcp/handler"
+	"zabbixMcp/register"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	mcpserver "github.com/mark3labs/mcp-go/server"
+)
+
+// subscribeTester 通过 MCP 服务器收发 resources/subscribe 与 resources/unsubscribe
+type subscribeTester struct {
+	t *testing.T
+	s *mcpserver.MCPServer
+	n int
+}
+
+func newSubscribeTester(t *testing.T, maxPerSession int) *subscribeTester {
+	t.Helper()
+	testServer(t)
+	handler.SetSubscriptionPolicy(handler.SubscriptionPolicy{Enabled: true, Interval: time.Hour, MaxPerSession: maxPerSession})
+
+	s := mcpserver.NewMCPServer("test", "1.0.0",
+		mcpserver.WithResourceCapabilities(true, false),
+		mcpserver.WithHooks(handler.ResourceHooks()),
+	)
+	register.Registers(s)
+	return &subscribeTester{t: t, s: s}
+}
+
+// session 注册一个会话，测试结束时注销，注销钩子会清除它的订阅
+func (st *subscribeTester) session(id string) mcpserver.ClientSession {
+	st.t.Helper()
+	session := mcpserver.NewInProcessSession(id, nil)
+	if err := st.s.RegisterSession(context.Background(), session); err != nil {
+		st.t.Fatal(err)
+	}
+	st.t.Cleanup(func() { st.s.UnregisterSession(context.Background(), id) })
+	return session
+}
+
+// send 以 session 身份发送一个请求，返回 JSON-RPC 错误信息，成功时为空
+func (st *subscribeTester) send(session mcpserver.ClientSession, method mcp.MCPMethod, uri string) string {
+	st.t.Helper()
+	st.n++
+	msg, _ := json.Marshal(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      st.n,
+		"method":  method,
+		"params":  map[string]string{"uri": uri},
+	})
+	ctx := st.s.WithContext(context.Background(), session)
+	switch resp := st.s.HandleMessage(ctx, msg).(type) {
+	case mcp.JSONRPCResponse:
+		return ""
+	case mcp.JSONRPCError:
+		return resp.Error.Message
+	default:
+		st.t.Fatalf("%s %s: 意外的响应 %#v", method, uri, resp)
+		return ""
+	}
+}
+
+func TestSubscribeRejectsUnknownResources(t *testing.T) {
+	st := newSubscribeTester(t, 0)
+	session := st.session("unknown")
+
+	for _, uri := range []string{
+		"zabbix://instances",
+		"zabbix://zbx/problems",
+		"zabbix://zbx/hosts/web01",
+		"zabbix://zbx/templates/Linux%20by%20Zabbix%20agent/export",
+	} {
+		if msg := st.send(session, mcp.MethodResourcesSubscribe, uri); msg != "" {
+			t.Errorf("订阅 %s 被拒绝: %s", uri, msg)
+		}
+	}
+	for uri, want := range map[string]string{
+		"zabbix://zbx":                    "not_found",
+		"zabbix://zbx/hosts":              "not_found",
+		"zabbix://zbx/items/1":            "not_found",
+		"zabbix://zbx/templates/x":        "not_found",
+		"http://zbx/problems":             "not_found",
+		"zabbix://missing/problems":       "instance_not_found",
+		"zabbix://missing/hosts/web01":    "instance_not_found",
+		"zabbix://zbx/hosts/web01/extra/": "not_found",
+	} {
+		if msg := st.send(session, mcp.MethodResourcesSubscribe, uri); !strings.Contains(msg, want) {
+			t.Errorf("订阅 %s: 错误 %q，期望包含 %q", uri, msg, want)
+		}
+	}
+}
+
+func TestSubscribePerSessionLimit(t *testing.T) {
+	st := newSubscribeTester(t, 2)
+	a := st.session("limit-a")
+	b := st.session("limit-b")
+
+	uris := []string{"zabbix://zbx/hosts/web01", "zabbix://zbx/hosts/web02", "zabbix://zbx/hosts/web03"}
+	for _, uri := range uris[:2] {
+		if msg := st.send(a, mcp.MethodResourcesSubscribe, uri); msg != "" {
+			t.Fatalf("订阅 %s 被拒绝: %s", uri, msg)
+		}
+	}
+	if msg := st.send(a, mcp.MethodResourcesSubscribe, uris[0]); msg != "" {
+		t.Errorf("重复订阅已订阅的资源不应计入上限: %s", msg)
+	}
+	if msg := st.send(a, mcp.MethodResourcesSubscribe, uris[2]); !strings.Contains(msg, "最多订阅 2 个资源") {
+		t.Errorf("超出上限的订阅: 错误 %q", msg)
+	}
+	if msg := st.send(b, mcp.MethodResourcesSubscribe, uris[2]); msg != "" {
+		t.Errorf("上限按会话计算，其他会话的订阅被拒绝: %s", msg)
+	}
+	if msg := st.send(a, mcp.MethodResourcesUnsubscribe, uris[1]); msg != "" {
+		t.Fatalf("取消订阅失败: %s", msg)
+	}
+	if msg := st.send(a, mcp.MethodResourcesSubscribe, uris[2]); msg != "" {
+		t.Errorf("取消订阅后仍被拒绝: %s", msg)
+	}
+}
@@ -0,0 +1,67 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-28 15:02:18
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-28 15:02:18
+ * @FilePath: \zabbix-mcp-go\handler\summary.go
+ * @Description: 各工具的默认摘要：按常用维度分组计数
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+package handler
+
+import (
+	"fmt"
+	"strings"
+)
+
+// summaryDimensions 工具名 -> 摘要维度（名称 -> 字段路径）
+var summaryDimensions = map[string]map[string]string{
+	"get_users": {
+		"by_role":      "roleid",
+		"by_usergroup": "usrgrps.name",
+	},
+	"get_groups": {
+		"by_users_status": "users_status",
+		"by_gui_access":   "gui_access",
+	},
+	"get_hosts": {
+		"by_status":      "status",
+		"by_maintenance": "maintenance_status",
+		"by_hostgroup":   "hostgroups.name",
+	},
+	"get_audit_log": {
+		"by_tool":     "tool",
+		"by_status":   "status",
+		"by_instance": "instance",
+	},
+}
+
+// summarize 返回条数与各维度的分组计数（items 为截断前的本页条目）；没有取值的维度省略，工具未定义摘要时返回 nil
+func summarize(tool string, items []interface{}) map[string]interface{} {
+	dims, ok := summaryDimensions[tool]
+	if !ok {
+		return nil
+	}
+	summary := map[string]interface{}{"count": len(items)}
+	for name, path := range dims {
+		if counts := countBy(items, path); len(counts) > 0 {
+			summary[name] = counts
+		}
+	}
+	return summary
+}
+
+// countBy 按 path 的取值分组计数，途经数组时每个取值各计一次；没有取值的条目不计入
+func countBy(items []interface{}, path string) map[string]int {
+	counts := map[string]int{}
+	keys := strings.Split(path, ".")
+	for _, item := range items {
+		for _, v := range valuesAt(item, keys) {
+			if v == nil {
+				continue
+			}
+			counts[fmt.Sprint(v)]++
+		}
+	}
+	return counts
+}
@@ -0,0 +1,110 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-30 15:02:18
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-30 15:02:18
+ * @FilePath: \zabbix-mcp-go\handler\timezone.go
+ * @Description: 实例时区：按 server_tz 解析工具参数中的时间范围，并把结果中的时间换算到实例时区
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	"zabbixMcp/models"
+	"zabbixMcp/utils"
+)
+
+// instanceLocation 实例的服务器时区；instance 为空时，全部实例时区相同则使用该时区，否则使用本地时区
+func instanceLocation(instance string) *time.Location {
+	if clientPool == nil {
+		return time.Local
+	}
+	tz := ""
+	for i, info := range clientPool.Info(instance) {
+		if i > 0 && info.ServerTZ != tz {
+			return time.Local
+		}
+		tz = info.ServerTZ
+	}
+	loc, err := utils.LoadLocation(tz)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+// parseTimeRange 在实例时区中解析 from/to 参数，错误以参数错误返回
+func parseTimeRange(instance, fromArg, from, toArg, to string) (time.Time, time.Time, error) {
+	loc := instanceLocation(instance)
+	now := time.Now()
+	if _, err := utils.ParseTimeIn(from, loc, now); err != nil {
+		return time.Time{}, time.Time{}, models.ArgsError{{Arg: fromArg, Problem: err.Error()}}
+	}
+	if _, err := utils.ParseTimeIn(to, loc, now); err != nil {
+		return time.Time{}, time.Time{}, models.ArgsError{{Arg: toArg, Problem: err.Error()}}
+	}
+	start, end, err := utils.ParseTimeRange(from, to, loc, now)
+	if err != nil {
+		return time.Time{}, time.Time{}, models.ArgsError{{Arg: fromArg, Problem: err.Error()}}
+	}
+	return start, end, nil
+}
+
+// localizeTimes 把结果树中 models.Timestamp 序列化出的 {"epoch", "iso"} 换算到 loc
+func localizeTimes(v interface{}, loc *time.Location) {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		if epoch, ok := timeValueEpoch(val); ok {
+			val["iso"] = models.Timestamp(epoch).In(loc).ISO
+			return
+		}
+		for _, child := range val {
+			localizeTimes(child, loc)
+		}
+	case []interface{}:
+		for _, child := range val {
+			localizeTimes(child, loc)
+		}
+	}
+}
+
+func timeValueEpoch(obj map[string]interface{}) (int64, bool) {
+	if len(obj) != 2 {
+		return 0, false
+	}
+	if _, ok := obj["iso"].(string); !ok {
+		return 0, false
+	}
+	switch n := obj["epoch"].(type) {
+	case json.Number:
+		epoch, err := n.Int64()
+		return epoch, err == nil
+	case float64:
+		return int64(n), true
+	}
+	return 0, false
+}
+
+// localized 按实例时区序列化 v 并解码为通用结构，用于资源、提示词与推送等不经过输出层的结果
+func localized(v interface{}, loc *time.Location) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if loc == time.Local {
+		return json.RawMessage(data), nil
+	}
+	var out interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&out); err != nil {
+		return nil, err
+	}
+	localizeTimes(out, loc)
+	return out, nil
+}
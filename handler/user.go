@@ -12,7 +12,9 @@ package handler
 import (
 	"context"
 	"fmt"
+	"strings"
 
+	"zabbixMcp/credential"
 	"zabbixMcp/logger"
 	"zabbixMcp/models"
 	"zabbixMcp/server"
@@ -23,184 +25,275 @@ import (
 
 // GetUsersHandler 通过注入的 ClientProvider 调用 user.get 并返回结果
 func GetUsersHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	instanceName := ""
-	username := ""
-	if args, ok := req.Params.Arguments.(map[string]interface{}); ok {
-		if v, ok2 := args["instance"].(string); ok2 {
-			instanceName = v
-		}
-		if v, ok2 := args["username"].(string); ok2 {
-			username = v
-		}
+	var args models.GetUsersArgs
+	if err := bindArgs(req, &args); err != nil {
+		return nil, err
 	}
+	ctx = withRawOption(ctx, args.Raw)
+	ctx = withCacheOption(ctx, args.Cache)
 	if clientPool == nil {
 		return mcp.NewToolResultStructuredOnly(makeResult([]map[string]interface{}{})), nil
 	}
 	// 使用 server 层处理业务逻辑
 	spec := models.UserParams{Output: "extend"}
-	if username != "" {
+	if args.Username != "" {
 		// 兼容低版本
-		spec.Alias = username
-		spec.Filter = map[string]interface{}{"username": username}
+		spec.Alias = args.Username
+		spec.Filter = map[string]interface{}{"username": args.Username}
 		spec.GetAccess = true
 		spec.SelectUsrgrps = []string{"usrgrpid", "name"}
 	}
-	users, err := server.GetUsers(ctx, clientPool, spec, instanceName)
-	if err != nil {
-		return nil, fmt.Errorf("调用 user.get 失败: %w", err)
-	}
-	return mcp.NewToolResultStructuredOnly(makeResult(users)), nil
+	return getPaged[models.User](ctx, "get_users", args.Instance, "user.get", spec, args.PageArgs, args.Raw)
 }
 
 // CreateUsersHandler 通过注入的 ClientProvider 调用 user.create 并返回结果
 func CreateUsersHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	instanceName := ""
-	username := ""
-	name := ""
-	userGroup := ""
-	roleID := ""
-	if args, ok := req.Params.Arguments.(map[string]interface{}); ok {
-		if v, ok2 := args["instance"].(string); ok2 {
-			instanceName = v
-		}
-		if v, ok2 := args["username"].(string); ok2 {
-			username = v
-		}
-		if v, ok2 := args["name"].(string); ok2 {
-			name = v
-		}
-		if v, ok2 := args["userGroup"].(string); ok2 {
-			userGroup = v
-		}
-		if v, ok2 := args["roleID"].(string); ok2 {
-			roleID = v
-		}
+	var args models.CreateUserArgs
+	if err := bindArgs(req, &args); err != nil {
+		return nil, err
 	}
 	if clientPool == nil {
 		return mcp.NewToolResultStructuredOnly(makeResult([]map[string]interface{}{})), nil
 	}
-	passwd, err := utils.GenerateSecurePassword(12)
+	userGroup, err := server.ResolveID(ctx, clientPool, args.Instance, server.KindUserGroup, "userGroup", args.UserGroup)
 	if err != nil {
-		return nil, fmt.Errorf("生成密码失败: %w", err)
+		return nil, err
 	}
-	// 使用 server 层处理业务逻辑
-	spec := models.UserParams{
-		UserName:  username,
-		Name:      name,
-		Passwd:    passwd,
+	roleID, err := server.ResolveID(ctx, clientPool, args.Instance, server.KindRole, "roleID", args.RoleID)
+	if err != nil {
+		return nil, err
+	}
+	// 预览时不生成真实密码
+	planSpec := models.UserParams{
+		UserName:  args.Username,
+		Name:      args.Name,
+		Passwd:    models.MaskedValue,
 		Roleid:    roleID,
 		UserGroup: userGroup,
 	}
-	users, err := server.CreateUsers(ctx, clientPool, spec, instanceName, passwd)
+	if email := strings.TrimSpace(args.Email); email != "" {
+		mediaTypeID, err := server.ResolveID(ctx, clientPool, args.Instance, server.KindMediaType, "email", credentialPolicy.MediaType)
+		if err != nil {
+			return nil, err
+		}
+		planSpec.Medias = []map[string]interface{}{server.NewUserMedia(mediaTypeID, email, true)}
+	}
+	if args.DryRun {
+		plan, err := server.PlanCreateUser(ctx, clientPool, planSpec, args.Instance)
+		if err != nil {
+			return nil, fmt.Errorf("预览 user.create 失败: %w", err)
+		}
+		return mcp.NewToolResultStructuredOnly(makeResult(plan)), nil
+	}
+	if res, err := confirmMutation(ctx, req, "create_user", 1, func() (*models.MutationPlan, error) {
+		return server.PlanCreateUser(ctx, clientPool, planSpec, args.Instance)
+	}); res != nil || err != nil {
+		return res, err
+	}
+	passwd, err := utils.GenerateSecurePassword(12)
+	if err != nil {
+		return nil, fmt.Errorf("生成密码失败: %w", err)
+	}
+	// 使用 server 层处理业务逻辑
+	spec := planSpec
+	spec.Passwd = passwd
+	users, err := server.CreateUsers(ctx, clientPool, spec, args.Instance)
 	if err != nil {
 		return nil, fmt.Errorf("调用 user.create 失败: %w", err)
 	}
+	users["credential"] = deliverPassword(ctx, credential.Credential{
+		Instance: args.Instance, UserID: server.FirstID(users["userids"]), Username: args.Username, Password: passwd, Reason: credential.ReasonCreate,
+	})
 	return mcp.NewToolResultStructuredOnly(makeResult(users)), nil
 }
 
 func UpdateUsersHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	instanceName := ""
-	name := ""
-	// surname := ""
-	userid := ""
-	usrgrps := []string{}
-	updatePasswd := false
-	passwd := ""
-	if args, ok := req.Params.Arguments.(map[string]interface{}); ok {
-		if v, ok2 := args["instance"].(string); ok2 {
-			instanceName = v
-		}
-		// if v, ok2 := args["surname"].(string); ok2 {
-		// 	surname = v
-		// }
-		if v, ok2 := args["name"].(string); ok2 {
-			name = v
-		}
-		if v, ok2 := args["usrgrps"].([]string); ok2 {
-			usrgrps = v
-		}
-		if v, ok2 := args["userid"].(string); ok2 {
-			userid = v
-		}
-		if v, ok2 := args["updatePasswd"].(bool); ok2 {
-			updatePasswd = v
-		}
+	var args models.UpdateUserArgs
+	if err := bindArgs(req, &args); err != nil {
+		return nil, err
 	}
 	if clientPool == nil {
 		return mcp.NewToolResultStructuredOnly(makeResult([]map[string]interface{}{})), nil
 	}
-	// 使用 server 层处理业务逻辑
-	spec := models.UserParams{Userid: userid}
-	// if surname != "" {
-	// 	spec.Surname = surname
-	// }
-	if name != "" {
-		spec.Name = name
+	userID, err := server.ResolveID(ctx, clientPool, args.Instance, server.KindUser, "userid", args.UserID)
+	if err != nil {
+		return nil, err
 	}
+	usrgrps := args.Usrgrps
 	if len(usrgrps) > 0 {
-		spec.Usrgrps = usrgrps
+		if usrgrps, err = server.ResolveIDs(ctx, clientPool, args.Instance, server.KindUserGroup, "usrgrps", usrgrps); err != nil {
+			return nil, err
+		}
+	}
+	// 使用 server 层处理业务逻辑
+	spec := models.UserParams{Userid: userID, Name: args.Name, Usrgrps: usrgrps}
+	planSpec := spec
+	if args.UpdatePasswd {
+		planSpec.Passwd = models.MaskedValue
+		planSpec.CurrentPasswd = models.MaskedValue
 	}
-	if updatePasswd {
-		passwd, err := utils.GenerateSecurePassword(12)
+	if args.DryRun {
+		plan, err := server.PlanUpdateUser(ctx, clientPool, planSpec, args.Instance)
 		if err != nil {
+			return nil, fmt.Errorf("预览 user.update 失败: %w", err)
+		}
+		return mcp.NewToolResultStructuredOnly(makeResult(plan)), nil
+	}
+	if res, err := confirmMutation(ctx, req, "update_user", 1, func() (*models.MutationPlan, error) {
+		return server.PlanUpdateUser(ctx, clientPool, planSpec, args.Instance)
+	}); res != nil || err != nil {
+		return res, err
+	}
+	if args.UpdatePasswd {
+		if spec.Passwd, err = utils.GenerateSecurePassword(12); err != nil {
 			return nil, fmt.Errorf("生成密码失败: %w", err)
 		}
-		spec.Passwd = passwd
-		spec.CurrentPasswd = passwd
+		spec.CurrentPasswd = spec.Passwd
 	}
-	users, err := server.UpdateUser(ctx, clientPool, spec, instanceName, passwd)
+	users, err := server.UpdateUser(ctx, clientPool, spec, args.Instance)
 	if err != nil {
 		return nil, fmt.Errorf("调用 user.update 失败: %w", err)
 	}
+	if args.UpdatePasswd {
+		users["credential"] = deliverPassword(ctx, credential.Credential{
+			Instance: args.Instance, UserID: userID, Username: usernameOf(ctx, args.Instance, userID, args.UserID), Password: spec.Passwd, Reason: credential.ReasonReset,
+		})
+	}
 	return mcp.NewToolResultStructuredOnly(makeResult(users)), nil
 }
 
+// usernameOf 查询用户ID对应的用户名，查询失败时返回 fallback
+func usernameOf(ctx context.Context, instance, userID, fallback string) string {
+	users, err := server.GetUsers(ctx, clientPool, models.UserParams{UserIDs: []string{userID}, OutputFields: []string{"userid", "username"}}, instance)
+	if err != nil || len(users) == 0 {
+		return fallback
+	}
+	return users[0].Username
+}
+
 func DisableUserHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	instanceName := ""
-	userid := ""
-	// surname := ""
-	if args, ok := req.Params.Arguments.(map[string]interface{}); ok {
-		if v, ok2 := args["instance"].(string); ok2 {
-			instanceName = v
-		}
-		if v, ok2 := args["userid"].(string); ok2 {
-			userid = v
-		}
+	var args models.DisableUserArgs
+	if err := bindArgs(req, &args); err != nil {
+		return nil, err
 	}
 	if clientPool == nil {
 		return mcp.NewToolResultStructuredOnly(makeResult([]map[string]interface{}{})), nil
 	}
+	userID, err := server.ResolveID(ctx, clientPool, args.Instance, server.KindUser, "userid", args.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if args.DryRun {
+		plan, err := server.PlanDisableUser(ctx, clientPool, userID, args.Instance)
+		if err != nil {
+			return nil, fmt.Errorf("预览 user.disable 失败: %w", err)
+		}
+		return mcp.NewToolResultStructuredOnly(makeResult(plan)), nil
+	}
+	if res, err := confirmMutation(ctx, req, "disable_user", 1, func() (*models.MutationPlan, error) {
+		return server.PlanDisableUser(ctx, clientPool, userID, args.Instance)
+	}); res != nil || err != nil {
+		return res, err
+	}
 
-	users, err := server.DisableUser(ctx, clientPool, userid, instanceName)
+	users, err := server.DisableUser(ctx, clientPool, userID, args.Instance)
 	if err != nil {
 		return nil, fmt.Errorf("调用 user.disable 失败: %w", err)
 	}
 	return mcp.NewToolResultStructuredOnly(makeResult(users)), nil
 }
 
-func DeleteUsersHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	instanceName := ""
-	userIDs := []string{}
-	if args, ok := req.Params.Arguments.(map[string]interface{}); ok {
-		if v, ok2 := args["instance"].(string); ok2 {
-			instanceName = v
-		}
-		if arr, ok := args["userids"].([]interface{}); ok {
-			for _, v := range arr {
-				if s, ok := v.(string); ok && s != "" {
-					userIDs = append(userIDs, s)
-				}
-			}
+// OffboardUserHandler 在全部实例中查找离职人员的账号，逐个禁用、吊销 API 令牌并移除告警媒介，返回按实例的处理报告
+func OffboardUserHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var args models.OffboardUserArgs
+	if err := bindArgs(req, &args); err != nil {
+		return nil, err
+	}
+	query := strings.TrimSpace(args.Query)
+	if query == "" {
+		return nil, models.ArgsError{{Arg: "query", Problem: "不能为空"}}
+	}
+	if clientPool == nil {
+		return mcp.NewToolResultStructuredOnly(makeResult([]map[string]interface{}{})), nil
+	}
+	targets, err := server.FindOffboardTargets(ctx, clientPool, query, args.Instances)
+	if err != nil {
+		return nil, err
+	}
+	if args.DryRun {
+		return mcp.NewToolResultStructuredOnly(makeResult(server.PlanOffboard(targets))), nil
+	}
+	accounts := 0
+	for _, t := range targets {
+		accounts += len(t.Accounts)
+	}
+	if accounts > 0 {
+		if res, err := confirmMutationFor(ctx, req, "offboard_user", accounts, server.OffboardTargetIDs(targets), func() (*models.MutationPlan, error) {
+			return server.PlanOffboard(targets), nil
+		}); res != nil || err != nil {
+			return res, err
 		}
-		if v, ok := args["userid"].(string); ok && v != "" {
-			userIDs = append(userIDs, v)
+	}
+	report := server.Offboard(ctx, clientPool, query, targets)
+	logger.L().Infof("离职处理 %s: 匹配 %d 个账号，禁用 %d 个，失败 %d 个", query, report.Summary.Matched, report.Summary.Disabled, report.Summary.Failed)
+	return mcp.NewToolResultStructuredOnly(makeResult(report)), nil
+}
+
+// BulkCreateUsersHandler 按 CSV/JSON 批量开通用户，返回逐行的处理结果
+func BulkCreateUsersHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var args models.BulkCreateUsersArgs
+	if err := bindArgs(req, &args); err != nil {
+		return nil, err
+	}
+	rows, err := server.ParseBulkUsers(args.Data, args.DataFormat)
+	if err != nil {
+		return nil, err
+	}
+	if clientPool == nil {
+		return mcp.NewToolResultStructuredOnly(makeResult([]map[string]interface{}{})), nil
+	}
+	batch, err := server.PrepareBulkUsers(ctx, clientPool, args.Instance, rows, server.BulkUserOptions{Mode: args.Mode, EmailMediaType: args.EmailMediaType})
+	if err != nil {
+		return nil, err
+	}
+	if args.DryRun {
+		return mcp.NewToolResultStructuredOnly(makeResult(batch.Report(true))), nil
+	}
+	if changes := batch.Changes(); changes > 0 {
+		if res, err := confirmMutation(ctx, req, "bulk_create_users", changes, func() (*models.MutationPlan, error) {
+			return batch.Plan(), nil
+		}); res != nil || err != nil {
+			return res, err
 		}
 	}
+	report := server.ExecuteBulkUsers(ctx, clientPool, batch, credentialPolicy.Deliverer)
+	logger.L().Infof("批量开通 %s: 共 %d 行，创建 %d 个，更新 %d 个，跳过 %d 个，失败 %d 个", args.Instance,
+		report.Summary.Total, report.Summary.Created, report.Summary.Updated, report.Summary.Skipped, report.Summary.Failed)
+	return mcp.NewToolResultStructuredOnly(makeResult(report)), nil
+}
+
+func DeleteUsersHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var args models.DeleteUsersArgs
+	if err := bindArgs(req, &args); err != nil {
+		return nil, err
+	}
 	if clientPool == nil {
 		return mcp.NewToolResultStructuredOnly(makeResult([]map[string]interface{}{})), nil
 	}
-	spec := models.UserParams{UserIDs: userIDs}
-	users, err := server.DeleteUsers(ctx, clientPool, spec, instanceName)
+	spec := models.UserParams{UserIDs: args.UserIDs}
+	if args.DryRun {
+		plan, err := server.PlanDeleteUsers(ctx, clientPool, spec, args.Instance)
+		if err != nil {
+			return nil, fmt.Errorf("预览 user.delete 失败: %w", err)
+		}
+		return mcp.NewToolResultStructuredOnly(makeResult(plan)), nil
+	}
+	if res, err := confirmMutation(ctx, req, "delete_user", len(args.UserIDs), func() (*models.MutationPlan, error) {
+		return server.PlanDeleteUsers(ctx, clientPool, spec, args.Instance)
+	}); res != nil || err != nil {
+		return res, err
+	}
+	users, err := server.DeleteUsers(ctx, clientPool, spec, args.Instance)
 	if err != nil {
 		logger.L().Errorf("调用 user.delete 失败: %w", err)
 		return nil, fmt.Errorf("调用 user.delete 失败: %w", err)
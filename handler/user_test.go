@@ -0,0 +1,97 @@
+package handler_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"zabbixMcp/handler"
+	"zabbixMcp/models"
+	"zabbixMcp/zabbix/zabbixtest"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// callTool 直接调用处理器，返回结构化结果中的 data
+func callTool(t *testing.T, h func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error), args map[string]interface{}) (map[string]interface{}, error) {
+	t.Helper()
+	var req mcp.CallToolRequest
+	req.Params.Arguments = args
+	res, err := h(context.Background(), req)
+	if err != nil {
+		return nil, err
+	}
+	content, _ := res.StructuredContent.(map[string]interface{})
+	data, _ := content["data"].(map[string]interface{})
+	return data, nil
+}
+
+// TestOffboardConfirmRejectsChangedTargets 预览之后匹配到的对象发生变化时，同样的参数与令牌不能执行离职处理
+func TestOffboardConfirmRejectsChangedTargets(t *testing.T) {
+	srv := testServer(t)
+	handler.SetConfirmationPolicy(handler.ConfirmationPolicy{Enabled: true, Tools: []string{"offboard_user"}, TokenTTL: time.Minute})
+	userID := srv.Store().AddUser(zabbixtest.User{
+		Username: "leaver", Name: "Lea", Surname: "Ver", RoleID: "1", GroupIDs: []string{"8"},
+		Medias: []zabbixtest.Media{{MediaTypeID: "1", SendTo: "leaver@example.com", Active: "0"}},
+	})
+	args := map[string]interface{}{"query": "leaver@example.com", "instances": []interface{}{"zbx"}}
+
+	preview, err := callTool(t, handler.OffboardUserHandler, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, _ := preview["confirm_token"].(string)
+	if token == "" {
+		t.Fatalf("首次调用应返回确认令牌: %v", preview)
+	}
+
+	// 预览之后为该账号新建了 API 令牌
+	srv.Store().AddAPIToken(zabbixtest.APIToken{Name: "late", UserID: userID, Token: "late-token"})
+	args["confirm_token"] = token
+	_, err = callTool(t, handler.OffboardUserHandler, args)
+	var merr *models.Error
+	if !errors.As(err, &merr) || merr.Code != models.CodeConflict {
+		t.Fatalf("目标变化后确认: err = %v，期望 %s", err, models.CodeConflict)
+	}
+	if u, _ := srv.Store().User(userID); len(u.GroupIDs) != 1 || u.GroupIDs[0] != "8" {
+		t.Fatalf("被拒绝的确认不应修改账号，用户组 = %v", u.GroupIDs)
+	}
+	if _, err := callTool(t, handler.OffboardUserHandler, args); err == nil {
+		t.Fatal("被拒绝的令牌应已作废")
+	}
+
+	// 重新预览后使用新令牌执行
+	delete(args, "confirm_token")
+	preview, err = callTool(t, handler.OffboardUserHandler, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	args["confirm_token"], _ = preview["confirm_token"].(string)
+	if _, err := callTool(t, handler.OffboardUserHandler, args); err != nil {
+		t.Fatalf("重新预览后确认失败: %v", err)
+	}
+	if u, _ := srv.Store().User(userID); !slices.Contains(u.GroupIDs, "12") || slices.Contains(u.GroupIDs, "8") {
+		t.Errorf("离职处理后用户组 = %v，期望移出 Guests(8) 并加入 Disabled(12)", u.GroupIDs)
+	}
+}
+
+// TestDeleteUserAcceptsUserID 旧版本的 userid 参数与 userids 合并
+func TestDeleteUserAcceptsUserID(t *testing.T) {
+	srv := testServer(t)
+	handler.SetConfirmationPolicy(handler.ConfirmationPolicy{})
+	single := srv.Store().AddUser(zabbixtest.User{Username: "delete-single", RoleID: "1", GroupIDs: []string{"8"}})
+	listed := srv.Store().AddUser(zabbixtest.User{Username: "delete-listed", RoleID: "1", GroupIDs: []string{"8"}})
+
+	if _, err := callTool(t, handler.DeleteUsersHandler, map[string]interface{}{
+		"instance": "zbx", "userids": []interface{}{listed}, "userid": single,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{single, listed} {
+		if _, ok := srv.Store().User(id); ok {
+			t.Errorf("用户 %s 应已删除", id)
+		}
+	}
+}
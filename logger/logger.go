@@ -69,6 +69,11 @@ func InitLogger() error {
 	// 创建控制台写入器
 	consoleWriter := zapcore.Lock(os.Stdout)
 
+	// 未调用 SetLogLevel 时（例如测试中直接使用 L()）默认使用 info 等级
+	if atomicLevel == (zap.AtomicLevel{}) {
+		atomicLevel = zap.NewAtomicLevelAt(zapcore.InfoLevel)
+	}
+
 	// 创建多写入器（同时写入文件和控制台），使用 atomicLevel 以支持运行时调整
 	core := zapcore.NewTee(
 		zapcore.NewCore(jsonEncoder, fileWriter, atomicLevel),
@@ -10,23 +10,45 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/user"
+	"strings"
+	"time"
+	"zabbixMcp/audit"
+	"zabbixMcp/credential"
 	"zabbixMcp/handler"
 	lg "zabbixMcp/logger"
+	"zabbixMcp/metrics"
 	"zabbixMcp/register"
+	pkgserver "zabbixMcp/server"
+	"zabbixMcp/tracing"
 	zabbix "zabbixMcp/zabbix"
 
 	"github.com/mark3labs/mcp-go/server"
 )
 
 func main() {
+	// 子命令
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "bulk-users":
+			os.Exit(runBulkUsers(os.Args[2:]))
+		case "credentials":
+			os.Exit(runCredentials(os.Args[2:]))
+		}
+	}
 	// 定义命令行参数
 	var (
 		stdioMode = flag.Bool("stdio", false, "使用stdio传输方式")
 		httpMode  = flag.Bool("http", false, "使用HTTP/SSE传输方式")
 		port      = flag.Int("port", 5443, "HTTP/SSE监听端口")
 		level     = flag.String("loglevel", "info", "日志等级 (debug, info, warn, error, panic, fatal)")
+		recordDir = flag.String("record", "", "录制 Zabbix API 请求/响应（已脱敏）到指定目录，用于生成回放 fixture")
 	)
 	flag.Parse()
 	// 初始化日志
@@ -42,12 +64,44 @@ func main() {
 		lg.L().Fatalf("加载配置失败: %v", err)
 	}
 
+	// 链路追踪
+	shutdownTracing, err := tracing.Init(context.Background(), tracing.Config{
+		Enabled:     AppConfig.Tracing.Enabled,
+		Endpoint:    AppConfig.Tracing.Endpoint,
+		Insecure:    AppConfig.Tracing.Insecure,
+		ServiceName: AppConfig.Tracing.ServiceName,
+		SampleRatio: AppConfig.Tracing.SampleRatio,
+	})
+	if err != nil {
+		lg.L().Fatalf("初始化链路追踪失败: %v", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			lg.L().Warnf("刷新链路追踪数据失败: %v", err)
+		}
+	}()
+
 	// 根据配置创建 Zabbix 客户端池（通过接口方式，不直接暴露底层类型）
-	poolHandler, err := InitPoolsFromConfig()
+	if *recordDir != "" {
+		lg.L().Warnf("已开启 Zabbix API 录制，fixture 目录: %s", *recordDir)
+	}
+	poolHandler, err := InitPoolsFromConfig(*recordDir)
 	if err != nil {
 		lg.L().Fatalf("初始化 Zabbix 客户端池失败: %v", err)
 	}
 	if poolHandler != nil {
+		if err := metrics.RegisterPoolCollector(func() []metrics.PoolMember {
+			infos := poolHandler.Info("")
+			members := make([]metrics.PoolMember, 0, len(infos))
+			for _, info := range infos {
+				members = append(members, metrics.PoolMember{Instance: info.Instance, InUse: info.InUse, Connected: info.Connected})
+			}
+			return members
+		}); err != nil {
+			lg.L().Warnf("注册连接池指标失败: %v", err)
+		}
 		infos := poolHandler.Info("")
 		lg.L().Infof("已初始化 Zabbix 客户端池，容量=%d", len(infos))
 		for _, info := range infos {
@@ -55,72 +109,300 @@ func main() {
 		}
 	}
 
+	// 审计日志
+	var auditLog *audit.Log
+	if AppConfig.Audit.Enabled {
+		if auditLog, err = audit.Open(AppConfig.Audit.Path); err != nil {
+			lg.L().Fatalf("初始化审计日志失败: %v", err)
+		}
+		defer auditLog.Close()
+		handler.SetAuditLog(auditLog)
+		lg.L().Infof("审计日志: %s", auditLog.Path())
+	}
+
 	// 创建MCP服务器
 	s := server.NewMCPServer(
 		"zabbix-mcp-server",
 		"1.0.0",
+		server.WithElicitation(),
+		server.WithLogging(),
+		server.WithResourceCapabilities(AppConfig.Resources.Subscribe, false),
+		server.WithHooks(handler.ResourceHooks()),
 	)
+	s.Use(toolMiddlewares(auditLog)...)
 	lg.L().Info("MCP服务器创建成功")
 
+	// 破坏性操作二次确认策略
+	handler.SetConfirmationPolicy(handler.ConfirmationPolicy{
+		Enabled:     AppConfig.Confirmation.Enabled,
+		Tools:       AppConfig.Confirmation.Tools,
+		MaxObjects:  AppConfig.Confirmation.MaxObjects,
+		TokenTTL:    time.Duration(AppConfig.Confirmation.TokenTTL) * time.Second,
+		Elicitation: AppConfig.Confirmation.Elicitation,
+	})
+
+	// 输出预算
+	handler.SetOutputPolicy(handler.OutputPolicy{MaxTokens: AppConfig.Output.MaxTokens})
+
+	// *.get 分页策略
+	handler.SetPaginationPolicy(handler.PaginationPolicy{
+		DefaultLimit: AppConfig.Pagination.DefaultLimit,
+		MaxLimit:     AppConfig.Pagination.MaxLimit,
+		Tools:        AppConfig.Pagination.Tools,
+	})
+
+	// api_call 方法放行策略
+	handler.SetAPICallPolicy(handler.APICallPolicy{
+		Enabled:        AppConfig.APICall.Enabled,
+		Allow:          AppConfig.APICall.Allow,
+		Deny:           AppConfig.APICall.Deny,
+		MaxParamsBytes: AppConfig.APICall.MaxParamsBytes,
+	})
+
+	// 资源与订阅
+	handler.SetResourcePolicy(handler.ResourcePolicy{ProblemLimit: AppConfig.Resources.ProblemLimit})
+	handler.SetSubscriptionPolicy(handler.SubscriptionPolicy{
+		Enabled:       AppConfig.Resources.Subscribe,
+		Interval:      time.Duration(AppConfig.Resources.PollInterval) * time.Second,
+		MaxPerSession: AppConfig.Resources.MaxSubscriptions,
+	})
+
+	// 实时问题推送
+	handler.SetProblemFeedPolicy(handler.ProblemFeedPolicy{
+		Enabled:  AppConfig.ProblemFeed.Enabled,
+		Interval: time.Duration(AppConfig.ProblemFeed.Interval) * time.Second,
+	})
+
+	// 生成密码的投递方式
+	deliverer, links, err := newCredentialDeliverer(AppConfig.Credentials, poolHandler, *port, !*stdioMode)
+	if err != nil {
+		lg.L().Fatalf("初始化密码投递失败: %v", err)
+	}
+	handler.SetCredentialPolicy(handler.CredentialPolicy{Deliverer: deliverer, MediaType: AppConfig.Credentials.SMTP.MediaType})
+	lg.L().Infof("生成的密码投递方式: %s", deliverer.Method())
+
 	// 注册工具
 	register.Registers(s)
 	lg.L().Info("工具注册完成")
+	go handler.WatchResources(context.Background(), s)
+	go handler.WatchProblems(context.Background(), s)
 
 	// 根据参数选择传输方式
 	if *stdioMode {
 		// 启动stdio服务器
 		lg.L().Info("启动stdio传输方式的MCP服务器...")
-		if err := server.ServeStdio(s); err != nil {
+		if err := serveStdio(s); err != nil {
 			lg.L().Fatalf("stdio服务器启动失败: %v", err)
 		}
 	} else if *httpMode {
 		// 启动HTTP/SSE服务器
-		startHTTPServer(s, *port)
+		startHTTPServer(s, *port, links)
 	} else {
 		// 默认同时启动两种方式（在不同的goroutine中）
 		lg.L().Info("同时启动stdio和HTTP/SSE传输方式的MCP服务器...")
 
 		// 在后台启动HTTP服务器
-		go startHTTPServer(s, *port)
+		go startHTTPServer(s, *port, links)
 
 		// 在主线程启动stdio服务器
-		if err := server.ServeStdio(s); err != nil {
+		if err := serveStdio(s); err != nil {
 			lg.L().Fatalf("stdio服务器启动失败: %v", err)
 		}
 	}
 }
 
-// startHTTPServer 启动HTTP传输服务器（使用SSE）
-func startHTTPServer(s *server.MCPServer, port int) {
+// toolMiddlewares 工具调用中间件，由外到内依次执行。审计位于输出层之外，
+// fields/format/输出预算等参数校验失败而被输出层拒绝的调用同样会记录审计日志
+func toolMiddlewares(auditLog *audit.Log) []server.ToolHandlerMiddleware {
+	return []server.ToolHandlerMiddleware{
+		handler.ErrorMiddleware(),
+		audit.Middleware(auditLog),
+		handler.OutputMiddleware(),
+		tracing.Middleware(),
+		metrics.Middleware(),
+	}
+}
+
+// startHTTPServer 启动HTTP传输服务器（使用SSE），同时在 /metrics 暴露 Prometheus 指标；
+// links 不为 nil 时在 /credentials/ 提供一次性密码取回链接
+func startHTTPServer(s *server.MCPServer, port int, links *credential.LinkStore) {
 	addr := fmt.Sprintf(":%d", port)
 	lg.L().Infof("启动HTTP/SSE传输服务器，监听端口: %d", port)
 	lg.L().Infof("MCP端点: http://localhost:%d", port)
+	lg.L().Infof("指标端点: http://localhost:%d/metrics", port)
 
 	// 使用v0.9.0版本支持的API：创建SSE服务器
-	sseServer := server.NewSSEServer(s)
-	if err := sseServer.Start(addr); err != nil {
+	trusted, err := parseTrustedProxies(AppConfig.Audit.TrustedProxies)
+	if err != nil {
+		lg.L().Fatalf("%v", err)
+	}
+	sseServer := server.NewSSEServer(s, server.WithSSEContextFunc(httpCallerContext(trusted)))
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	if links != nil {
+		mux.Handle(credential.LinkPath, links)
+	}
+	mux.Handle("/", sseServer)
+	if err := http.ListenAndServe(addr, mux); err != nil {
 		lg.L().Fatalf("HTTP/SSE服务器启动失败: %v", err)
 	}
 }
 
-// InitPoolsFromConfig 根据全局 AppConfig 创建并返回一个客户端池，池容量等于实例数量
-func InitPoolsFromConfig() (zabbix.ClientProvider, error) {
+// serveStdio 启动 stdio 传输，调用方身份记为当前系统用户
+func serveStdio(s *server.MCPServer) error {
+	identity := "unknown"
+	if u, err := user.Current(); err == nil {
+		identity = u.Username
+	}
+	return server.ServeStdio(s, server.WithStdioContextFunc(func(ctx context.Context) context.Context {
+		return audit.WithCaller(ctx, identity, "stdio")
+	}))
+}
+
+// httpCallerContext 从 HTTP 请求中提取调用方身份：只有远端地址属于 trusted 中的可信代理时才采信
+// X-Forwarded-User / X-User 请求头，否则一律记录远端地址，避免直连客户端伪造审计身份
+func httpCallerContext(trusted []*net.IPNet) func(ctx context.Context, r *http.Request) context.Context {
+	return func(ctx context.Context, r *http.Request) context.Context {
+		identity := r.RemoteAddr
+		if fromTrustedProxy(trusted, r.RemoteAddr) {
+			if user := r.Header.Get("X-Forwarded-User"); user != "" {
+				identity = user
+			} else if user := r.Header.Get("X-User"); user != "" {
+				identity = user
+			}
+		}
+		return audit.WithCaller(ctx, identity, "sse")
+	}
+}
+
+// fromTrustedProxy 判断远端地址是否属于可信代理
+func fromTrustedProxy(trusted []*net.IPNet, remoteAddr string) bool {
+	if len(trusted) == 0 {
+		return false
+	}
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, n := range trusted {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTrustedProxies 解析 audit.trusted_proxies，单个 IP 视为 /32 或 /128
+func parseTrustedProxies(entries []string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, e := range entries {
+		e = strings.TrimSpace(e)
+		if e == "" {
+			continue
+		}
+		if !strings.Contains(e, "/") {
+			ip := net.ParseIP(e)
+			if ip == nil {
+				return nil, fmt.Errorf("audit.trusted_proxies 中的 %q 不是有效的 IP 或 CIDR", e)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(e)
+		if err != nil {
+			return nil, fmt.Errorf("audit.trusted_proxies 中的 %q 不是有效的 IP 或 CIDR", e)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+// newCredentialDeliverer 按 credentials 配置创建密码投递方式；link 方式需要 HTTP 服务，同时返回挂载到 HTTP 服务上的取回链接存储
+func newCredentialDeliverer(cfg CredentialsConfig, provider zabbix.ClientProvider, port int, httpEnabled bool) (credential.Deliverer, *credential.LinkStore, error) {
+	switch cfg.Delivery {
+	case credential.MethodFile, "":
+		store, err := credential.OpenFile(cfg.File.Path, cfg.File.KeyEnv, cfg.File.KeyFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		return store, nil, nil
+	case credential.MethodSMTP:
+		if provider == nil {
+			return nil, nil, fmt.Errorf("smtp 方式需要至少配置一个 Zabbix 实例，用于读取邮件媒介类型的 SMTP 设置")
+		}
+		mailer := &credential.SMTPMailer{
+			Lookup:            pkgserver.EmailMediaLookup(provider, cfg.SMTP.MediaType),
+			AllowInsecureAuth: cfg.SMTP.AllowInsecureAuth,
+		}
+		if cfg.SMTP.SMTPPasswordEnv != "" {
+			mailer.SMTPPassword = os.Getenv(cfg.SMTP.SMTPPasswordEnv)
+		}
+		return mailer, nil, nil
+	case credential.MethodLink:
+		if !httpEnabled {
+			return nil, nil, fmt.Errorf("link 方式需要启动 HTTP 服务，不能与 -stdio 同时使用")
+		}
+		baseURL := cfg.Link.BaseURL
+		if baseURL == "" {
+			baseURL = fmt.Sprintf("http://localhost:%d", port)
+		}
+		links := credential.NewLinkStore(baseURL, time.Duration(cfg.Link.TTL)*time.Second)
+		return links, links, nil
+	case credential.MethodInline:
+		lg.L().Warn("credentials.delivery 为 inline，生成的密码会出现在工具结果、对话记录与日志中")
+		return credential.Inline{}, nil, nil
+	}
+	return nil, nil, fmt.Errorf("不支持的 credentials.delivery %q，可选 file、smtp、link、inline", cfg.Delivery)
+}
+
+// cacheConfig 把 cache 配置转换为客户端的缓存配置；未启用时返回 nil（只缓存版本信息）
+func cacheConfig(cfg CacheConfig) *zabbix.CacheConfig {
+	if !cfg.Enabled {
+		return nil
+	}
+	out := &zabbix.CacheConfig{
+		TTL:        time.Duration(cfg.TTL) * time.Second,
+		MaxEntries: cfg.MaxEntries,
+		Methods:    make(map[string]time.Duration, len(cfg.Methods)),
+	}
+	for method, ttl := range cfg.Methods {
+		out.Methods[method] = time.Duration(ttl) * time.Second
+	}
+	return out
+}
+
+// InitPoolsFromConfig 根据全局 AppConfig 创建并返回一个客户端池，池容量等于实例数量；
+// recordDir 非空时所有实例开启请求录制
+func InitPoolsFromConfig(recordDir string) (zabbix.ClientProvider, error) {
 	n := len(AppConfig.Instances)
 	if n == 0 {
 		return nil, nil
 	}
 
+	cache := cacheConfig(AppConfig.Cache)
 	cfgs := make([]zabbix.ClientConfig, 0, n)
+	names := make([]string, 0, n)
 	for _, inst := range AppConfig.Instances {
+		names = append(names, inst.Name)
 		cfgs = append(cfgs, zabbix.ClientConfig{
-			Instance: inst.Name,
-			URL:      inst.URL,
-			User:     inst.User,
-			Pass:     inst.Pass,
-			Token:    inst.Token,
-			AuthType: inst.AuthType,
-			Timeout:  30,
-			ServerTZ: "",
+			Instance:  inst.Name,
+			URL:       inst.URL,
+			User:      inst.User,
+			Pass:      inst.Pass,
+			Token:     inst.Token,
+			AuthType:  inst.AuthType,
+			Timeout:   30,
+			ServerTZ:  inst.ServerTZ,
+			RecordDir: recordDir,
+			Cache:     cache,
 		})
 	}
 
@@ -131,5 +413,6 @@ func InitPoolsFromConfig() (zabbix.ClientProvider, error) {
 	}
 
 	handler.SetClientPool(handlerObj)
+	metrics.SetInstances(names)
 	return handlerObj, nil
 }
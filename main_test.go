@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"zabbixMcp/audit"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func TestHTTPCallerContext(t *testing.T) {
+	trusted, err := parseTrustedProxies([]string{"10.0.0.1", "192.168.0.0/16"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name    string
+		trusted bool
+		remote  string
+		headers map[string]string
+		want    string
+	}{
+		{"无可信代理时忽略请求头", false, "10.0.0.1:4000", map[string]string{"X-Forwarded-User": "admin"}, "10.0.0.1:4000"},
+		{"直连客户端伪造请求头", true, "172.16.0.9:4000", map[string]string{"X-Forwarded-User": "admin"}, "172.16.0.9:4000"},
+		{"可信代理单个IP", true, "10.0.0.1:4000", map[string]string{"X-Forwarded-User": "alice"}, "alice"},
+		{"可信代理网段与 X-User", true, "192.168.3.4:4000", map[string]string{"X-User": "bob"}, "bob"},
+		{"可信代理未带请求头", true, "192.168.3.4:4000", nil, "192.168.3.4:4000"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/sse", nil)
+			r.RemoteAddr = c.remote
+			for k, v := range c.headers {
+				r.Header.Set(k, v)
+			}
+			var nets = trusted
+			if !c.trusted {
+				nets = nil
+			}
+			caller, ok := audit.CallerFromContext(httpCallerContext(nets)(context.Background(), r))
+			if !ok || caller.Identity != c.want || caller.Transport != "sse" {
+				t.Fatalf("caller = %+v, want identity %q", caller, c.want)
+			}
+		})
+	}
+}
+
+func TestParseTrustedProxiesInvalid(t *testing.T) {
+	for _, e := range []string{"proxy.local", "10.0.0.0/33"} {
+		if _, err := parseTrustedProxies([]string{e}); err == nil {
+			t.Errorf("parseTrustedProxies(%q) 应当报错", e)
+		}
+	}
+}
+
+// TestToolMiddlewaresAuditOutputRejections 输出层拒绝的调用（例如 format 取值非法）同样写入审计日志
+func TestToolMiddlewaresAuditOutputRejections(t *testing.T) {
+	log, err := audit.Open(filepath.Join(t.TempDir(), "audit.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer log.Close()
+	s := server.NewMCPServer("test", "1.0.0")
+	s.Use(toolMiddlewares(log)...)
+	s.AddTool(mcp.NewTool("echo"), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultStructuredOnly(map[string]interface{}{"ok": true, "data": req.GetArguments()}), nil
+	})
+
+	for i, args := range []map[string]interface{}{
+		{"instance": "zbx"},
+		{"instance": "zbx", "format": "yaml"},
+		{"instance": "zbx", "max_tokens": -1},
+	} {
+		msg, _ := json.Marshal(map[string]any{
+			"jsonrpc": "2.0",
+			"id":      i + 1,
+			"method":  mcp.MethodToolsCall,
+			"params":  map[string]any{"name": "echo", "arguments": args},
+		})
+		if resp, ok := s.HandleMessage(context.Background(), msg).(mcp.JSONRPCResponse); !ok {
+			t.Fatalf("调用 %v: 意外的响应 %#v", args, resp)
+		}
+	}
+
+	entries, err := log.Query(audit.Filter{Tool: "echo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("审计记录数 = %d，期望 3", len(entries))
+	}
+	want := []string{audit.StatusOK, audit.StatusError, audit.StatusError}
+	for i, e := range entries {
+		if e.Status != want[i] || e.Instance != "zbx" {
+			t.Errorf("第 %d 条审计记录 = {status=%s instance=%s error=%q}，期望 status=%s", i, e.Status, e.Instance, e.Error, want[i])
+		}
+	}
+	if _, ok := entries[1].Arguments["format"]; !ok {
+		t.Errorf("被拒绝的调用应记录输出参数: %v", entries[1].Arguments)
+	}
+}
@@ -0,0 +1,88 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2026-01-06 10:12:08
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2026-01-06 10:12:08
+ * @FilePath: \zabbix-mcp-go\metrics\labels.go
+ * @Description: 限制来自用户输入的标签取值，避免任意实例名或方法名产生无限多的时间序列
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+package metrics
+
+import (
+	"strings"
+	"sync"
+)
+
+// 不在已知集合内的标签取值
+const (
+	unknownInstance = "unknown"
+	otherMethod     = "other"
+)
+
+var (
+	instancesMu sync.RWMutex
+	instances   = map[string]bool{}
+)
+
+// SetInstances 设置已配置的实例名称，其余实例名在标签中记为 unknown
+func SetInstances(names []string) {
+	m := make(map[string]bool, len(names))
+	for _, name := range names {
+		m[name] = true
+	}
+	instancesMu.Lock()
+	instances = m
+	instancesMu.Unlock()
+}
+
+// instanceLabel 返回实例标签值：空为 *，未配置的实例为 unknown
+func instanceLabel(instance string) string {
+	if instance == "" {
+		return anyInstance
+	}
+	instancesMu.RLock()
+	defer instancesMu.RUnlock()
+	if instances[instance] {
+		return instance
+	}
+	return unknownInstance
+}
+
+// apiObjects Zabbix API 的对象名称（4.0 至 7.x）
+var apiObjects = map[string]bool{}
+
+// apiVerbs Zabbix API 方法名中点号之后的部分，按小写保存规范写法
+var apiVerbs = map[string]string{}
+
+func init() {
+	for _, o := range strings.Fields(`action alert apiinfo application auditlog authentication autoregistration
+		configuration connector correlation dashboard dcheck dhost discoveryrule drule dservice event graph
+		graphitem graphprototype hanode history host hostgroup hostinterface hostprototype housekeeping httptest
+		iconmap image item itemprototype maintenance map mediatype mfa module problem proxy proxygroup regexp
+		report role screen screenitem script service settings sla task template templatedashboard templategroup
+		templatescreen templatescreenitem token trend trigger triggerprototype user userdirectory usergroup
+		usermacro valuemap`) {
+		apiObjects[o] = true
+	}
+	for _, v := range strings.Fields(`get create update delete massadd massupdate massremove login logout
+		checkAuthentication version export import importcompare acknowledge execute getscriptsbyhosts
+		getscriptsbyevents test generate copy resettotp unblock provisionsso getsli clear replacehostinterfaces
+		createglobal updateglobal deleteglobal getcredentials`) {
+		apiVerbs[strings.ToLower(v)] = v
+	}
+}
+
+// methodLabel 返回方法标签值：已知对象与动作组合保持规范写法，其余记为 other
+func methodLabel(method string) string {
+	object, verb, ok := strings.Cut(method, ".")
+	if !ok {
+		return otherMethod
+	}
+	object = strings.ToLower(object)
+	canonical, known := apiVerbs[strings.ToLower(verb)]
+	if !apiObjects[object] || !known {
+		return otherMethod
+	}
+	return object + "." + canonical
+}
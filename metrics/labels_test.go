@@ -0,0 +1,39 @@
+package metrics
+
+import "testing"
+
+func TestInstanceLabel(t *testing.T) {
+	SetInstances([]string{"prod", "test"})
+	defer SetInstances(nil)
+	cases := map[string]string{
+		"":        anyInstance,
+		"prod":    "prod",
+		"test":    "test",
+		"prdo":    unknownInstance,
+		"'; drop": unknownInstance,
+	}
+	for in, want := range cases {
+		if got := instanceLabel(in); got != want {
+			t.Errorf("instanceLabel(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestMethodLabel(t *testing.T) {
+	cases := map[string]string{
+		"host.get":                 "host.get",
+		"HOST.GET":                 "host.get",
+		"user.checkauthentication": "user.checkAuthentication",
+		"hostgroup.massadd":        "hostgroup.massadd",
+		"host.nosuchverb":          otherMethod,
+		"nosuchobject.get":         otherMethod,
+		"host":                     otherMethod,
+		"":                         otherMethod,
+		"host.get.extra":           otherMethod,
+	}
+	for in, want := range cases {
+		if got := methodLabel(in); got != want {
+			t.Errorf("methodLabel(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
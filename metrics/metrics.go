@@ -0,0 +1,182 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-24 09:30:12
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-24 09:30:12
+ * @FilePath: \zabbix-mcp-go\metrics\metrics.go
+ * @Description: Prometheus 指标
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"zabbixMcp/models"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "zabbix_mcp"
+
+// 工具调用结果
+const (
+	OutcomeOK        = "ok"
+	OutcomeToolError = "tool_error"
+	OutcomeError     = "error"
+)
+
+// anyInstance 未指定实例（由连接池任选）时使用的标签值
+const anyInstance = "*"
+
+var (
+	toolCalls = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tool_calls_total",
+		Help:      "MCP 工具调用次数",
+	}, []string{"tool", "instance", "outcome"})
+
+	toolDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "tool_call_duration_seconds",
+		Help:      "MCP 工具调用耗时",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"tool", "instance", "outcome"})
+
+	apiCalls = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "zabbix_api_calls_total",
+		Help:      "Zabbix API 调用次数，error_code 为 RPCError 错误码，成功为 none，非 API 错误为 transport",
+	}, []string{"instance", "method", "error_code"})
+
+	apiDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "zabbix_api_call_duration_seconds",
+		Help:      "Zabbix API 调用耗时",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"instance", "method"})
+
+	leaseWait = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "pool_lease_wait_seconds",
+		Help:      "从连接池租借客户端的等待时间",
+		Buckets:   []float64{0.001, 0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30},
+	}, []string{"instance"})
+
+	logins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "zabbix_logins_total",
+		Help:      "Zabbix 登录次数，kind 为 login（首次/令牌校验）或 relogin（认证失效后重新登录）",
+	}, []string{"instance", "kind", "result"})
+
+	authFallbacks = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "zabbix_auth_fallbacks_total",
+		Help:      "首选认证方式失败后改用另一种方式成功的次数，to 为 header 或 body",
+	}, []string{"instance", "to"})
+
+	cacheLookups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "zabbix_cache_lookups_total",
+		Help:      "响应缓存查找次数，result 为 hit、miss 或 bypass（调用方要求跳过缓存）",
+	}, []string{"instance", "method", "result"})
+
+	cacheInvalidations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "zabbix_cache_invalidated_entries_total",
+		Help:      "变更调用后清除的响应缓存条目数，method 为触发清除的变更方法",
+	}, []string{"instance", "method"})
+)
+
+func init() {
+	prometheus.MustRegister(toolCalls, toolDuration, apiCalls, apiDuration, leaseWait, logins, authFallbacks, cacheLookups, cacheInvalidations)
+}
+
+// Handler 返回 /metrics 的 HTTP 处理器
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// ObserveAPICall 记录一次 Zabbix API 调用，api_call 可传入任意方法名，不在已知方法集合内的记为 other
+func ObserveAPICall(instance, method string, d time.Duration, err error) {
+	method = methodLabel(method)
+	apiCalls.WithLabelValues(instance, method, errorCode(err)).Inc()
+	apiDuration.WithLabelValues(instance, method).Observe(d.Seconds())
+}
+
+// ObserveCache 记录一次响应缓存查找
+func ObserveCache(instance, method, result string) {
+	cacheLookups.WithLabelValues(instance, methodLabel(method), result).Inc()
+}
+
+// ObserveCacheInvalidation 记录变更调用后清除的缓存条目数
+func ObserveCacheInvalidation(instance, method string, n int) {
+	cacheInvalidations.WithLabelValues(instance, methodLabel(method)).Add(float64(n))
+}
+
+// ObserveLeaseWait 记录一次租借客户端的等待时间，未配置的实例名记为 unknown
+func ObserveLeaseWait(instance string, d time.Duration) {
+	leaseWait.WithLabelValues(instanceLabel(instance)).Observe(d.Seconds())
+}
+
+// ObserveLogin 记录一次登录，relogin 表示认证失效后的重新登录
+func ObserveLogin(instance string, relogin bool, err error) {
+	kind := "login"
+	if relogin {
+		kind = "relogin"
+	}
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	logins.WithLabelValues(instance, kind, result).Inc()
+}
+
+// ObserveAuthFallback 记录一次认证方式回退，header 表示改用 Authorization 头成功
+func ObserveAuthFallback(instance string, header bool) {
+	to := "body"
+	if header {
+		to = "header"
+	}
+	authFallbacks.WithLabelValues(instance, to).Inc()
+}
+
+func errorCode(err error) string {
+	if err == nil {
+		return "none"
+	}
+	var rpcErr *models.RPCError
+	if errors.As(err, &rpcErr) {
+		return strconv.Itoa(rpcErr.Code)
+	}
+	return "transport"
+}
+
+// Middleware 返回统计工具调用次数与耗时的中间件
+func Middleware() server.ToolHandlerMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			start := time.Now()
+			result, err := next(ctx, req)
+			outcome := OutcomeOK
+			switch {
+			case err != nil:
+				outcome = OutcomeError
+			case result != nil && result.IsError:
+				outcome = OutcomeToolError
+			}
+			arg, _ := req.GetArguments()["instance"].(string)
+			instance := instanceLabel(arg)
+			toolCalls.WithLabelValues(req.Params.Name, instance, outcome).Inc()
+			toolDuration.WithLabelValues(req.Params.Name, instance, outcome).Observe(time.Since(start).Seconds())
+			return result, err
+		}
+	}
+}
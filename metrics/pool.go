@@ -0,0 +1,69 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-24 10:05:44
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-24 10:05:44
+ * @FilePath: \zabbix-mcp-go\metrics\pool.go
+ * @Description: 连接池状态指标，采集时从 ClientPool.Info 实时计算
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PoolMember 连接池中一个客户端在采集时刻的状态
+type PoolMember struct {
+	Instance  string
+	InUse     bool
+	Connected bool
+}
+
+// poolCollector 每次采集时调用 source 获取连接池快照并生成 gauge
+type poolCollector struct {
+	source    func() []PoolMember
+	total     *prometheus.Desc
+	inUse     *prometheus.Desc
+	connected *prometheus.Desc
+}
+
+// RegisterPoolCollector 注册连接池 gauge（total / in_use / connected，按实例区分）
+func RegisterPoolCollector(source func() []PoolMember) error {
+	return prometheus.Register(&poolCollector{
+		source:    source,
+		total:     prometheus.NewDesc(namespace+"_pool_clients", "连接池中的客户端数量", []string{"instance"}, nil),
+		inUse:     prometheus.NewDesc(namespace+"_pool_clients_in_use", "正在被租借的客户端数量", []string{"instance"}, nil),
+		connected: prometheus.NewDesc(namespace+"_pool_clients_connected", "已具备认证信息的客户端数量", []string{"instance"}, nil),
+	})
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.total
+	ch <- c.inUse
+	ch <- c.connected
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	type counts struct{ total, inUse, connected int }
+	byInstance := map[string]*counts{}
+	for _, m := range c.source() {
+		cnt, ok := byInstance[m.Instance]
+		if !ok {
+			cnt = &counts{}
+			byInstance[m.Instance] = cnt
+		}
+		cnt.total++
+		if m.InUse {
+			cnt.inUse++
+		}
+		if m.Connected {
+			cnt.connected++
+		}
+	}
+	for instance, cnt := range byInstance {
+		ch <- prometheus.MustNewConstMetric(c.total, prometheus.GaugeValue, float64(cnt.total), instance)
+		ch <- prometheus.MustNewConstMetric(c.inUse, prometheus.GaugeValue, float64(cnt.inUse), instance)
+		ch <- prometheus.MustNewConstMetric(c.connected, prometheus.GaugeValue, float64(cnt.connected), instance)
+	}
+}
@@ -0,0 +1,540 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-26 14:05:32
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-26 14:05:32
+ * @FilePath: \zabbix-mcp-go\models\args.go
+ * @Description: 基于结构体标签的工具参数定义：生成输入 schema，并解码、校验工具参数
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+package models
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 工具参数结构体的字段通过标签声明一次，同时用于生成 MCP 输入 schema 与解码参数：
+//
+//	arg     参数名，追加 ,required 表示必填，例如 `arg:"instance,required"`
+//	desc    参数说明
+//	enum    允许的取值，逗号分隔，例如 `enum:"0,1"`
+//	default 未传入时使用的默认值，同时写入 schema
+//	type    仅用于 interface{} 字段，允许的 JSON 类型，逗号分隔，例如 `type:"object,array"`
+//	alias   仅用于数组字段，兼容旧版本的单值参数名，传入的单个值或数组合并到该字段，例如 `alias:"userid"`
+//
+// 支持的字段类型：string、bool、整数、浮点数，以及它们的切片；interface{} 接收任意 JSON 值（可用 type 限定）；
+// 匿名嵌入的结构体会被展开。
+// 没有 arg 标签的字段会被忽略。
+
+// ArgError 单个参数的错误
+type ArgError struct {
+	Arg     string `json:"arg"`
+	Problem string `json:"problem"`
+}
+
+func (e ArgError) Error() string {
+	return fmt.Sprintf("参数 %s %s", e.Arg, e.Problem)
+}
+
+// ArgsError 一次调用中全部参数错误，按参数名排序
+type ArgsError []ArgError
+
+func (e ArgsError) Error() string {
+	msgs := make([]string, len(e))
+	for i, ae := range e {
+		msgs[i] = ae.Error()
+	}
+	return "参数错误: " + strings.Join(msgs, "; ")
+}
+
+// argField 解析标签得到的参数描述
+type argField struct {
+	name     string
+	index    []int
+	typ      reflect.Type
+	required bool
+	desc     string
+	enum     []reflect.Value
+	def      *reflect.Value
+	types    []string // interface{} 字段允许的 JSON 类型
+	alias    string   // 合并到该数组字段的单值参数名
+}
+
+var argFieldCache sync.Map // reflect.Type -> []argField
+
+func argFieldsOf(t reflect.Type) ([]argField, error) {
+	if cached, ok := argFieldCache.Load(t); ok {
+		return cached.([]argField), nil
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("参数定义必须是结构体，实际为 %s", t)
+	}
+	var fields []argField
+	if err := collectArgFields(t, nil, &fields); err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	for _, f := range fields {
+		for _, name := range []string{f.name, f.alias} {
+			if name == "" {
+				continue
+			}
+			if seen[name] {
+				return nil, fmt.Errorf("%s 中参数 %s 重复定义", t, name)
+			}
+			seen[name] = true
+		}
+	}
+	argFieldCache.Store(t, fields)
+	return fields, nil
+}
+
+func collectArgFields(t reflect.Type, prefix []int, out *[]argField) error {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		index := append(append([]int(nil), prefix...), i)
+		tag, ok := sf.Tag.Lookup("arg")
+		if !ok {
+			if sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+				if err := collectArgFields(sf.Type, index, out); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		parts := strings.Split(tag, ",")
+		f := argField{name: parts[0], index: index, typ: sf.Type, desc: sf.Tag.Get("desc")}
+		for _, opt := range parts[1:] {
+			if opt != "required" {
+				return fmt.Errorf("%s.%s: 未知的 arg 选项 %q", t, sf.Name, opt)
+			}
+			f.required = true
+		}
+		if _, err := jsonType(f.typ); err != nil {
+			return fmt.Errorf("%s.%s: %w", t, sf.Name, err)
+		}
+		if types, ok := sf.Tag.Lookup("type"); ok {
+			if f.typ.Kind() != reflect.Interface {
+				return fmt.Errorf("%s.%s: type 标签只能用于 interface{} 字段", t, sf.Name)
+			}
+			f.types = strings.Split(types, ",")
+		}
+		if alias, ok := sf.Tag.Lookup("alias"); ok {
+			if f.typ.Kind() != reflect.Slice {
+				return fmt.Errorf("%s.%s: alias 标签只能用于数组字段", t, sf.Name)
+			}
+			f.alias = alias
+		}
+		elem := f.typ
+		if elem.Kind() == reflect.Slice {
+			elem = elem.Elem()
+		}
+		if enum, ok := sf.Tag.Lookup("enum"); ok {
+			for _, s := range strings.Split(enum, ",") {
+				v, err := parseScalar(elem, s)
+				if err != nil {
+					return fmt.Errorf("%s.%s: enum 取值 %q 无效: %w", t, sf.Name, s, err)
+				}
+				f.enum = append(f.enum, v)
+			}
+		}
+		if def, ok := sf.Tag.Lookup("default"); ok {
+			if f.typ.Kind() == reflect.Slice {
+				return fmt.Errorf("%s.%s: 数组参数不支持 default", t, sf.Name)
+			}
+			v, err := parseScalar(f.typ, def)
+			if err != nil {
+				return fmt.Errorf("%s.%s: default 取值 %q 无效: %w", t, sf.Name, def, err)
+			}
+			f.def = &v
+		}
+		*out = append(*out, f)
+	}
+	return nil
+}
+
+// jsonType 返回 Go 类型对应的 JSON schema 类型
+func jsonType(t reflect.Type) (string, error) {
+	switch t.Kind() {
+	case reflect.String:
+		return "string", nil
+	case reflect.Bool:
+		return "boolean", nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer", nil
+	case reflect.Float32, reflect.Float64:
+		return "number", nil
+	case reflect.Interface:
+		return "", nil
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Slice {
+			return "", fmt.Errorf("不支持嵌套数组 %s", t)
+		}
+		if et, err := jsonType(t.Elem()); err != nil || et == "" {
+			return "", fmt.Errorf("不支持的数组元素类型 %s", t.Elem())
+		}
+		return "array", nil
+	}
+	return "", fmt.Errorf("不支持的参数类型 %s", t)
+}
+
+// typeName 用于错误提示的类型名称
+func typeName(t reflect.Type) string {
+	names := map[string]string{"string": "字符串", "boolean": "布尔值", "integer": "整数", "number": "数字"}
+	jt, _ := jsonType(t)
+	if jt == "array" {
+		et, _ := jsonType(t.Elem())
+		return names[et] + "数组"
+	}
+	return names[jt]
+}
+
+// ArgsSchema 根据参数结构体 v 生成输入 schema 的 properties 与 required
+func ArgsSchema(v interface{}) (map[string]interface{}, []string, error) {
+	fields, err := argFieldsOf(reflect.TypeOf(v))
+	if err != nil {
+		return nil, nil, err
+	}
+	props := make(map[string]interface{}, len(fields))
+	required := []string{}
+	for _, f := range fields {
+		jt, _ := jsonType(f.typ)
+		prop := map[string]interface{}{}
+		switch {
+		case len(f.types) == 1:
+			prop["type"] = f.types[0]
+		case len(f.types) > 1:
+			prop["type"] = f.types
+		case jt != "":
+			prop["type"] = jt
+		}
+		if f.desc != "" {
+			prop["description"] = f.desc
+		}
+		var enum []interface{}
+		for _, e := range f.enum {
+			enum = append(enum, e.Interface())
+		}
+		if jt == "array" {
+			et, _ := jsonType(f.typ.Elem())
+			items := map[string]interface{}{"type": et}
+			if len(enum) > 0 {
+				items["enum"] = enum
+			}
+			prop["items"] = items
+		} else if len(enum) > 0 {
+			prop["enum"] = enum
+		}
+		if f.def != nil {
+			prop["default"] = f.def.Interface()
+		}
+		props[f.name] = prop
+		if f.alias != "" {
+			et, _ := jsonType(f.typ.Elem())
+			props[f.alias] = map[string]interface{}{
+				"type":        []string{et, "array"},
+				"description": fmt.Sprintf("%s 的单值写法，与 %s 合并", f.name, f.name),
+			}
+		}
+		if f.required {
+			required = append(required, f.name)
+		}
+	}
+	return props, required, nil
+}
+
+// DecodeArgs 将工具参数解码到 out（指向参数结构体的指针）。
+// 类型不符、取值不在 enum 中、缺少必填参数以及未声明的参数都会返回 ArgsError，逐个列出问题。
+func DecodeArgs(args map[string]interface{}, out interface{}) error {
+	rv := reflect.ValueOf(out)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("DecodeArgs 需要结构体指针，实际为 %T", out)
+	}
+	fields, err := argFieldsOf(rv.Elem().Type())
+	if err != nil {
+		return err
+	}
+	var problems ArgsError
+	known := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		known[f.name] = true
+		target := rv.Elem().FieldByIndex(f.index)
+		raw, ok := args[f.name]
+		if f.alias != "" {
+			known[f.alias] = true
+			merged, problem := mergeAlias(f, raw, args[f.alias])
+			if problem != "" {
+				problems = append(problems, ArgError{Arg: f.alias, Problem: problem})
+				continue
+			}
+			raw, ok = merged, merged != nil
+		}
+		if !ok || raw == nil {
+			switch {
+			case f.required:
+				problems = append(problems, ArgError{Arg: f.name, Problem: "为必填参数"})
+			case f.def != nil:
+				target.Set(*f.def)
+			}
+			continue
+		}
+		v, problem := convertArg(f.typ, raw)
+		if problem == "" && len(f.types) > 0 && !typeAllowed(f.types, jsonKind(raw)) {
+			problem = fmt.Sprintf("应为 %s，实际为%s", strings.Join(f.types, " 或 "), describe(raw))
+		}
+		if problem == "" {
+			problem = checkEnum(f, v)
+		}
+		if problem == "" && f.required && f.typ.Kind() == reflect.String && v.String() == "" {
+			problem = "为必填参数，不能为空"
+		}
+		if problem != "" {
+			problems = append(problems, ArgError{Arg: f.name, Problem: problem})
+			continue
+		}
+		target.Set(v)
+	}
+	for name := range args {
+		if !known[name] {
+			names := make([]string, 0, len(known))
+			for k := range known {
+				names = append(names, k)
+			}
+			sort.Strings(names)
+			problems = append(problems, ArgError{Arg: name, Problem: fmt.Sprintf("未定义，可用参数: %s", strings.Join(names, ", "))})
+		}
+	}
+	if len(problems) > 0 {
+		sort.SliceStable(problems, func(i, j int) bool { return problems[i].Arg < problems[j].Arg })
+		return problems
+	}
+	return nil
+}
+
+// mergeAlias 将别名参数的取值（单个值或数组）追加到数组参数 raw 之后，两者都未传入时返回 nil
+func mergeAlias(f argField, raw, alias interface{}) (interface{}, string) {
+	if alias == nil {
+		return raw, ""
+	}
+	items, ok := alias.([]interface{})
+	if !ok {
+		if _, problem := convertArg(f.typ.Elem(), alias); problem != "" {
+			return nil, problem
+		}
+		items = []interface{}{alias}
+	}
+	main, ok := raw.([]interface{})
+	if raw != nil && !ok {
+		return raw, "" // 类型错误由数组参数本身报告
+	}
+	return append(append([]interface{}(nil), main...), items...), ""
+}
+
+// convertArg 将 JSON 解码得到的值转换为字段类型，失败时返回问题描述
+func convertArg(t reflect.Type, raw interface{}) (reflect.Value, string) {
+	if t.Kind() == reflect.Slice {
+		items, ok := raw.([]interface{})
+		if !ok {
+			return reflect.Value{}, fmt.Sprintf("应为%s，实际为%s", typeName(t), describe(raw))
+		}
+		out := reflect.MakeSlice(t, 0, len(items))
+		for i, item := range items {
+			v, problem := convertArg(t.Elem(), item)
+			if problem != "" {
+				return reflect.Value{}, fmt.Sprintf("第 %d 个元素%s", i+1, problem)
+			}
+			out = reflect.Append(out, v)
+		}
+		return out, ""
+	}
+	v := reflect.New(t).Elem()
+	if t.Kind() == reflect.Interface {
+		v.Set(reflect.ValueOf(raw))
+		return v, ""
+	}
+	mismatch := fmt.Sprintf("应为%s，实际为%s", typeName(t), describe(raw))
+	switch t.Kind() {
+	case reflect.String:
+		s, ok := raw.(string)
+		if !ok {
+			return v, mismatch
+		}
+		v.SetString(s)
+	case reflect.Bool:
+		b, ok := raw.(bool)
+		if !ok {
+			return v, mismatch
+		}
+		v.SetBool(b)
+	case reflect.Float32, reflect.Float64:
+		n, ok := raw.(float64)
+		if !ok {
+			return v, mismatch
+		}
+		v.SetFloat(n)
+	default: // 整数
+		n, ok := raw.(float64)
+		if !ok {
+			return v, mismatch
+		}
+		if n != math.Trunc(n) {
+			return v, fmt.Sprintf("应为整数，实际为 %v", n)
+		}
+		switch t.Kind() {
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if n < 0 || v.OverflowUint(uint64(n)) {
+				return v, fmt.Sprintf("取值 %v 超出范围", n)
+			}
+			v.SetUint(uint64(n))
+		default:
+			if v.OverflowInt(int64(n)) {
+				return v, fmt.Sprintf("取值 %v 超出范围", n)
+			}
+			v.SetInt(int64(n))
+		}
+	}
+	return v, ""
+}
+
+func checkEnum(f argField, v reflect.Value) string {
+	if len(f.enum) == 0 {
+		return ""
+	}
+	values := []reflect.Value{v}
+	if v.Kind() == reflect.Slice {
+		values = values[:0]
+		for i := 0; i < v.Len(); i++ {
+			values = append(values, v.Index(i))
+		}
+	}
+	allowed := make([]string, len(f.enum))
+	for i, e := range f.enum {
+		allowed[i] = fmt.Sprint(e.Interface())
+	}
+	for _, item := range values {
+		found := false
+		for _, e := range f.enum {
+			if item.Interface() == e.Interface() {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Sprintf("取值 %v 无效，可选: %s", item.Interface(), strings.Join(allowed, ", "))
+		}
+	}
+	return ""
+}
+
+// parseScalar 解析 enum/default 标签中的取值
+func parseScalar(t reflect.Type, s string) (reflect.Value, error) {
+	v := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return v, err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, t.Bits())
+		if err != nil {
+			return v, err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, t.Bits())
+		if err != nil {
+			return v, err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, t.Bits())
+		if err != nil {
+			return v, err
+		}
+		v.SetFloat(n)
+	default:
+		return v, fmt.Errorf("类型 %s 不支持该标签", t)
+	}
+	return v, nil
+}
+
+// jsonKind 返回 JSON 解码得到的值对应的 JSON schema 类型
+func jsonKind(raw interface{}) string {
+	switch v := raw.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return "null"
+}
+
+// typeAllowed 判断 JSON 类型 kind 是否在 types 中，整数同时满足 number
+func typeAllowed(types []string, kind string) bool {
+	for _, item := range types {
+		if item == kind || (item == "number" && kind == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// describe 用于错误提示的实际取值描述
+func describe(raw interface{}) string {
+	switch v := raw.(type) {
+	case string:
+		return fmt.Sprintf("字符串 %q", v)
+	case bool:
+		return fmt.Sprintf("布尔值 %v", v)
+	case float64:
+		return fmt.Sprintf("数字 %v", v)
+	case []interface{}:
+		return "数组"
+	case map[string]interface{}:
+		return "对象"
+	}
+	return fmt.Sprintf("%T", raw)
+}
+
+// MutationArgs 变更类工具共用的预览与确认参数
+type MutationArgs struct {
+	DryRun       bool   `arg:"dry_run" desc:"仅预览将要执行的变更(方法、适配后的参数、受影响对象及新旧值对比)，不实际执行 默认: false"`
+	ConfirmToken string `arg:"confirm_token" desc:"二次确认令牌：需要确认的操作首次调用会返回该令牌，经用户同意后携带相同参数与令牌再次调用才会执行"`
+}
+
+// RawArg 查询类工具的原始结构开关
+type RawArg struct {
+	Raw bool `arg:"raw" desc:"返回Zabbix原始结构，不做跨版本字段归一化（调试用） 默认: false"`
+}
+
+// CacheArg 查询类工具的响应缓存开关
+type CacheArg struct {
+	Cache string `arg:"cache" enum:"use,bypass" default:"use" desc:"bypass 时不读取元数据缓存，直接请求 Zabbix 并刷新缓存"`
+}
+
+// PageArgs *.get 类工具共用的分页参数
+type PageArgs struct {
+	Limit     int    `arg:"limit" desc:"每页最多返回的条数，默认值与上限见服务端 pagination 配置"`
+	Cursor    string `arg:"cursor" desc:"继续获取下一页：传入上一次结果中 page.next_cursor 的值，其余查询参数需保持不变"`
+	CountOnly bool   `arg:"count_only" desc:"只返回匹配的数量（page.total），不返回对象，适合回答“有多少”类问题 默认: false"`
+}
@@ -0,0 +1,20 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-28 10:48:05
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-28 10:48:05
+ * @FilePath: \zabbix-mcp-go\models\args_host.go
+ * @Description: 主机工具参数
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+package models
+
+// GetHostsArgs get_hosts 工具参数
+type GetHostsArgs struct {
+	Instance string   `arg:"instance,required" desc:"Zabbix实例名称必须填"`
+	Name     string   `arg:"name" desc:"按主机可见名称模糊匹配,留空表示不限"`
+	GroupIDs []string `arg:"groupids" desc:"主机组ID列表,只返回属于这些主机组的主机"`
+	PageArgs
+	RawArg
+	CacheArg
+}
@@ -0,0 +1,54 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-26 15:20:44
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-26 15:20:44
+ * @FilePath: \zabbix-mcp-go\models\args_system.go
+ * @Description: 实例、审计、版本兼容等工具参数
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+package models
+
+// GetInstancesInfoArgs get_instances_info 工具参数
+type GetInstancesInfoArgs struct {
+	Instance string `arg:"instance" desc:"Zabbix实例名称"`
+}
+
+// GetAuditLogArgs get_audit_log 工具参数
+type GetAuditLogArgs struct {
+	Since    string `arg:"since" desc:"开始时间，支持Unix时间戳、RFC3339、2025-12-30 08:00、now-2h、now/d、last 30 minutes、yesterday、昨天 等写法；为 today、yesterday 等整段时间且未指定 until 时查询整段时间"`
+	Until    string `arg:"until" desc:"结束时间，写法同 since；now/d 等整段时间取其结束"`
+	Tool     string `arg:"tool" desc:"按工具名称过滤"`
+	Instance string `arg:"instance" desc:"按Zabbix实例名称过滤"`
+	Limit    int    `arg:"limit" default:"100" desc:"最多返回最新的N条记录"`
+}
+
+// GetAPICompatArgs get_api_compat 工具参数
+type GetAPICompatArgs struct {
+	Instance string `arg:"instance" desc:"Zabbix实例名称"`
+	Method   string `arg:"method" desc:"只查看指定API方法的规则，例如 user.get"`
+	All      bool   `arg:"all" desc:"同时列出当前版本不生效的规则 默认: false"`
+}
+
+// APICallArgs api_call 工具参数
+type APICallArgs struct {
+	Instance string      `arg:"instance,required" desc:"Zabbix实例名称必须填"`
+	Method   string      `arg:"method,required" desc:"Zabbix API方法，例如 host.get；只能调用配置中允许的方法（默认 *.get）"`
+	Params   interface{} `arg:"params" type:"object,array" desc:"方法参数，与 Zabbix API 文档一致；对象参数会按实例版本自动适配字段 默认: {}"`
+	RawArg
+	CacheArg
+	MutationArgs
+}
+
+// SubscribeProblemsArgs subscribe_problems 工具参数
+type SubscribeProblemsArgs struct {
+	Instance    string   `arg:"instance" desc:"只订阅指定实例的问题，为空时订阅全部实例"`
+	MinSeverity int      `arg:"min_severity" enum:"0,1,2,3,4,5" default:"0" desc:"最低严重级别 0未分类 1信息 2警告 3一般严重 4严重 5灾难"`
+	HostGroups  []string `arg:"hostgroups" desc:"只推送这些主机组（ID或名称）中主机的问题"`
+	Tags        []string `arg:"tags" desc:"只推送带有全部这些标签的问题，格式为 tag 或 tag=value"`
+}
+
+// UnsubscribeProblemsArgs unsubscribe_problems 工具参数
+type UnsubscribeProblemsArgs struct {
+	SubscriptionID string `arg:"subscription_id" desc:"要取消的订阅ID，为空时取消当前会话的全部问题订阅"`
+}
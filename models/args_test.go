@@ -0,0 +1,221 @@
+package models
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type testArgs struct {
+	Instance string      `arg:"instance,required" desc:"实例"`
+	Status   int         `arg:"status" enum:"0,1" default:"0" desc:"状态"`
+	Mode     string      `arg:"mode" enum:"use,bypass" default:"use"`
+	Count    uint8       `arg:"count"`
+	Ratio    float64     `arg:"ratio"`
+	Verbose  bool        `arg:"verbose"`
+	Tags     []string    `arg:"tags" enum:"a,b"`
+	IDs      []string    `arg:"ids" alias:"id"`
+	Filter   interface{} `arg:"filter" type:"object,array"`
+	Ignored  string
+	PageArgs
+}
+
+// problemsOf 返回 DecodeArgs 的错误中出错的参数名与问题，参数名有序
+func problemsOf(t *testing.T, err error) map[string]string {
+	t.Helper()
+	var ae ArgsError
+	if !errors.As(err, &ae) {
+		t.Fatalf("错误应为 ArgsError，实际为 %T: %v", err, err)
+	}
+	out := make(map[string]string, len(ae))
+	for i, p := range ae {
+		if i > 0 && ae[i-1].Arg > p.Arg {
+			t.Errorf("参数错误未按参数名排序: %v", ae)
+		}
+		out[p.Arg] = p.Problem
+	}
+	return out
+}
+
+func TestDecodeArgs(t *testing.T) {
+	var a testArgs
+	err := DecodeArgs(map[string]interface{}{
+		"instance": "zbx",
+		"status":   float64(1),
+		"count":    float64(20),
+		"ratio":    float64(1.5),
+		"verbose":  true,
+		"tags":     []interface{}{"a", "b"},
+		"filter":   map[string]interface{}{"k": "v"},
+		"cursor":   "abc",
+	}, &a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := testArgs{
+		Instance: "zbx", Status: 1, Mode: "use", Count: 20, Ratio: 1.5, Verbose: true,
+		Tags: []string{"a", "b"}, Filter: map[string]interface{}{"k": "v"}, PageArgs: PageArgs{Cursor: "abc"},
+	}
+	if !reflect.DeepEqual(a, want) {
+		t.Errorf("解码结果 = %+v\n期望 %+v", a, want)
+	}
+}
+
+func TestDecodeArgsDefaults(t *testing.T) {
+	var a testArgs
+	if err := DecodeArgs(map[string]interface{}{"instance": "zbx", "status": nil}, &a); err != nil {
+		t.Fatal(err)
+	}
+	if a.Status != 0 || a.Mode != "use" || a.IDs != nil || a.Filter != nil {
+		t.Errorf("未传入的参数应取默认值: %+v", a)
+	}
+}
+
+func TestDecodeArgsProblems(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		args map[string]interface{}
+		want map[string]string // 参数名 -> 问题描述中应包含的内容
+	}{
+		{"缺少必填", map[string]interface{}{}, map[string]string{"instance": "为必填参数"}},
+		{"必填为空串", map[string]interface{}{"instance": ""}, map[string]string{"instance": "不能为空"}},
+		{"类型不符", map[string]interface{}{"instance": float64(1), "verbose": "true", "tags": "a"},
+			map[string]string{"instance": "应为字符串，实际为数字 1", "verbose": "应为布尔值", "tags": "应为字符串数组"}},
+		{"数字字符串不转换", map[string]interface{}{"instance": "zbx", "status": "1"}, map[string]string{"status": "应为整数"}},
+		{"非整数", map[string]interface{}{"instance": "zbx", "status": float64(1.5)}, map[string]string{"status": "应为整数，实际为 1.5"}},
+		{"超出范围", map[string]interface{}{"instance": "zbx", "count": float64(256)}, map[string]string{"count": "超出范围"}},
+		{"负数", map[string]interface{}{"instance": "zbx", "count": float64(-1)}, map[string]string{"count": "超出范围"}},
+		{"不在 enum 中", map[string]interface{}{"instance": "zbx", "status": float64(2), "mode": "skip"},
+			map[string]string{"status": "取值 2 无效，可选: 0, 1", "mode": "可选: use, bypass"}},
+		{"数组元素不在 enum 中", map[string]interface{}{"instance": "zbx", "tags": []interface{}{"a", "c"}}, map[string]string{"tags": "取值 c 无效"}},
+		{"数组元素类型不符", map[string]interface{}{"instance": "zbx", "ids": []interface{}{"1", float64(2)}}, map[string]string{"ids": "第 2 个元素应为字符串"}},
+		{"interface 类型限定", map[string]interface{}{"instance": "zbx", "filter": "x"}, map[string]string{"filter": "应为 object 或 array"}},
+		{"未定义的参数", map[string]interface{}{"instance": "zbx", "userid": "1", "Ignored": "x"},
+			map[string]string{"userid": "未定义，可用参数: count, count_only, cursor, filter, id, ids,", "Ignored": "未定义"}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			var a testArgs
+			got := problemsOf(t, DecodeArgs(c.args, &a))
+			if len(got) != len(c.want) {
+				t.Errorf("出错参数 = %v，期望 %v", got, c.want)
+			}
+			for arg, want := range c.want {
+				if !strings.Contains(got[arg], want) {
+					t.Errorf("%s: 问题 = %q，期望包含 %q", arg, got[arg], want)
+				}
+			}
+		})
+	}
+}
+
+// TestDecodeArgsAlias 别名参数接受单个值或数组，合并到对应的数组参数
+func TestDecodeArgsAlias(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		args map[string]interface{}
+		want []string
+	}{
+		{"仅数组", map[string]interface{}{"ids": []interface{}{"1", "2"}}, []string{"1", "2"}},
+		{"仅别名单值", map[string]interface{}{"id": "3"}, []string{"3"}},
+		{"仅别名数组", map[string]interface{}{"id": []interface{}{"3", "4"}}, []string{"3", "4"}},
+		{"合并", map[string]interface{}{"ids": []interface{}{"1"}, "id": "3"}, []string{"1", "3"}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			c.args["instance"] = "zbx"
+			var a testArgs
+			if err := DecodeArgs(c.args, &a); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(a.IDs, c.want) {
+				t.Errorf("ids = %v，期望 %v", a.IDs, c.want)
+			}
+		})
+	}
+
+	var a testArgs
+	got := problemsOf(t, DecodeArgs(map[string]interface{}{"instance": "zbx", "id": float64(3)}, &a))
+	if !strings.Contains(got["id"], "应为字符串") {
+		t.Errorf("别名类型不符: %v", got)
+	}
+}
+
+// TestDeleteUsersArgsUserID delete_user 兼容旧版本的 userid 参数
+func TestDeleteUsersArgsUserID(t *testing.T) {
+	var a DeleteUsersArgs
+	if err := DecodeArgs(map[string]interface{}{"instance": "zbx", "userid": "42"}, &a); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(a.UserIDs, []string{"42"}) {
+		t.Errorf("userids = %v，期望 [42]", a.UserIDs)
+	}
+	got := problemsOf(t, DecodeArgs(map[string]interface{}{"instance": "zbx"}, &a))
+	if !strings.Contains(got["userids"], "为必填参数") {
+		t.Errorf("userids 与 userid 都未传入: %v", got)
+	}
+}
+
+func TestArgsSchema(t *testing.T) {
+	props, required, err := ArgsSchema(testArgs{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(required, []string{"instance"}) {
+		t.Errorf("required = %v", required)
+	}
+	want := map[string]interface{}{
+		"instance":   map[string]interface{}{"type": "string", "description": "实例"},
+		"status":     map[string]interface{}{"type": "integer", "description": "状态", "enum": []interface{}{0, 1}, "default": 0},
+		"mode":       map[string]interface{}{"type": "string", "enum": []interface{}{"use", "bypass"}, "default": "use"},
+		"count":      map[string]interface{}{"type": "integer"},
+		"ratio":      map[string]interface{}{"type": "number"},
+		"verbose":    map[string]interface{}{"type": "boolean"},
+		"tags":       map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string", "enum": []interface{}{"a", "b"}}},
+		"ids":        map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+		"id":         map[string]interface{}{"type": []string{"string", "array"}, "description": "ids 的单值写法，与 ids 合并"},
+		"filter":     map[string]interface{}{"type": []string{"object", "array"}},
+		"limit":      map[string]interface{}{"type": "integer", "description": "每页最多返回的条数，默认值与上限见服务端 pagination 配置"},
+		"cursor":     map[string]interface{}{"type": "string", "description": "继续获取下一页：传入上一次结果中 page.next_cursor 的值，其余查询参数需保持不变"},
+		"count_only": map[string]interface{}{"type": "boolean", "description": "只返回匹配的数量（page.total），不返回对象，适合回答“有多少”类问题 默认: false"},
+	}
+	if !reflect.DeepEqual(props, want) {
+		t.Errorf("schema = %#v\n期望 %#v", props, want)
+	}
+}
+
+// TestArgsDefinitionErrors 标签定义错误在生成 schema 时报告
+func TestArgsDefinitionErrors(t *testing.T) {
+	for name, v := range map[string]interface{}{
+		"重复参数": struct {
+			A string `arg:"limit"`
+			PageArgs
+		}{},
+		"别名与参数重名": struct {
+			A []string `arg:"a" alias:"b"`
+			B string   `arg:"b"`
+		}{},
+		"别名用于非数组": struct {
+			A string `arg:"a" alias:"b"`
+		}{},
+		"未知选项": struct {
+			A string `arg:"a,optional"`
+		}{},
+		"无效 enum": struct {
+			A int `arg:"a" enum:"x"`
+		}{},
+		"数组 default": struct {
+			A []int `arg:"a" default:"1"`
+		}{},
+		"type 用于非接口": struct {
+			A string `arg:"a" type:"object"`
+		}{},
+		"不支持的类型": struct {
+			A map[string]string `arg:"a"`
+		}{},
+		"非结构体": "x",
+	} {
+		if _, _, err := ArgsSchema(v); err == nil {
+			t.Errorf("%s: 应返回错误", name)
+		}
+	}
+}
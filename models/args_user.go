@@ -0,0 +1,84 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-26 15:12:09
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-26 15:12:09
+ * @FilePath: \zabbix-mcp-go\models\args_user.go
+ * @Description: 用户与用户组工具参数
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+package models
+
+// GetUsersArgs get_users 工具参数
+type GetUsersArgs struct {
+	Instance string `arg:"instance,required" desc:"Zabbix实例名称必须填"`
+	Username string `arg:"username" desc:"Zabbix用户名,留空表示获取所有用户"`
+	PageArgs
+	RawArg
+	CacheArg
+}
+
+// CreateUserArgs create_user 工具参数
+type CreateUserArgs struct {
+	Instance  string `arg:"instance,required" desc:"Zabbix实例名称必须填"`
+	Username  string `arg:"username,required" desc:"Zabbix用户名"`
+	Name      string `arg:"name" desc:"用户真实姓名"`
+	UserGroup string `arg:"userGroup,required" desc:"用户组ID或名称"`
+	RoleID    string `arg:"roleID" desc:"角色ID或名称，例如 User role"`
+	Email     string `arg:"email" desc:"用户邮箱，添加为邮件告警媒介（媒介类型见 credentials.media.media_type）；密码通过邮件投递时发送到该地址"`
+	MutationArgs
+}
+
+// UpdateUserArgs update_user 工具参数
+type UpdateUserArgs struct {
+	Instance     string   `arg:"instance,required" desc:"Zabbix实例名称必须填"`
+	UserID       string   `arg:"userid,required" desc:"Zabbix用户ID或用户名"`
+	Name         string   `arg:"name" desc:"用户名字"`
+	Usrgrps      []string `arg:"usrgrps" desc:"用户组ID或名称列表，会替换用户当前所属的全部用户组"`
+	UpdatePasswd bool     `arg:"updatePasswd" desc:"是否更新密码 默认: false；新密码按 credentials.delivery 投递，结果中只有投递回执"`
+	MutationArgs
+}
+
+// DisableUserArgs disable_user 工具参数
+type DisableUserArgs struct {
+	Instance string `arg:"instance,required" desc:"Zabbix实例名称必须填"`
+	UserID   string `arg:"userid,required" desc:"Zabbix用户ID或用户名"`
+	MutationArgs
+}
+
+// OffboardUserArgs offboard_user 工具参数
+type OffboardUserArgs struct {
+	Query     string   `arg:"query,required" desc:"离职人员的用户名、姓名（name surname）或告警媒介中的邮箱地址，不区分大小写完全一致"`
+	Instances []string `arg:"instances" desc:"只在这些实例中查找，留空表示全部实例"`
+	MutationArgs
+}
+
+// BulkCreateUsersArgs bulk_create_users 工具参数
+type BulkCreateUsersArgs struct {
+	Instance       string `arg:"instance,required" desc:"Zabbix实例名称必须填"`
+	Data           string `arg:"data,required" desc:"CSV（首行为表头：username,name,surname,email,groups,role,media）或 JSON 数组；groups 为 ; 分隔的用户组名称或ID，media 为 ; 分隔的 媒介类型=收件人"`
+	DataFormat     string `arg:"data_format" enum:"auto,csv,json" default:"auto" desc:"data 的格式，auto 按首个字符判断（[ 为 JSON）"`
+	Mode           string `arg:"mode" enum:"skip,update,fail" default:"skip" desc:"用户已存在时的处理方式: skip跳过 update更新姓名、用户组、角色与媒介(不改密码) fail记为失败"`
+	EmailMediaType string `arg:"email_media_type" default:"Email" desc:"email 列使用的媒介类型名称或ID"`
+	MutationArgs
+}
+
+// DeleteUsersArgs delete_user 工具参数
+type DeleteUsersArgs struct {
+	Instance string   `arg:"instance,required" desc:"Zabbix实例名称必须填"`
+	UserIDs  []string `arg:"userids,required" alias:"userid" desc:"Zabbix用户ID列表，也可以通过 userid 传入单个用户ID"`
+	MutationArgs
+}
+
+// GetUserGroupsArgs get_groups 工具参数
+type GetUserGroupsArgs struct {
+	Instance         string `arg:"instance,required" desc:"Zabbix实例名称必须填"`
+	Name             string `arg:"name" desc:"用户组名称"`
+	Status           int    `arg:"status" enum:"0,1" default:"0" desc:"用户组状态: 0启用 1禁用"`
+	SelectUsers      bool   `arg:"selectUsers" desc:"是否获取用户组下用户列表 默认: false"`
+	SelectRights     bool   `arg:"selectRights" desc:"是否获取用户组权限列表 默认: false"`
+	SelectTagFilters bool   `arg:"selectTagFilters" desc:"是否获取用户组标签过滤器列表 默认: false"`
+	PageArgs
+	RawArg
+	CacheArg
+}
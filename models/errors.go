@@ -0,0 +1,69 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-27 10:12:45
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-27 10:12:45
+ * @FilePath: \zabbix-mcp-go\models\errors.go
+ * @Description: 工具调用错误分类
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+package models
+
+import "fmt"
+
+// ErrorCode 工具错误分类，调用方可据此决定如何修正调用
+type ErrorCode string
+
+const (
+	CodeInstanceNotFound    ErrorCode = "instance_not_found"   // 实例名称不存在
+	CodeInstanceUnavailable ErrorCode = "instance_unavailable" // 实例网络不可达或响应异常
+	CodeAuthFailed          ErrorCode = "auth_failed"          // 登录失败、会话或 Token 失效
+	CodePermissionDenied    ErrorCode = "permission_denied"    // 当前账号无权执行该操作
+	CodeInvalidParams       ErrorCode = "invalid_params"       // 工具参数或 API 参数错误
+	CodeNotFound            ErrorCode = "not_found"            // 引用的对象不存在（或无权查看）
+	CodeConflict            ErrorCode = "conflict"             // 对象已存在等冲突
+	CodeVersionUnsupported  ErrorCode = "version_unsupported"  // 实例版本不支持该方法或参数
+	CodeTimeout             ErrorCode = "timeout"              // 超时
+	CodeInternal            ErrorCode = "internal"             // 未归类的错误
+)
+
+// Error 带分类的错误，既是 Go error，也是返回给客户端的结构化错误体
+type Error struct {
+	Code      ErrorCode `json:"code"`
+	Message   string    `json:"message"`
+	Hint      string    `json:"hint,omitempty"`     // 如何修正这次调用
+	Retryable bool      `json:"retryable"`          // 原样重试是否可能成功
+	Instance  string    `json:"instance,omitempty"` // 出错的 Zabbix 实例
+	Zabbix    *RPCError `json:"zabbix,omitempty"`   // Zabbix API 原始错误
+	Params    ArgsError `json:"params,omitempty"`   // 逐个列出的参数问题
+	// Candidates 按名称引用对象而名称有歧义时的候选对象
+	Candidates []Candidate `json:"candidates,omitempty"`
+	Err        error       `json:"-"`
+}
+
+// Candidate 名称有歧义时的一个候选对象
+type Candidate struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Field string `json:"field"` // 与名称匹配的字段，例如主机的 host 或 name
+}
+
+// Errorf 创建指定分类的错误，format 中可以使用 %w 包装底层错误
+func Errorf(code ErrorCode, format string, args ...interface{}) *Error {
+	err := fmt.Errorf(format, args...)
+	return &Error{Code: code, Message: err.Error(), Err: err}
+}
+
+// WithHint 设置修正建议
+func (e *Error) WithHint(hint string) *Error {
+	e.Hint = hint
+	return e
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("[%s] %s", e.Code, e.Message)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,43 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-26 10:27:41
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-26 10:27:41
+ * @FilePath: \zabbix-mcp-go\models\event.go
+ * @Description: 问题与事件
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+package models
+
+// Problem problem.get 返回的问题
+type Problem struct {
+	EventID      string    `json:"eventid"`
+	Source       Int       `json:"source" jsonschema_description:"0触发器 3内部"`
+	Object       Int       `json:"object" jsonschema_description:"0触发器 4监控项 5LLD规则"`
+	ObjectID     string    `json:"objectid"`
+	Clock        Timestamp `json:"clock,omitempty"`
+	Name         string    `json:"name"`
+	Severity     Int       `json:"severity" jsonschema_description:"0未分类 1信息 2警告 3一般严重 4严重 5灾难"`
+	Acknowledged Int       `json:"acknowledged"`
+	Suppressed   Int       `json:"suppressed" jsonschema_description:"0正常 1处于维护抑制中"`
+	OpData       string    `json:"opdata,omitempty"`
+	REventID     string    `json:"r_eventid,omitempty" jsonschema_description:"恢复事件ID 0表示未恢复"`
+	RClock       Timestamp `json:"r_clock,omitempty"`
+	Tags         []Tag     `json:"tags,omitempty"`
+}
+
+// Event event.get 返回的事件
+type Event struct {
+	EventID      string    `json:"eventid"`
+	Source       Int       `json:"source"`
+	Object       Int       `json:"object"`
+	ObjectID     string    `json:"objectid"`
+	Clock        Timestamp `json:"clock,omitempty"`
+	Value        Int       `json:"value" jsonschema_description:"0正常(恢复) 1问题"`
+	Name         string    `json:"name"`
+	Severity     Int       `json:"severity"`
+	Acknowledged Int       `json:"acknowledged"`
+	REventID     string    `json:"r_eventid,omitempty"`
+	Hosts        []HostRef `json:"hosts,omitempty"`
+	Tags         []Tag     `json:"tags,omitempty"`
+}
@@ -0,0 +1,67 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-26 10:02:55
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-26 10:02:55
+ * @FilePath: \zabbix-mcp-go\models\host.go
+ * @Description: 主机、主机组、接口与标签
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+package models
+
+// Host host.get 返回的主机（已归一化：proxyid、hostgroups）
+type Host struct {
+	HostID            string          `json:"hostid"`
+	Host              string          `json:"host"`
+	Name              string          `json:"name"`
+	Description       string          `json:"description,omitempty"`
+	Status            Int             `json:"status" jsonschema_description:"0已启用 1未启用"`
+	ProxyID           string          `json:"proxyid,omitempty"`
+	MaintenanceStatus Int             `json:"maintenance_status" jsonschema_description:"0正常 1维护中"`
+	Flags             Int             `json:"flags" jsonschema_description:"0普通主机 4自动发现的主机"`
+	HostGroups        []HostGroupRef  `json:"hostgroups,omitempty"`
+	Interfaces        []HostInterface `json:"interfaces,omitempty"`
+	Tags              []Tag           `json:"tags,omitempty"`
+}
+
+// HostGroup hostgroup.get 返回的主机组
+type HostGroup struct {
+	GroupID  string    `json:"groupid"`
+	Name     string    `json:"name"`
+	Flags    Int       `json:"flags" jsonschema_description:"0普通主机组 4自动发现的主机组"`
+	Internal Int       `json:"internal" jsonschema_description:"0普通 1内部主机组"`
+	Hosts    []HostRef `json:"hosts,omitempty"`
+}
+
+// HostRef selectHosts 等关联查询中引用的主机
+type HostRef struct {
+	HostID string `json:"hostid"`
+	Host   string `json:"host,omitempty"`
+	Name   string `json:"name,omitempty"`
+	Status *Int   `json:"status,omitempty" jsonschema_description:"0已启用 1未启用"`
+}
+
+// HostGroupRef selectHostGroups 等关联查询中引用的主机组
+type HostGroupRef struct {
+	GroupID string `json:"groupid"`
+	Name    string `json:"name,omitempty"`
+}
+
+// HostInterface 主机接口
+type HostInterface struct {
+	InterfaceID string `json:"interfaceid"`
+	Type        Int    `json:"type" jsonschema_description:"1Agent 2SNMP 3IPMI 4JMX"`
+	Main        Int    `json:"main"`
+	UseIP       Int    `json:"useip"`
+	IP          string `json:"ip"`
+	DNS         string `json:"dns"`
+	Port        string `json:"port"`
+	Available   *Int   `json:"available,omitempty" jsonschema_description:"0未知 1可用 2不可用 (5.2+)"`
+	Error       string `json:"error,omitempty"`
+}
+
+// Tag 主机、监控项、触发器、问题共用的标签
+type Tag struct {
+	Tag   string `json:"tag"`
+	Value string `json:"value"`
+}
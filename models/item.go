@@ -0,0 +1,48 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-26 10:15:08
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-26 10:15:08
+ * @FilePath: \zabbix-mcp-go\models\item.go
+ * @Description: 监控项与触发器
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+package models
+
+// Item item.get 返回的监控项；lastvalue 与 units 相关，保持字符串
+type Item struct {
+	ItemID    string    `json:"itemid"`
+	HostID    string    `json:"hostid"`
+	Name      string    `json:"name"`
+	Key       string    `json:"key_"`
+	Type      Int       `json:"type"`
+	ValueType Int       `json:"value_type" jsonschema_description:"0浮点 1字符 2日志 3无符号整数 4文本"`
+	Delay     string    `json:"delay,omitempty"`
+	Units     string    `json:"units,omitempty"`
+	Status    Int       `json:"status" jsonschema_description:"0已启用 1未启用"`
+	State     Int       `json:"state" jsonschema_description:"0正常 1不支持"`
+	Error     string    `json:"error,omitempty"`
+	LastValue string    `json:"lastvalue,omitempty"`
+	PrevValue string    `json:"prevvalue,omitempty"`
+	LastClock Timestamp `json:"lastclock,omitempty"`
+	Hosts     []HostRef `json:"hosts,omitempty"`
+	Tags      []Tag     `json:"tags,omitempty"`
+}
+
+// Trigger trigger.get 返回的触发器
+type Trigger struct {
+	TriggerID   string    `json:"triggerid"`
+	TemplateID  string    `json:"templateid,omitempty" jsonschema_description:"继承自的模板触发器ID 0表示不是继承的"`
+	Description string    `json:"description"`
+	Expression  string    `json:"expression,omitempty"`
+	Comments    string    `json:"comments,omitempty"`
+	URL         string    `json:"url,omitempty"`
+	Priority    Int       `json:"priority" jsonschema_description:"0未分类 1信息 2警告 3一般严重 4严重 5灾难"`
+	Status      Int       `json:"status" jsonschema_description:"0已启用 1未启用"`
+	Value       Int       `json:"value" jsonschema_description:"0正常 1问题"`
+	State       Int       `json:"state" jsonschema_description:"0正常 1未知"`
+	Error       string    `json:"error,omitempty"`
+	LastChange  Timestamp `json:"lastchange,omitempty"`
+	Hosts       []HostRef `json:"hosts,omitempty"`
+	Tags        []Tag     `json:"tags,omitempty"`
+}
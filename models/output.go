@@ -0,0 +1,26 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-28 14:06:52
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-28 14:06:52
+ * @FilePath: \zabbix-mcp-go\models\output.go
+ * @Description: 所有工具共用的输出裁剪参数
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+package models
+
+// OutputArgs 所有工具共用的输出参数，注册时自动加入每个工具的输入 schema，由输出中间件处理
+type OutputArgs struct {
+	Fields    []string `arg:"fields" desc:"只返回这些字段，a.b 表示嵌套字段，例如 [\"hostid\",\"name\",\"hostgroups.name\"]；裁剪后的结果放在 items 中"`
+	Format    string   `arg:"format" enum:"json,table,markdown" default:"json" desc:"文本内容的格式：json、table（制表符分隔的紧凑表格）或 markdown 表格"`
+	MaxItems  int      `arg:"max_items" desc:"最多返回的条数，超出部分省略并注明省略数量"`
+	MaxTokens int      `arg:"max_tokens" desc:"结果大小预算（按约 4 字节 1 个 token 估算），超出时截断并注明省略数量；默认值见服务端 output 配置"`
+	Summary   bool     `arg:"summary" desc:"附带按常用维度分组计数的摘要；结果被截断时总会附带 默认: false"`
+}
+
+// Truncation 结果被截断时的说明
+type Truncation struct {
+	Omitted int    `json:"omitted" jsonschema_description:"省略的条数"`
+	Total   int    `json:"total" jsonschema_description:"截断前的条数"`
+	Message string `json:"message"`
+}
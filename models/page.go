@@ -0,0 +1,26 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-28 09:40:16
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-28 09:40:16
+ * @FilePath: \zabbix-mcp-go\models\page.go
+ * @Description: *.get 分页请求与分页信息
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+package models
+
+// PageRequest 一次分页查询：Limit 为本页条数，Cursor 为上一页返回的 NextCursor（首页为空）
+type PageRequest struct {
+	Limit  int
+	Cursor string
+}
+
+// PageInfo 随查询结果返回的分页信息
+type PageInfo struct {
+	Total      int64  `json:"total" jsonschema_description:"匹配查询条件的对象总数"`
+	Returned   int    `json:"returned" jsonschema_description:"本页返回的对象数"`
+	Limit      int    `json:"limit,omitempty" jsonschema_description:"本页条数上限"`
+	HasMore    bool   `json:"has_more" jsonschema_description:"是否还有下一页"`
+	NextCursor string `json:"next_cursor,omitempty" jsonschema_description:"获取下一页时作为 cursor 参数传入"`
+	Query      string `json:"-"` // 查询条件摘要，结果被截断时据此重新生成游标
+}
@@ -5,6 +5,7 @@ type HostGetParams struct {
 	HostIDs  []string
 	GroupIDs []string
 	Output   string
+	Search   map[string]interface{} // search 条件，例如 {"name": "web*"}
 }
 
 // BuildParams 将 HostGetParams 转换为 API 参数
@@ -19,6 +20,13 @@ func (p HostGetParams) BuildParams() map[string]interface{} {
 	if p.Output != "" {
 		params["output"] = p.Output
 	}
+	if len(p.Search) > 0 {
+		search := make(map[string]interface{}, len(p.Search))
+		for k, v := range p.Search {
+			search[k] = v
+		}
+		params["search"] = search
+	}
 	return params
 }
 
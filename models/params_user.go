@@ -23,9 +23,10 @@ type UserParams struct {
 	Surname       string
 	CurrentPasswd string
 	Usrgrps       []string
-	Alias         string                 // 兼容旧用法：filter.alias = alias
-	Filter        map[string]interface{} // 任意 filter 条件，例如 {"username": []string{"admin"}}
-	Search        map[string]interface{} // search 条件，例如 {"username": "ops*"}
+	Medias        []map[string]interface{} // 新建用户的告警媒介
+	Alias         string                   // 兼容旧用法：filter.alias = alias
+	Filter        map[string]interface{}   // 任意 filter 条件，例如 {"username": []string{"admin"}}
+	Search        map[string]interface{}   // search 条件，例如 {"username": "ops*"}
 
 	Output       string   // "extend" 等字符串形式
 	OutputFields []string // 明确字段列表
@@ -129,6 +130,9 @@ func (p UserParams) BuildParams() map[string]interface{} {
 	if p.CurrentPasswd != "" {
 		params["currentpasswd"] = p.CurrentPasswd
 	}
+	if len(p.Medias) > 0 {
+		params["medias"] = p.Medias
+	}
 
 	if len(p.Usrgrps) > 0 {
 		var groups []map[string]interface{}
@@ -9,7 +9,8 @@
  */
 package models
 
-type UserGroup struct {
+// UserGroupParams usergroup.get 等方法的参数
+type UserGroupParams struct {
 	Name             string
 	Groupids         []string
 	GroupPer         map[int]int
@@ -24,7 +25,7 @@ type UserGroup struct {
 	SelectTagFilters bool
 }
 
-func (P UserGroup) BuildParams() map[string]interface{} {
+func (P UserGroupParams) BuildParams() map[string]interface{} {
 	params := map[string]interface{}{}
 	if P.Name != "" {
 		params["name"] = P.Name
@@ -66,7 +67,7 @@ func (P UserGroup) BuildParams() map[string]interface{} {
 	return params
 }
 
-func (p UserGroup) BuildDeleteParams() []string {
+func (p UserGroupParams) BuildDeleteParams() []string {
 	if len(p.Groupids) > 0 {
 		return append([]string(nil), p.Groupids...)
 
@@ -0,0 +1,45 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-23 10:12:40
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-23 10:12:40
+ * @FilePath: \zabbix-mcp-go\models\plan.go
+ * @Description: 变更预览（dry_run）结果
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+package models
+
+// MaskedValue 用于在预览结果中替代密码等敏感字段
+const MaskedValue = "******"
+
+// FieldChange 描述某个对象字段在变更前后的取值
+type FieldChange struct {
+	ObjectID string      `json:"objectid"`
+	Field    string      `json:"field"`
+	Old      interface{} `json:"old"`
+	New      interface{} `json:"new"`
+}
+
+// MutationPlan 描述一次变更操作的执行计划，dry_run 时返回给调用方而不实际执行
+type MutationPlan struct {
+	DryRun   bool                     `json:"dry_run"`
+	Instance string                   `json:"instance"`
+	Method   string                   `json:"method"`   // 将要调用的 JSON-RPC 方法
+	Params   interface{}              `json:"params"`   // 经过 AdaptAPIParams 适配后的参数
+	Resolved map[string]string        `json:"resolved"` // 名称到ID的解析结果
+	Affected []map[string]interface{} `json:"affected"` // 受影响对象的当前状态
+	Changes  []FieldChange            `json:"changes"`  // 字段级别的新旧值对比
+	Warnings []string                 `json:"warnings,omitempty"`
+}
+
+// NewMutationPlan 创建一个空的执行计划，保证切片/映射字段序列化为 [] / {} 而不是 null
+func NewMutationPlan(instance, method string) *MutationPlan {
+	return &MutationPlan{
+		DryRun:   true,
+		Instance: instance,
+		Method:   method,
+		Resolved: map[string]string{},
+		Affected: []map[string]interface{}{},
+		Changes:  []FieldChange{},
+	}
+}
@@ -0,0 +1,106 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-26 09:30:14
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-26 09:30:14
+ * @FilePath: \zabbix-mcp-go\models\types.go
+ * @Description: Zabbix 响应中以字符串编码的数字与时间戳
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/invopop/jsonschema"
+)
+
+// Int Zabbix 以字符串返回的整数（"0"、"1"），同时兼容数字与空字符串，序列化为 JSON 数字
+type Int int64
+
+// UnmarshalJSON 接受 "12"、12、"" 与 null
+func (i *Int) UnmarshalJSON(data []byte) error {
+	n, err := decodeInt(data)
+	if err != nil {
+		return fmt.Errorf("无法解析整数 %s: %w", data, err)
+	}
+	*i = Int(n)
+	return nil
+}
+
+// Timestamp Zabbix 以字符串返回的 Unix 秒时间戳（clock、lastchange 等），
+// 序列化为同时带 Unix 秒与本地化 ISO 8601 时间的对象，0 表示未发生，配合 omitempty 省略
+type Timestamp int64
+
+// TimeValue Timestamp 的序列化形式：Epoch 为 Unix 秒，ISO 为带时区偏移的 RFC3339 时间。
+// 序列化时 ISO 使用本地时区，工具结果、资源与推送再由 handler 换算到实例的 server_tz
+type TimeValue struct {
+	Epoch int64  `json:"epoch"`
+	ISO   string `json:"iso"`
+}
+
+// UnmarshalJSON 接受 "1700000000"、1700000000、""、null 以及序列化后的 {"epoch": 1700000000, ...}
+func (t *Timestamp) UnmarshalJSON(data []byte) error {
+	if d := bytes.TrimSpace(data); len(d) > 0 && d[0] == '{' {
+		var v TimeValue
+		if err := json.Unmarshal(d, &v); err != nil {
+			return fmt.Errorf("无法解析时间戳 %s: %w", data, err)
+		}
+		*t = Timestamp(v.Epoch)
+		return nil
+	}
+	n, err := decodeInt(data)
+	if err != nil {
+		return fmt.Errorf("无法解析时间戳 %s: %w", data, err)
+	}
+	*t = Timestamp(n)
+	return nil
+}
+
+// MarshalJSON 输出 {"epoch": 1700000000, "iso": "2023-11-15T06:13:20+08:00"}，0 输出 null
+func (t Timestamp) MarshalJSON() ([]byte, error) {
+	if t == 0 {
+		return []byte("null"), nil
+	}
+	return json.Marshal(t.In(time.Local))
+}
+
+// In 在指定时区中的序列化形式
+func (t Timestamp) In(loc *time.Location) TimeValue {
+	return TimeValue{Epoch: int64(t), ISO: time.Unix(int64(t), 0).In(loc).Format(time.RFC3339)}
+}
+
+// Time 转换为 time.Time（UTC）
+func (t Timestamp) Time() time.Time {
+	return time.Unix(int64(t), 0).UTC()
+}
+
+// JSONSchema 声明 outputSchema 中的类型
+func (Timestamp) JSONSchema() *jsonschema.Schema {
+	props := jsonschema.NewProperties()
+	props.Set("epoch", &jsonschema.Schema{Type: "integer", Description: "Unix 时间戳（秒）"})
+	props.Set("iso", &jsonschema.Schema{Type: "string", Format: "date-time", Description: "实例时区（server_tz）中的 RFC3339 时间"})
+	return &jsonschema.Schema{Type: "object", Properties: props, Required: []string{"epoch", "iso"}}
+}
+
+func decodeInt(data []byte) (int64, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		return 0, nil
+	}
+	if data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return 0, err
+		}
+		if s == "" {
+			return 0, nil
+		}
+		return strconv.ParseInt(s, 10, 64)
+	}
+	return strconv.ParseInt(string(data), 10, 64)
+}
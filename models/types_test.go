@@ -0,0 +1,138 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIntUnmarshal(t *testing.T) {
+	cases := []struct {
+		in   string
+		want Int
+		err  bool
+	}{
+		{`"12"`, 12, false},
+		{`12`, 12, false},
+		{`"0"`, 0, false},
+		{`""`, 0, false},
+		{`null`, 0, false},
+		{`"-1"`, -1, false},
+		{`"abc"`, 0, true},
+		{`1.5`, 0, true},
+		{`true`, 0, true},
+	}
+	for _, c := range cases {
+		var got Int
+		err := json.Unmarshal([]byte(c.in), &got)
+		if (err != nil) != c.err {
+			t.Errorf("Int(%s): err = %v，期望出错 = %v", c.in, err, c.err)
+			continue
+		}
+		if !c.err && got != c.want {
+			t.Errorf("Int(%s) = %d，期望 %d", c.in, got, c.want)
+		}
+	}
+}
+
+func TestTimestampUnmarshal(t *testing.T) {
+	cases := []struct {
+		in   string
+		want Timestamp
+		err  bool
+	}{
+		{`"1700000000"`, 1700000000, false},
+		{`1700000000`, 1700000000, false},
+		{`"0"`, 0, false},
+		{`""`, 0, false},
+		{`null`, 0, false},
+		{`{"epoch": 1700000000, "iso": "2023-11-15T06:13:20+08:00"}`, 1700000000, false},
+		{`"yesterday"`, 0, true},
+		{`{"epoch": "x"}`, 0, true},
+	}
+	for _, c := range cases {
+		var got Timestamp
+		err := json.Unmarshal([]byte(c.in), &got)
+		if (err != nil) != c.err {
+			t.Errorf("Timestamp(%s): err = %v，期望出错 = %v", c.in, err, c.err)
+			continue
+		}
+		if !c.err && got != c.want {
+			t.Errorf("Timestamp(%s) = %d，期望 %d", c.in, got, c.want)
+		}
+	}
+}
+
+func TestTimestampMarshal(t *testing.T) {
+	raw, err := json.Marshal(struct {
+		Clock  Timestamp `json:"clock"`
+		RClock Timestamp `json:"r_clock,omitempty"`
+	}{Clock: 1700000000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out map[string]TimeValue
+	if err := json.Unmarshal(raw, &out); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := out["r_clock"]; ok {
+		t.Errorf("未发生的时间应省略: %s", raw)
+	}
+	iso, err := time.Parse(time.RFC3339, out["clock"].ISO)
+	if out["clock"].Epoch != 1700000000 || err != nil || iso.Unix() != 1700000000 {
+		t.Errorf("clock = %+v", out["clock"])
+	}
+	if got := Timestamp(1700000000).In(time.FixedZone("CST", 8*3600)).ISO; got != "2023-11-15T06:13:20+08:00" {
+		t.Errorf("In(+08:00) = %s", got)
+	}
+	if null, _ := json.Marshal(Timestamp(0)); string(null) != "null" {
+		t.Errorf("Timestamp(0) = %s，期望 null", null)
+	}
+}
+
+// TestUserKeepsZeroEnums 字符串编码的取值为 0 的枚举字段必须保留；getAccess 未请求时不输出
+func TestUserKeepsZeroEnums(t *testing.T) {
+	var u User
+	if err := json.Unmarshal([]byte(`{"userid":"1","username":"Admin","autologin":"0","attempt_clock":"0",
+		"gui_access":"0","users_status":"0","debug_mode":"0","usrgrps":[{"usrgrpid":"7","gui_access":"0","users_status":"0"}]}`), &u); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := json.Marshal(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{`"autologin":0`, `"gui_access":0`, `"users_status":0`, `"debug_mode":0`,
+		`"usrgrps":[{"usrgrpid":"7","gui_access":0,"users_status":0}]`} {
+		if !strings.Contains(string(raw), field) {
+			t.Errorf("序列化结果缺少 %s: %s", field, raw)
+		}
+	}
+	if strings.Contains(string(raw), "attempt_clock") {
+		t.Errorf("未发生的 attempt_clock 应省略: %s", raw)
+	}
+
+	var plain User
+	if err := json.Unmarshal([]byte(`{"userid":"2","username":"guest","usrgrps":[{"usrgrpid":"8"}]}`), &plain); err != nil {
+		t.Fatal(err)
+	}
+	raw, _ = json.Marshal(plain)
+	for _, field := range []string{"gui_access", "users_status", "debug_mode"} {
+		if strings.Contains(string(raw), field) {
+			t.Errorf("未请求 getAccess 时不应输出 %s: %s", field, raw)
+		}
+	}
+}
+
+func TestEventKeepsZeroEnums(t *testing.T) {
+	var p Problem
+	if err := json.Unmarshal([]byte(`{"eventid":"1","source":"0","object":"0","severity":"0","acknowledged":"0","suppressed":"0"}`), &p); err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := json.Marshal(p)
+	for _, field := range []string{`"source":0`, `"object":0`, `"severity":0`, `"acknowledged":0`, `"suppressed":0`} {
+		if !strings.Contains(string(raw), field) {
+			t.Errorf("序列化结果缺少 %s: %s", field, raw)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-26 09:41:37
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-26 09:41:37
+ * @FilePath: \zabbix-mcp-go\models\user.go
+ * @Description: 用户、用户组、角色、媒介
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+package models
+
+// User user.get 返回的用户（已归一化为最新版本的字段名：username、roleid）
+type User struct {
+	UserID        string         `json:"userid"`
+	Username      string         `json:"username"`
+	Name          string         `json:"name"`
+	Surname       string         `json:"surname"`
+	RoleID        string         `json:"roleid,omitempty"`
+	URL           string         `json:"url,omitempty"`
+	Autologin     Int            `json:"autologin" jsonschema_description:"自动登录 0禁用 1启用"`
+	Autologout    string         `json:"autologout,omitempty" jsonschema_description:"自动登出时长 例如 15m 0表示禁用"`
+	Lang          string         `json:"lang,omitempty"`
+	Refresh       string         `json:"refresh,omitempty"`
+	Theme         string         `json:"theme,omitempty"`
+	RowsPerPage   Int            `json:"rows_per_page,omitempty"`
+	Timezone      string         `json:"timezone,omitempty"`
+	AttemptFailed Int            `json:"attempt_failed"`
+	AttemptIP     string         `json:"attempt_ip,omitempty"`
+	AttemptClock  Timestamp      `json:"attempt_clock,omitempty"`
+	GuiAccess     *Int           `json:"gui_access,omitempty" jsonschema_description:"前端访问方式 0系统默认 1内部 2LDAP 3禁止访问 (getAccess)"`
+	UsersStatus   *Int           `json:"users_status,omitempty" jsonschema_description:"0启用 1禁用 (getAccess)"`
+	DebugMode     *Int           `json:"debug_mode,omitempty" jsonschema_description:"0禁用 1启用 (getAccess)"`
+	Usrgrps       []UserGroupRef `json:"usrgrps,omitempty"`
+	Medias        []Media        `json:"medias,omitempty"`
+	Role          *Role          `json:"role,omitempty"`
+}
+
+// UserGroup usergroup.get 返回的用户组
+type UserGroup struct {
+	UsrgrpID    string      `json:"usrgrpid"`
+	Name        string      `json:"name"`
+	GuiAccess   Int         `json:"gui_access" jsonschema_description:"前端访问方式 0系统默认 1内部 2LDAP 3禁止访问"`
+	UsersStatus Int         `json:"users_status" jsonschema_description:"0启用 1禁用"`
+	DebugMode   Int         `json:"debug_mode"`
+	Users       []UserRef   `json:"users,omitempty"`
+	Rights      []Right     `json:"rights,omitempty"`
+	TagFilters  []TagFilter `json:"tag_filters,omitempty"`
+}
+
+// UserRef selectUsers 等关联查询中引用的用户
+type UserRef struct {
+	UserID   string `json:"userid"`
+	Username string `json:"username,omitempty"`
+	Name     string `json:"name,omitempty"`
+	Surname  string `json:"surname,omitempty"`
+	RoleID   string `json:"roleid,omitempty"`
+}
+
+// UserGroupRef selectUsrgrps 等关联查询中引用的用户组
+type UserGroupRef struct {
+	UsrgrpID    string `json:"usrgrpid"`
+	Name        string `json:"name,omitempty"`
+	GuiAccess   *Int   `json:"gui_access,omitempty" jsonschema_description:"前端访问方式 0系统默认 1内部 2LDAP 3禁止访问"`
+	UsersStatus *Int   `json:"users_status,omitempty" jsonschema_description:"0启用 1禁用"`
+}
+
+// Right 用户组对主机组的权限
+type Right struct {
+	ID         string `json:"id"`
+	Permission Int    `json:"permission" jsonschema_description:"0拒绝 2只读 3读写"`
+}
+
+// TagFilter 用户组的标签过滤
+type TagFilter struct {
+	GroupID string `json:"groupid,omitempty"`
+	Tag     string `json:"tag"`
+	Value   string `json:"value"`
+}
+
+// Role role.get 返回的用户角色（5.2+）
+type Role struct {
+	RoleID   string `json:"roleid"`
+	Name     string `json:"name"`
+	Type     Int    `json:"type" jsonschema_description:"1用户 2管理员 3超级管理员"`
+	ReadOnly Int    `json:"readonly"`
+}
+
+// Media 用户的告警媒介
+type Media struct {
+	MediaID     string      `json:"mediaid,omitempty"`
+	MediaTypeID string      `json:"mediatypeid"`
+	SendTo      interface{} `json:"sendto" jsonschema_description:"收件人 邮件类媒介为字符串数组"`
+	Active      Int         `json:"active" jsonschema_description:"0启用 1禁用"`
+	Severity    Int         `json:"severity"`
+	Period      string      `json:"period,omitempty"`
+}
+
+// MediaType mediatype.get 返回的媒介类型
+type MediaType struct {
+	MediaTypeID string `json:"mediatypeid"`
+	Name        string `json:"name"`
+	Type        Int    `json:"type" jsonschema_description:"0邮件 1脚本 2短信 4Webhook"`
+	Status      Int    `json:"status" jsonschema_description:"0启用 1禁用"`
+}
+
+// APIToken token.get 返回的 API 令牌（5.4+）
+type APIToken struct {
+	TokenID   string    `json:"tokenid"`
+	Name      string    `json:"name"`
+	UserID    string    `json:"userid"`
+	Status    Int       `json:"status" jsonschema_description:"0启用 1禁用"`
+	ExpiresAt Timestamp `json:"expires_at,omitempty"`
+}
@@ -0,0 +1,28 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-27 15:41:09
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-27 15:41:09
+ * @FilePath: \zabbix-mcp-go\register\api_call.go
+ * @Description: 通用 API 调用工具注册
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+package register
+
+import (
+	"zabbixMcp/handler"
+	"zabbixMcp/models"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func registerAPICall(s *server.MCPServer) {
+	addTool(s,
+		mcp.NewTool("api_call",
+			mcp.WithDescription("直接调用Zabbix API方法（没有专用工具时使用），默认只允许 *.get 查询；非查询方法需经过二次确认"),
+			withArgs[models.APICallArgs](),
+		),
+		handler.APICallHandler,
+	)
+}
@@ -0,0 +1,44 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-26 15:31:26
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-26 15:31:26
+ * @FilePath: \zabbix-mcp-go\register\args.go
+ * @Description: 由 models 中的参数结构体生成工具输入 schema
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+package register
+
+import (
+	"fmt"
+
+	"zabbixMcp/models"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// withArgs 用参数结构体 T 的标签生成工具的输入 schema，handler 使用同一结构体解码参数；
+// 所有工具共用的输出参数（models.OutputArgs）一并加入，由输出中间件处理。
+// 标签定义错误或参数名冲突属于编程错误，在注册阶段直接 panic。
+func withArgs[T any]() mcp.ToolOption {
+	var zero T
+	props, required, err := models.ArgsSchema(zero)
+	if err != nil {
+		panic(fmt.Sprintf("生成工具参数 schema 失败: %v", err))
+	}
+	outputProps, _, err := models.ArgsSchema(models.OutputArgs{})
+	if err != nil {
+		panic(fmt.Sprintf("生成输出参数 schema 失败: %v", err))
+	}
+	for name, prop := range outputProps {
+		if _, ok := props[name]; ok {
+			panic(fmt.Sprintf("工具参数 %s 与输出参数重名", name))
+		}
+		props[name] = prop
+	}
+	return func(t *mcp.Tool) {
+		t.InputSchema.Type = "object"
+		t.InputSchema.Properties = props
+		t.InputSchema.Required = required
+	}
+}
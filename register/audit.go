@@ -0,0 +1,29 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-23 17:24:51
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-23 17:24:51
+ * @FilePath: \zabbix-mcp-go\register\audit.go
+ * @Description: 审计日志功能注册
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+package register
+
+import (
+	"zabbixMcp/handler"
+	"zabbixMcp/models"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func registerAudit(s *server.MCPServer) {
+	addTool(s,
+		mcp.NewTool("get_audit_log",
+			mcp.WithDescription("查询MCP工具调用审计记录（调用方、参数、目标实例、Zabbix方法、结果、耗时）"),
+			mcp.WithReadOnlyHintAnnotation(true),
+			withArgs[models.GetAuditLogArgs](),
+		),
+		handler.GetAuditLogHandler,
+	)
+}
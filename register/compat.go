@@ -0,0 +1,29 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-26 10:20:37
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-26 10:20:37
+ * @FilePath: \zabbix-mcp-go\register\compat.go
+ * @Description: 版本兼容规则工具注册
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+package register
+
+import (
+	"zabbixMcp/handler"
+	"zabbixMcp/models"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func registerCompat(s *server.MCPServer) {
+	addTool(s,
+		mcp.NewTool("get_api_compat",
+			mcp.WithDescription("说明指定Zabbix实例的版本会触发哪些API参数适配（字段改名、删除、转换）及原因"),
+			mcp.WithReadOnlyHintAnnotation(true),
+			withArgs[models.GetAPICompatArgs](),
+		),
+		handler.GetAPICompatHandler,
+	)
+}
@@ -0,0 +1,29 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-28 10:55:21
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-28 10:55:21
+ * @FilePath: \zabbix-mcp-go\register\host.go
+ * @Description: 主机工具注册
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+package register
+
+import (
+	"zabbixMcp/handler"
+	"zabbixMcp/models"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func registerHost(s *server.MCPServer) {
+	addTool(s,
+		mcp.NewTool("get_hosts",
+			mcp.WithDescription("分页查询Zabbix主机，可按名称或主机组过滤；count_only 只返回数量"),
+			withArgs[models.GetHostsArgs](),
+			mcp.WithOutputSchema[handler.ToolResult[[]models.Host]](),
+		),
+		handler.GetHostsHandler,
+	)
+}
@@ -11,6 +11,7 @@ package register
 
 import (
 	"zabbixMcp/handler"
+	"zabbixMcp/models"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
@@ -19,10 +20,10 @@ import (
 // registerClientPool 注册与 Zabbix 客户端池相关的 MCP 工具。
 // 目前仅注册工具元信息；具体处理器在 handler 包未实现时暂留为 nil，以免影响构建。
 func registerInstances(s *server.MCPServer) {
-	s.AddTool(
+	addTool(s,
 		mcp.NewTool("get_instances_info",
 			mcp.WithDescription("获取所有Zabbix实例的详细信息"),
-			mcp.WithString("instance", mcp.Description("Zabbix实例名称")),
+			withArgs[models.GetInstancesInfoArgs](),
 		),
 		handler.GetInstancesInfoHandler,
 	)
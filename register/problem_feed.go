@@ -0,0 +1,35 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-30 11:34:08
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-30 11:34:08
+ * @FilePath: \zabbix-mcp-go\register\problem_feed.go
+ * @Description: 实时问题推送订阅工具注册
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+package register
+
+import (
+	"zabbixMcp/handler"
+	"zabbixMcp/models"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func registerProblemFeed(s *server.MCPServer) {
+	addTool(s,
+		mcp.NewTool("subscribe_problems",
+			mcp.WithDescription("订阅实时问题推送：新问题与问题恢复以日志通知（notifications/message，logger 为 zabbix.problems）推送到当前会话，可按实例、最低严重级别、主机组与标签过滤"),
+			withArgs[models.SubscribeProblemsArgs](),
+		),
+		handler.SubscribeProblemsHandler,
+	)
+	addTool(s,
+		mcp.NewTool("unsubscribe_problems",
+			mcp.WithDescription("取消当前会话的实时问题推送订阅"),
+			withArgs[models.UnsubscribeProblemsArgs](),
+		),
+		handler.UnsubscribeProblemsHandler,
+	)
+}
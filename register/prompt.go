@@ -0,0 +1,68 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-29 16:40:31
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-29 16:40:31
+ * @FilePath: \zabbix-mcp-go\register\prompt.go
+ * @Description: 注册常用运维流程的提示词
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+package register
+
+import (
+	"zabbixMcp/handler"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func registerPrompts(s *server.MCPServer) {
+	instanceArg := mcp.WithArgument("instance", mcp.RequiredArgument(), mcp.ArgumentDescription("Zabbix实例名称"))
+
+	s.AddPrompt(
+		mcp.NewPrompt("triage_problems",
+			mcp.WithPromptTitle("分诊当前问题"),
+			mcp.WithPromptDescription("读取实例的当前问题及所在主机，按严重级别与影响范围分诊并给出处理顺序"),
+			instanceArg,
+		),
+		handler.TriageProblemsPrompt,
+	)
+	s.AddPrompt(
+		mcp.NewPrompt("diagnose_host_unreachable",
+			mcp.WithPromptTitle("排查主机不可达"),
+			mcp.WithPromptDescription("读取主机配置、接口可用性、可用性监控项与最近 24 小时的事件，分析主机为何不可达"),
+			instanceArg,
+			mcp.WithArgument("host", mcp.RequiredArgument(), mcp.ArgumentDescription("主机ID、主机名或可见名称")),
+		),
+		handler.DiagnoseHostPrompt,
+	)
+	s.AddPrompt(
+		mcp.NewPrompt("onboard_user",
+			mcp.WithPromptTitle("新用户入职"),
+			mcp.WithPromptDescription("检查同名用户、目标用户组与可选角色，引导以 dry_run 方式创建账号并加入用户组"),
+			instanceArg,
+			mcp.WithArgument("username", mcp.RequiredArgument(), mcp.ArgumentDescription("新用户的用户名")),
+			mcp.WithArgument("group", mcp.RequiredArgument(), mcp.ArgumentDescription("用户组ID或名称")),
+		),
+		handler.OnboardUserPrompt,
+	)
+	s.AddPrompt(
+		mcp.NewPrompt("offboard_user",
+			mcp.WithPromptTitle("用户离职"),
+			mcp.WithPromptDescription("在全部实例中查找用户的账号、用户组与告警媒介，引导逐个实例禁用账号"),
+			mcp.WithArgument("username", mcp.RequiredArgument(), mcp.ArgumentDescription("离职用户的用户名")),
+		),
+		handler.OffboardUserPrompt,
+	)
+	s.AddPrompt(
+		mcp.NewPrompt("review_template_noise",
+			mcp.WithPromptTitle("评估模板噪音触发器"),
+			mcp.WithPromptDescription("统计模板中每个触发器在主机上产生的问题事件数，找出告警过于频繁的触发器并给出调整建议"),
+			instanceArg,
+			mcp.WithArgument("name", mcp.RequiredArgument(), mcp.ArgumentDescription("模板ID、技术名称或可见名称")),
+			mcp.WithArgument("days", mcp.ArgumentDescription("统计最近多少天，默认 7")),
+			mcp.WithArgument("since", mcp.ArgumentDescription("统计的起点，按实例时区解释，支持 now-2w、now/M、last 14 days、上周 等写法；指定时忽略 days")),
+		),
+		handler.ReviewTemplatePrompt,
+	)
+}
@@ -10,6 +10,9 @@
 package register
 
 import (
+	"zabbixMcp/tracing"
+
+	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 )
 
@@ -18,4 +21,16 @@ func Registers(s *server.MCPServer) {
 	registerInstances(s)
 	registerUser(s)
 	registerUserGroup(s)
+	registerHost(s)
+	registerAudit(s)
+	registerCompat(s)
+	registerAPICall(s)
+	registerResources(s)
+	registerProblemFeed(s)
+	registerPrompts(s)
+}
+
+// addTool 注册工具，处理器外包一层 span，与中间件创建的根 span 区分
+func addTool(s *server.MCPServer, tool mcp.Tool, h server.ToolHandlerFunc) {
+	s.AddTool(tool, tracing.Handler(tool.Name, h))
 }
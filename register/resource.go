@@ -0,0 +1,48 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-29 10:47:09
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-29 10:47:09
+ * @FilePath: \zabbix-mcp-go\register\resource.go
+ * @Description: 注册 zabbix:// 资源与资源模板
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+package register
+
+import (
+	"zabbixMcp/handler"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func registerResources(s *server.MCPServer) {
+	s.AddResource(
+		mcp.NewResource("zabbix://instances", "Zabbix 实例",
+			mcp.WithResourceDescription("已配置的Zabbix实例：地址、登录方式、版本与连接状态"),
+			mcp.WithMIMEType("application/json"),
+		),
+		handler.ResourceHandler,
+	)
+	s.AddResourceTemplate(
+		mcp.NewResourceTemplate("zabbix://{instance}/problems", "当前问题",
+			mcp.WithTemplateDescription("实例中当前未恢复的问题，最新的在前"),
+			mcp.WithTemplateMIMEType("application/json"),
+		),
+		handler.ResourceHandler,
+	)
+	s.AddResourceTemplate(
+		mcp.NewResourceTemplate("zabbix://{instance}/hosts/{host}", "主机配置",
+			mcp.WithTemplateDescription("主机的配置：接口、主机组、链接的模板与标签；host 可以是主机ID、主机名或可见名称"),
+			mcp.WithTemplateMIMEType("application/json"),
+		),
+		handler.ResourceHandler,
+	)
+	s.AddResourceTemplate(
+		mcp.NewResourceTemplate("zabbix://{instance}/templates/{name}/export", "模板导出",
+			mcp.WithTemplateDescription("以JSON导出模板配置（configuration.export）；name 可以是模板ID、技术名称或可见名称"),
+			mcp.WithTemplateMIMEType("application/json"),
+		),
+		handler.ResourceHandler,
+	)
+}
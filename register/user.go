@@ -2,6 +2,7 @@ package register
 
 import (
 	"zabbixMcp/handler"
+	"zabbixMcp/models"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
@@ -10,46 +11,50 @@ import (
 // registerClientPool 注册与 Zabbix 客户端池相关的 MCP 工具。
 // 目前仅注册工具元信息；具体处理器在 handler 包未实现时暂留为 nil，以免影响构建。
 func registerUser(s *server.MCPServer) {
-	s.AddTool(
+	addTool(s,
 		mcp.NewTool("get_users",
 			mcp.WithDescription("获取所有Zabbix用户信息"),
-			mcp.WithString("instance", mcp.Required(), mcp.Description("Zabbix实例名称必须填")),
-			mcp.WithString("username", mcp.Description("Zabbix用户名,留空表示获取所有用户")),
+			withArgs[models.GetUsersArgs](),
+			mcp.WithOutputSchema[handler.ToolResult[[]models.User]](),
 		),
 		handler.GetUsersHandler,
 	)
-	s.AddTool(
+	addTool(s,
 		mcp.NewTool("create_user", mcp.WithDescription("创建Zabbix用户"),
-			mcp.WithString("instance", mcp.Required(), mcp.Description("Zabbix实例名称必须填")),
-			mcp.WithString("username", mcp.Required(), mcp.Description("Zabbix用户名")),
-			mcp.WithString("name", mcp.Description("用户真实姓名")),
-			mcp.WithString("userGroup", mcp.Required(), mcp.Description("用户组ID")),
-			mcp.WithString("roleID", mcp.Description("角色ID")),
+			withArgs[models.CreateUserArgs](),
 		),
 		handler.CreateUsersHandler,
 	)
-	s.AddTool(
+	addTool(s,
 		mcp.NewTool("update_user", mcp.WithDescription("更新Zabbix用户"),
-			mcp.WithString("instance", mcp.Required(), mcp.Description("Zabbix实例名称必须填")),
-			mcp.WithString("userid", mcp.Required(), mcp.Description("Zabbix用户ID")),
-			// mcp.WithString("surname", mcp.Description("用户姓氏")),
-			mcp.WithString("name", mcp.Description("用户名字")),
-			mcp.WithString("usrgrps", mcp.Description("用户组ID列表")),
-			mcp.WithBoolean("updatePasswd", mcp.Description("是否更新密码 默认: false")),
+			withArgs[models.UpdateUserArgs](),
 		),
 		handler.UpdateUsersHandler,
 	)
-	s.AddTool(
+	addTool(s,
 		mcp.NewTool("disable_user", mcp.WithDescription("禁用Zabbix用户"),
-			mcp.WithString("instance", mcp.Required(), mcp.Description("Zabbix实例名称必须填")),
-			mcp.WithString("userid", mcp.Required(), mcp.Description("Zabbix用户ID")),
+			mcp.WithDestructiveHintAnnotation(true),
+			withArgs[models.DisableUserArgs](),
 		),
 		handler.DisableUserHandler,
 	)
-	s.AddTool(
+	addTool(s,
+		mcp.NewTool("offboard_user", mcp.WithDescription("离职处理：在全部实例中按用户名、姓名或邮箱查找账号，逐个禁用（移入 No access to the frontend 与禁用用户组并重置密码）、吊销API令牌、移除告警媒介，返回按实例的处理报告"),
+			mcp.WithDestructiveHintAnnotation(true),
+			withArgs[models.OffboardUserArgs](),
+		),
+		handler.OffboardUserHandler,
+	)
+	addTool(s,
+		mcp.NewTool("bulk_create_users", mcp.WithDescription("批量开通用户：按 CSV 或 JSON 逐行校验用户组、角色与媒介类型后创建用户（随机初始密码），已存在的用户按 mode 跳过、更新或记为失败，返回逐行结果"),
+			withArgs[models.BulkCreateUsersArgs](),
+		),
+		handler.BulkCreateUsersHandler,
+	)
+	addTool(s,
 		mcp.NewTool("delete_user", mcp.WithDescription("删除Zabbix用户"),
-			mcp.WithString("instance", mcp.Required(), mcp.Description("Zabbix实例名称必须填")),
-			mcp.WithArray("userids", mcp.Required(), mcp.Description("Zabbix用户ID列表")),
+			mcp.WithDestructiveHintAnnotation(true),
+			withArgs[models.DeleteUsersArgs](),
 		),
 		handler.DeleteUsersHandler,
 	)
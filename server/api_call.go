@@ -0,0 +1,66 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-27 15:02:37
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-27 15:02:37
+ * @FilePath: \zabbix-mcp-go\server\api_call.go
+ * @Description: 直接调用任意 Zabbix API 方法（api_call 工具）
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+
+	"zabbixMcp/models"
+	"zabbixMcp/tracing"
+	"zabbixMcp/zabbix"
+)
+
+// CallAPI 通过 ZabbixClient.Call 调用任意方法并返回原始结果；对象形式的参数会先经过 AdaptAPIParams，
+// compat.yaml 中有该方法的规则时自动按实例版本适配（数组形式的参数，例如 *.delete 的ID列表，原样发送）
+func CallAPI(ctx context.Context, provider zabbix.ClientProvider, instance, method string, params interface{}) (json.RawMessage, error) {
+	ctx, span := tracing.Start(ctx, "server.CallAPI", tracing.AttrInstance.String(instance))
+	defer span.End()
+	lease, err := acquire(ctx, provider, instance)
+	if err != nil {
+		return nil, err
+	}
+	var callErr error
+	defer func() { lease.Release(callErr) }()
+	client := lease.Client()
+	var result json.RawMessage
+	callErr = client.Call(ctx, method, adaptRawParams(client, method, params), &result)
+	if callErr != nil {
+		return nil, callErr
+	}
+	return result, nil
+}
+
+// PlanAPICall 预览 api_call：只展示适配后的（已脱敏）参数，不执行
+func PlanAPICall(ctx context.Context, provider zabbix.ClientProvider, instance, method string, params interface{}) (*models.MutationPlan, error) {
+	lease, err := acquire(ctx, provider, instance)
+	if err != nil {
+		return nil, err
+	}
+	defer lease.Release(nil)
+	plan := models.NewMutationPlan(instance, method)
+	adapted, err := json.Marshal(adaptRawParams(lease.Client(), method, params))
+	if err != nil {
+		return nil, err
+	}
+	plan.Params = zabbix.ScrubParams(adapted)
+	return plan, nil
+}
+
+func adaptRawParams(client zabbix.APIClient, method string, params interface{}) interface{} {
+	switch p := params.(type) {
+	case nil:
+		return map[string]interface{}{}
+	case map[string]interface{}:
+		return client.AdaptAPIParams(method, models.MapParams(p))
+	}
+	return params
+}
@@ -0,0 +1,35 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-23 17:12:40
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-23 17:12:40
+ * @FilePath: \zabbix-mcp-go\server\audit.go
+ * @Description: 审计日志查询
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+
+package server
+
+import (
+	"context"
+	"zabbixMcp/audit"
+	"zabbixMcp/models"
+)
+
+// QueryAuditLog 按时间范围、工具与实例查询审计记录
+func QueryAuditLog(ctx context.Context, log *audit.Log, filter audit.Filter) ([]audit.Entry, error) {
+	if log == nil {
+		return nil, models.Errorf(models.CodeInternal, "审计日志未启用").WithHint("在 config.yml 中开启 audit 后重启服务")
+	}
+	if ctx != nil {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+	}
+	if !filter.Since.IsZero() && !filter.Until.IsZero() && filter.Until.Before(filter.Since) {
+		return nil, models.ArgsError{{Arg: "until", Problem: "早于开始时间 since"}}
+	}
+	return log.Query(filter)
+}
@@ -0,0 +1,499 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-31 14:18:52
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-31 14:18:52
+ * @FilePath: \zabbix-mcp-go\server\bulk_user.go
+ * @Description: 批量开通用户：解析 CSV/JSON，按目标实例校验用户组、角色与媒介类型，逐行创建、更新或跳过
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"zabbixMcp/credential"
+	"zabbixMcp/logger"
+	"zabbixMcp/models"
+	"zabbixMcp/tracing"
+	"zabbixMcp/utils"
+	"zabbixMcp/zabbix"
+)
+
+// 已存在的用户的处理方式
+const (
+	BulkModeSkip   = "skip"   // 跳过
+	BulkModeUpdate = "update" // 更新姓名、用户组、角色与媒介，不修改密码
+	BulkModeFail   = "fail"   // 记为失败
+)
+
+// 每行的计划动作与处理结果
+const (
+	BulkActionCreate = "create"
+	BulkActionUpdate = "update"
+	BulkActionSkip   = "skip"
+
+	BulkStatusPlanned = "planned" // dry_run 时校验通过
+	BulkStatusDone    = "done"
+	BulkStatusFailed  = "failed"
+)
+
+// bulkPasswordLength 批量创建时生成的初始密码长度
+const bulkPasswordLength = 12
+
+// 新建媒介的默认设置：全部严重级别、全天
+const (
+	defaultMediaSeverity = 63
+	defaultMediaPeriod   = "1-7,00:00-24:00"
+)
+
+// BulkUserMedia 一行中的告警媒介
+type BulkUserMedia struct {
+	Type   string `json:"type"` // 媒介类型名称或ID
+	SendTo string `json:"sendto"`
+}
+
+// BulkUserRow 批量开通的一行
+type BulkUserRow struct {
+	Username string          `json:"username"`
+	Name     string          `json:"name,omitempty"`
+	Surname  string          `json:"surname,omitempty"`
+	Email    string          `json:"email,omitempty"`
+	Groups   []string        `json:"groups"`         // 用户组名称或ID
+	Role     string          `json:"role,omitempty"` // 角色名称或ID
+	Media    []BulkUserMedia `json:"media,omitempty"`
+}
+
+// BulkUserOptions 批量开通选项
+type BulkUserOptions struct {
+	Mode           string // 已存在的用户的处理方式：skip、update、fail
+	EmailMediaType string // email 列使用的媒介类型名称或ID
+}
+
+// BulkUserResult 一行的处理结果
+type BulkUserResult struct {
+	Row        int                 `json:"row"` // 数据行序号，从 1 开始（CSV 不含表头）
+	Username   string              `json:"username"`
+	Action     string              `json:"action,omitempty" jsonschema_description:"create 创建 update 更新 skip 跳过"`
+	Status     string              `json:"status" jsonschema_description:"planned 校验通过(dry_run) done 完成 failed 失败"`
+	UserID     string              `json:"userid,omitempty"`
+	Credential *credential.Receipt `json:"credential,omitempty"` // 新建用户初始密码的投递回执
+	Errors     []models.ArgError   `json:"errors,omitempty"`
+}
+
+// BulkUserSummary 批量开通汇总
+type BulkUserSummary struct {
+	Total   int `json:"total"`
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+	Skipped int `json:"skipped"`
+	Failed  int `json:"failed"`
+}
+
+// BulkUserReport 批量开通报告
+type BulkUserReport struct {
+	Instance string               `json:"instance"`
+	Mode     string               `json:"mode"`
+	DryRun   bool                 `json:"dry_run"`
+	Summary  BulkUserSummary      `json:"summary"`
+	Rows     []BulkUserResult     `json:"rows"`
+	Plan     *models.MutationPlan `json:"plan,omitempty"` // dry_run 时的变更预览
+}
+
+// BulkUserBatch 校验后的批量开通：每行的动作与已解析为ID的 user.create / user.update 参数
+type BulkUserBatch struct {
+	Instance string
+	Mode     string
+	Results  []BulkUserResult
+	params   []models.MapParams // 与 Results 一一对应，校验失败或跳过的行为 nil
+}
+
+// ParseBulkUsers 解析 CSV 或 JSON 数组，format 为空或 auto 时按首个非空字符判断（[ 为 JSON）。
+// CSV 第一行为表头，列名不区分大小写：username、name、surname、email、groups、role、media；
+// groups 以 ; 分隔，media 为 ; 分隔的 "媒介类型=收件人"
+func ParseBulkUsers(data, format string) ([]BulkUserRow, error) {
+	data = strings.TrimPrefix(strings.TrimSpace(data), "\ufeff")
+	if data == "" {
+		return nil, models.ArgsError{{Arg: "data", Problem: "不能为空"}}
+	}
+	if format == "" || format == "auto" {
+		format = "csv"
+		if data[0] == '[' {
+			format = "json"
+		}
+	}
+	switch format {
+	case "json":
+		var rows []BulkUserRow
+		if err := json.Unmarshal([]byte(data), &rows); err != nil {
+			return nil, models.ArgsError{{Arg: "data", Problem: fmt.Sprintf("JSON 格式错误: %v", err)}}
+		}
+		return rows, nil
+	case "csv":
+		return parseBulkCSV(data)
+	}
+	return nil, models.ArgsError{{Arg: "data_format", Problem: fmt.Sprintf("不支持的格式 %q，可选 auto、csv、json", format)}}
+}
+
+func parseBulkCSV(data string) ([]BulkUserRow, error) {
+	r := csv.NewReader(bytes.NewReader([]byte(data)))
+	r.TrimLeadingSpace = true
+	r.FieldsPerRecord = -1
+	header, err := r.Read()
+	if err != nil {
+		return nil, models.ArgsError{{Arg: "data", Problem: fmt.Sprintf("CSV 表头读取失败: %v", err)}}
+	}
+	columns := make(map[string]int, len(header))
+	for i, h := range header {
+		h = strings.ToLower(strings.TrimSpace(h))
+		switch h {
+		case "alias":
+			h = "username"
+		case "usrgrps", "group":
+			h = "groups"
+		case "roleid":
+			h = "role"
+		case "medias":
+			h = "media"
+		}
+		columns[h] = i
+	}
+	if _, ok := columns["username"]; !ok {
+		return nil, models.ArgsError{{Arg: "data", Problem: "CSV 表头缺少 username 列"}}
+	}
+	var rows []BulkUserRow
+	for line := 2; ; line++ {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, models.ArgsError{{Arg: "data", Problem: fmt.Sprintf("CSV 第 %d 行格式错误: %v", line, err)}}
+		}
+		cell := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		row := BulkUserRow{
+			Username: cell("username"),
+			Name:     cell("name"),
+			Surname:  cell("surname"),
+			Email:    cell("email"),
+			Groups:   splitList(cell("groups")),
+			Role:     cell("role"),
+		}
+		for _, m := range splitList(cell("media")) {
+			typ, sendTo, _ := strings.Cut(m, "=")
+			row.Media = append(row.Media, BulkUserMedia{Type: strings.TrimSpace(typ), SendTo: strings.TrimSpace(sendTo)})
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ";") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// PrepareBulkUsers 按目标实例校验每一行：用户名必填且不重复、用户组/角色/媒介类型存在，
+// 并按 mode 决定已存在的用户是更新、跳过还是记为失败。只读取，不修改 Zabbix
+func PrepareBulkUsers(ctx context.Context, provider zabbix.ClientProvider, instance string, rows []BulkUserRow, opts BulkUserOptions) (*BulkUserBatch, error) {
+	ctx, span := tracing.Start(ctx, "server.PrepareBulkUsers", tracing.AttrInstance.String(instance))
+	defer span.End()
+	if opts.Mode == "" {
+		opts.Mode = BulkModeSkip
+	}
+	if len(rows) == 0 {
+		return nil, models.ArgsError{{Arg: "data", Problem: "没有任何数据行"}}
+	}
+
+	var usernames []string
+	for _, row := range rows {
+		if u := strings.TrimSpace(row.Username); u != "" {
+			usernames = append(usernames, u)
+		}
+	}
+	var existing []models.User
+	var mediaTypes []models.MediaType
+	if err := getAll(ctx, provider, instance,
+		zabbix.BatchCall{Method: "user.get", Params: models.MapParams{
+			"output": []string{"userid", "username"},
+			"filter": map[string]interface{}{"username": usernames},
+		}, Result: &existing},
+		zabbix.BatchCall{Method: "mediatype.get", Params: models.MapParams{
+			"output": []string{"mediatypeid", "name", "type"},
+		}, Result: &mediaTypes},
+	); err != nil {
+		return nil, err
+	}
+	existingIDs := make(map[string]string, len(existing))
+	for _, u := range existing {
+		existingIDs[u.Username] = u.UserID
+	}
+	emailTypes := make(map[string]bool)
+	for _, mt := range mediaTypes {
+		if mt.Type == 0 {
+			emailTypes[mt.MediaTypeID] = true
+		}
+	}
+
+	r := &bulkResolver{ctx: ctx, provider: provider, instance: instance, ids: map[string]string{}, errs: map[string]error{}}
+	batch := &BulkUserBatch{Instance: instance, Mode: opts.Mode}
+	seen := make(map[string]int)
+	for i, row := range rows {
+		res := BulkUserResult{Row: i + 1, Username: strings.TrimSpace(row.Username), Status: BulkStatusPlanned}
+		var params models.MapParams
+		problem := func(arg, format string, a ...interface{}) {
+			res.Errors = append(res.Errors, models.ArgError{Arg: arg, Problem: fmt.Sprintf(format, a...)})
+		}
+
+		switch first, dup := seen[res.Username]; {
+		case res.Username == "":
+			problem("username", "为必填项，不能为空")
+		case dup:
+			problem("username", "与第 %d 行重复", first)
+		default:
+			seen[res.Username] = res.Row
+		}
+		if len(row.Groups) == 0 {
+			problem("groups", "至少需要一个用户组")
+		}
+		var usrgrps []map[string]interface{}
+		for _, g := range row.Groups {
+			if id, err := r.resolve(KindUserGroup, "groups", g); err != nil {
+				problem("groups", "%v", err)
+			} else {
+				usrgrps = append(usrgrps, map[string]interface{}{"usrgrpid": id})
+			}
+		}
+		roleID, err := r.resolve(KindRole, "role", row.Role)
+		if err != nil {
+			problem("role", "%v", err)
+		}
+		medias := []map[string]interface{}{}
+		addMedia := func(arg, typ, sendTo string) {
+			if sendTo == "" {
+				problem(arg, "媒介 %s 缺少收件人", typ)
+				return
+			}
+			id, err := r.resolve(KindMediaType, arg, typ)
+			if err != nil {
+				problem(arg, "%v", err)
+				return
+			}
+			medias = append(medias, NewUserMedia(id, sendTo, emailTypes[id]))
+		}
+		if email := strings.TrimSpace(row.Email); email != "" {
+			if opts.EmailMediaType == "" {
+				problem("email", "未指定 email 使用的媒介类型")
+			} else {
+				addMedia("email", opts.EmailMediaType, email)
+			}
+		}
+		for _, m := range row.Media {
+			if strings.TrimSpace(m.Type) == "" {
+				problem("media", "媒介缺少类型，应为 媒介类型=收件人")
+				continue
+			}
+			addMedia("media", strings.TrimSpace(m.Type), strings.TrimSpace(m.SendTo))
+		}
+
+		userID, exists := existingIDs[res.Username]
+		switch {
+		case len(res.Errors) > 0:
+			res.Status = BulkStatusFailed
+		case !exists:
+			res.Action = BulkActionCreate
+			params = models.MapParams{"username": res.Username, "usrgrps": usrgrps, "medias": medias}
+		case opts.Mode == BulkModeUpdate:
+			res.Action, res.UserID = BulkActionUpdate, userID
+			params = models.MapParams{"userid": userID, "usrgrps": usrgrps}
+			if len(medias) > 0 { // 未提供媒介时保留用户现有的媒介
+				params["medias"] = medias
+			}
+		case opts.Mode == BulkModeFail:
+			res.Status, res.UserID = BulkStatusFailed, userID
+			problem("username", "用户已存在（userid %s）", userID)
+		default:
+			res.Action, res.UserID = BulkActionSkip, userID
+		}
+		if params != nil {
+			for k, v := range map[string]string{"name": row.Name, "surname": row.Surname, "roleid": roleID} {
+				if v = strings.TrimSpace(v); v != "" {
+					params[k] = v
+				}
+			}
+		}
+		batch.Results = append(batch.Results, res)
+		batch.params = append(batch.params, params)
+	}
+	return batch, nil
+}
+
+// bulkResolver 缓存同一批次中重复出现的用户组、角色与媒介类型引用
+type bulkResolver struct {
+	ctx      context.Context
+	provider zabbix.ClientProvider
+	instance string
+	ids      map[string]string
+	errs     map[string]error
+}
+
+func (r *bulkResolver) resolve(kind ObjectKind, arg, ref string) (string, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return "", nil
+	}
+	key := string(kind) + "\x00" + ref
+	if id, ok := r.ids[key]; ok {
+		return id, nil
+	}
+	if err, ok := r.errs[key]; ok {
+		return "", err
+	}
+	id, err := ResolveID(r.ctx, r.provider, r.instance, kind, arg, ref)
+	if err != nil {
+		if args, ok := err.(models.ArgsError); ok && len(args) == 1 {
+			err = fmt.Errorf("%s", args[0].Problem)
+		} else {
+			err = fmt.Errorf("%s", zabbix.ClassifyError(err).Message)
+		}
+		r.errs[key] = err
+		return "", err
+	}
+	r.ids[key] = id
+	return id, nil
+}
+
+// Changes 需要创建或更新的行数
+func (b *BulkUserBatch) Changes() int {
+	n := 0
+	for _, p := range b.params {
+		if p != nil {
+			n++
+		}
+	}
+	return n
+}
+
+// Report 汇总当前结果
+func (b *BulkUserBatch) Report(dryRun bool) *BulkUserReport {
+	report := &BulkUserReport{Instance: b.Instance, Mode: b.Mode, DryRun: dryRun, Rows: b.Results}
+	if dryRun {
+		report.Plan = b.Plan()
+		report.Plan.DryRun = true
+	}
+	report.Summary.Total = len(b.Results)
+	for _, res := range b.Results {
+		switch {
+		case res.Status == BulkStatusFailed:
+			report.Summary.Failed++
+		case res.Action == BulkActionCreate:
+			report.Summary.Created++
+		case res.Action == BulkActionUpdate:
+			report.Summary.Updated++
+		case res.Action == BulkActionSkip:
+			report.Summary.Skipped++
+		}
+	}
+	return report
+}
+
+// Plan 变更预览：每个需要创建或更新的行对应一次 user.create / user.update（密码已隐藏）
+func (b *BulkUserBatch) Plan() *models.MutationPlan {
+	plan := models.NewMutationPlan(b.Instance, "user.create")
+	var params []interface{}
+	for i, res := range b.Results {
+		p := b.params[i]
+		if p == nil {
+			if res.Status == BulkStatusFailed {
+				plan.Warnings = append(plan.Warnings, fmt.Sprintf("第 %d 行 %s 校验失败，不会处理", res.Row, res.Username))
+			}
+			continue
+		}
+		method := "user." + res.Action
+		shown := models.MapParams{"method": method}
+		for k, v := range p {
+			shown[k] = v
+		}
+		if res.Action == BulkActionCreate {
+			shown["passwd"] = models.MaskedValue
+		}
+		params = append(params, shown)
+		plan.Changes = append(plan.Changes, models.FieldChange{ObjectID: res.Username, Field: "action", Old: res.UserID, New: res.Action})
+	}
+	plan.Params = params
+	return plan
+}
+
+// ExecuteBulkUsers 按校验结果逐行创建或更新用户，新建用户使用随机生成的初始密码并通过 deliverer 投递；单行失败不影响其他行
+func ExecuteBulkUsers(ctx context.Context, provider zabbix.ClientProvider, b *BulkUserBatch, deliverer credential.Deliverer) *BulkUserReport {
+	ctx, span := tracing.Start(ctx, "server.ExecuteBulkUsers", tracing.AttrInstance.String(b.Instance))
+	defer span.End()
+	for i := range b.Results {
+		res := &b.Results[i]
+		p := b.params[i]
+		if p == nil {
+			continue
+		}
+		fail := func(err error) {
+			res.Status = BulkStatusFailed
+			res.Errors = append(res.Errors, models.ArgError{Arg: "username", Problem: zabbix.ClassifyError(err).Message})
+		}
+		switch res.Action {
+		case BulkActionCreate:
+			passwd, err := utils.GenerateSecurePassword(bulkPasswordLength)
+			if err != nil {
+				fail(fmt.Errorf("生成密码失败: %w", err))
+				continue
+			}
+			p["passwd"] = passwd
+			created, err := CreateUsers(ctx, provider, p, b.Instance)
+			delete(p, "passwd")
+			if err != nil {
+				fail(err)
+				continue
+			}
+			res.UserID = FirstID(created["userids"])
+			res.Credential = credential.Deliver(ctx, deliverer, credential.Credential{
+				Instance: b.Instance, UserID: res.UserID, Username: res.Username, Password: passwd, Reason: credential.ReasonCreate,
+			})
+		case BulkActionUpdate:
+			if _, err := UpdateUser(ctx, provider, p, b.Instance); err != nil {
+				fail(err)
+				continue
+			}
+		}
+		res.Status = BulkStatusDone
+		logger.L().Infof("批量开通: 实例 %s 第 %d 行 %s %s", b.Instance, res.Row, res.Username, res.Action)
+	}
+	for i := range b.Results {
+		if res := &b.Results[i]; res.Status == BulkStatusPlanned {
+			res.Status = BulkStatusDone
+		}
+	}
+	return b.Report(false)
+}
+
+// FirstID 取 *.create 返回的ID列表中的第一个
+func FirstID(v interface{}) string {
+	if ids, ok := v.([]interface{}); ok && len(ids) > 0 {
+		return fmt.Sprint(ids[0])
+	}
+	return ""
+}
@@ -0,0 +1,263 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"zabbixMcp/credential"
+	"zabbixMcp/models"
+	"zabbixMcp/zabbix/zabbixtest"
+)
+
+func TestParseBulkUsersCSV(t *testing.T) {
+	data := "\ufeffAlias, Name ,USRGRPS,Medias,RoleID,Email\n" +
+		"zhangsan,San,Guests; 7 ;,Email=zs@example.com;SMS = 13800000000,Admin role,zs@corp.example.com\n" +
+		"\"li,si\",\"Si, Li\",8\n" +
+		"wangwu,,,=nobody\n"
+	rows, err := ParseBulkUsers(data, "auto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []BulkUserRow{
+		{
+			Username: "zhangsan", Name: "San", Email: "zs@corp.example.com", Groups: []string{"Guests", "7"}, Role: "Admin role",
+			Media: []BulkUserMedia{{Type: "Email", SendTo: "zs@example.com"}, {Type: "SMS", SendTo: "13800000000"}},
+		},
+		{Username: "li,si", Name: "Si, Li", Groups: []string{"8"}},
+		{Username: "wangwu", Media: []BulkUserMedia{{Type: "", SendTo: "nobody"}}},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("ParseBulkUsers = %+v\n期望 %+v", rows, want)
+	}
+
+	// group 同样是 groups 的别名
+	rows, err = ParseBulkUsers("username,group\nzhaoliu,Guests", "csv")
+	if err != nil || len(rows) != 1 || !reflect.DeepEqual(rows[0].Groups, []string{"Guests"}) {
+		t.Errorf("group 列 = %+v, %v", rows, err)
+	}
+}
+
+func TestParseBulkUsersFormats(t *testing.T) {
+	rows, err := ParseBulkUsers(` [{"username": "zhangsan", "groups": ["Guests"], "media": [{"type": "Email", "sendto": "zs@example.com"}]}]`, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []BulkUserRow{{Username: "zhangsan", Groups: []string{"Guests"}, Media: []BulkUserMedia{{Type: "Email", SendTo: "zs@example.com"}}}}; !reflect.DeepEqual(rows, want) {
+		t.Errorf("JSON = %+v", rows)
+	}
+
+	for name, c := range map[string][2]string{
+		"空数据":           {" \ufeff ", "auto"},
+		"缺少 username 列": {"name,groups\nSan,Guests", "csv"},
+		"CSV 引号未闭合":     {"username\n\"zhangsan", "csv"},
+		"JSON 格式错误":     {`[{"username": 1}]`, "json"},
+		"不支持的格式":        {"username\nzhangsan", "xlsx"},
+	} {
+		_, err := ParseBulkUsers(c[0], c[1])
+		var ae models.ArgsError
+		if !errors.As(err, &ae) {
+			t.Errorf("%s: err = %v，期望参数错误", name, err)
+		}
+	}
+}
+
+// newBulkServer 预置媒介类型 SMS 与已存在用户 zhangsan（带一个邮件媒介）的模拟服务器，返回用户ID与 SMS 的ID
+func newBulkServer(t *testing.T, version string) (*zabbixtest.Server, string, string) {
+	t.Helper()
+	srv := zabbixtest.NewServer(zabbixtest.Options{Version: version})
+	t.Cleanup(srv.Close)
+	sms := srv.Store().AddMediaType(zabbixtest.MediaType{Name: "SMS", Type: "2", Status: "0"})
+	existing := srv.Store().AddUser(zabbixtest.User{
+		Username: "zhangsan", Name: "San", Surname: "Zhang", Passwd: "Old-pass-1", RoleID: "1", Type: "1", GroupIDs: []string{"8"},
+		Medias: []zabbixtest.Media{{MediaID: "1", MediaTypeID: "1", SendTo: "old@example.com", Active: "0"}},
+	})
+	return srv, existing, sms
+}
+
+func bulkRows() []BulkUserRow {
+	return []BulkUserRow{
+		{Username: "zhangsan", Name: "San", Surname: "Zhang-updated", Groups: []string{"Zabbix administrators"}, Role: "Admin role"},
+		{Username: "lisi", Name: "Si", Groups: []string{"Guests", "7"}, Email: "lisi@example.com", Media: []BulkUserMedia{{Type: "SMS", SendTo: "13800000000"}}},
+		{Username: "lisi", Groups: []string{"Guests"}},
+		{Username: "wangwu", Groups: []string{"No such group"}, Role: "Nobody", Media: []BulkUserMedia{{Type: "Pager", SendTo: "1"}, {Type: "SMS"}}},
+		{Username: " ", Email: "x@example.com"},
+	}
+}
+
+func problemArgs(res BulkUserResult) []string {
+	var args []string
+	for _, e := range res.Errors {
+		args = append(args, e.Arg)
+	}
+	return args
+}
+
+// TestPrepareBulkUsers 逐行校验，已存在的用户按 mode 处理；只读取，不修改 Zabbix
+func TestPrepareBulkUsers(t *testing.T) {
+	for _, v := range []string{"5.0.0", "6.0.0", "7.0.0"} {
+		t.Run(v, func(t *testing.T) {
+			srv, existing, _ := newBulkServer(t, v)
+			provider, err := zabbixtest.NewProvider(srv)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer provider.Close()
+			ctx := context.Background()
+
+			for _, c := range []struct {
+				mode    string
+				action  string
+				status  string
+				summary BulkUserSummary
+			}{
+				{BulkModeSkip, BulkActionSkip, BulkStatusPlanned, BulkUserSummary{Total: 5, Created: 1, Skipped: 1, Failed: 3}},
+				{BulkModeUpdate, BulkActionUpdate, BulkStatusPlanned, BulkUserSummary{Total: 5, Created: 1, Updated: 1, Failed: 3}},
+				{BulkModeFail, "", BulkStatusFailed, BulkUserSummary{Total: 5, Created: 1, Failed: 4}},
+			} {
+				srv.ResetCalls()
+				batch, err := PrepareBulkUsers(ctx, provider, "zabbixtest", bulkRows(), BulkUserOptions{Mode: c.mode, EmailMediaType: "Email"})
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, m := range srv.Methods() {
+					if !strings.HasSuffix(m, ".get") && m != "apiinfo.version" {
+						t.Errorf("%s: 校验阶段调用了 %s", c.mode, m)
+					}
+				}
+				rows := batch.Results
+				if rows[0].Action != c.action || rows[0].Status != c.status || rows[0].UserID != existing {
+					t.Errorf("%s: 已存在的用户 = %+v", c.mode, rows[0])
+				}
+				if rows[1].Action != BulkActionCreate || rows[1].Status != BulkStatusPlanned || len(rows[1].Errors) != 0 {
+					t.Errorf("%s: 新用户 = %+v", c.mode, rows[1])
+				}
+				if got := problemArgs(rows[2]); !reflect.DeepEqual(got, []string{"username"}) || !strings.Contains(rows[2].Errors[0].Problem, "与第 2 行重复") {
+					t.Errorf("%s: 重复行 = %+v", c.mode, rows[2])
+				}
+				if got := problemArgs(rows[3]); !reflect.DeepEqual(got, []string{"groups", "role", "media", "media"}) {
+					t.Errorf("%s: 引用不存在的对象 = %+v", c.mode, rows[3])
+				}
+				if got := problemArgs(rows[4]); !reflect.DeepEqual(got, []string{"username", "groups"}) {
+					t.Errorf("%s: 缺少用户名与用户组 = %+v", c.mode, rows[4])
+				}
+				if got := batch.Report(true).Summary; got != c.summary {
+					t.Errorf("%s: 汇总 = %+v，期望 %+v", c.mode, got, c.summary)
+				}
+			}
+		})
+	}
+}
+
+// TestPrepareBulkUsersParams 已解析为ID的参数；email 列使用 EmailMediaType，未指定时报错
+func TestPrepareBulkUsersParams(t *testing.T) {
+	srv, existing, sms := newBulkServer(t, "7.0.0")
+	provider, err := zabbixtest.NewProvider(srv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer provider.Close()
+	ctx := context.Background()
+
+	batch, err := PrepareBulkUsers(ctx, provider, "zabbixtest", bulkRows()[:2], BulkUserOptions{Mode: BulkModeUpdate, EmailMediaType: "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	update := batch.params[0]
+	if want := (models.MapParams{"userid": existing, "usrgrps": []map[string]interface{}{{"usrgrpid": "7"}}, "name": "San", "surname": "Zhang-updated", "roleid": "2"}); !reflect.DeepEqual(update, want) {
+		t.Errorf("user.update 参数 = %v\n期望 %v", update, want)
+	}
+	create := batch.params[1]
+	medias, _ := create["medias"].([]map[string]interface{})
+	if create["username"] != "lisi" || !reflect.DeepEqual(create["usrgrps"], []map[string]interface{}{{"usrgrpid": "8"}, {"usrgrpid": "7"}}) || len(medias) != 2 {
+		t.Fatalf("user.create 参数 = %v", create)
+	}
+	if medias[0]["mediatypeid"] != "1" || !reflect.DeepEqual(medias[0]["sendto"], []string{"lisi@example.com"}) {
+		t.Errorf("邮件媒介的收件人应为数组: %v", medias[0])
+	}
+	if medias[1]["mediatypeid"] != sms || medias[1]["sendto"] != "13800000000" {
+		t.Errorf("SMS 媒介 = %v", medias[1])
+	}
+	plan := batch.Plan()
+	if raw := plan.Params.([]interface{}); len(raw) != 2 || raw[1].(models.MapParams)["passwd"] != models.MaskedValue {
+		t.Errorf("预览 = %+v，新建用户的密码应隐藏", plan.Params)
+	}
+
+	batch, err = PrepareBulkUsers(ctx, provider, "zabbixtest", bulkRows()[1:2], BulkUserOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := problemArgs(batch.Results[0]); !reflect.DeepEqual(got, []string{"email"}) || batch.Mode != BulkModeSkip {
+		t.Errorf("未指定 email 媒介类型 = %+v (mode %s)", batch.Results[0], batch.Mode)
+	}
+}
+
+// recordingDeliverer 记录投递的密码
+type recordingDeliverer struct {
+	delivered []credential.Credential
+}
+
+func (d *recordingDeliverer) Method() string { return "test" }
+
+func (d *recordingDeliverer) Deliver(_ context.Context, c credential.Credential) (*credential.Receipt, error) {
+	d.delivered = append(d.delivered, c)
+	return &credential.Receipt{Method: "test", Location: c.Username}, nil
+}
+
+// TestExecuteBulkUsers 新建用户使用生成的密码并投递；更新不修改密码，未提供媒介时保留现有媒介；单行失败不影响其他行
+func TestExecuteBulkUsers(t *testing.T) {
+	for _, v := range []string{"5.0.0", "7.0.0"} {
+		t.Run(v, func(t *testing.T) {
+			srv, existing, _ := newBulkServer(t, v)
+			provider, err := zabbixtest.NewProvider(srv)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer provider.Close()
+			ctx := context.Background()
+			rows := append(bulkRows()[:2], BulkUserRow{Username: "zhaoliu", Groups: []string{"Guests"}})
+
+			batch, err := PrepareBulkUsers(ctx, provider, "zabbixtest", rows, BulkUserOptions{Mode: BulkModeUpdate, EmailMediaType: "Email"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			srv.FailNext("user.create", -32500, "Application error.", "Incorrect value for field \"username\".")
+			deliverer := &recordingDeliverer{}
+			report := ExecuteBulkUsers(ctx, provider, batch, deliverer)
+
+			if want := (BulkUserSummary{Total: 3, Created: 1, Updated: 1, Failed: 1}); report.Summary != want {
+				t.Errorf("汇总 = %+v，期望 %+v (%+v)", report.Summary, want, report.Rows)
+			}
+			if report.Rows[0].Status != BulkStatusDone || report.Rows[1].Status != BulkStatusFailed || report.Rows[2].Status != BulkStatusDone {
+				t.Errorf("各行状态 = %+v", report.Rows)
+			}
+
+			u, _ := srv.Store().User(existing)
+			if u.Passwd != "Old-pass-1" || u.Surname != "Zhang-updated" || !reflect.DeepEqual(u.GroupIDs, []string{"7"}) {
+				t.Errorf("更新后的用户 = %+v，密码应保持不变", u)
+			}
+			if len(u.Medias) != 1 || u.Medias[0].SendTo != "old@example.com" {
+				t.Errorf("未提供媒介时应保留现有媒介: %+v", u.Medias)
+			}
+
+			created := report.Rows[2]
+			nu, ok := srv.Store().User(created.UserID)
+			if !ok || nu.Username != "zhaoliu" || nu.Passwd == "" {
+				t.Fatalf("新建的用户 = %+v", nu)
+			}
+			if len(deliverer.delivered) != 1 || deliverer.delivered[0].Password != nu.Passwd || deliverer.delivered[0].UserID != created.UserID {
+				t.Errorf("投递的密码 = %+v，期望与新建用户的密码一致", deliverer.delivered)
+			}
+			if created.Credential == nil || created.Credential.Passwd != "" || created.Credential.Method != "test" {
+				t.Errorf("回执 = %+v，不应包含密码", created.Credential)
+			}
+			for _, p := range batch.params {
+				if _, ok := p["passwd"]; ok {
+					t.Error("执行后不应保留密码参数")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-23 10:20:11
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-23 10:20:11
+ * @FilePath: \zabbix-mcp-go\server\common.go
+ * @Description: 业务层公共方法
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+
+package server
+
+import (
+	"context"
+
+	"zabbixMcp/models"
+	"zabbixMcp/zabbix"
+)
+
+// acquire 租借客户端：instance 为空时使用任意可用客户端，否则强制选择指定实例
+func acquire(ctx context.Context, provider zabbix.ClientProvider, instance string) (zabbix.ClientLease, error) {
+	if provider == nil {
+		return nil, models.Errorf(models.CodeInstanceUnavailable, "没有可用的 Zabbix 客户端").WithHint("检查 config.yml 中是否配置了 Zabbix 实例")
+	}
+	if instance != "" {
+		return provider.AcquireByInstance(ctx, instance)
+	}
+	return provider.Acquire(ctx)
+}
+
+// adaptParams 租借客户端仅用于按版本适配参数，不发起实际调用
+func adaptParams(ctx context.Context, provider zabbix.ClientProvider, instance, method string, spec models.ParamSpec) (map[string]interface{}, error) {
+	lease, err := acquire(ctx, provider, instance)
+	if err != nil {
+		return nil, err
+	}
+	defer lease.Release(nil)
+	return lease.Client().AdaptAPIParams(method, spec), nil
+}
+
+// get 租借客户端执行 *.get 类方法：按版本适配参数，并把（已归一化的）结果解码到 out
+func get(ctx context.Context, provider zabbix.ClientProvider, instance, method string, spec models.ParamSpec, out interface{}) error {
+	lease, err := acquire(ctx, provider, instance)
+	if err != nil {
+		return err
+	}
+	var callErr error
+	defer func() { lease.Release(callErr) }()
+	client := lease.Client()
+	callErr = client.Call(ctx, method, client.AdaptAPIParams(method, spec), out)
+	return callErr
+}
+
+// call 租借客户端执行参数无需按版本适配的方法（例如 *.delete 的ID数组），结果解码到 out
+func call(ctx context.Context, provider zabbix.ClientProvider, instance, method string, params interface{}, out interface{}) error {
+	lease, err := acquire(ctx, provider, instance)
+	if err != nil {
+		return err
+	}
+	var callErr error
+	defer func() { lease.Release(callErr) }()
+	callErr = lease.Client().Call(ctx, method, params, out)
+	return callErr
+}
+
+// getAll 在一次批量请求中执行多个互不依赖的 *.get：Params 为 models.ParamSpec 时按版本适配，
+// 结果解码到各自的 Result；任一条目失败时返回第一个错误
+func getAll(ctx context.Context, provider zabbix.ClientProvider, instance string, calls ...zabbix.BatchCall) error {
+	if len(calls) == 0 {
+		return nil
+	}
+	lease, err := acquire(ctx, provider, instance)
+	if err != nil {
+		return err
+	}
+	var callErr error
+	defer func() { lease.Release(callErr) }()
+	client := lease.Client()
+	for i := range calls {
+		if spec, ok := calls[i].Params.(models.ParamSpec); ok {
+			calls[i].Params = client.AdaptAPIParams(calls[i].Method, spec)
+		}
+	}
+	if callErr = client.CallBatch(ctx, calls); callErr != nil {
+		return callErr
+	}
+	for _, call := range calls {
+		if call.Err != nil {
+			callErr = call.Err
+			return callErr
+		}
+	}
+	return nil
+}
+
+// GetRecords 以 map 形式返回 *.get 的结果，用于 raw 模式以及需要按 API 字段名处理结果的场景（dry_run 对比等）
+func GetRecords(ctx context.Context, provider zabbix.ClientProvider, instance, method string, spec models.ParamSpec) ([]map[string]interface{}, error) {
+	var records []map[string]interface{}
+	if err := get(ctx, provider, instance, method, spec, &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
@@ -0,0 +1,31 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-26 10:12:40
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-26 10:12:40
+ * @FilePath: \zabbix-mcp-go\server\compat.go
+ * @Description: 版本兼容规则查询
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"zabbixMcp/zabbix"
+)
+
+// GetAPICompat 说明指定实例上哪些版本兼容规则会生效；all 为 true 时同时列出不生效的规则
+func GetAPICompat(ctx context.Context, provider zabbix.ClientProvider, instance, method string, all bool) (*zabbix.CompatReport, error) {
+	lease, err := acquire(ctx, provider, instance)
+	if err != nil {
+		return nil, err
+	}
+	report, err := lease.Client().CompatReport(method, all)
+	lease.Release(err)
+	if err != nil {
+		return nil, fmt.Errorf("获取版本兼容规则失败: %w", err)
+	}
+	return report, nil
+}
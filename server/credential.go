@@ -0,0 +1,92 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2026-01-05 15:10:27
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2026-01-05 15:10:27
+ * @FilePath: \zabbix-mcp-go\server\credential.go
+ * @Description: 密码邮件投递所需的 Zabbix 数据：邮件媒介类型的 SMTP 设置与用户的收件地址
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"zabbixMcp/credential"
+	"zabbixMcp/models"
+	"zabbixMcp/tracing"
+	"zabbixMcp/zabbix"
+)
+
+// emailMediaType mediatype.get 返回的邮件媒介 SMTP 设置
+type emailMediaType struct {
+	MediaTypeID        string     `json:"mediatypeid"`
+	Type               models.Int `json:"type"`
+	SMTPServer         string     `json:"smtp_server"`
+	SMTPPort           string     `json:"smtp_port"`
+	SMTPHelo           string     `json:"smtp_helo"`
+	SMTPEmail          string     `json:"smtp_email"`
+	SMTPSecurity       models.Int `json:"smtp_security"`
+	SMTPVerifyPeer     models.Int `json:"smtp_verify_peer"`
+	SMTPVerifyHost     models.Int `json:"smtp_verify_host"`
+	SMTPAuthentication models.Int `json:"smtp_authentication"`
+	Username           string     `json:"username"`
+}
+
+// EmailMediaLookup 按名称或ID查找每个实例中用于投递密码的邮件媒介类型，收件地址取用户在该媒介类型下已启用的媒介
+func EmailMediaLookup(provider zabbix.ClientProvider, mediaType string) credential.MediaLookup {
+	return func(ctx context.Context, instance, userID string) (*credential.SMTPSettings, []string, error) {
+		ctx, span := tracing.Start(ctx, "server.EmailMediaLookup", tracing.AttrInstance.String(instance))
+		defer span.End()
+		mediaTypeID, err := ResolveID(ctx, provider, instance, KindMediaType, "media_type", mediaType)
+		if err != nil {
+			return nil, nil, err
+		}
+		var types []emailMediaType
+		var users []models.User
+		if err := getAll(ctx, provider, instance,
+			zabbix.BatchCall{Method: "mediatype.get", Params: models.MapParams{
+				"output":       []string{"mediatypeid", "type", "smtp_server", "smtp_port", "smtp_helo", "smtp_email", "smtp_security", "smtp_verify_peer", "smtp_verify_host", "smtp_authentication", "username"},
+				"mediatypeids": []string{mediaTypeID},
+			}, Result: &types},
+			zabbix.BatchCall{Method: "user.get", Params: models.MapParams{
+				"output":       []string{"userid"},
+				"userids":      []string{userID},
+				"selectMedias": []string{"mediatypeid", "sendto", "active"},
+			}, Result: &users},
+		); err != nil {
+			return nil, nil, err
+		}
+		if len(types) == 0 {
+			return nil, nil, fmt.Errorf("媒介类型 %s 不存在", mediaType)
+		}
+		mt := types[0]
+		if mt.Type != 0 {
+			return nil, nil, fmt.Errorf("媒介类型 %s 不是邮件类型", mediaType)
+		}
+		port, _ := strconv.Atoi(mt.SMTPPort)
+		settings := &credential.SMTPSettings{
+			Server:     mt.SMTPServer,
+			Port:       port,
+			HELO:       mt.SMTPHelo,
+			From:       mt.SMTPEmail,
+			Security:   int(mt.SMTPSecurity),
+			VerifyPeer: mt.SMTPVerifyPeer == 1,
+			VerifyHost: mt.SMTPVerifyHost == 1,
+			Auth:       mt.SMTPAuthentication == 1,
+			Username:   mt.Username,
+		}
+		var recipients []string
+		for _, u := range users {
+			for _, m := range u.Medias {
+				if m.MediaTypeID == mediaTypeID && m.Active == 0 {
+					recipients = append(recipients, sendToList(m.SendTo)...)
+				}
+			}
+		}
+		return settings, recipients, nil
+	}
+}
@@ -12,29 +12,19 @@ package server
 
 import (
 	"context"
-	"fmt"
 
 	"zabbixMcp/models"
+	"zabbixMcp/tracing"
 	"zabbixMcp/zabbix"
 )
 
 // GetHosts 调用底层 ClientProvider 执行 host.get，并返回解析后的列表
-func GetHosts(ctx context.Context, provider zabbix.ClientProvider, spec models.ParamSpec) ([]map[string]interface{}, error) {
-	if provider == nil {
-		return nil, fmt.Errorf("no zabbix client")
-	}
-	lease, err := provider.Acquire(ctx)
-	if err != nil {
+func GetHosts(ctx context.Context, provider zabbix.ClientProvider, spec models.ParamSpec, instance string) ([]models.Host, error) {
+	ctx, span := tracing.Start(ctx, "server.GetHosts", tracing.AttrInstance.String(instance))
+	defer span.End()
+	var hosts []models.Host
+	if err := get(ctx, provider, instance, "host.get", spec, &hosts); err != nil {
 		return nil, err
 	}
-	var callErr error
-	defer func() { lease.Release(callErr) }()
-	client := lease.Client()
-	adapted := client.AdaptAPIParams("host.get", spec)
-	var hosts []map[string]interface{}
-	callErr = client.Call(ctx, "host.get", adapted, &hosts)
-	if callErr != nil {
-		return nil, callErr
-	}
 	return hosts, nil
 }
@@ -0,0 +1,459 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-31 09:40:26
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-31 09:40:26
+ * @FilePath: \zabbix-mcp-go\server\offboard.go
+ * @Description: 离职处理：在全部实例中按用户名、姓名或邮箱查找账号，禁用、吊销 API 令牌并移除告警媒介
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"zabbixMcp/logger"
+	"zabbixMcp/models"
+	"zabbixMcp/tracing"
+	"zabbixMcp/utils"
+	"zabbixMcp/zabbix"
+)
+
+// DisabledGroupName 内置的禁用用户组（users_status 为 1）名称，存在多个禁用组时优先使用
+const DisabledGroupName = "Disabled"
+
+// 账号的匹配方式
+const (
+	MatchByUsername = "username"
+	MatchByName     = "name"
+	MatchByEmail    = "email"
+)
+
+// 账号的处理结果
+const (
+	OffboardDone    = "done"    // 已禁用，令牌与媒介均已清除
+	OffboardPartial = "partial" // 已禁用，但吊销令牌失败
+	OffboardFailed  = "failed"  // 禁用失败，账号保持原状
+	OffboardSkipped = "skipped" // 当前连接使用的账号，不处理
+)
+
+// OffboardAccount 一个实例中匹配到的账号
+type OffboardAccount struct {
+	models.User
+	MatchedBy []string          `json:"matched_by" jsonschema_description:"username 用户名 name 姓名 email 告警媒介中的邮箱"`
+	Tokens    []models.APIToken `json:"tokens,omitempty"`
+}
+
+// OffboardTarget 一个实例中的匹配结果以及禁用后账号所属的用户组
+type OffboardTarget struct {
+	Instance          string                `json:"instance"`
+	Accounts          []OffboardAccount     `json:"accounts"`
+	TargetGroups      []models.UserGroupRef `json:"target_groups,omitempty"`
+	TokensUnsupported bool                  `json:"tokens_unsupported,omitempty"` // 5.4 之前没有 API 令牌
+	Self              string                `json:"-"`                            // 当前连接使用的用户名
+	Error             *models.Error         `json:"error,omitempty"`
+}
+
+// OffboardOutcome 一个账号的处理结果
+type OffboardOutcome struct {
+	UserID        string                `json:"userid"`
+	Username      string                `json:"username"`
+	Name          string                `json:"name,omitempty"`
+	MatchedBy     []string              `json:"matched_by"`
+	Status        string                `json:"status" jsonschema_description:"done 已完成 partial 已禁用但吊销令牌失败 failed 失败 skipped 跳过"`
+	GroupsBefore  []models.UserGroupRef `json:"groups_before"`
+	GroupsAfter   []models.UserGroupRef `json:"groups_after"`
+	PasswordReset bool                  `json:"password_reset"`
+	MediasRemoved []models.Media        `json:"medias_removed"`
+	TokensRevoked []models.APIToken     `json:"tokens_revoked"`
+	Errors        []*models.Error       `json:"errors,omitempty"`
+}
+
+// OffboardInstanceReport 一个实例的处理结果
+type OffboardInstanceReport struct {
+	Instance string            `json:"instance"`
+	Accounts []OffboardOutcome `json:"accounts"`
+	Error    *models.Error     `json:"error,omitempty"` // 查找账号失败，该实例未处理
+}
+
+// OffboardSummary 离职处理汇总
+type OffboardSummary struct {
+	Instances     int `json:"instances"`
+	Matched       int `json:"matched"`
+	Disabled      int `json:"disabled"`
+	Failed        int `json:"failed"`
+	Skipped       int `json:"skipped"`
+	TokensRevoked int `json:"tokens_revoked"`
+	MediasRemoved int `json:"medias_removed"`
+	Unreachable   int `json:"unreachable"` // 查找失败的实例数
+}
+
+// OffboardReport 离职处理报告，可直接附在合规工单中
+type OffboardReport struct {
+	Query      string                   `json:"query"`
+	StartedAt  models.Timestamp         `json:"started_at"`
+	FinishedAt models.Timestamp         `json:"finished_at"`
+	Summary    OffboardSummary          `json:"summary"`
+	Instances  []OffboardInstanceReport `json:"instances"`
+}
+
+// OffboardTargetIDs 列出将被处理的对象（实例、账号、告警媒介与 API 令牌的ID），已排序；
+// 确认执行时会重新查找一次，与预览时不同说明其间账号发生了变化
+func OffboardTargetIDs(targets []OffboardTarget) []string {
+	ids := []string{}
+	for _, t := range targets {
+		for _, a := range t.Accounts {
+			user := t.Instance + "/user:" + a.UserID
+			ids = append(ids, user)
+			for _, m := range a.Medias {
+				ids = append(ids, user+"/media:"+m.MediaID)
+			}
+			for _, token := range a.Tokens {
+				ids = append(ids, user+"/token:"+token.TokenID)
+			}
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// FindOffboardTargets 在指定实例（为空时全部实例）中查找与 query 匹配的账号：用户名、姓名（name surname）
+// 或告警媒介中的邮箱地址，不区分大小写完全一致；同时读取账号的 API 令牌与禁用时移入的用户组。
+// 单个实例失败时记录在该实例的 Error 中，不影响其他实例
+func FindOffboardTargets(ctx context.Context, provider zabbix.ClientProvider, query string, instances []string) ([]OffboardTarget, error) {
+	ctx, span := tracing.Start(ctx, "server.FindOffboardTargets")
+	defer span.End()
+	infos, err := offboardInstances(ctx, provider, instances)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]OffboardTarget, 0, len(infos))
+	for _, info := range infos {
+		target := OffboardTarget{Instance: info.Instance, Accounts: []OffboardAccount{}, Self: info.User}
+		if err := findOffboardAccounts(ctx, provider, query, &target); err != nil {
+			target.Error = zabbix.ClassifyError(err)
+			target.Error.Instance = info.Instance
+		}
+		out = append(out, target)
+	}
+	return out, nil
+}
+
+func offboardInstances(ctx context.Context, provider zabbix.ClientProvider, instances []string) ([]zabbix.ClientInfo, error) {
+	if len(instances) == 0 {
+		return GetInstancesInfo(ctx, provider, "")
+	}
+	var infos []zabbix.ClientInfo
+	for _, name := range instances {
+		found, err := GetInstancesInfo(ctx, provider, name)
+		if err != nil {
+			return nil, err
+		}
+		if len(found) == 0 {
+			return nil, models.Errorf(models.CodeInstanceNotFound, "实例 %s 不存在", name).WithHint("调用 get_instances_info 查看可用的实例名称后重试")
+		}
+		infos = append(infos, found...)
+	}
+	return infos, nil
+}
+
+func findOffboardAccounts(ctx context.Context, provider zabbix.ClientProvider, query string, target *OffboardTarget) error {
+	instance := target.Instance
+	var users []models.User
+	if err := get(ctx, provider, instance, "user.get", models.MapParams{
+		"output":        []string{"userid", "username", "name", "surname"},
+		"selectUsrgrps": []string{"usrgrpid", "name"},
+		"selectMedias":  "extend",
+	}, &users); err != nil {
+		return err
+	}
+	var ids []string
+	for _, u := range users {
+		if matched := matchAccount(u, query); len(matched) > 0 {
+			target.Accounts = append(target.Accounts, OffboardAccount{User: u, MatchedBy: matched})
+			ids = append(ids, u.UserID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	groups, err := offboardGroups(ctx, provider, instance)
+	if err != nil {
+		return err
+	}
+	target.TargetGroups = groups
+
+	var tokens []models.APIToken
+	err = get(ctx, provider, instance, "token.get", models.MapParams{
+		"output":  []string{"tokenid", "name", "userid", "status", "expires_at"},
+		"userids": ids,
+	}, &tokens)
+	switch {
+	case err != nil && zabbix.ClassifyError(err).Code == models.CodeVersionUnsupported:
+		target.TokensUnsupported = true
+	case err != nil:
+		return err
+	}
+	for i := range target.Accounts {
+		for _, t := range tokens {
+			if t.UserID == target.Accounts[i].UserID {
+				target.Accounts[i].Tokens = append(target.Accounts[i].Tokens, t)
+			}
+		}
+	}
+	return nil
+}
+
+// offboardGroups 禁用账号时移入的用户组："No access to the frontend" 禁止前端登录，
+// 再加上一个 users_status 为禁用的用户组（优先内置的 Disabled）使 API 同样无法登录
+func offboardGroups(ctx context.Context, provider zabbix.ClientProvider, instance string) ([]models.UserGroupRef, error) {
+	noAccessID, err := findNoAccessGroupID(ctx, provider, instance)
+	if err != nil {
+		return nil, err
+	}
+	groups := []models.UserGroupRef{{UsrgrpID: noAccessID, Name: NoAccessGroupName}}
+	var disabled []models.UserGroup
+	if err := get(ctx, provider, instance, "usergroup.get", models.MapParams{
+		"output": []string{"usrgrpid", "name", "users_status"},
+		"filter": map[string]interface{}{"users_status": 1},
+	}, &disabled); err != nil {
+		return nil, err
+	}
+	sort.Slice(disabled, func(i, j int) bool {
+		if (disabled[i].Name == DisabledGroupName) != (disabled[j].Name == DisabledGroupName) {
+			return disabled[i].Name == DisabledGroupName
+		}
+		return idLess(disabled[i].UsrgrpID, disabled[j].UsrgrpID)
+	})
+	for _, g := range disabled {
+		if g.UsrgrpID != noAccessID && g.UsersStatus == 1 {
+			groups = append(groups, models.UserGroupRef{UsrgrpID: g.UsrgrpID, Name: g.Name})
+			break
+		}
+	}
+	return groups, nil
+}
+
+// matchAccount 返回账号与 query 匹配的方式
+func matchAccount(u models.User, query string) []string {
+	var matched []string
+	if strings.EqualFold(u.Username, query) {
+		matched = append(matched, MatchByUsername)
+	}
+	name, surname := strings.TrimSpace(u.Name), strings.TrimSpace(u.Surname)
+	for _, full := range []string{name + " " + surname, surname + " " + name, surname + name} {
+		if full = strings.TrimSpace(full); full != "" && strings.EqualFold(full, query) {
+			matched = append(matched, MatchByName)
+			break
+		}
+	}
+	if strings.Contains(query, "@") {
+	medias:
+		for _, m := range u.Medias {
+			for _, addr := range sendToList(m.SendTo) {
+				if strings.EqualFold(strings.TrimSpace(addr), query) {
+					matched = append(matched, MatchByEmail)
+					break medias
+				}
+			}
+		}
+	}
+	return matched
+}
+
+// sendToList 媒介的收件人：邮件类媒介为字符串数组，其余为字符串
+func sendToList(v interface{}) []string {
+	switch s := v.(type) {
+	case string:
+		return []string{s}
+	case []interface{}:
+		out := make([]string, 0, len(s))
+		for _, item := range s {
+			if str, ok := item.(string); ok {
+				out = append(out, str)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// offboardUpdate 禁用账号的 user.update 参数：替换用户组、重置密码并清空媒介
+func offboardUpdate(userID string, groups []models.UserGroupRef, passwd string) models.MapParams {
+	usrgrps := make([]map[string]interface{}, len(groups))
+	for i, g := range groups {
+		usrgrps[i] = map[string]interface{}{"usrgrpid": g.UsrgrpID}
+	}
+	return models.MapParams{
+		"userid":  userID,
+		"usrgrps": usrgrps,
+		"passwd":  passwd,
+		"medias":  []interface{}{},
+	}
+}
+
+// PlanOffboard 预览离职处理：每个匹配的账号对应一次 user.update，有 API 令牌时再加一次 token.delete
+func PlanOffboard(targets []OffboardTarget) *models.MutationPlan {
+	var instances []string
+	params := map[string][]interface{}{}
+	plan := models.NewMutationPlan("", "user.update")
+	for _, t := range targets {
+		if t.Error != nil {
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("实例 %s 查找失败，不会处理: %s", t.Instance, t.Error.Message))
+			continue
+		}
+		if len(t.Accounts) == 0 {
+			continue
+		}
+		instances = append(instances, t.Instance)
+		if t.TokensUnsupported {
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("实例 %s 的版本低于 5.4，没有 API 令牌", t.Instance))
+		}
+		if len(t.TargetGroups) < 2 {
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("实例 %s 没有 users_status 为禁用的用户组，账号只会被禁止前端登录，仍可调用 API", t.Instance))
+		}
+		for _, a := range t.Accounts {
+			objectID := t.Instance + "/" + a.UserID
+			plan.Affected = append(plan.Affected, map[string]interface{}{
+				"instance":   t.Instance,
+				"userid":     a.UserID,
+				"username":   a.Username,
+				"name":       strings.TrimSpace(a.Name + " " + a.Surname),
+				"matched_by": a.MatchedBy,
+				"usrgrps":    a.Usrgrps,
+				"medias":     a.Medias,
+				"tokens":     a.Tokens,
+			})
+			if a.Username == t.Self {
+				plan.Warnings = append(plan.Warnings, fmt.Sprintf("%s 是实例 %s 当前连接使用的账号，不会处理", a.Username, t.Instance))
+				continue
+			}
+			params[t.Instance] = append(params[t.Instance], offboardUpdate(a.UserID, t.TargetGroups, models.MaskedValue))
+			plan.Changes = append(plan.Changes,
+				models.FieldChange{ObjectID: objectID, Field: "usrgrps", Old: groupNames(a.Usrgrps), New: groupNames(t.TargetGroups)},
+				models.FieldChange{ObjectID: objectID, Field: "passwd", Old: models.MaskedValue, New: models.MaskedValue},
+			)
+			if len(a.Medias) > 0 {
+				plan.Changes = append(plan.Changes, models.FieldChange{ObjectID: objectID, Field: "medias", Old: len(a.Medias), New: 0})
+			}
+			if len(a.Tokens) > 0 {
+				tokenIDs := make([]string, len(a.Tokens))
+				for i, tok := range a.Tokens {
+					tokenIDs[i] = tok.TokenID
+				}
+				params[t.Instance] = append(params[t.Instance], map[string]interface{}{"method": "token.delete", "params": tokenIDs})
+				plan.Changes = append(plan.Changes, models.FieldChange{ObjectID: objectID, Field: "tokens", Old: tokenIDs, New: nil})
+			}
+		}
+	}
+	plan.Instance = strings.Join(instances, ",")
+	plan.Params = params
+	return plan
+}
+
+func groupNames(groups []models.UserGroupRef) []string {
+	names := make([]string, len(groups))
+	for i, g := range groups {
+		names[i] = g.Name
+		if names[i] == "" {
+			names[i] = g.UsrgrpID
+		}
+	}
+	return names
+}
+
+// Offboard 按 FindOffboardTargets 的结果逐个禁用账号：移入禁用用户组、重置密码（不回传）、清空告警媒介，
+// 再删除其 API 令牌。单个账号失败不影响其他账号，结果记录在报告中
+func Offboard(ctx context.Context, provider zabbix.ClientProvider, query string, targets []OffboardTarget) *OffboardReport {
+	ctx, span := tracing.Start(ctx, "server.Offboard")
+	defer span.End()
+	report := &OffboardReport{Query: query, StartedAt: models.Timestamp(time.Now().Unix()), Instances: []OffboardInstanceReport{}}
+	for _, t := range targets {
+		ir := OffboardInstanceReport{Instance: t.Instance, Accounts: []OffboardOutcome{}, Error: t.Error}
+		report.Summary.Instances++
+		if t.Error != nil {
+			report.Summary.Unreachable++
+		}
+		for _, a := range t.Accounts {
+			outcome := offboardAccount(ctx, provider, t, a)
+			report.Summary.Matched++
+			switch outcome.Status {
+			case OffboardDone, OffboardPartial:
+				report.Summary.Disabled++
+			case OffboardFailed:
+				report.Summary.Failed++
+			case OffboardSkipped:
+				report.Summary.Skipped++
+			}
+			report.Summary.TokensRevoked += len(outcome.TokensRevoked)
+			report.Summary.MediasRemoved += len(outcome.MediasRemoved)
+			ir.Accounts = append(ir.Accounts, outcome)
+		}
+		report.Instances = append(report.Instances, ir)
+	}
+	report.FinishedAt = models.Timestamp(time.Now().Unix())
+	return report
+}
+
+func offboardAccount(ctx context.Context, provider zabbix.ClientProvider, t OffboardTarget, a OffboardAccount) OffboardOutcome {
+	out := OffboardOutcome{
+		UserID:        a.UserID,
+		Username:      a.Username,
+		Name:          strings.TrimSpace(a.Name + " " + a.Surname),
+		MatchedBy:     a.MatchedBy,
+		GroupsBefore:  a.Usrgrps,
+		GroupsAfter:   a.Usrgrps,
+		MediasRemoved: []models.Media{},
+		TokensRevoked: []models.APIToken{},
+	}
+	fail := func(err error) {
+		e := zabbix.ClassifyError(err)
+		e.Instance = t.Instance
+		out.Errors = append(out.Errors, e)
+	}
+	if a.Username == t.Self {
+		out.Status = OffboardSkipped
+		fail(models.Errorf(models.CodePermissionDenied, "%s 是当前连接使用的账号，不能禁用", a.Username).
+			WithHint("在 Zabbix 前端手动禁用，或改用其他账号连接该实例后重试"))
+		return out
+	}
+	passwd, err := utils.GenerateSecurePassword(16) // 离职账号的密码无需回传
+	if err != nil {
+		out.Status = OffboardFailed
+		fail(fmt.Errorf("生成密码失败: %w", err))
+		return out
+	}
+	if _, err := UpdateUser(ctx, provider, offboardUpdate(a.UserID, t.TargetGroups, passwd), t.Instance); err != nil {
+		out.Status = OffboardFailed
+		fail(err)
+		return out
+	}
+	logger.L().Infof("离职处理: 已禁用实例 %s 的用户 %s(%s)", t.Instance, a.Username, a.UserID)
+	out.GroupsAfter = t.TargetGroups
+	out.PasswordReset = true
+	if a.Medias != nil {
+		out.MediasRemoved = a.Medias
+	}
+	out.Status = OffboardDone
+	if len(a.Tokens) == 0 {
+		return out
+	}
+	ids := make([]string, len(a.Tokens))
+	for i, tok := range a.Tokens {
+		ids[i] = tok.TokenID
+	}
+	var deleted map[string]interface{}
+	if err := call(ctx, provider, t.Instance, "token.delete", ids, &deleted); err != nil {
+		out.Status = OffboardPartial
+		fail(err)
+		return out
+	}
+	out.TokensRevoked = a.Tokens
+	return out
+}
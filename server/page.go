@@ -0,0 +1,258 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-28 09:52:31
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-28 09:52:31
+ * @FilePath: \zabbix-mcp-go\server\page.go
+ * @Description: *.get 按排序后的 ID 区间分页与 countOutput 计数
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+
+	"zabbixMcp/models"
+	"zabbixMcp/tracing"
+	"zabbixMcp/zabbix"
+)
+
+// pagedMethod 支持分页的方法：对象ID字段与按ID过滤的参数名
+type pagedMethod struct {
+	idField  string
+	idsParam string
+}
+
+var pagedMethods = map[string]pagedMethod{
+	"user.get":      {"userid", "userids"},
+	"usergroup.get": {"usrgrpid", "usrgrpids"},
+	"host.get":      {"hostid", "hostids"},
+	"hostgroup.get": {"groupid", "groupids"},
+	"template.get":  {"templateid", "templateids"},
+	"item.get":      {"itemid", "itemids"},
+	"trigger.get":   {"triggerid", "triggerids"},
+	"problem.get":   {"eventid", "eventids"},
+	"event.get":     {"eventid", "eventids"},
+	"mediatype.get": {"mediatypeid", "mediatypeids"},
+	"role.get":      {"roleid", "roleids"},
+}
+
+// pageCursor 游标内容：上一页最后一个ID，以及查询条件摘要（防止换了条件继续翻页）
+type pageCursor struct {
+	After string `json:"a"`
+	Query string `json:"q"`
+}
+
+// GetPage 分页执行 *.get。Zabbix 没有 offset，先只取匹配对象的ID并按数值排序，
+// 再按本页的ID区间取完整对象；游标记录上一页最后一个ID，新增或删除对象不会导致重复或遗漏。
+// page.Limit 由调用方按分页配置确定，必须大于 0
+func GetPage[T any](ctx context.Context, provider zabbix.ClientProvider, instance, method string, spec models.ParamSpec, page models.PageRequest) ([]T, *models.PageInfo, error) {
+	ctx, span := tracing.Start(ctx, "server.GetPage",
+		tracing.AttrInstance.String(instance),
+		tracing.AttrMethod.String(method),
+	)
+	defer span.End()
+	pm, ok := pagedMethods[method]
+	if !ok {
+		return nil, nil, fmt.Errorf("方法 %s 不支持分页", method)
+	}
+	limit := page.Limit
+	if limit <= 0 {
+		return nil, nil, fmt.Errorf("%s 分页条数必须大于 0，实际为 %d", method, limit)
+	}
+
+	lease, err := acquire(ctx, provider, instance)
+	if err != nil {
+		return nil, nil, err
+	}
+	var callErr error
+	defer func() { lease.Release(callErr) }()
+	client := lease.Client()
+	params := client.AdaptAPIParams(method, spec)
+	delete(params, "limit")
+	digest := queryDigest(method, params)
+
+	after := ""
+	if page.Cursor != "" {
+		c, err := decodeCursor(page.Cursor)
+		if err != nil {
+			return nil, nil, err
+		}
+		if c.Query != digest {
+			return nil, nil, models.ArgsError{{Arg: "cursor", Problem: "与本次查询条件不匹配，翻页时其余参数必须与上一页相同"}}
+		}
+		after = c.After
+	}
+
+	// 第一步：只取ID，负载很小
+	scan := listParams(params)
+	scan["output"] = []string{pm.idField}
+	var idRecords []map[string]interface{}
+	if callErr = client.Call(ctx, method, scan, &idRecords); callErr != nil {
+		return nil, nil, callErr
+	}
+	ids := sortedIDs(idRecords, pm.idField)
+	start := 0
+	if after != "" {
+		start = sort.Search(len(ids), func(i int) bool { return idLess(after, ids[i]) })
+	}
+	end := start + limit
+	if end > len(ids) {
+		end = len(ids)
+	}
+	info := &models.PageInfo{Total: int64(len(ids)), Limit: limit, Query: digest}
+	if start >= end {
+		return []T{}, info, nil
+	}
+	pageIDs := ids[start:end]
+	if end < len(ids) {
+		info.HasMore = true
+		info.NextCursor = encodeCursor(pageCursor{After: pageIDs[len(pageIDs)-1], Query: digest})
+	}
+
+	// 第二步：按本页ID取完整对象，原有的过滤条件保留
+	detail := make(map[string]interface{}, len(params)+1)
+	for k, v := range params {
+		detail[k] = v
+	}
+	detail[pm.idsParam] = pageIDs
+	var records []json.RawMessage
+	if callErr = client.Call(ctx, method, detail, &records); callErr != nil {
+		return nil, nil, callErr
+	}
+	out, err := decodeSorted[T](records, pm.idField)
+	if err != nil {
+		return nil, nil, err
+	}
+	info.Returned = len(out)
+	return out, info, nil
+}
+
+// ResumeCursor 从 lastID 之后继续翻页的游标，用于本页结果在输出时被截断的情况
+func ResumeCursor(info *models.PageInfo, lastID string) string {
+	return encodeCursor(pageCursor{After: lastID, Query: info.Query})
+}
+
+// PageIDField 分页方法的对象ID字段
+func PageIDField(method string) string {
+	return pagedMethods[method].idField
+}
+
+// Count 使用 countOutput 返回匹配的对象数量
+func Count(ctx context.Context, provider zabbix.ClientProvider, instance, method string, spec models.ParamSpec) (int64, error) {
+	ctx, span := tracing.Start(ctx, "server.Count",
+		tracing.AttrInstance.String(instance),
+		tracing.AttrMethod.String(method),
+	)
+	defer span.End()
+	lease, err := acquire(ctx, provider, instance)
+	if err != nil {
+		return 0, err
+	}
+	var callErr error
+	defer func() { lease.Release(callErr) }()
+	client := lease.Client()
+	params := listParams(client.AdaptAPIParams(method, spec))
+	params["countOutput"] = true
+	var count models.Int
+	if callErr = client.Call(ctx, method, params, &count); callErr != nil {
+		return 0, callErr
+	}
+	return int64(count), nil
+}
+
+// listParams 复制查询条件，去掉输出、子查询、排序与条数限制，用于只取ID或计数
+func listParams(params map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(params))
+	for k, v := range params {
+		switch {
+		case k == "output", k == "limit", k == "sortfield", k == "sortorder", k == "preservekeys", strings.HasPrefix(k, "select"):
+			continue
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// sortedIDs 提取去重后的ID并按数值升序排列
+func sortedIDs(records []map[string]interface{}, idField string) []string {
+	seen := make(map[string]bool, len(records))
+	ids := make([]string, 0, len(records))
+	for _, r := range records {
+		id := fmt.Sprint(r[idField])
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return idLess(ids[i], ids[j]) })
+	return ids
+}
+
+// decodeSorted 按ID升序解码本页对象（*.get 不保证按ID返回）
+func decodeSorted[T any](records []json.RawMessage, idField string) ([]T, error) {
+	type keyed struct {
+		id  string
+		raw json.RawMessage
+	}
+	items := make([]keyed, 0, len(records))
+	for _, raw := range records {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &fields); err != nil {
+			return nil, err
+		}
+		var id string
+		_ = json.Unmarshal(fields[idField], &id)
+		items = append(items, keyed{id: id, raw: raw})
+	}
+	sort.SliceStable(items, func(i, j int) bool { return idLess(items[i].id, items[j].id) })
+	out := make([]T, len(items))
+	for i, item := range items {
+		if err := json.Unmarshal(item.raw, &out[i]); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// idLess 按数值比较ID，无法解析为数字时按字符串比较
+func idLess(a, b string) bool {
+	x, errA := strconv.ParseUint(a, 10, 64)
+	y, errB := strconv.ParseUint(b, 10, 64)
+	if errA != nil || errB != nil {
+		return a < b
+	}
+	return x < y
+}
+
+// queryDigest 查询条件摘要；map 序列化时按键排序，结果稳定
+func queryDigest(method string, params map[string]interface{}) string {
+	data, _ := json.Marshal(params)
+	h := fnv.New64a()
+	h.Write([]byte(method))
+	h.Write(data)
+	return strconv.FormatUint(h.Sum64(), 36)
+}
+
+func encodeCursor(c pageCursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string) (pageCursor, error) {
+	var c pageCursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || json.Unmarshal(data, &c) != nil || c.After == "" {
+		return c, models.ArgsError{{Arg: "cursor", Problem: "不是有效的分页游标，请原样传入上一页结果中的 page.next_cursor"}}
+	}
+	return c, nil
+}
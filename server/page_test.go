@@ -0,0 +1,176 @@
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"zabbixMcp/models"
+	"zabbixMcp/zabbix"
+	"zabbixMcp/zabbix/zabbixtest"
+)
+
+// newPageServer 返回一个包含 ID 跨越位数（9、10、99、100、1000）主机的模拟服务器，
+// 按字符串排序与按数值排序的结果不同
+func newPageServer(t *testing.T, version string) (*zabbixtest.Server, zabbix.ClientProvider, string) {
+	t.Helper()
+	srv := zabbixtest.NewServer(zabbixtest.Options{Version: version})
+	t.Cleanup(srv.Close)
+	store := srv.Store()
+	group := store.AddHostGroup(zabbixtest.HostGroup{Name: "Paged"})
+	other := store.AddHostGroup(zabbixtest.HostGroup{Name: "Other"})
+	for _, id := range []string{"1000", "99", "9", "100", "10"} {
+		store.AddHost(zabbixtest.Host{ID: id, Host: "host-" + id, Name: "host-" + id, Status: "0", GroupIDs: []string{group}})
+	}
+	store.AddHost(zabbixtest.Host{ID: "50", Host: "other-50", Name: "other-50", Status: "0", GroupIDs: []string{other}})
+	provider, err := zabbixtest.NewProvider(srv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { provider.Close() })
+	return srv, provider, group
+}
+
+func hostIDs(hosts []map[string]interface{}) []string {
+	ids := make([]string, len(hosts))
+	for i, h := range hosts {
+		ids[i], _ = h["hostid"].(string)
+	}
+	return ids
+}
+
+// TestGetPageWalksAllPages 按数值顺序翻页，跨越 ID 位数时不重复不遗漏，最后一页没有游标
+func TestGetPageWalksAllPages(t *testing.T) {
+	for _, v := range []string{"5.0.0", "6.0.0", "7.0.0"} {
+		t.Run(v, func(t *testing.T) {
+			_, provider, group := newPageServer(t, v)
+			ctx := context.Background()
+			spec := models.HostGetParams{GroupIDs: []string{group}, Output: "extend"}
+
+			var pages [][]string
+			cursor := ""
+			for i := 0; i < 5; i++ {
+				hosts, info, err := GetPage[map[string]interface{}](ctx, provider, "zabbixtest", "host.get", spec, models.PageRequest{Limit: 2, Cursor: cursor})
+				if err != nil {
+					t.Fatal(err)
+				}
+				pages = append(pages, hostIDs(hosts))
+				if info.Total != 5 || info.Limit != 2 || info.Returned != len(hosts) {
+					t.Errorf("第 %d 页分页信息 = %+v", i+1, info)
+				}
+				if !info.HasMore {
+					if info.NextCursor != "" {
+						t.Errorf("最后一页不应返回游标: %+v", info)
+					}
+					break
+				}
+				cursor = info.NextCursor
+			}
+			if want := [][]string{{"9", "10"}, {"99", "100"}, {"1000"}}; !reflect.DeepEqual(pages, want) {
+				t.Errorf("分页结果 = %v，期望 %v", pages, want)
+			}
+		})
+	}
+}
+
+// TestGetPageCursor 游标记录上一页最后一个 ID，之后新增的较小 ID 不会导致重复；游标与查询条件绑定
+func TestGetPageCursor(t *testing.T) {
+	srv, provider, group := newPageServer(t, "6.0.0")
+	ctx := context.Background()
+	spec := models.HostGetParams{GroupIDs: []string{group}}
+
+	first, info, err := GetPage[map[string]interface{}](ctx, provider, "zabbixtest", "host.get", spec, models.PageRequest{Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := hostIDs(first); !reflect.DeepEqual(got, []string{"9", "10"}) {
+		t.Fatalf("第一页 = %v", got)
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(info.NextCursor)
+	if err != nil {
+		t.Fatalf("游标应为 base64url: %v", err)
+	}
+	var c pageCursor
+	if err := json.Unmarshal(raw, &c); err != nil || c.After != "10" || c.Query != info.Query {
+		t.Fatalf("游标内容 = %s", raw)
+	}
+
+	srv.Store().AddHost(zabbixtest.Host{ID: "5", Host: "host-5", Name: "host-5", Status: "0", GroupIDs: []string{group}})
+	second, info, err := GetPage[map[string]interface{}](ctx, provider, "zabbixtest", "host.get", spec, models.PageRequest{Limit: 2, Cursor: info.NextCursor})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := hostIDs(second); !reflect.DeepEqual(got, []string{"99", "100"}) || info.Total != 6 {
+		t.Errorf("新增对象后第二页 = %v (total %d)，期望 [99 100]", got, info.Total)
+	}
+
+	// 换了查询条件的游标与无效游标都返回参数错误
+	other := models.HostGetParams{GroupIDs: []string{group}, Search: map[string]interface{}{"name": "host"}}
+	for name, cursor := range map[string]string{"条件不同": info.NextCursor, "无效游标": "not-a-cursor", "缺少ID": encodeCursor(pageCursor{Query: info.Query})} {
+		_, _, err := GetPage[map[string]interface{}](ctx, provider, "zabbixtest", "host.get", other, models.PageRequest{Limit: 2, Cursor: cursor})
+		var ae models.ArgsError
+		if !errors.As(err, &ae) || ae[0].Arg != "cursor" {
+			t.Errorf("%s: err = %v，期望 cursor 参数错误", name, err)
+		}
+	}
+}
+
+// TestGetPageLastPage 游标之后没有对象时返回空页
+func TestGetPageLastPage(t *testing.T) {
+	_, provider, group := newPageServer(t, "7.0.0")
+	ctx := context.Background()
+	spec := models.HostGetParams{GroupIDs: []string{group}}
+
+	hosts, info, err := GetPage[map[string]interface{}](ctx, provider, "zabbixtest", "host.get", spec, models.PageRequest{Limit: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hosts) != 5 || info.HasMore || info.NextCursor != "" {
+		t.Fatalf("恰好一页时 = %v %+v，期望没有下一页", hostIDs(hosts), info)
+	}
+
+	hosts, info, err = GetPage[map[string]interface{}](ctx, provider, "zabbixtest", "host.get", spec,
+		models.PageRequest{Limit: 5, Cursor: ResumeCursor(info, "1000")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hosts == nil || len(hosts) != 0 || info.Returned != 0 || info.HasMore || info.Total != 5 {
+		t.Errorf("最后一个对象之后 = %v %+v，期望空页", hosts, info)
+	}
+
+	if _, _, err := GetPage[map[string]interface{}](ctx, provider, "zabbixtest", "host.get", spec, models.PageRequest{}); err == nil {
+		t.Error("未指定每页条数应返回错误")
+	}
+	if _, _, err := GetPage[map[string]interface{}](ctx, provider, "zabbixtest", "script.get", spec, models.PageRequest{Limit: 5}); err == nil {
+		t.Error("不支持分页的方法应返回错误")
+	}
+}
+
+// TestCount count_only 使用 countOutput，保留过滤条件
+func TestCount(t *testing.T) {
+	for _, v := range []string{"5.0.0", "7.0.0"} {
+		t.Run(v, func(t *testing.T) {
+			srv, provider, group := newPageServer(t, v)
+			srv.ResetCalls()
+			ctx := context.Background()
+			total, err := Count(ctx, provider, "zabbixtest", "host.get", models.HostGetParams{GroupIDs: []string{group}, Output: "extend"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if total != 5 {
+				t.Errorf("count = %d，期望 5", total)
+			}
+			calls := srv.Calls()
+			var params map[string]interface{}
+			if len(calls) != 1 || json.Unmarshal(calls[0].Params, &params) != nil {
+				t.Fatalf("服务器收到的调用 = %v", srv.Methods())
+			}
+			if params["countOutput"] != true || params["output"] != nil || params["groupids"] == nil {
+				t.Errorf("host.get 参数 = %v，期望 countOutput 且保留 groupids", params)
+			}
+		})
+	}
+}
@@ -0,0 +1,262 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-23 10:35:02
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-23 10:35:02
+ * @FilePath: \zabbix-mcp-go\server\plan.go
+ * @Description: 变更操作预览（dry_run），只读取当前状态不执行变更
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"zabbixMcp/models"
+	"zabbixMcp/tracing"
+	"zabbixMcp/zabbix"
+)
+
+// secretParams 预览时需要隐藏取值的参数；currentpasswd 在 6.4+ 适配后为 current_passwd
+var secretParams = []string{"passwd", "currentpasswd", "current_passwd"}
+
+// PlanCreateUser 预览 user.create：展示适配后的参数、所属用户组及同名用户冲突
+func PlanCreateUser(ctx context.Context, provider zabbix.ClientProvider, spec models.UserParams, instance string) (*models.MutationPlan, error) {
+	ctx, span := tracing.Start(ctx, "server.PlanCreateUser", tracing.AttrInstance.String(instance))
+	defer span.End()
+	plan := models.NewMutationPlan(instance, "user.create")
+	// 同名用户与目标用户组互不依赖，合并为一次批量请求
+	var existing []map[string]interface{}
+	var groups []models.UserGroup
+	var calls []zabbix.BatchCall
+	if spec.UserName != "" {
+		calls = append(calls, zabbix.BatchCall{Method: "user.get", Params: models.UserParams{
+			Output: "extend",
+			Alias:  spec.UserName,
+			Filter: map[string]interface{}{"username": spec.UserName},
+		}, Result: &existing})
+	}
+	if spec.UserGroup != "" {
+		calls = append(calls, zabbix.BatchCall{Method: "usergroup.get", Params: userGroupNamesSpec([]string{spec.UserGroup}), Result: &groups})
+	}
+	if err := getAll(ctx, provider, instance, calls...); err != nil {
+		return nil, err
+	}
+	if len(existing) > 0 {
+		plan.Affected = existing
+		plan.Warnings = append(plan.Warnings, fmt.Sprintf("用户名 %s 已存在，user.create 将会失败", spec.UserName))
+	}
+	if spec.UserGroup != "" {
+		recordUserGroupNames(groups, []string{spec.UserGroup}, plan)
+	}
+	params, err := adaptParams(ctx, provider, instance, "user.create", spec)
+	if err != nil {
+		return nil, err
+	}
+	maskSecrets(params)
+	plan.Params = params
+	plan.Changes = diffFields("", nil, params)
+	return plan, nil
+}
+
+// PlanUpdateUser 预览 user.update：读取用户当前状态并逐字段对比新旧值
+func PlanUpdateUser(ctx context.Context, provider zabbix.ClientProvider, spec models.UserParams, instance string) (*models.MutationPlan, error) {
+	ctx, span := tracing.Start(ctx, "server.PlanUpdateUser", tracing.AttrInstance.String(instance))
+	defer span.End()
+	if spec.Userid == "" {
+		return nil, models.ArgsError{{Arg: "userid", Problem: "为必填参数"}}
+	}
+	plan := models.NewMutationPlan(instance, "user.update")
+	// 当前用户与目标用户组互不依赖，合并为一次批量请求
+	var current []map[string]interface{}
+	var groups []models.UserGroup
+	calls := []zabbix.BatchCall{{Method: "user.get", Params: usersWithGroupsSpec([]string{spec.Userid}), Result: &current}}
+	if len(spec.Usrgrps) > 0 {
+		calls = append(calls, zabbix.BatchCall{Method: "usergroup.get", Params: userGroupNamesSpec(spec.Usrgrps), Result: &groups})
+	}
+	if err := getAll(ctx, provider, instance, calls...); err != nil {
+		return nil, err
+	}
+	if len(current) == 0 {
+		return nil, models.Errorf(models.CodeNotFound, "用户 %s 不存在", spec.Userid).
+			WithHint("先调用 get_users 按用户名查找正确的 userid")
+	}
+	if len(spec.Usrgrps) > 0 {
+		recordUserGroupNames(groups, spec.Usrgrps, plan)
+	}
+	params, err := adaptParams(ctx, provider, instance, "user.update", spec)
+	if err != nil {
+		return nil, err
+	}
+	maskSecrets(params)
+	plan.Params = params
+	plan.Affected = current
+	plan.Changes = diffFields(spec.Userid, current[0], params)
+	return plan, nil
+}
+
+// PlanDisableUser 预览禁用用户：解析 No access to the frontend 用户组后按 user.update 预览
+func PlanDisableUser(ctx context.Context, provider zabbix.ClientProvider, userId, instance string) (*models.MutationPlan, error) {
+	ctx, span := tracing.Start(ctx, "server.PlanDisableUser", tracing.AttrInstance.String(instance))
+	defer span.End()
+	spec, err := buildDisableUserSpec(ctx, provider, userId, instance)
+	if err != nil {
+		return nil, err
+	}
+	spec.Passwd = models.MaskedValue
+	return PlanUpdateUser(ctx, provider, spec, instance)
+}
+
+// PlanDeleteUsers 预览 user.delete：列出将被删除的用户及其当前用户组
+func PlanDeleteUsers(ctx context.Context, provider zabbix.ClientProvider, spec models.ParamSpec, instance string) (*models.MutationPlan, error) {
+	ctx, span := tracing.Start(ctx, "server.PlanDeleteUsers", tracing.AttrInstance.String(instance))
+	defer span.End()
+	deleteIDs := spec.BuildDeleteParams()
+	if len(deleteIDs) == 0 {
+		return nil, models.ArgsError{{Arg: "userids", Problem: "至少需要一个用户ID"}}
+	}
+	plan := models.NewMutationPlan(instance, "user.delete")
+	plan.Params = deleteIDs
+	current, err := getUsersWithGroups(ctx, provider, deleteIDs, instance)
+	if err != nil {
+		return nil, err
+	}
+	plan.Affected = current
+	found := make(map[string]bool, len(current))
+	for _, u := range current {
+		id, _ := u["userid"].(string)
+		found[id] = true
+		plan.Changes = append(plan.Changes, models.FieldChange{
+			ObjectID: id,
+			Field:    "user",
+			Old:      userDisplayName(u),
+			New:      nil,
+		})
+	}
+	for _, id := range deleteIDs {
+		if !found[id] {
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("用户 %s 不存在，user.delete 将会失败", id))
+		}
+	}
+	return plan, nil
+}
+
+// getUsersWithGroups 按 ID 读取用户及其当前所属用户组；保留 API 字段名以便与参数逐字段对比
+func getUsersWithGroups(ctx context.Context, provider zabbix.ClientProvider, ids []string, instance string) ([]map[string]interface{}, error) {
+	return GetRecords(ctx, provider, instance, "user.get", usersWithGroupsSpec(ids))
+}
+
+func usersWithGroupsSpec(ids []string) models.UserParams {
+	return models.UserParams{
+		UserIDs:       ids,
+		Output:        "extend",
+		SelectUsrgrps: []string{"usrgrpid", "name"},
+	}
+}
+
+func userGroupNamesSpec(ids []string) models.MapParams {
+	return models.MapParams{
+		"output":    []string{"usrgrpid", "name"},
+		"usrgrpids": ids,
+	}
+}
+
+// recordUserGroupNames 将用户组名称记录到 plan.Resolved，不存在的ID记为警告
+func recordUserGroupNames(groups []models.UserGroup, ids []string, plan *models.MutationPlan) {
+	found := make(map[string]bool, len(groups))
+	for _, g := range groups {
+		found[g.UsrgrpID] = true
+		plan.Resolved["usergroup:"+g.Name] = g.UsrgrpID
+	}
+	for _, id := range ids {
+		if !found[id] {
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("用户组 %s 不存在", id))
+		}
+	}
+}
+
+// diffFields 对比当前对象与将要提交的参数，返回取值发生变化的字段
+func diffFields(objectID string, current map[string]interface{}, params map[string]interface{}) []models.FieldChange {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	changes := []models.FieldChange{}
+	for _, k := range keys {
+		if k == "userid" {
+			continue
+		}
+		newVal := params[k]
+		var oldVal interface{}
+		if current != nil {
+			oldVal = current[k]
+		}
+		if k == "usrgrps" {
+			oldVal, newVal = groupIDs(oldVal), groupIDs(newVal)
+		}
+		if isSecretParam(k) {
+			oldVal = nil
+		}
+		if current != nil && !isSecretParam(k) && fmt.Sprint(oldVal) == fmt.Sprint(newVal) {
+			continue
+		}
+		changes = append(changes, models.FieldChange{ObjectID: objectID, Field: k, Old: oldVal, New: newVal})
+	}
+	return changes
+}
+
+// groupIDs 将 user.get 返回的或 BuildParams 生成的 usrgrps 统一转换为有序的ID列表
+func groupIDs(v interface{}) []string {
+	ids := []string{}
+	switch groups := v.(type) {
+	case []map[string]interface{}:
+		for _, g := range groups {
+			if id, ok := g["usrgrpid"].(string); ok {
+				ids = append(ids, id)
+			}
+		}
+	case []interface{}:
+		for _, item := range groups {
+			if g, ok := item.(map[string]interface{}); ok {
+				if id, ok := g["usrgrpid"].(string); ok {
+					ids = append(ids, id)
+				}
+			}
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func maskSecrets(params map[string]interface{}) {
+	for _, k := range secretParams {
+		if _, ok := params[k]; ok {
+			params[k] = models.MaskedValue
+		}
+	}
+}
+
+func isSecretParam(key string) bool {
+	for _, k := range secretParams {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// userDisplayName 兼容 5.x(alias) 与 6.x+(username)
+func userDisplayName(u map[string]interface{}) string {
+	if v, ok := u["username"].(string); ok && v != "" {
+		return v
+	}
+	if v, ok := u["alias"].(string); ok {
+		return v
+	}
+	return ""
+}
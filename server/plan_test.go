@@ -0,0 +1,135 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"zabbixMcp/models"
+	"zabbixMcp/zabbix/zabbixtest"
+)
+
+func TestDiffFieldsOnlyChanged(t *testing.T) {
+	current := map[string]interface{}{
+		"userid":  "42",
+		"name":    "Li",
+		"surname": "Si",
+		"roleid":  "1",
+		"usrgrps": []interface{}{
+			map[string]interface{}{"usrgrpid": "8", "name": "Guests"},
+			map[string]interface{}{"usrgrpid": "7", "name": "Zabbix administrators"},
+		},
+	}
+	params := map[string]interface{}{
+		"userid":  "42",
+		"name":    "Li",
+		"surname": "Xi",
+		"roleid":  "1",
+		// 顺序不同但集合相同的用户组不算变化
+		"usrgrps": []map[string]interface{}{{"usrgrpid": "7"}, {"usrgrpid": "8"}},
+		"passwd":  models.MaskedValue,
+	}
+	changes := diffFields("42", current, params)
+	var fields []string
+	for _, c := range changes {
+		fields = append(fields, c.Field)
+		if c.ObjectID != "42" {
+			t.Errorf("%s: objectid = %q", c.Field, c.ObjectID)
+		}
+	}
+	if want := []string{"passwd", "surname"}; !reflect.DeepEqual(fields, want) {
+		t.Fatalf("变化字段 = %v，期望 %v", fields, want)
+	}
+	if changes[0].Old != nil || changes[0].New != models.MaskedValue {
+		t.Errorf("passwd 变化 = %+v，旧值应为空、新值应为掩码", changes[0])
+	}
+	if changes[1].Old != "Si" || changes[1].New != "Xi" {
+		t.Errorf("surname 变化 = %+v", changes[1])
+	}
+
+	// 新建对象时列出全部字段（userid 除外）
+	if got := diffFields("", nil, map[string]interface{}{"username": "lisi", "name": "Li"}); len(got) != 2 {
+		t.Errorf("新建对象的变化 = %+v，期望 2 个字段", got)
+	}
+}
+
+func TestMaskSecrets(t *testing.T) {
+	params := map[string]interface{}{"passwd": "S3cret", "currentpasswd": "0ld", "current_passwd": "0ld", "name": "Li"}
+	maskSecrets(params)
+	want := map[string]interface{}{"passwd": models.MaskedValue, "currentpasswd": models.MaskedValue, "current_passwd": models.MaskedValue, "name": "Li"}
+	if !reflect.DeepEqual(params, want) {
+		t.Errorf("maskSecrets = %v，期望 %v", params, want)
+	}
+}
+
+// TestPlanUserMasksSecrets dry_run 结果中不能出现明文密码，且只列出实际变化的字段
+func TestPlanUserMasksSecrets(t *testing.T) {
+	for _, c := range []struct {
+		version string
+		fields  []string // 6.4 之前没有当前密码参数，6.4 起改名为 current_passwd
+	}{
+		{"5.0.0", []string{"passwd", "surname"}},
+		{"6.0.0", []string{"passwd", "surname"}},
+		{"7.0.0", []string{"current_passwd", "passwd", "surname"}},
+	} {
+		v := c.version
+		t.Run(v, func(t *testing.T) {
+			srv := zabbixtest.NewServer(zabbixtest.Options{Version: v})
+			defer srv.Close()
+			userID := srv.Store().AddUser(zabbixtest.User{
+				Username: "lisi", Name: "Li", Surname: "Si", RoleID: "1", Type: "1", GroupIDs: []string{"8"},
+			})
+			provider, err := zabbixtest.NewProvider(srv)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer provider.Close()
+			ctx := context.Background()
+
+			update, err := PlanUpdateUser(ctx, provider, models.UserParams{
+				Userid: userID, Name: "Li", Surname: "Xi", Usrgrps: []string{"8"},
+				Passwd: "N3w-secret", CurrentPasswd: "0ld-secret",
+			}, "zabbixtest")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var fields []string
+			for _, ch := range update.Changes {
+				fields = append(fields, ch.Field)
+			}
+			if !reflect.DeepEqual(fields, c.fields) {
+				t.Errorf("user.update 变化字段 = %v，期望 %v", fields, c.fields)
+			}
+
+			create, err := PlanCreateUser(ctx, provider, models.UserParams{
+				UserName: "wangwu", Passwd: "N3w-secret", UserGroup: "8", Roleid: "1",
+			}, "zabbixtest")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for method, plan := range map[string]*models.MutationPlan{"user.update": update, "user.create": create} {
+				params, _ := plan.Params.(map[string]interface{})
+				for _, k := range secretParams {
+					if v, ok := params[k]; ok && v != models.MaskedValue {
+						t.Errorf("%s: %s = %v，期望掩码", method, k, v)
+					}
+				}
+				if params["passwd"] != models.MaskedValue {
+					t.Errorf("%s: passwd = %v，期望掩码", method, params["passwd"])
+				}
+				raw, err := json.Marshal(plan)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, secret := range []string{"N3w-secret", "0ld-secret"} {
+					if strings.Contains(string(raw), secret) {
+						t.Errorf("%s 预览中出现明文密码 %q: %s", method, secret, raw)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,240 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-30 10:08:14
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-30 10:08:14
+ * @FilePath: \zabbix-mcp-go\server\problem_feed.go
+ * @Description: 问题动态：按事件ID增量读取新问题，并跟踪未恢复的问题何时恢复
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+
+package server
+
+import (
+	"context"
+	"sort"
+	"strconv"
+
+	"zabbixMcp/models"
+	"zabbixMcp/tracing"
+	"zabbixMcp/zabbix"
+)
+
+// 问题动态的类型
+const (
+	ProblemNew      = "problem"
+	ProblemResolved = "resolved"
+)
+
+// problemBatchSize 每轮最多读取的新问题数，其余的在下一轮继续读取
+const problemBatchSize = 1000
+
+// ProblemChange 一条问题动态：出现新问题或问题恢复
+type ProblemChange struct {
+	Instance   string                `json:"instance"`
+	Type       string                `json:"type" jsonschema_description:"problem 新问题 resolved 问题恢复"`
+	EventID    string                `json:"eventid"`
+	REventID   string                `json:"r_eventid,omitempty" jsonschema_description:"恢复事件ID"`
+	Name       string                `json:"name"`
+	Severity   models.Int            `json:"severity" jsonschema_description:"0未分类 1信息 2警告 3一般严重 4严重 5灾难"`
+	Clock      models.Timestamp      `json:"clock,omitempty"`
+	RClock     models.Timestamp      `json:"r_clock,omitempty"`
+	Hosts      []models.HostRef      `json:"hosts,omitempty"`
+	HostGroups []models.HostGroupRef `json:"hostgroups,omitempty"`
+	Tags       []models.Tag          `json:"tags,omitempty"`
+}
+
+// ProblemTracker 跟踪单个实例的问题变化：记录已读取到的最大事件ID以及仍未恢复的问题。
+// 不是并发安全的，每个实例由一个轮询协程持有
+type ProblemTracker struct {
+	instance    string
+	lastEventID int64
+	open        map[string]ProblemChange // 事件ID -> 未恢复的问题
+}
+
+// NewProblemTracker 以当前状态为基准创建跟踪器：已有的未恢复问题不作为新问题推送，只在恢复时推送
+func NewProblemTracker(ctx context.Context, provider zabbix.ClientProvider, instance string) (*ProblemTracker, error) {
+	ctx, span := tracing.Start(ctx, "server.NewProblemTracker", tracing.AttrInstance.String(instance))
+	defer span.End()
+	var problems []models.Problem
+	var latest []models.Event
+	err := getAll(ctx, provider, instance,
+		zabbix.BatchCall{Method: "problem.get", Params: models.MapParams{
+			"output":     "extend",
+			"source":     0,
+			"object":     0,
+			"selectTags": "extend",
+		}, Result: &problems},
+		zabbix.BatchCall{Method: "event.get", Params: models.MapParams{
+			"output":    []string{"eventid"},
+			"sortfield": []string{"eventid"},
+			"sortorder": "DESC",
+			"limit":     1,
+		}, Result: &latest},
+	)
+	if err != nil {
+		return nil, err
+	}
+	t := &ProblemTracker{instance: instance, open: make(map[string]ProblemChange, len(problems))}
+	if len(latest) > 0 {
+		t.lastEventID, _ = strconv.ParseInt(latest[0].EventID, 10, 64)
+	}
+	changes, err := t.describe(ctx, provider, problems)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range changes {
+		t.open[c.EventID] = c
+		t.advance(c.EventID)
+	}
+	return t, nil
+}
+
+// Instance 跟踪的实例
+func (t *ProblemTracker) Instance() string {
+	return t.instance
+}
+
+// Poll 读取上次之后出现的新问题与已恢复的问题，按事件先后返回；
+// 两次轮询之间出现又恢复的问题会先后返回一条新问题与一条恢复动态
+func (t *ProblemTracker) Poll(ctx context.Context, provider zabbix.ClientProvider) ([]ProblemChange, error) {
+	ctx, span := tracing.Start(ctx, "server.ProblemTracker.Poll", tracing.AttrInstance.String(t.instance))
+	defer span.End()
+	var fresh, tracked []models.Problem
+	calls := []zabbix.BatchCall{{Method: "problem.get", Params: models.MapParams{
+		"output":       "extend",
+		"source":       0,
+		"object":       0,
+		"eventid_from": strconv.FormatInt(t.lastEventID+1, 10),
+		"recent":       true,
+		"selectTags":   "extend",
+		"sortfield":    []string{"eventid"},
+		"sortorder":    "ASC",
+		"limit":        problemBatchSize,
+	}, Result: &fresh}}
+	if len(t.open) > 0 {
+		calls = append(calls, zabbix.BatchCall{Method: "problem.get", Params: models.MapParams{
+			"output":   []string{"eventid", "r_eventid", "r_clock"},
+			"eventids": t.openIDs(),
+			"recent":   true,
+		}, Result: &tracked})
+	}
+	if err := getAll(ctx, provider, t.instance, calls...); err != nil {
+		return nil, err
+	}
+
+	var changes []ProblemChange
+	// 已跟踪的问题：已恢复，或因超出 recent 的时间范围、触发器被删除而查不到的，都视为恢复
+	current := make(map[string]models.Problem, len(tracked))
+	for _, p := range tracked {
+		current[p.EventID] = p
+	}
+	for _, id := range t.openIDs() {
+		p, found := current[id]
+		if found && p.REventID == "0" {
+			continue
+		}
+		c := t.open[id]
+		c.Type = ProblemResolved
+		if found {
+			c.REventID, c.RClock = p.REventID, p.RClock
+		}
+		changes = append(changes, c)
+		delete(t.open, id)
+	}
+
+	described, err := t.describe(ctx, provider, fresh)
+	if err != nil {
+		return nil, err
+	}
+	for i, c := range described {
+		t.advance(c.EventID)
+		changes = append(changes, c)
+		if p := fresh[i]; p.REventID != "" && p.REventID != "0" {
+			c.Type = ProblemResolved
+			c.REventID, c.RClock = p.REventID, p.RClock
+			changes = append(changes, c)
+			continue
+		}
+		t.open[c.EventID] = c
+	}
+	return changes, nil
+}
+
+// describe 把问题转换为动态，补充所在的主机与主机组
+func (t *ProblemTracker) describe(ctx context.Context, provider zabbix.ClientProvider, problems []models.Problem) ([]ProblemChange, error) {
+	out := make([]ProblemChange, len(problems))
+	if len(problems) == 0 {
+		return out, nil
+	}
+	eventIDs := make([]string, len(problems))
+	for i, p := range problems {
+		eventIDs[i] = p.EventID
+	}
+	hosts, err := eventHosts(ctx, provider, t.instance, eventIDs)
+	if err != nil {
+		return nil, err
+	}
+	var hostIDs []string
+	seen := make(map[string]bool)
+	for _, refs := range hosts {
+		for _, h := range refs {
+			if !seen[h.HostID] {
+				seen[h.HostID] = true
+				hostIDs = append(hostIDs, h.HostID)
+			}
+		}
+	}
+	groups := make(map[string][]models.HostGroupRef, len(hostIDs))
+	if len(hostIDs) > 0 {
+		var withGroups []models.Host
+		if err := get(ctx, provider, t.instance, "host.get", models.MapParams{
+			"output":           []string{"hostid"},
+			"hostids":          hostIDs,
+			"selectHostGroups": []string{"groupid", "name"},
+		}, &withGroups); err != nil {
+			return nil, err
+		}
+		for _, h := range withGroups {
+			groups[h.HostID] = h.HostGroups
+		}
+	}
+	for i, p := range problems {
+		c := ProblemChange{
+			Instance: t.instance,
+			Type:     ProblemNew,
+			EventID:  p.EventID,
+			Name:     p.Name,
+			Severity: p.Severity,
+			Clock:    p.Clock,
+			Hosts:    hosts[p.EventID],
+			Tags:     p.Tags,
+		}
+		seenGroup := make(map[string]bool)
+		for _, h := range c.Hosts {
+			for _, g := range groups[h.HostID] {
+				if !seenGroup[g.GroupID] {
+					seenGroup[g.GroupID] = true
+					c.HostGroups = append(c.HostGroups, g)
+				}
+			}
+		}
+		out[i] = c
+	}
+	return out, nil
+}
+
+func (t *ProblemTracker) advance(eventID string) {
+	if id, err := strconv.ParseInt(eventID, 10, 64); err == nil && id > t.lastEventID {
+		t.lastEventID = id
+	}
+}
+
+func (t *ProblemTracker) openIDs() []string {
+	ids := make([]string, 0, len(t.open))
+	for id := range t.open {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return idLess(ids[i], ids[j]) })
+	return ids
+}
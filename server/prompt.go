@@ -0,0 +1,340 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-29 15:36:20
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-29 15:36:20
+ * @FilePath: \zabbix-mcp-go\server\prompt.go
+ * @Description: MCP 提示词预取的数据：问题分诊、主机不可达排查、用户入职/离职与模板触发器噪音评估
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+
+package server
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"zabbixMcp/models"
+	"zabbixMcp/tracing"
+	"zabbixMcp/zabbix"
+)
+
+// availabilityItemKeys 排查主机不可达时关注的可用性监控项
+var availabilityItemKeys = []string{
+	"agent.ping",
+	"icmpping",
+	"icmppingloss",
+	"icmppingsec",
+	"zabbix[host,agent,available]",
+	"zabbix[host,snmp,available]",
+	"zabbix[host,ipmi,available]",
+	"zabbix[host,jmx,available]",
+}
+
+// ProblemWithHosts 问题及其所在的主机（problem.get 不支持 selectHosts，由 event.get 补充）
+type ProblemWithHosts struct {
+	models.Problem
+	Hosts []models.HostRef `json:"hosts,omitempty"`
+}
+
+// HostDiagnosis 排查主机不可达所需的数据
+type HostDiagnosis struct {
+	Host         map[string]interface{} `json:"host"`
+	Problems     []ProblemWithHosts     `json:"problems"`
+	Availability []models.Item          `json:"availability_items"`
+	RecentEvents []models.Event         `json:"recent_events"`
+}
+
+// OnboardingContext 新用户加入用户组前需要确认的信息
+type OnboardingContext struct {
+	Group         models.UserGroup         `json:"group"`
+	ExistingUsers []models.User            `json:"existing_users"` // 同名的已有用户
+	Roles         []map[string]interface{} `json:"roles"`
+}
+
+// UserPresence 用户在一个实例中的账号情况
+type UserPresence struct {
+	Instance string        `json:"instance"`
+	Users    []models.User `json:"users"`
+	Error    *models.Error `json:"error,omitempty"`
+}
+
+// TriggerNoise 模板触发器在统计窗口内的告警情况
+type TriggerNoise struct {
+	models.Trigger
+	ProblemEvents int `json:"problem_events"` // 继承到主机上的触发器产生的问题事件数
+	Hosts         int `json:"hosts"`          // 产生过问题事件的主机数
+	LinkedHosts   int `json:"linked_hosts"`   // 继承了该触发器的主机数
+}
+
+// hostStatusTemplate 模板在 hosts 表中的状态值，trigger.get 的 selectHosts 对模板触发器返回该状态
+const hostStatusTemplate = 3
+
+// TemplateNoise 模板触发器噪音统计
+type TemplateNoise struct {
+	TemplateID string           `json:"templateid"`
+	Since      models.Timestamp `json:"since"`
+	Triggers   []TriggerNoise   `json:"triggers"`
+	Truncated  bool             `json:"truncated,omitempty"` // 事件数达到上限，统计不完整
+}
+
+// GetProblemsWithHosts 读取当前问题（最新的在前，最多 limit 条，hostIDs 非空时只读取这些主机的问题），
+// 并补充问题所在的主机
+func GetProblemsWithHosts(ctx context.Context, provider zabbix.ClientProvider, instance string, hostIDs []string, limit int) ([]ProblemWithHosts, error) {
+	ctx, span := tracing.Start(ctx, "server.GetProblemsWithHosts", tracing.AttrInstance.String(instance))
+	defer span.End()
+	params := models.MapParams{
+		"output":     "extend",
+		"selectTags": "extend",
+		"sortfield":  []string{"eventid"},
+		"sortorder":  "DESC",
+	}
+	if len(hostIDs) > 0 {
+		params["hostids"] = hostIDs
+	}
+	if limit > 0 {
+		params["limit"] = limit
+	}
+	var problems []models.Problem
+	if err := get(ctx, provider, instance, "problem.get", params, &problems); err != nil {
+		return nil, err
+	}
+	out := make([]ProblemWithHosts, len(problems))
+	if len(problems) == 0 {
+		return out, nil
+	}
+	eventIDs := make([]string, len(problems))
+	for i, p := range problems {
+		eventIDs[i] = p.EventID
+	}
+	hosts, err := eventHosts(ctx, provider, instance, eventIDs)
+	if err != nil {
+		return nil, err
+	}
+	for i, p := range problems {
+		out[i] = ProblemWithHosts{Problem: p, Hosts: hosts[p.EventID]}
+	}
+	return out, nil
+}
+
+// eventHosts 通过 event.get 查询事件所在的主机，返回 事件ID -> 主机
+func eventHosts(ctx context.Context, provider zabbix.ClientProvider, instance string, eventIDs []string) (map[string][]models.HostRef, error) {
+	hosts := make(map[string][]models.HostRef, len(eventIDs))
+	if len(eventIDs) == 0 {
+		return hosts, nil
+	}
+	var events []models.Event
+	if err := get(ctx, provider, instance, "event.get", models.MapParams{
+		"output":      []string{"eventid"},
+		"eventids":    eventIDs,
+		"selectHosts": []string{"hostid", "host", "name"},
+	}, &events); err != nil {
+		return nil, err
+	}
+	for _, e := range events {
+		hosts[e.EventID] = e.Hosts
+	}
+	return hosts, nil
+}
+
+// DiagnoseHost 读取主机配置与接口可用性、主机上的当前问题、可用性监控项的最新值以及 since 之后的事件
+func DiagnoseHost(ctx context.Context, provider zabbix.ClientProvider, instance, hostID string, since time.Time) (*HostDiagnosis, error) {
+	ctx, span := tracing.Start(ctx, "server.DiagnoseHost", tracing.AttrInstance.String(instance))
+	defer span.End()
+	host, err := GetHostDetail(ctx, provider, instance, hostID)
+	if err != nil {
+		return nil, err
+	}
+	problems, err := GetProblemsWithHosts(ctx, provider, instance, []string{hostID}, 0)
+	if err != nil {
+		return nil, err
+	}
+	d := &HostDiagnosis{Host: host, Problems: problems}
+	err = getAll(ctx, provider, instance,
+		zabbix.BatchCall{Method: "item.get", Params: models.MapParams{
+			"output":  []string{"itemid", "name", "key_", "status", "state", "error", "lastvalue", "lastclock"},
+			"hostids": []string{hostID},
+			"filter":  map[string]interface{}{"key_": availabilityItemKeys},
+		}, Result: &d.Availability},
+		zabbix.BatchCall{Method: "event.get", Params: models.MapParams{
+			"output":     "extend",
+			"hostids":    []string{hostID},
+			"time_from":  since.Unix(),
+			"selectTags": "extend",
+			"sortfield":  []string{"clock", "eventid"},
+			"sortorder":  "DESC",
+			"limit":      50,
+		}, Result: &d.RecentEvents},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// GetOnboardingContext 读取目标用户组、同名的已有用户与可选角色；5.2 之前没有角色 API，返回内置角色
+func GetOnboardingContext(ctx context.Context, provider zabbix.ClientProvider, instance, username, groupID string) (*OnboardingContext, error) {
+	ctx, span := tracing.Start(ctx, "server.GetOnboardingContext", tracing.AttrInstance.String(instance))
+	defer span.End()
+	var groups []models.UserGroup
+	oc := &OnboardingContext{}
+	err := getAll(ctx, provider, instance,
+		zabbix.BatchCall{Method: "usergroup.get", Params: models.MapParams{
+			"output":      "extend",
+			"usrgrpids":   []string{groupID},
+			"selectUsers": []string{"userid", "username"},
+		}, Result: &groups},
+		zabbix.BatchCall{Method: "user.get", Params: models.MapParams{
+			"output":        []string{"userid", "username", "name", "surname", "roleid"},
+			"filter":        map[string]interface{}{"username": username},
+			"selectUsrgrps": []string{"usrgrpid", "name"},
+		}, Result: &oc.ExistingUsers},
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(groups) == 0 {
+		return nil, models.Errorf(models.CodeNotFound, "用户组 %s 不存在", groupID)
+	}
+	oc.Group = groups[0]
+	oc.Roles, err = GetRecords(ctx, provider, instance, "role.get", models.MapParams{"output": []string{"roleid", "name", "type"}})
+	if err != nil {
+		if zabbix.ClassifyError(err).Code != models.CodeVersionUnsupported {
+			return nil, err
+		}
+		oc.Roles = builtinRoleObjects()
+		sort.Slice(oc.Roles, func(i, j int) bool {
+			return idLess(oc.Roles[i]["roleid"].(string), oc.Roles[j]["roleid"].(string))
+		})
+	}
+	return oc, nil
+}
+
+// FindUserAcrossInstances 在全部实例中查找用户名为 username 的账号及其用户组与媒介；
+// 单个实例查询失败时记录在该实例的 Error 中，不影响其他实例
+func FindUserAcrossInstances(ctx context.Context, provider zabbix.ClientProvider, username string) ([]UserPresence, error) {
+	ctx, span := tracing.Start(ctx, "server.FindUserAcrossInstances")
+	defer span.End()
+	infos, err := GetInstancesInfo(ctx, provider, "")
+	if err != nil {
+		return nil, err
+	}
+	out := make([]UserPresence, 0, len(infos))
+	for _, info := range infos {
+		users, err := GetUsers(ctx, provider, models.MapParams{
+			"output":        "extend",
+			"filter":        map[string]interface{}{"username": username},
+			"selectUsrgrps": []string{"usrgrpid", "name"},
+			"selectMedias":  "extend",
+			"getAccess":     true,
+		}, info.Instance)
+		presence := UserPresence{Instance: info.Instance, Users: users}
+		if err != nil {
+			presence.Error = zabbix.ClassifyError(err)
+			presence.Error.Instance = info.Instance
+		}
+		out = append(out, presence)
+	}
+	return out, nil
+}
+
+// GetTemplateTriggerNoise 统计模板中每个触发器继承到主机（含经由子模板间接继承）后，自 since 起产生的问题事件数与涉及的主机数，
+// 按问题事件数从多到少排序；最多读取 maxEvents 条事件
+func GetTemplateTriggerNoise(ctx context.Context, provider zabbix.ClientProvider, instance, templateID string, since time.Time, maxEvents int) (*TemplateNoise, error) {
+	ctx, span := tracing.Start(ctx, "server.GetTemplateTriggerNoise", tracing.AttrInstance.String(instance))
+	defer span.End()
+	var triggers []models.Trigger
+	if err := get(ctx, provider, instance, "trigger.get", models.MapParams{
+		"output":           []string{"triggerid", "description", "expression", "priority", "status", "comments"},
+		"templateids":      []string{templateID},
+		"expandExpression": true,
+		"selectTags":       "extend",
+	}, &triggers); err != nil {
+		return nil, err
+	}
+	noise := &TemplateNoise{TemplateID: templateID, Since: models.Timestamp(since.Unix()), Triggers: make([]TriggerNoise, len(triggers))}
+	if len(triggers) == 0 {
+		return noise, nil
+	}
+	// root 记录每个触发器对应的模板触发器下标
+	root := make(map[string]int, len(triggers))
+	frontier := make([]string, len(triggers))
+	for i, t := range triggers {
+		noise.Triggers[i] = TriggerNoise{Trigger: t}
+		root[t.TriggerID] = i
+		frontier[i] = t.TriggerID
+	}
+
+	// 继承的触发器通过 templateid 指向上一级触发器；模板链接到其他模板时，副本先落在子模板上再被主机继承，
+	// 因此沿 templateid 逐层向下查找，模板上的副本继续展开，主机上的副本参与统计
+	hostOf := make(map[string]string)
+	var objectIDs []string
+	for len(frontier) > 0 {
+		var inherited []models.Trigger
+		if err := get(ctx, provider, instance, "trigger.get", models.MapParams{
+			"output":      []string{"triggerid", "templateid"},
+			"filter":      map[string]interface{}{"templateid": frontier},
+			"selectHosts": []string{"hostid", "status"},
+		}, &inherited); err != nil {
+			return nil, err
+		}
+		frontier = nil
+		for _, t := range inherited {
+			i, ok := root[t.TemplateID]
+			if _, seen := root[t.TriggerID]; !ok || seen {
+				continue
+			}
+			root[t.TriggerID] = i
+			if len(t.Hosts) > 0 && t.Hosts[0].Status != nil && *t.Hosts[0].Status == hostStatusTemplate {
+				frontier = append(frontier, t.TriggerID)
+				continue
+			}
+			if len(t.Hosts) > 0 {
+				hostOf[t.TriggerID] = t.Hosts[0].HostID
+			}
+			noise.Triggers[i].LinkedHosts++
+			objectIDs = append(objectIDs, t.TriggerID)
+		}
+	}
+
+	if len(objectIDs) > 0 {
+		params := models.MapParams{
+			"output":    []string{"eventid", "objectid"},
+			"source":    0,
+			"object":    0,
+			"value":     1,
+			"objectids": objectIDs,
+			"time_from": since.Unix(),
+			"sortfield": []string{"eventid"},
+			"sortorder": "DESC",
+		}
+		if maxEvents > 0 {
+			params["limit"] = maxEvents
+		}
+		var events []models.Event
+		if err := get(ctx, provider, instance, "event.get", params, &events); err != nil {
+			return nil, err
+		}
+		noise.Truncated = maxEvents > 0 && len(events) >= maxEvents
+		hosts := make([]map[string]bool, len(triggers))
+		for _, e := range events {
+			i, ok := root[e.ObjectID]
+			if !ok {
+				continue
+			}
+			noise.Triggers[i].ProblemEvents++
+			if hosts[i] == nil {
+				hosts[i] = make(map[string]bool)
+			}
+			hosts[i][hostOf[e.ObjectID]] = true
+		}
+		for i := range noise.Triggers {
+			noise.Triggers[i].Hosts = len(hosts[i])
+		}
+	}
+	sort.SliceStable(noise.Triggers, func(i, j int) bool {
+		return noise.Triggers[i].ProblemEvents > noise.Triggers[j].ProblemEvents
+	})
+	return noise, nil
+}
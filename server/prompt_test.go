@@ -0,0 +1,85 @@
+package server_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"zabbixMcp/server"
+	"zabbixMcp/zabbix/zabbixtest"
+)
+
+// TestGetTemplateTriggerNoiseNestedTemplates 模板 A 链接到模板 B、B 再链接到模板 C，
+// 主机分别链接 A、B、C 时，A 的触发器应统计到全部三台主机上的继承触发器
+func TestGetTemplateTriggerNoiseNestedTemplates(t *testing.T) {
+	for _, v := range []string{"5.0.0", "6.0.0", "7.0.0"} {
+		t.Run(v, func(t *testing.T) {
+			srv := zabbixtest.NewServer(zabbixtest.Options{Version: v})
+			defer srv.Close()
+			st := srv.Store()
+
+			tplA, tplB, tplC := st.AddTemplate("Template A"), st.AddTemplate("Template B"), st.AddTemplate("Template C")
+			cpu := st.AddTrigger(zabbixtest.Trigger{HostID: tplA, Description: "High CPU", Priority: "3"})
+			disk := st.AddTrigger(zabbixtest.Trigger{HostID: tplA, Description: "Low disk", Priority: "2"})
+			cpuB := st.AddTrigger(zabbixtest.Trigger{HostID: tplB, TemplateID: cpu, Description: "High CPU"})
+			diskB := st.AddTrigger(zabbixtest.Trigger{HostID: tplB, TemplateID: disk, Description: "Low disk"})
+			cpuC := st.AddTrigger(zabbixtest.Trigger{HostID: tplC, TemplateID: cpuB, Description: "High CPU"})
+
+			web01 := st.AddHost(zabbixtest.Host{Host: "web01"})
+			web02 := st.AddHost(zabbixtest.Host{Host: "web02"})
+			web03 := st.AddHost(zabbixtest.Host{Host: "web03"})
+			cpu1 := st.AddTrigger(zabbixtest.Trigger{HostID: web01, TemplateID: cpu, Description: "High CPU"})
+			cpu2 := st.AddTrigger(zabbixtest.Trigger{HostID: web02, TemplateID: cpuB, Description: "High CPU"})
+			disk2 := st.AddTrigger(zabbixtest.Trigger{HostID: web02, TemplateID: diskB, Description: "Low disk"})
+			cpu3 := st.AddTrigger(zabbixtest.Trigger{HostID: web03, TemplateID: cpuC, Description: "High CPU"})
+			other := st.AddTrigger(zabbixtest.Trigger{HostID: web03, Description: "Not templated"})
+
+			now := time.Now()
+			since := now.Add(-time.Hour)
+			for _, p := range []zabbixtest.Problem{
+				{TriggerID: cpu1, HostID: web01},
+				{TriggerID: cpu2, HostID: web02},
+				{TriggerID: cpu2, HostID: web02},
+				{TriggerID: disk2, HostID: web02},
+				{TriggerID: cpu3, HostID: web03},
+				{TriggerID: cpu3, HostID: web03, Clock: since.Add(-time.Minute).Unix()},
+				{TriggerID: other, HostID: web03},
+			} {
+				if p.Clock == 0 {
+					p.Clock = now.Unix()
+				}
+				st.AddProblem(p)
+			}
+
+			provider, err := zabbixtest.NewProvider(srv)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer provider.Close()
+			noise, err := server.GetTemplateTriggerNoise(context.Background(), provider, "zabbixtest", tplA, since, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(noise.Triggers) != 2 {
+				t.Fatalf("模板触发器数 = %d，期望 2", len(noise.Triggers))
+			}
+			want := []struct {
+				id                    string
+				linked, events, hosts int
+			}{
+				{cpu, 3, 4, 3},
+				{disk, 1, 1, 1},
+			}
+			for i, w := range want {
+				got := noise.Triggers[i]
+				if got.TriggerID != w.id || got.LinkedHosts != w.linked || got.ProblemEvents != w.events || got.Hosts != w.hosts {
+					t.Errorf("第 %d 个触发器 = {%s linked=%d events=%d hosts=%d}，期望 {%s linked=%d events=%d hosts=%d}",
+						i, got.TriggerID, got.LinkedHosts, got.ProblemEvents, got.Hosts, w.id, w.linked, w.events, w.hosts)
+				}
+			}
+			if noise.Truncated {
+				t.Error("未设置 maxEvents 时不应截断")
+			}
+		})
+	}
+}
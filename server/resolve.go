@@ -0,0 +1,229 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-28 19:42:17
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-28 19:42:17
+ * @FilePath: \zabbix-mcp-go\server\resolve.go
+ * @Description: 对象引用解析：工具参数既可以传 ID 也可以传名称，名称有歧义时列出候选对象
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"zabbixMcp/models"
+	"zabbixMcp/tracing"
+	"zabbixMcp/zabbix"
+)
+
+// ObjectKind 可以按 ID 或名称引用的对象类型
+type ObjectKind string
+
+const (
+	KindUser      ObjectKind = "user"
+	KindUserGroup ObjectKind = "usergroup"
+	KindRole      ObjectKind = "role"
+	KindHost      ObjectKind = "host"
+	KindHostGroup ObjectKind = "hostgroup"
+	KindTemplate  ObjectKind = "template"
+	KindMediaType ObjectKind = "mediatype"
+)
+
+// resolverSpec 对象类型的查询方式：names 为按名称匹配的字段（主机、模板同时匹配技术名称与可见名称），
+// lookup 为找不到对象时提示调用方使用的查询方式
+type resolverSpec struct {
+	method string
+	names  []string
+	label  string
+	lookup string
+}
+
+var resolvers = map[ObjectKind]resolverSpec{
+	KindUser:      {"user.get", []string{"username"}, "用户", "get_users"},
+	KindUserGroup: {"usergroup.get", []string{"name"}, "用户组", "get_groups"},
+	KindRole:      {"role.get", []string{"name"}, "角色", "api_call 调用 role.get"},
+	KindHost:      {"host.get", []string{"host", "name"}, "主机", "get_hosts"},
+	KindHostGroup: {"hostgroup.get", []string{"name"}, "主机组", "api_call 调用 hostgroup.get"},
+	KindTemplate:  {"template.get", []string{"host", "name"}, "模板", "api_call 调用 template.get"},
+	KindMediaType: {"mediatype.get", []string{"name"}, "媒介类型", "api_call 调用 mediatype.get"},
+}
+
+// builtinRoles 5.2 之前没有 role API，角色即用户类型，名称与 5.2 起的内置角色一致（见 roleid_to_type）
+var builtinRoles = map[string]string{
+	"1": "User role",
+	"2": "Admin role",
+	"3": "Super admin role",
+	"4": "Guest role",
+}
+
+// ResolveID 把 ref（对象ID或名称）解析为对象ID，arg 为工具参数名，用于错误信息；ref 为空时返回空串
+func ResolveID(ctx context.Context, provider zabbix.ClientProvider, instance string, kind ObjectKind, arg, ref string) (string, error) {
+	if strings.TrimSpace(ref) == "" {
+		return "", nil
+	}
+	ids, err := ResolveIDs(ctx, provider, instance, kind, arg, []string{ref})
+	if err != nil {
+		return "", err
+	}
+	return ids[0], nil
+}
+
+// ResolveIDs 批量解析对象引用，结果与 refs 顺序一致并去重。纯数字的引用先按ID查找，
+// 不存在时再按名称匹配；一个名称匹配到多个对象时返回候选列表，由调用方改用ID或更精确的名称
+func ResolveIDs(ctx context.Context, provider zabbix.ClientProvider, instance string, kind ObjectKind, arg string, refs []string) ([]string, error) {
+	spec, ok := resolvers[kind]
+	if !ok {
+		return nil, fmt.Errorf("不支持解析的对象类型 %s", kind)
+	}
+	ctx, span := tracing.Start(ctx, "server.ResolveIDs",
+		tracing.AttrInstance.String(instance),
+		tracing.AttrMethod.String(spec.method),
+	)
+	defer span.End()
+
+	var cleaned, numeric []string
+	for _, ref := range refs {
+		if ref = strings.TrimSpace(ref); ref != "" {
+			cleaned = append(cleaned, ref)
+			if isNumericID(ref) {
+				numeric = append(numeric, ref)
+			}
+		}
+	}
+	if len(cleaned) == 0 {
+		return []string{}, nil
+	}
+	objects, err := lookupObjects(ctx, provider, instance, spec, cleaned, numeric)
+	if err != nil && kind == KindRole && zabbix.ClassifyError(err).Code == models.CodeVersionUnsupported {
+		objects, err = builtinRoleObjects(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	idField := PageIDField(spec.method)
+	out := make([]string, 0, len(cleaned))
+	seen := make(map[string]bool, len(cleaned))
+	for _, ref := range cleaned {
+		id, err := matchRef(spec, idField, arg, ref, objects)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	return out, nil
+}
+
+// lookupObjects 在一次批量请求中按ID与各名称字段查询候选对象
+func lookupObjects(ctx context.Context, provider zabbix.ClientProvider, instance string, spec resolverSpec, refs, numeric []string) ([]map[string]interface{}, error) {
+	pm := pagedMethods[spec.method]
+	output := append([]string{pm.idField}, spec.names...)
+	results := make([][]map[string]interface{}, 0, len(spec.names)+1)
+	calls := make([]zabbix.BatchCall, 0, len(spec.names)+1)
+	add := func(params models.MapParams) {
+		results = append(results, nil)
+		calls = append(calls, zabbix.BatchCall{Method: spec.method, Params: params})
+	}
+	if len(numeric) > 0 {
+		add(models.MapParams{"output": output, pm.idsParam: numeric})
+	}
+	for _, field := range spec.names {
+		add(models.MapParams{"output": output, "filter": map[string]interface{}{field: refs}})
+	}
+	for i := range calls {
+		calls[i].Result = &results[i]
+	}
+	if err := getAll(ctx, provider, instance, calls...); err != nil {
+		return nil, err
+	}
+	var objects []map[string]interface{}
+	for _, r := range results {
+		objects = append(objects, r...)
+	}
+	return objects, nil
+}
+
+// matchRef 在候选对象中确定 ref 指向的唯一对象
+func matchRef(spec resolverSpec, idField, arg, ref string, objects []map[string]interface{}) (string, error) {
+	if isNumericID(ref) {
+		for _, obj := range objects {
+			if fmt.Sprint(obj[idField]) == ref {
+				return ref, nil
+			}
+		}
+	}
+	var matches []models.Candidate
+	seen := make(map[string]bool)
+	for _, obj := range objects {
+		id := fmt.Sprint(obj[idField])
+		if seen[id] {
+			continue
+		}
+		for _, field := range spec.names {
+			if fmt.Sprint(obj[field]) == ref {
+				seen[id] = true
+				matches = append(matches, models.Candidate{ID: id, Name: candidateName(spec, obj), Field: field})
+				break
+			}
+		}
+	}
+	switch len(matches) {
+	case 1:
+		return matches[0].ID, nil
+	case 0:
+		return "", models.Errorf(models.CodeNotFound, "未找到%s %q", spec.label, ref).
+			WithHint(fmt.Sprintf("参数 %s 可以传%sID或名称（名称须完全一致），可用 %s 查询", arg, spec.label, spec.lookup))
+	}
+	desc := make([]string, len(matches))
+	for i, m := range matches {
+		desc[i] = fmt.Sprintf("%s (%s)", m.ID, m.Name)
+	}
+	e := models.Errorf(models.CodeInvalidParams, "%s名称 %q 匹配到 %d 个对象: %s", spec.label, ref, len(matches), strings.Join(desc, ", ")).
+		WithHint(fmt.Sprintf("参数 %s 改用候选列表中的ID", arg))
+	e.Params = models.ArgsError{{Arg: arg, Problem: "名称有歧义"}}
+	e.Candidates = matches
+	return "", e
+}
+
+// candidateName 候选对象的展示名称：技术名称与可见名称不同时一并给出
+func candidateName(spec resolverSpec, obj map[string]interface{}) string {
+	var parts []string
+	for _, field := range spec.names {
+		if v, ok := obj[field]; ok && v != nil {
+			if s := fmt.Sprint(v); s != "" && (len(parts) == 0 || parts[0] != s) {
+				parts = append(parts, s)
+			}
+		}
+	}
+	if len(parts) == 2 {
+		return parts[0] + " / " + parts[1]
+	}
+	return strings.Join(parts, "")
+}
+
+func builtinRoleObjects() []map[string]interface{} {
+	objects := make([]map[string]interface{}, 0, len(builtinRoles))
+	for id, name := range builtinRoles {
+		objects = append(objects, map[string]interface{}{"roleid": id, "name": name})
+	}
+	return objects
+}
+
+func isNumericID(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
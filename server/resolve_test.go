@@ -0,0 +1,149 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+
+	"zabbixMcp/models"
+	"zabbixMcp/zabbix"
+	"zabbixMcp/zabbix/zabbixtest"
+)
+
+func newResolveServer(t *testing.T, version string) (*zabbixtest.Server, zabbix.ClientProvider) {
+	t.Helper()
+	srv := zabbixtest.NewServer(zabbixtest.Options{Version: version})
+	t.Cleanup(srv.Close)
+	provider, err := zabbixtest.NewProvider(srv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { provider.Close() })
+	return srv, provider
+}
+
+// TestResolveIDs 按 ID 或名称解析，结果保持引用顺序并去重；各版本的用户名字段不同
+func TestResolveIDs(t *testing.T) {
+	for _, v := range []string{"4.0.0", "5.0.0", "5.4.0", "6.0.0", "7.0.0"} {
+		t.Run(v, func(t *testing.T) {
+			srv, provider := newResolveServer(t, v)
+			ctx := context.Background()
+			lisi := srv.Store().AddUser(zabbixtest.User{Username: "lisi", RoleID: "1", Type: "1", GroupIDs: []string{"8"}})
+
+			ids, err := ResolveIDs(ctx, provider, "zabbixtest", KindUser, "userids", []string{" lisi ", "1", "", "Admin", lisi})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := []string{lisi, "1"}; !reflect.DeepEqual(ids, want) {
+				t.Errorf("ResolveIDs = %v，期望 %v", ids, want)
+			}
+
+			ids, err = ResolveIDs(ctx, provider, "zabbixtest", KindUserGroup, "usrgrps", []string{"Guests", "7"})
+			if err != nil || !reflect.DeepEqual(ids, []string{"8", "7"}) {
+				t.Errorf("用户组 = %v, %v，期望 [8 7]", ids, err)
+			}
+
+			if id, err := ResolveID(ctx, provider, "zabbixtest", KindUser, "userid", "  "); id != "" || err != nil {
+				t.Errorf("空引用 = %q, %v，期望空串", id, err)
+			}
+		})
+	}
+}
+
+// TestResolveNumericName 纯数字的引用先按 ID 查找，不存在该 ID 时按名称匹配
+func TestResolveNumericName(t *testing.T) {
+	srv, provider := newResolveServer(t, "6.0.0")
+	ctx := context.Background()
+	named := srv.Store().AddUserGroup(zabbixtest.UserGroup{Name: "2024", GUIAccess: "0", UsersStatus: "0"})
+	idLike := srv.Store().AddUserGroup(zabbixtest.UserGroup{Name: "8", GUIAccess: "0", UsersStatus: "0"})
+
+	for ref, want := range map[string]string{
+		"2024": named, // 没有 ID 为 2024 的用户组
+		"8":    "8",   // ID 8 (Guests) 优先于名称为 "8" 的用户组
+		idLike: idLike,
+	} {
+		if id, err := ResolveID(ctx, provider, "zabbixtest", KindUserGroup, "usrgrps", ref); err != nil || id != want {
+			t.Errorf("ResolveID(%q) = %q, %v，期望 %s", ref, id, err, want)
+		}
+	}
+}
+
+// TestResolveAmbiguous 名称匹配到多个对象时返回候选列表；找不到时返回 not_found
+func TestResolveAmbiguous(t *testing.T) {
+	srv, provider := newResolveServer(t, "7.0.0")
+	ctx := context.Background()
+	group := srv.Store().AddHostGroup(zabbixtest.HostGroup{Name: "Web"})
+	a := srv.Store().AddHost(zabbixtest.Host{Host: "web-a", Name: "web", Status: "0", GroupIDs: []string{group}})
+	b := srv.Store().AddHost(zabbixtest.Host{Host: "web-b", Name: "web", Status: "0", GroupIDs: []string{group}})
+	c := srv.Store().AddHost(zabbixtest.Host{Host: "web", Name: "Web frontend", Status: "0", GroupIDs: []string{group}})
+
+	_, err := ResolveIDs(ctx, provider, "zabbixtest", KindHost, "hosts", []string{"web"})
+	var merr *models.Error
+	if !errors.As(err, &merr) || merr.Code != models.CodeInvalidParams {
+		t.Fatalf("err = %v，期望 invalid_params", err)
+	}
+	if len(merr.Params) != 1 || merr.Params[0].Arg != "hosts" {
+		t.Errorf("参数错误 = %v", merr.Params)
+	}
+	got := map[string]models.Candidate{}
+	for _, cand := range merr.Candidates {
+		got[cand.ID] = cand
+	}
+	want := map[string]models.Candidate{
+		a: {ID: a, Name: "web-a / web", Field: "name"},
+		b: {ID: b, Name: "web-b / web", Field: "name"},
+		c: {ID: c, Name: "web / Web frontend", Field: "host"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("候选对象 = %+v\n期望 %+v", merr.Candidates, want)
+	}
+
+	_, err = ResolveIDs(ctx, provider, "zabbixtest", KindHost, "hosts", []string{"web-a", "nosuch"})
+	if !errors.As(err, &merr) || merr.Code != models.CodeNotFound || merr.Hint == "" {
+		t.Errorf("err = %v，期望 not_found 并提示查询方式", err)
+	}
+}
+
+// TestResolveHostNames 主机同时按技术名称与可见名称匹配，两者相同时只算一个对象
+func TestResolveHostNames(t *testing.T) {
+	for _, v := range []string{"5.0.0", "6.2.0", "7.0.0"} {
+		t.Run(v, func(t *testing.T) {
+			srv, provider := newResolveServer(t, v)
+			ctx := context.Background()
+			db := srv.Store().AddHost(zabbixtest.Host{Host: "db01.internal", Name: "Primary database", Status: "0", GroupIDs: []string{"2"}})
+
+			ids, err := ResolveIDs(ctx, provider, "zabbixtest", KindHost, "hosts", []string{"db01.internal", "Primary database", "Zabbix server"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := []string{db, "10084"}; !reflect.DeepEqual(ids, want) {
+				t.Errorf("ResolveIDs = %v，期望 %v", ids, want)
+			}
+		})
+	}
+}
+
+// TestResolveRoles 5.2 之前没有 role.get，按内置角色名称解析为用户类型
+func TestResolveRoles(t *testing.T) {
+	for _, v := range []string{"4.0.0", "5.0.0", "5.2.0", "7.0.0"} {
+		t.Run(v, func(t *testing.T) {
+			srv, provider := newResolveServer(t, v)
+			ctx := context.Background()
+			ids, err := ResolveIDs(ctx, provider, "zabbixtest", KindRole, "roleid", []string{"Admin role", "3"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := []string{"2", "3"}; !reflect.DeepEqual(ids, want) {
+				t.Errorf("ResolveIDs = %v，期望 %v", ids, want)
+			}
+			if _, err := ResolveID(ctx, provider, "zabbixtest", KindRole, "roleid", "Operator"); zabbix.ClassifyError(err).Code != models.CodeNotFound {
+				t.Errorf("不存在的角色: err = %v，期望 not_found", err)
+			}
+			if called := slices.Contains(srv.Methods(), "role.get"); !called {
+				t.Errorf("应先尝试 role.get: %v", srv.Methods())
+			}
+		})
+	}
+}
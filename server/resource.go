@@ -0,0 +1,70 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-29 10:05:38
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-29 10:05:38
+ * @FilePath: \zabbix-mcp-go\server\resource.go
+ * @Description: MCP 资源读取的业务层：主机配置、当前问题与模板导出
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+
+package server
+
+import (
+	"context"
+
+	"zabbixMcp/models"
+	"zabbixMcp/tracing"
+	"zabbixMcp/zabbix"
+)
+
+// GetHostDetail 读取单个主机的配置：接口、主机组、链接的模板与标签
+func GetHostDetail(ctx context.Context, provider zabbix.ClientProvider, instance, hostID string) (map[string]interface{}, error) {
+	ctx, span := tracing.Start(ctx, "server.GetHostDetail", tracing.AttrInstance.String(instance))
+	defer span.End()
+	records, err := GetRecords(ctx, provider, instance, "host.get", models.MapParams{
+		"output":                "extend",
+		"hostids":               []string{hostID},
+		"selectInterfaces":      "extend",
+		"selectHostGroups":      []string{"groupid", "name"},
+		"selectParentTemplates": []string{"templateid", "host", "name"},
+		"selectTags":            "extend",
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, models.Errorf(models.CodeNotFound, "主机 %s 不存在", hostID)
+	}
+	return records[0], nil
+}
+
+// GetCurrentProblems 读取当前未恢复的问题，按事件ID倒序（最新的在前），最多 limit 条
+func GetCurrentProblems(ctx context.Context, provider zabbix.ClientProvider, instance string, limit int) ([]models.Problem, error) {
+	ctx, span := tracing.Start(ctx, "server.GetCurrentProblems", tracing.AttrInstance.String(instance))
+	defer span.End()
+	params := models.MapParams{
+		"output":     "extend",
+		"selectTags": "extend",
+		"sortfield":  []string{"eventid"},
+		"sortorder":  "DESC",
+	}
+	if limit > 0 {
+		params["limit"] = limit
+	}
+	var problems []models.Problem
+	err := get(ctx, provider, instance, "problem.get", params, &problems)
+	return problems, err
+}
+
+// ExportTemplate 以 JSON 格式导出模板配置（configuration.export），返回 Zabbix 生成的导出文本
+func ExportTemplate(ctx context.Context, provider zabbix.ClientProvider, instance, templateID string) (string, error) {
+	ctx, span := tracing.Start(ctx, "server.ExportTemplate", tracing.AttrInstance.String(instance))
+	defer span.End()
+	var exported string
+	err := get(ctx, provider, instance, "configuration.export", models.MapParams{
+		"format":  "json",
+		"options": map[string]interface{}{"templates": []string{templateID}},
+	}, &exported)
+	return exported, err
+}
@@ -16,55 +16,32 @@ import (
 
 	"zabbixMcp/logger"
 	"zabbixMcp/models"
+	"zabbixMcp/tracing"
 	"zabbixMcp/utils"
 	"zabbixMcp/zabbix"
 )
 
+// NoAccessGroupName 禁用用户时移入的内置用户组名称
+const NoAccessGroupName = "No access to the frontend"
+
 // GetUsers 调用底层 ClientProvider 执行 user.get，并返回解析后的列表。
 // instanceName 为空时使用任意可用客户端，否则强制选择指定实例。
-func GetUsers(ctx context.Context, provider zabbix.ClientProvider, spec models.ParamSpec, instance string) ([]map[string]interface{}, error) {
-	if provider == nil {
-		return nil, fmt.Errorf("no zabbix client")
-	}
-	var (
-		lease zabbix.ClientLease
-		err   error
-	)
-	if instance != "" {
-		lease, err = provider.AcquireByInstance(ctx, instance)
-	} else {
-		lease, err = provider.Acquire(ctx)
-	}
-	if err != nil {
+func GetUsers(ctx context.Context, provider zabbix.ClientProvider, spec models.ParamSpec, instance string) ([]models.User, error) {
+	ctx, span := tracing.Start(ctx, "server.GetUsers", tracing.AttrInstance.String(instance))
+	defer span.End()
+	var users []models.User
+	if err := get(ctx, provider, instance, "user.get", spec, &users); err != nil {
+		logger.L().Error("get user error: %s", err.Error())
 		return nil, err
 	}
-	var callErr error
-	defer func() { lease.Release(callErr) }()
-	client := lease.Client()
-	adapted := client.AdaptAPIParams("user.get", spec)
-	var users []map[string]interface{}
-	callErr = client.Call(ctx, "user.get", adapted, &users)
-	if callErr != nil {
-		logger.L().Error("get user error: %s", callErr.Error())
-		return nil, callErr
-	}
 	return users, nil
 }
 
 // 创建用户
-func CreateUsers(ctx context.Context, provider zabbix.ClientProvider, spec models.ParamSpec, instance, passwd string) (map[string]interface{}, error) {
-	if provider == nil {
-		return nil, fmt.Errorf("no zabbix client")
-	}
-	var (
-		lease zabbix.ClientLease
-		err   error
-	)
-	if instance != "" {
-		lease, err = provider.AcquireByInstance(ctx, instance)
-	} else {
-		lease, err = provider.Acquire(ctx)
-	}
+func CreateUsers(ctx context.Context, provider zabbix.ClientProvider, spec models.ParamSpec, instance string) (map[string]interface{}, error) {
+	ctx, span := tracing.Start(ctx, "server.CreateUsers", tracing.AttrInstance.String(instance))
+	defer span.End()
+	lease, err := acquire(ctx, provider, instance)
 	if err != nil {
 		return nil, err
 	}
@@ -78,23 +55,13 @@ func CreateUsers(ctx context.Context, provider zabbix.ClientProvider, spec model
 		logger.L().Error("create user error: %s", callErr.Error())
 		return nil, callErr
 	}
-	users["passwd"] = passwd
 	return users, nil
 }
 
-func UpdateUser(ctx context.Context, provider zabbix.ClientProvider, spec models.ParamSpec, instance, passwd string) (map[string]interface{}, error) {
-	if provider == nil {
-		return nil, fmt.Errorf("no zabbix client")
-	}
-	var (
-		lease zabbix.ClientLease
-		err   error
-	)
-	if instance != "" {
-		lease, err = provider.AcquireByInstance(ctx, instance)
-	} else {
-		lease, err = provider.Acquire(ctx)
-	}
+func UpdateUser(ctx context.Context, provider zabbix.ClientProvider, spec models.ParamSpec, instance string) (map[string]interface{}, error) {
+	ctx, span := tracing.Start(ctx, "server.UpdateUser", tracing.AttrInstance.String(instance))
+	defer span.End()
+	lease, err := acquire(ctx, provider, instance)
 	if err != nil {
 		return nil, err
 	}
@@ -108,51 +75,34 @@ func UpdateUser(ctx context.Context, provider zabbix.ClientProvider, spec models
 		logger.L().Error("update user error: %s", callErr.Error())
 		return nil, callErr
 	}
-	if passwd != "" {
-		users["passwd"] = passwd
-	}
 	return users, nil
 }
 
+// NewUserMedia 新建用户告警媒介的参数：全部严重级别、全天启用；邮件类媒介的收件人为数组
+func NewUserMedia(mediaTypeID, sendTo string, email bool) map[string]interface{} {
+	media := map[string]interface{}{"mediatypeid": mediaTypeID, "sendto": sendTo, "active": 0, "severity": defaultMediaSeverity, "period": defaultMediaPeriod}
+	if email {
+		media["sendto"] = []string{sendTo}
+	}
+	return media
+}
+
 // 禁用用户
 func DisableUser(ctx context.Context, provider zabbix.ClientProvider, userId, instance string) (map[string]interface{}, error) {
-	// 群组设置为：No access to the frontend
-	// 查找No access to the frontend 群组id
-	groupSpec := models.UserGroup{
-		Output: "extend",
-		Status: 0,
-		Filter: map[string]interface{}{"name": "No access to the frontend"},
-	}
-	groups, err := GetUserGroups(ctx, provider, groupSpec, instance)
+	ctx, span := tracing.Start(ctx, "server.DisableUser", tracing.AttrInstance.String(instance))
+	defer span.End()
+	userSpec, err := buildDisableUserSpec(ctx, provider, userId, instance)
 	if err != nil {
-		logger.L().Error("获取\"No access to the frontend\"用户组失败: %s", err.Error())
 		return nil, err
 	}
-	if len(groups) == 0 {
-		return nil, fmt.Errorf("未找到 \"No access to the frontend\" 用户组")
-	}
-	fmt.Println(groups)
-	var targetGroupID string
-	for _, g := range groups {
-		if id, ok := g["usrgrpid"].(string); ok && id != "" {
-			targetGroupID = id
-			break
-		}
-	}
-	if targetGroupID == "" {
-		return nil, fmt.Errorf("用户组数据缺少 usrgrpid")
-	}
-	logger.L().Infof("禁用用户: %s, 加入用户组: %s", userId, targetGroupID)
-	var userSpec models.UserParams
-	userSpec.Userid = userId
-	userSpec.Usrgrps = []string{targetGroupID}
-	pwd, err := utils.GenerateSecurePassword(12) // 密码无需回传
+	logger.L().Infof("禁用用户: %s, 加入用户组: %v", userId, userSpec.Usrgrps)
+	pwd, err := utils.GenerateSecurePassword(12) // 禁用账号的密码不投递给任何人
 	if err != nil {
 		logger.L().Error("生成密码失败: %s", err.Error())
 		return nil, err
 	}
-	// TODO 设置
-	users, err := UpdateUser(ctx, provider, userSpec, instance, pwd)
+	userSpec.Passwd = pwd
+	users, err := UpdateUser(ctx, provider, userSpec, instance)
 	if err != nil {
 		logger.L().Error("禁用用户失败: %s", err.Error())
 		return nil, err
@@ -160,20 +110,48 @@ func DisableUser(ctx context.Context, provider zabbix.ClientProvider, userId, in
 	return users, nil
 }
 
+// buildDisableUserSpec 构造禁用用户的 user.update 参数：
+// 群组设置为 No access to the frontend
+func buildDisableUserSpec(ctx context.Context, provider zabbix.ClientProvider, userId, instance string) (models.UserParams, error) {
+	targetGroupID, err := findNoAccessGroupID(ctx, provider, instance)
+	if err != nil {
+		return models.UserParams{}, err
+	}
+	return models.UserParams{
+		Userid:  userId,
+		Usrgrps: []string{targetGroupID},
+	}, nil
+}
+
+// findNoAccessGroupID 查找 No access to the frontend 群组id
+func findNoAccessGroupID(ctx context.Context, provider zabbix.ClientProvider, instance string) (string, error) {
+	groupSpec := models.UserGroupParams{
+		Output: "extend",
+		Status: 0,
+		Filter: map[string]interface{}{"name": NoAccessGroupName},
+	}
+	groups, err := GetUserGroups(ctx, provider, groupSpec, instance)
+	if err != nil {
+		logger.L().Errorf("获取\"%s\"用户组失败: %s", NoAccessGroupName, err.Error())
+		return "", err
+	}
+	if len(groups) == 0 {
+		return "", models.Errorf(models.CodeNotFound, "未找到 \"%s\" 用户组", NoAccessGroupName).
+			WithHint("该内置用户组被改名或删除，请在 Zabbix 中恢复后重试")
+	}
+	for _, g := range groups {
+		if g.UsrgrpID != "" {
+			return g.UsrgrpID, nil
+		}
+	}
+	return "", fmt.Errorf("用户组数据缺少 usrgrpid")
+}
+
 // 删除用户
 func DeleteUsers(ctx context.Context, provider zabbix.ClientProvider, spec models.ParamSpec, instance string) (map[string]interface{}, error) {
-	if provider == nil {
-		return nil, fmt.Errorf("no zabbix client")
-	}
-	var (
-		lease zabbix.ClientLease
-		err   error
-	)
-	if instance != "" {
-		lease, err = provider.AcquireByInstance(ctx, instance)
-	} else {
-		lease, err = provider.Acquire(ctx)
-	}
+	ctx, span := tracing.Start(ctx, "server.DeleteUsers", tracing.AttrInstance.String(instance))
+	defer span.End()
+	lease, err := acquire(ctx, provider, instance)
 	if err != nil {
 		return nil, err
 	}
@@ -183,7 +161,7 @@ func DeleteUsers(ctx context.Context, provider zabbix.ClientProvider, spec model
 
 	deleteIDs := spec.BuildDeleteParams()
 	if len(deleteIDs) == 0 {
-		return nil, fmt.Errorf("user.delete 需要至少一个 userid")
+		return nil, models.ArgsError{{Arg: "userids", Problem: "至少需要一个用户ID"}}
 	}
 
 	var users map[string]interface{}
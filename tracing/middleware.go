@@ -0,0 +1,75 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-24 14:48:02
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-24 14:48:02
+ * @FilePath: \zabbix-mcp-go\tracing\middleware.go
+ * @Description: MCP 工具调用的根 span
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+package tracing
+
+import (
+	"context"
+	"errors"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Middleware 为每次工具调用创建根 span。
+// 客户端在请求 _meta 中携带 traceparent/tracestate 时延续其链路。
+func Middleware() server.ToolHandlerMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			ctx = extractMeta(ctx, req)
+			instance, _ := req.GetArguments()["instance"].(string)
+			ctx, span := Start(ctx, "tools/call "+req.Params.Name,
+				AttrTool.String(req.Params.Name),
+				AttrInstance.String(instance),
+			)
+			result, err := next(ctx, req)
+			spanErr := err
+			if spanErr == nil && result != nil && result.IsError {
+				spanErr = errors.New("tool returned isError result")
+			}
+			End(span, spanErr)
+			return result, err
+		}
+	}
+}
+
+// extractMeta 从 MCP 请求的 _meta 中提取 W3C trace context
+func extractMeta(ctx context.Context, req mcp.CallToolRequest) context.Context {
+	if trace.SpanContextFromContext(ctx).IsValid() || req.Params.Meta == nil {
+		return ctx
+	}
+	carrier := propagation.MapCarrier{}
+	for _, key := range []string{"traceparent", "tracestate", "baggage"} {
+		if v, ok := req.Params.Meta.AdditionalFields[key].(string); ok {
+			carrier[key] = v
+		}
+	}
+	if len(carrier) == 0 {
+		return ctx
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, carrier)
+}
+
+// Handler 为单个工具处理器创建 span，位于根 span 之下，与输出裁剪、审计等中间件的耗时区分开；
+// 处理器内的 server.* 与 zabbix.Call span 都挂在它下面
+func Handler(name string, next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		ctx, span := Start(ctx, "handler "+name, AttrTool.String(name))
+		result, err := next(ctx, req)
+		spanErr := err
+		if spanErr == nil && result != nil && result.IsError {
+			spanErr = errors.New("tool returned isError result")
+		}
+		End(span, spanErr)
+		return result, err
+	}
+}
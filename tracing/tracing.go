@@ -0,0 +1,105 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-24 14:20:36
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-24 14:20:36
+ * @FilePath: \zabbix-mcp-go\tracing\tracing.go
+ * @Description: OpenTelemetry 链路追踪（OTLP/HTTP 导出）
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+package tracing
+
+import (
+	"context"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName 本项目所有 span 使用的 instrumentation 名称
+const tracerName = "zabbixMcp"
+
+// 通用 span 属性
+const (
+	AttrInstance   = attribute.Key("zabbix.instance")
+	AttrMethod     = attribute.Key("zabbix.method")
+	AttrVersion    = attribute.Key("zabbix.version")
+	AttrResultSize = attribute.Key("zabbix.result.size")
+	AttrErrorCode  = attribute.Key("zabbix.error.code")
+	AttrTool       = attribute.Key("mcp.tool")
+	AttrBatchSize  = attribute.Key("zabbix.batch.size")
+	AttrCache      = attribute.Key("zabbix.cache")
+)
+
+// Config 链路追踪配置
+type Config struct {
+	Enabled     bool
+	Endpoint    string  // OTLP/HTTP 地址，例如 localhost:4318 或 http://collector:4318/v1/traces
+	Insecure    bool    // Endpoint 不带协议时是否使用 http
+	ServiceName string  // 上报的 service.name
+	SampleRatio float64 // 采样比例 (0,1]，0 表示全部采样
+}
+
+// Init 初始化全局 TracerProvider，返回的 shutdown 用于退出前刷新未导出的 span。
+// 未启用时保持 OpenTelemetry 默认的空实现，所有 Start 调用开销极低。
+func Init(ctx context.Context, cfg Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if !cfg.Enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	var opts []otlptracehttp.Option
+	if strings.Contains(cfg.Endpoint, "://") {
+		opts = append(opts, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+	} else if cfg.Endpoint != "" {
+		opts = append(opts, otlptracehttp.WithEndpoint(cfg.Endpoint))
+		if cfg.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+	}
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	serviceName := cfg.ServiceName
+	if serviceName == "" {
+		serviceName = "zabbix-mcp-server"
+	}
+	sampler := sdktrace.AlwaysSample()
+	if cfg.SampleRatio > 0 && cfg.SampleRatio < 1 {
+		sampler = sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sampler),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+	)
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, nil
+}
+
+// Start 创建一个子 span
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End 结束 span，err 非空时记录错误并标记状态
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
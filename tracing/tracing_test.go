@@ -0,0 +1,158 @@
+package tracing_test
+
+import (
+	"context"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"zabbixMcp/tracing"
+	"zabbixMcp/zabbix"
+	"zabbixMcp/zabbix/zabbixtest"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// collector 本地 OTLP/HTTP 收集器桩，记录收到的全部 span
+type collector struct {
+	mu    sync.Mutex
+	spans []*tracepb.Span
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/v1/traces" {
+		http.NotFound(w, r)
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var req coltracepb.ExportTraceServiceRequest
+	if err := proto.Unmarshal(body, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	c.mu.Lock()
+	for _, rs := range req.ResourceSpans {
+		for _, ss := range rs.ScopeSpans {
+			c.spans = append(c.spans, ss.Spans...)
+		}
+	}
+	c.mu.Unlock()
+	out, _ := proto.Marshal(&coltracepb.ExportTraceServiceResponse{})
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	_, _ = w.Write(out)
+}
+
+func (c *collector) byName(name string) *tracepb.Span {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, s := range c.spans {
+		if s.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+func TestSpansExportedAcrossZabbixCall(t *testing.T) {
+	col := &collector{}
+	stub := httptest.NewServer(col)
+	defer stub.Close()
+
+	ctx := context.Background()
+	shutdown, err := tracing.Init(ctx, tracing.Config{Enabled: true, Endpoint: stub.URL + "/v1/traces"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := zabbixtest.NewServer(zabbixtest.Options{Version: "6.0.0"})
+	defer srv.Close()
+	client, err := zabbix.NewZabbixClientFromConfig(srv.ClientConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const parentTrace = "4bf92f3577b34da6a3ce929d0e0e4736"
+	const parentSpan = "00f067aa0ba902b7"
+	req := mcp.CallToolRequest{}
+	req.Params.Name = "get_hosts"
+	req.Params.Arguments = map[string]interface{}{"instance": "zabbixtest"}
+	req.Params.Meta = &mcp.Meta{AdditionalFields: map[string]interface{}{
+		"traceparent": "00-" + parentTrace + "-" + parentSpan + "-01",
+	}}
+	tool := tracing.Middleware()(tracing.Handler("get_hosts", func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var hosts []map[string]interface{}
+		if err := client.Call(ctx, "host.get", map[string]interface{}{"output": "extend"}, &hosts); err != nil {
+			return nil, err
+		}
+		return mcp.NewToolResultText("ok"), nil
+	}))
+	if _, err := tool(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	if err := shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	root := col.byName("tools/call get_hosts")
+	handler := col.byName("handler get_hosts")
+	call := col.byName("zabbix.Call host.get")
+	if root == nil || handler == nil || call == nil {
+		t.Fatalf("缺少 span: root=%v handler=%v call=%v", root != nil, handler != nil, call != nil)
+	}
+	if got := hex.EncodeToString(root.TraceId); got != parentTrace {
+		t.Fatalf("根 span 未延续 _meta 中的链路: trace_id=%s", got)
+	}
+	if got := hex.EncodeToString(root.ParentSpanId); got != parentSpan {
+		t.Fatalf("根 span 的父 span = %s, want %s", got, parentSpan)
+	}
+	if string(handler.ParentSpanId) != string(root.SpanId) {
+		t.Fatal("handler span 不是根 span 的子 span")
+	}
+	if string(call.ParentSpanId) != string(handler.SpanId) {
+		t.Fatal("zabbix.Call span 不是 handler span 的子 span")
+	}
+
+	// 发往 Zabbix 的 HTTP 请求携带 traceparent，父 span 为本次调用下的 zabbix.doRequest
+	var requests []*tracepb.Span
+	col.mu.Lock()
+	for _, s := range col.spans {
+		if s.Name == "zabbix.doRequest" && string(s.TraceId) == string(root.TraceId) {
+			requests = append(requests, s)
+		}
+	}
+	col.mu.Unlock()
+	var hostGet *zabbixtest.Call
+	for _, c := range srv.Calls() {
+		if c.Method == "host.get" {
+			c := c
+			hostGet = &c
+		}
+	}
+	if hostGet == nil || hostGet.Traceparent == "" {
+		t.Fatal("host.get 请求没有携带 traceparent")
+	}
+	parts := strings.Split(hostGet.Traceparent, "-")
+	if len(parts) != 4 || parts[1] != parentTrace {
+		t.Fatalf("traceparent = %q, 不属于同一条链路", hostGet.Traceparent)
+	}
+	found := false
+	for _, s := range requests {
+		if hex.EncodeToString(s.SpanId) == parts[2] {
+			found = string(s.ParentSpanId) == string(call.SpanId)
+		}
+	}
+	if !found {
+		t.Fatalf("traceparent 中的 span %s 不是 zabbix.Call 下的 zabbix.doRequest", parts[2])
+	}
+}
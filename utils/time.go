@@ -0,0 +1,278 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-23 17:05:12
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-30 14:26:51
+ * @FilePath: \zabbix-mcp-go\utils\time.go
+ * @Description: 时间参数解析：Unix 时间戳、RFC3339、日期、Zabbix 风格的相对时间（now-2h、now/d）与常用短语
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TimeSyntax 错误提示中列出的时间写法
+const TimeSyntax = "支持 Unix 时间戳、RFC3339、2006-01-02 15:04:05、2006-01-02、now-2h、now/d、now-1d/d，" +
+	"以及 last 30 minutes、2 hours ago、today、yesterday、this week、previous month、最近30分钟、昨天、本周、上月 等短语"
+
+// localLayouts 不带时区的时间格式，按所在时区解释
+var localLayouts = []string{
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04",
+}
+
+// timeUnits 时间单位：Zabbix 的 s m h d w M y，以及英文与中文的单位名称
+var timeUnits = map[string]byte{
+	"s": 's', "sec": 's', "secs": 's', "second": 's', "seconds": 's', "秒": 's', "秒钟": 's',
+	"m": 'm', "min": 'm', "mins": 'm', "minute": 'm', "minutes": 'm', "分": 'm', "分钟": 'm',
+	"h": 'h', "hr": 'h', "hrs": 'h', "hour": 'h', "hours": 'h', "时": 'h', "小时": 'h', "个小时": 'h',
+	"d": 'd', "day": 'd', "days": 'd', "天": 'd', "日": 'd',
+	"w": 'w', "week": 'w', "weeks": 'w', "周": 'w', "星期": 'w', "个星期": 'w',
+	"M": 'M', "month": 'M', "months": 'M', "月": 'M', "个月": 'M',
+	"y": 'y', "year": 'y', "years": 'y', "年": 'y',
+}
+
+var (
+	// now、now-1d、now-1d/d、now/w+8h
+	zabbixRelative = regexp.MustCompile(`^now((?:[+-]\d+[smhdwMy])*)(?:/([smhdwMy]))?((?:[+-]\d+[smhdwMy])*)$`)
+	zabbixOffset   = regexp.MustCompile(`([+-])(\d+)([smhdwMy])`)
+	// last 30 minutes、past hour、最近30分钟、过去 2 小时
+	lastPhrase = regexp.MustCompile(`(?i)^(?:last|past|最近|过去)\s*(\d*)\s*([a-zA-Z\p{Han}]+)$`)
+	// 2 hours ago、30分钟前
+	agoPhrase = regexp.MustCompile(`(?i)^(\d+)\s*([a-zA-Z\p{Han}]+?)\s*(?:ago|前|之前|以前)$`)
+)
+
+// periodPhrases 表示一个完整日历周期的短语，对应 Zabbix 的 now-N?/unit
+var periodPhrases = map[string]string{
+	"today":          "now/d",
+	"yesterday":      "now-1d/d",
+	"this week":      "now/w",
+	"this month":     "now/M",
+	"this year":      "now/y",
+	"previous week":  "now-1w/w",
+	"previous month": "now-1M/M",
+	"previous year":  "now-1y/y",
+	"今天":             "now/d",
+	"昨天":             "now-1d/d",
+	"前天":             "now-2d/d",
+	"本周":             "now/w",
+	"这周":             "now/w",
+	"本月":             "now/M",
+	"这个月":            "now/M",
+	"今年":             "now/y",
+	"上周":             "now-1w/w",
+	"上个月":            "now-1M/M",
+	"上月":             "now-1M/M",
+	"去年":             "now-1y/y",
+}
+
+// ParseTime 解析工具参数中的时间，按本地时区解释不带时区的写法，空字符串返回零值时间；
+// 支持的写法见 ParseTimeIn
+func ParseTime(s string) (time.Time, error) {
+	return ParseTimeIn(s, time.Local, time.Now())
+}
+
+// ParseTimeIn 在时区 loc 中解析时间点，now 为相对时间的基准：
+//
+//	1700000000、1700000000000          Unix 时间戳（秒或毫秒）
+//	2025-12-30T08:00:00+08:00          RFC3339
+//	2025-12-30 08:00、2025-12-30       不带时区的日期时间，按 loc 解释
+//	now-2h、now/d、now-1d/d            Zabbix 风格的相对时间，/d 取所在周期的开始
+//	last 30 minutes、2 hours ago       相对 now 的短语
+//	today、yesterday、this week        日历周期短语，取周期的开始
+//
+// 空字符串返回零值时间
+func ParseTimeIn(s string, loc *time.Location, now time.Time) (time.Time, error) {
+	start, _, err := parseTimeExpr(s, loc, now)
+	return start, err
+}
+
+// ParseTimeRange 在时区 loc 中解析时间范围。from 为周期（today、now/d、2025-12-30、last 2 hours 等）且 to 为空时，
+// 范围为整个周期；to 为周期时取周期的结束（与 Zabbix 前端一致，to 为 now/d 表示今天结束）。
+// 为空的一端返回零值时间；from 晚于 to 时返回错误
+func ParseTimeRange(from, to string, loc *time.Location, now time.Time) (time.Time, time.Time, error) {
+	start, end, err := parseTimeExpr(from, loc, now)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("from: %w", err)
+	}
+	if strings.TrimSpace(to) != "" {
+		if _, end, err = parseTimeExpr(to, loc, now); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("to: %w", err)
+		}
+	} else if end.Equal(start) {
+		end = time.Time{}
+	}
+	if !start.IsZero() && !end.IsZero() && start.After(end) {
+		return time.Time{}, time.Time{}, fmt.Errorf("开始时间 %s 晚于结束时间 %s", start.Format(time.RFC3339), end.Format(time.RFC3339))
+	}
+	return start, end, nil
+}
+
+// LoadLocation 按 IANA 名称加载时区，空字符串与 Local 为本地时区
+func LoadLocation(name string) (*time.Location, error) {
+	if name == "" || name == "Local" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(name)
+}
+
+// parseTimeExpr 把时间表达式解析为 [start, end] 区间：时间点的 start 与 end 相同，周期的 end 为周期最后一秒
+func parseTimeExpr(s string, loc *time.Location, now time.Time) (time.Time, time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, time.Time{}, nil
+	}
+	if loc == nil {
+		loc = time.Local
+	}
+	now = now.In(loc)
+	point := func(t time.Time) (time.Time, time.Time, error) { return t, t, nil }
+
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		if n > 1e11 { // 毫秒
+			return point(time.UnixMilli(n).In(loc))
+		}
+		return point(time.Unix(n, 0).In(loc))
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return point(t)
+	}
+	for _, layout := range localLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return point(t)
+		}
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, loc); err == nil {
+		return t, t.AddDate(0, 0, 1).Add(-time.Second), nil
+	}
+	if m := zabbixRelative.FindStringSubmatch(s); m != nil {
+		return zabbixTime(m, now)
+	}
+
+	// 短语保留原始大小写匹配，单位 m（分钟）与 M（月）才能区分
+	phrase := strings.Join(strings.Fields(s), " ")
+	lower := strings.ToLower(phrase)
+	if lower == "now" || lower == "现在" {
+		return point(now)
+	}
+	if expr, ok := periodPhrases[lower]; ok {
+		return zabbixTime(zabbixRelative.FindStringSubmatch(expr), now)
+	}
+	if m := lastPhrase.FindStringSubmatch(phrase); m != nil {
+		n, unit, ok := phraseAmount(m[1], m[2])
+		if ok {
+			return shift(now, '-', n, unit), now, nil
+		}
+	}
+	if m := agoPhrase.FindStringSubmatch(phrase); m != nil {
+		n, unit, ok := phraseAmount(m[1], m[2])
+		if ok {
+			return point(shift(now, '-', n, unit))
+		}
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("无法解析时间 %q，%s", s, TimeSyntax)
+}
+
+// zabbixTime 计算 now[±N单位...][/单位][±N单位...]，带 /单位 时返回所在周期
+func zabbixTime(m []string, now time.Time) (time.Time, time.Time, error) {
+	t := applyOffsets(now, m[1])
+	if m[2] == "" {
+		t = applyOffsets(t, m[3])
+		return t, t, nil
+	}
+	unit := m[2][0]
+	start := truncate(t, unit)
+	end := shift(start, '+', 1, unit).Add(-time.Second)
+	return applyOffsets(start, m[3]), applyOffsets(end, m[3]), nil
+}
+
+func applyOffsets(t time.Time, offsets string) time.Time {
+	for _, o := range zabbixOffset.FindAllStringSubmatch(offsets, -1) {
+		n, _ := strconv.Atoi(o[2])
+		t = shift(t, o[1][0], n, o[3][0])
+	}
+	return t
+}
+
+// phraseAmount 解析短语中的数量与单位，数量省略时为 1；单位名称中的复数、"个"等均可识别。
+// 单位名称不区分大小写，只有单字母的 m（分钟）与 M（月）按原样匹配
+func phraseAmount(count, unitName string) (int, byte, bool) {
+	n := 1
+	if count != "" {
+		var err error
+		if n, err = strconv.Atoi(count); err != nil {
+			return 0, 0, false
+		}
+	}
+	unit, ok := timeUnits[unitName]
+	if !ok && unitName != "m" && unitName != "M" {
+		unit, ok = timeUnits[strings.ToLower(unitName)]
+	}
+	return n, unit, ok
+}
+
+// shift 按单位前后移动时间，天、周、月与年按日历计算
+func shift(t time.Time, sign byte, n int, unit byte) time.Time {
+	if sign == '-' {
+		n = -n
+	}
+	switch unit {
+	case 's':
+		return t.Add(time.Duration(n) * time.Second)
+	case 'm':
+		return t.Add(time.Duration(n) * time.Minute)
+	case 'h':
+		return t.Add(time.Duration(n) * time.Hour)
+	case 'd':
+		return t.AddDate(0, 0, n)
+	case 'w':
+		return t.AddDate(0, 0, 7*n)
+	case 'M':
+		return addMonths(t, n)
+	case 'y':
+		return addMonths(t, 12*n)
+	}
+	return t
+}
+
+// addMonths 按月移动时间，日期超出目标月份时取该月最后一天（3月31日减一个月为2月28日或29日）
+func addMonths(t time.Time, n int) time.Time {
+	y, mo, d := t.Date()
+	last := time.Date(y, mo+time.Month(n)+1, 0, 0, 0, 0, 0, t.Location()).Day()
+	if d > last {
+		d = last
+	}
+	return time.Date(y, mo+time.Month(n), d, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+}
+
+// truncate 取时间所在周期的开始，一周从周一开始
+func truncate(t time.Time, unit byte) time.Time {
+	y, mo, d := t.Date()
+	loc := t.Location()
+	switch unit {
+	case 's':
+		return t.Truncate(time.Second)
+	case 'm':
+		return time.Date(y, mo, d, t.Hour(), t.Minute(), 0, 0, loc)
+	case 'h':
+		return time.Date(y, mo, d, t.Hour(), 0, 0, 0, loc)
+	case 'd':
+		return time.Date(y, mo, d, 0, 0, 0, 0, loc)
+	case 'w':
+		offset := (int(t.Weekday()) + 6) % 7
+		return time.Date(y, mo, d-offset, 0, 0, 0, 0, loc)
+	case 'M':
+		return time.Date(y, mo, 1, 0, 0, 0, 0, loc)
+	case 'y':
+		return time.Date(y, 1, 1, 0, 0, 0, 0, loc)
+	}
+	return t
+}
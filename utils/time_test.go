@@ -0,0 +1,165 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+var (
+	cst = time.FixedZone("CST", 8*3600)
+	// 2025-03-31 周一 10:30:45（+08:00），月末，上个月只有 28 天
+	testNow = time.Date(2025, 3, 31, 10, 30, 45, 0, cst)
+)
+
+func at(y int, mo time.Month, d, h, mi, s int) time.Time {
+	return time.Date(y, mo, d, h, mi, s, 0, cst)
+}
+
+func TestParseTimeIn(t *testing.T) {
+	cases := []struct {
+		in   string
+		want time.Time
+	}{
+		{"", time.Time{}},
+		{"  ", time.Time{}},
+
+		// Unix 时间戳：大于 1e11 按毫秒解释
+		{"1700000000", time.Unix(1700000000, 0)},
+		{"1700000000123", time.UnixMilli(1700000000123)},
+		{"99999999999", time.Unix(99999999999, 0)},
+		{"100000000001", time.UnixMilli(100000000001)},
+		{"0", time.Unix(0, 0)},
+
+		// 带时区的写法保留原时区，不带时区的按 loc 解释
+		{"2025-03-01T08:00:00Z", time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)},
+		{"2025-03-01T08:00:00+09:00", time.Date(2025, 2, 28, 23, 0, 0, 0, time.UTC)},
+		{"2025-03-01 08:00:00", at(2025, 3, 1, 8, 0, 0)},
+		{"2025-03-01T08:00", at(2025, 3, 1, 8, 0, 0)},
+		{"2025-03-01", at(2025, 3, 1, 0, 0, 0)},
+
+		// Zabbix 风格的相对时间
+		{"now", testNow},
+		{"now-2h", at(2025, 3, 31, 8, 30, 45)},
+		{"now-90s+1m", at(2025, 3, 31, 10, 30, 15)},
+		{"now-1M", at(2025, 2, 28, 10, 30, 45)},
+		{"now-1y", at(2024, 3, 31, 10, 30, 45)},
+		{"now/d", at(2025, 3, 31, 0, 0, 0)},
+		{"now-1d/d", at(2025, 3, 30, 0, 0, 0)},
+		{"now/w", at(2025, 3, 31, 0, 0, 0)},
+		{"now-1d/w", at(2025, 3, 24, 0, 0, 0)},
+		{"now/M", at(2025, 3, 1, 0, 0, 0)},
+		{"now-1M/M", at(2025, 2, 1, 0, 0, 0)},
+		{"now/y", at(2025, 1, 1, 0, 0, 0)},
+		{"now/d+8h", at(2025, 3, 31, 8, 0, 0)},
+
+		// 相对 now 的短语
+		{"last 30 minutes", at(2025, 3, 31, 10, 0, 45)},
+		{"Last Hour", at(2025, 3, 31, 9, 30, 45)},
+		{"past 2 days", at(2025, 3, 29, 10, 30, 45)},
+		{"last 2 m", at(2025, 3, 31, 10, 28, 45)},
+		{"last 2 M", at(2025, 1, 31, 10, 30, 45)},
+		{"last 2 H", at(2025, 3, 31, 8, 30, 45)},
+		{"2 hours ago", at(2025, 3, 31, 8, 30, 45)},
+		{"2  Hours   AGO", at(2025, 3, 31, 8, 30, 45)},
+		{"1 month ago", at(2025, 2, 28, 10, 30, 45)},
+		{"3 m ago", at(2025, 3, 31, 10, 27, 45)},
+		{"3 M ago", at(2024, 12, 31, 10, 30, 45)},
+		{"最近30分钟", at(2025, 3, 31, 10, 0, 45)},
+		{"过去 2 小时", at(2025, 3, 31, 8, 30, 45)},
+		{"3天前", at(2025, 3, 28, 10, 30, 45)},
+		{"1个月之前", at(2025, 2, 28, 10, 30, 45)},
+
+		// 日历周期短语取周期的开始
+		{"today", at(2025, 3, 31, 0, 0, 0)},
+		{"Yesterday", at(2025, 3, 30, 0, 0, 0)},
+		{"this week", at(2025, 3, 31, 0, 0, 0)},
+		{"previous  month", at(2025, 2, 1, 0, 0, 0)},
+		{"前天", at(2025, 3, 29, 0, 0, 0)},
+		{"上月", at(2025, 2, 1, 0, 0, 0)},
+		{"现在", testNow},
+	}
+	for _, c := range cases {
+		got, err := ParseTimeIn(c.in, cst, testNow)
+		if err != nil {
+			t.Errorf("ParseTimeIn(%q): %v", c.in, err)
+			continue
+		}
+		if !got.Equal(c.want) {
+			t.Errorf("ParseTimeIn(%q) = %s，期望 %s", c.in, got, c.want)
+		}
+	}
+}
+
+func TestParseTimeInErrors(t *testing.T) {
+	for _, in := range []string{"tomorrow", "now-2x", "now/q", "last 2 fortnights", "2025-13-01", "2 ago", "2025/03/01"} {
+		if got, err := ParseTimeIn(in, cst, testNow); err == nil {
+			t.Errorf("ParseTimeIn(%q) = %s，期望出错", in, got)
+		}
+	}
+}
+
+// TestParseTimeInLocation 相对时间与不带时区的写法按 loc 解释，与 now 所在时区无关
+func TestParseTimeInLocation(t *testing.T) {
+	utcNow := testNow.UTC() // 2025-03-31 02:30:45 UTC
+	ny := time.FixedZone("EDT", -4*3600)
+	cases := []struct {
+		in   string
+		loc  *time.Location
+		want time.Time
+	}{
+		{"now/d", cst, at(2025, 3, 31, 0, 0, 0)},
+		{"now/d", time.UTC, time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC)},
+		{"now/d", ny, time.Date(2025, 3, 30, 0, 0, 0, 0, ny)},
+		{"yesterday", ny, time.Date(2025, 3, 29, 0, 0, 0, 0, ny)},
+		{"2025-03-01", time.UTC, time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{"2025-03-01", nil, time.Date(2025, 3, 1, 0, 0, 0, 0, time.Local)},
+	}
+	for _, c := range cases {
+		got, err := ParseTimeIn(c.in, c.loc, utcNow)
+		if err != nil {
+			t.Errorf("ParseTimeIn(%q, %v): %v", c.in, c.loc, err)
+			continue
+		}
+		if !got.Equal(c.want) {
+			t.Errorf("ParseTimeIn(%q, %v) = %s，期望 %s", c.in, c.loc, got, c.want)
+		}
+	}
+	if got, _ := ParseTimeIn("1700000000", ny, utcNow); got.Location() != ny {
+		t.Errorf("时间戳应转换到 loc: %s", got)
+	}
+}
+
+func TestParseTimeRange(t *testing.T) {
+	cases := []struct {
+		from, to   string
+		start, end time.Time
+	}{
+		{"today", "", at(2025, 3, 31, 0, 0, 0), at(2025, 3, 31, 23, 59, 59)},
+		{"yesterday", "", at(2025, 3, 30, 0, 0, 0), at(2025, 3, 30, 23, 59, 59)},
+		{"now-1M/M", "", at(2025, 2, 1, 0, 0, 0), at(2025, 2, 28, 23, 59, 59)},
+		{"previous year", "", at(2024, 1, 1, 0, 0, 0), at(2024, 12, 31, 23, 59, 59)},
+		{"this week", "", at(2025, 3, 31, 0, 0, 0), at(2025, 4, 6, 23, 59, 59)},
+		{"2025-03-01", "", at(2025, 3, 1, 0, 0, 0), at(2025, 3, 1, 23, 59, 59)},
+		{"last 2 hours", "", at(2025, 3, 31, 8, 30, 45), testNow},
+		{"now-2h", "", at(2025, 3, 31, 8, 30, 45), time.Time{}},
+		{"now-7d/d", "now/d", at(2025, 3, 24, 0, 0, 0), at(2025, 3, 31, 23, 59, 59)},
+		{"2025-03-01", "2025-03-02", at(2025, 3, 1, 0, 0, 0), at(2025, 3, 2, 23, 59, 59)},
+		{"", "now-1h", time.Time{}, at(2025, 3, 31, 9, 30, 45)},
+	}
+	for _, c := range cases {
+		start, end, err := ParseTimeRange(c.from, c.to, cst, testNow)
+		if err != nil {
+			t.Errorf("ParseTimeRange(%q, %q): %v", c.from, c.to, err)
+			continue
+		}
+		if !start.Equal(c.start) || !end.Equal(c.end) {
+			t.Errorf("ParseTimeRange(%q, %q) = %s ~ %s，期望 %s ~ %s", c.from, c.to, start, end, c.start, c.end)
+		}
+	}
+
+	for _, c := range [][2]string{{"today", "yesterday"}, {"bogus", ""}, {"now", "bogus"}} {
+		if _, _, err := ParseTimeRange(c[0], c[1], cst, testNow); err == nil {
+			t.Errorf("ParseTimeRange(%q, %q) 应返回错误", c[0], c[1])
+		}
+	}
+}
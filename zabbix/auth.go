@@ -13,10 +13,25 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+
+	"zabbixMcp/metrics"
 )
 
 // Login 登录Zabbix API
 func (c *ZabbixClient) Login(ctx context.Context) error {
+	err := c.login(ctx)
+	metrics.ObserveLogin(c.Instance, false, err)
+	return err
+}
+
+// relogin 认证失效后重新登录
+func (c *ZabbixClient) relogin(ctx context.Context) error {
+	err := c.login(ctx)
+	metrics.ObserveLogin(c.Instance, true, err)
+	return err
+}
+
+func (c *ZabbixClient) login(ctx context.Context) error {
 	authType := c.getAuthType()
 	currentToken := c.getAuthToken()
 
@@ -30,8 +45,8 @@ func (c *ZabbixClient) Login(ctx context.Context) error {
 
 	// 密码认证
 	params := map[string]string{
-		"user":     c.User,
-		"password": c.Pass,
+		c.loginUserField(ctx): c.User,
+		"password":            c.Pass,
 	}
 
 	// 使用内部调用，传入空auth进行登录
@@ -51,6 +66,16 @@ func (c *ZabbixClient) Login(ctx context.Context) error {
 	return nil
 }
 
+// loginUserField user.login 的用户名参数：5.4 起为 username，6.4 起不再接受 user。
+// apiinfo.version 无需认证，探测失败时按旧版本处理。
+func (c *ZabbixClient) loginUserField(ctx context.Context) string {
+	ver, err := NewVersionDetector(c).DetectVersion(ctx)
+	if err != nil || ver.Major < 5 || (ver.Major == 5 && ver.Minor < 4) {
+		return "user"
+	}
+	return "username"
+}
+
 // Logout 登出Zabbix API
 func (c *ZabbixClient) Logout(ctx context.Context) error {
 	token := c.getAuthToken()
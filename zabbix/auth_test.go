@@ -0,0 +1,54 @@
+package zabbix_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"zabbixMcp/zabbix"
+	"zabbixMcp/zabbix/zabbixtest"
+)
+
+func TestLoginUserField(t *testing.T) {
+	cases := []struct {
+		version string
+		field   string
+	}{
+		{"4.0.0", "user"},
+		{"5.2.0", "user"},
+		{"5.4.0", "username"},
+		{"6.2.0", "username"},
+		{"6.4.0", "username"},
+		{"7.0.0", "username"},
+	}
+	for _, c := range cases {
+		t.Run(c.version, func(t *testing.T) {
+			srv := zabbixtest.NewServer(zabbixtest.Options{Version: c.version})
+			defer srv.Close()
+			client, err := zabbix.NewZabbixClientFromConfig(srv.ClientConfig())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := client.Login(context.Background()); err != nil {
+				t.Fatalf("登录失败: %v", err)
+			}
+			var params map[string]interface{}
+			for _, call := range srv.Calls() {
+				if call.Method == "user.login" {
+					if err := json.Unmarshal(call.Params, &params); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+			if params == nil {
+				t.Fatal("没有发送 user.login")
+			}
+			if params[c.field] != "Admin" {
+				t.Fatalf("user.login 参数 = %v，应使用 %s", params, c.field)
+			}
+			if len(params) != 2 {
+				t.Fatalf("user.login 参数 = %v，只应包含 %s 与 password", params, c.field)
+			}
+		})
+	}
+}
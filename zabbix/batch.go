@@ -0,0 +1,249 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-27 16:30:44
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-27 16:30:44
+ * @FilePath: \zabbix-mcp-go\zabbix\batch.go
+ * @Description: JSON-RPC 批量请求：一次往返执行多个互不依赖的调用
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+package zabbix
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"zabbixMcp/audit"
+	"zabbixMcp/logger"
+	"zabbixMcp/metrics"
+	"zabbixMcp/models"
+	"zabbixMcp/tracing"
+)
+
+// BatchCall 批量请求中的一次调用：Result 为结果的解码目标（为 nil 时丢弃结果），Err 为该条目自身的错误
+type BatchCall struct {
+	Method string
+	Params interface{}
+	Result interface{}
+	Err    error
+}
+
+// batchResult 与 pending 顺序对应的单条响应
+type batchResult struct {
+	payload json.RawMessage
+	err     error
+}
+
+// CallBatch 在一次 JSON-RPC 批量请求中发送多个调用，按 id 关联响应。
+// 返回的 error 表示整个批次失败（网络、登录等）；各条目的 API 错误写入对应的 calls[i].Err，互不影响
+func (c *ZabbixClient) CallBatch(ctx context.Context, calls []BatchCall) (err error) {
+	if len(calls) == 0 {
+		return nil
+	}
+	start := time.Now()
+	ctx, span := tracing.Start(ctx, "zabbix.CallBatch",
+		tracing.AttrInstance.String(c.Instance),
+		tracing.AttrBatchSize.Int(len(calls)),
+	)
+	keys := make([]string, len(calls))
+	lookups := make([]string, len(calls))
+	defer func() {
+		elapsed := time.Since(start)
+		for i := range calls {
+			if lookups[i] == cacheHit {
+				continue
+			}
+			callErr := calls[i].Err
+			if err != nil {
+				callErr = err
+			}
+			metrics.ObserveAPICall(c.Instance, calls[i].Method, elapsed, callErr)
+		}
+		tracing.End(span, err)
+	}()
+	// 命中缓存的条目不发送
+	payloads := make([]json.RawMessage, len(calls))
+	var pending []int
+	var methods []string
+	for i := range calls {
+		calls[i].Err = nil
+		if key, ok := c.cache.key(calls[i].Method, calls[i].Params); ok {
+			keys[i] = key
+			if payloads[i], lookups[i] = c.cacheLookup(ctx, calls[i].Method, key); lookups[i] == cacheHit {
+				continue
+			}
+		}
+		pending = append(pending, i)
+		methods = append(methods, calls[i].Method)
+		audit.RecordCall(ctx, c.Instance, calls[i].Method)
+	}
+
+	if len(pending) > 0 {
+		logger.L().Infof("batch call methods:%v", methods)
+		if err = c.sendBatch(ctx, calls, pending, payloads); err != nil {
+			return err
+		}
+		for _, i := range pending {
+			switch {
+			case calls[i].Err != nil:
+			case lookups[i] != "":
+				c.cache.put(calls[i].Method, keys[i], payloads[i])
+			default:
+				c.invalidateCache(calls[i].Method)
+			}
+		}
+	}
+
+	var version *VersionInfo
+	if !IsRawResponse(ctx) {
+		if v, verr := NewVersionDetector(c).DetectVersion(ctx); verr == nil {
+			version = v
+		}
+	}
+	for i := range calls {
+		call := &calls[i]
+		if call.Err != nil {
+			logger.L().Errorf("batch call method: %s Failed, err: %v", call.Method, call.Err)
+			continue
+		}
+		payload := payloads[i]
+		if version != nil {
+			payload = NormalizeResponse(call.Method, version, payload)
+		}
+		if call.Result != nil {
+			call.Err = json.Unmarshal(payload, call.Result)
+		}
+	}
+	return nil
+}
+
+// sendBatch 发送 pending 指定的条目；会话失效时重新登录，只重发失败的条目，已成功的变更不会重复执行
+func (c *ZabbixClient) sendBatch(ctx context.Context, calls []BatchCall, pending []int, payloads []json.RawMessage) error {
+	authToken, err := c.ensureAuthToken(ctx)
+	if err != nil {
+		return err
+	}
+	if err := c.batch(ctx, calls, pending, authToken, payloads); err != nil {
+		return err
+	}
+	if c.getAuthType() == "token" {
+		return nil
+	}
+	var expired []int
+	for _, i := range pending {
+		if isAuthError(calls[i].Err) {
+			expired = append(expired, i)
+		}
+	}
+	if len(expired) == 0 {
+		return nil
+	}
+	if err := c.relogin(ctx); err != nil {
+		return err
+	}
+	return c.batch(ctx, calls, expired, c.getAuthToken(), payloads)
+}
+
+// batch 发送 pending 指定的条目并写回结果；与 call 一样，仅当所有条目都因认证方式被拒绝时换另一种认证方式重试
+func (c *ZabbixClient) batch(ctx context.Context, calls []BatchCall, pending []int, auth string, payloads []json.RawMessage) error {
+	header := c.prefersHeaderAuth()
+	results, err := c.postBatch(ctx, calls, pending, auth, header)
+	if err != nil {
+		return err
+	}
+	if allAuthRejected(results) {
+		if alt, altErr := c.postBatch(ctx, calls, pending, auth, !header); altErr == nil && !allAuthRejected(alt) {
+			c.setHeaderPreference(!header)
+			metrics.ObserveAuthFallback(c.Instance, !header)
+			results = alt
+		}
+	}
+	for k, i := range pending {
+		calls[i].Err = results[k].err
+		payloads[i] = results[k].payload
+	}
+	return nil
+}
+
+// postBatch 以 1..n 作为请求 id 发送批量请求，返回按 pending 顺序排列的结果
+func (c *ZabbixClient) postBatch(ctx context.Context, calls []BatchCall, pending []int, auth string, header bool) ([]batchResult, error) {
+	requests := make([]models.JSONRPCRequest, len(pending))
+	for k, i := range pending {
+		requests[k] = models.JSONRPCRequest{
+			JSONRPC: "2.0",
+			Method:  calls[i].Method,
+			Params:  calls[i].Params,
+			ID:      k + 1,
+		}
+		if !header {
+			requests[k].Auth = auth
+		}
+	}
+	requestData, err := json.Marshal(requests)
+	if err != nil {
+		return nil, fmt.Errorf("序列化请求失败: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.apiURL, bytes.NewReader(requestData))
+	if err != nil {
+		return nil, fmt.Errorf("创建HTTP请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if header && auth != "" {
+		req.Header.Set("Authorization", "Bearer "+auth)
+	}
+
+	body, status, err := c.roundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body = bytes.TrimSpace(body)
+	// 整个批次无效（解析失败、不支持批量等）时服务器返回单个错误对象
+	if len(body) > 0 && body[0] == '{' {
+		var single models.JSONRPCResponse
+		if err := json.Unmarshal(body, &single); err != nil {
+			return nil, fmt.Errorf("%w: 解析响应失败(HTTP %d): %w", ErrInstanceUnavailable, status, err)
+		}
+		if single.Error != nil {
+			return nil, single.Error
+		}
+		return nil, fmt.Errorf("%w: 批量请求返回了单个响应(HTTP %d)", ErrInstanceUnavailable, status)
+	}
+	var responses []models.JSONRPCResponse
+	if err := json.Unmarshal(body, &responses); err != nil {
+		return nil, fmt.Errorf("%w: 解析响应失败(HTTP %d): %w", ErrInstanceUnavailable, status, err)
+	}
+	byID := make(map[int]models.JSONRPCResponse, len(responses))
+	for _, resp := range responses {
+		byID[resp.ID] = resp
+	}
+	results := make([]batchResult, len(pending))
+	for k := range pending {
+		resp, ok := byID[k+1]
+		switch {
+		case !ok:
+			results[k].err = fmt.Errorf("%w: 批量响应中缺少 id=%d 的结果", ErrInstanceUnavailable, k+1)
+		case resp.Error != nil:
+			results[k].err = resp.Error
+		case resp.Result == nil:
+			results[k].payload = json.RawMessage("null")
+		default:
+			results[k].payload = resp.Result
+		}
+	}
+	return results, nil
+}
+
+// allAuthRejected 所有条目都因认证方式被拒绝（认证在方法执行之前检查，这种情况下没有条目被执行）
+func allAuthRejected(results []batchResult) bool {
+	for _, r := range results {
+		rpcErr, ok := r.err.(*models.RPCError)
+		if !ok || !authMethodRejected(rpcErr) {
+			return false
+		}
+	}
+	return len(results) > 0
+}
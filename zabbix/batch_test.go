@@ -0,0 +1,124 @@
+package zabbix_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"zabbixMcp/models"
+	"zabbixMcp/zabbix"
+	"zabbixMcp/zabbix/zabbixtest"
+)
+
+// TestCallBatchMatchesByID 服务器乱序返回时按 id 把结果写回对应条目，单条失败不影响其他条目，结果同样归一化
+func TestCallBatchMatchesByID(t *testing.T) {
+	for _, v := range serverVersions {
+		t.Run(v, func(t *testing.T) {
+			srv := zabbixtest.NewServer(zabbixtest.Options{Version: v, ReverseBatch: true})
+			defer srv.Close()
+			client, err := zabbix.NewZabbixClientFromConfig(srv.ClientConfig())
+			if err != nil {
+				t.Fatal(err)
+			}
+			srv.ResetCalls()
+
+			var admin, guest, groups []map[string]interface{}
+			calls := []zabbix.BatchCall{
+				{Method: "user.get", Params: map[string]interface{}{"output": "extend", "userids": []string{"1"}}, Result: &admin},
+				{Method: "usergroup.get", Params: map[string]interface{}{"output": "extend", "usrgrpids": []string{"8"}}, Result: &groups},
+				{Method: "nosuch.method", Params: map[string]interface{}{}},
+				{Method: "user.get", Params: map[string]interface{}{"output": "extend", "userids": []string{"2"}}, Result: &guest},
+			}
+			if err := client.CallBatch(context.Background(), calls); err != nil {
+				t.Fatal(err)
+			}
+
+			if want := []string{"user.get", "usergroup.get", "nosuch.method", "user.get"}; !reflect.DeepEqual(srv.Methods(), want) {
+				t.Errorf("服务器收到的调用 = %v，期望按条目顺序 %v", srv.Methods(), want)
+			}
+			if calls[0].Err != nil || len(admin) != 1 || admin[0]["username"] != "Admin" || admin[0]["roleid"] != "3" {
+				t.Errorf("第 1 条 = %v, %v，期望归一化后的 Admin", admin, calls[0].Err)
+			}
+			if calls[1].Err != nil || len(groups) != 1 || groups[0]["name"] != "Guests" {
+				t.Errorf("第 2 条 = %v, %v，期望 Guests", groups, calls[1].Err)
+			}
+			var rpcErr *models.RPCError
+			if !errors.As(calls[2].Err, &rpcErr) || rpcErr.Code != zabbixtest.CodeMethodNotFound {
+				t.Errorf("第 3 条应单独返回 -32601: %v", calls[2].Err)
+			}
+			if calls[3].Err != nil || len(guest) != 1 || guest[0]["username"] != "guest" {
+				t.Errorf("第 4 条 = %v, %v，期望 guest", guest, calls[3].Err)
+			}
+		})
+	}
+}
+
+// TestCallBatchPartialErrors 参数错误只影响对应条目，其他条目的结果照常解码
+func TestCallBatchPartialErrors(t *testing.T) {
+	srv := zabbixtest.NewServer(zabbixtest.Options{ReverseBatch: true})
+	defer srv.Close()
+	client, err := zabbix.NewZabbixClientFromConfig(srv.ClientConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.FailNext("usergroup.get", -32500, "Application error.", "No permissions to referred object or it does not exist!")
+
+	var users []map[string]interface{}
+	var groups []map[string]interface{}
+	calls := []zabbix.BatchCall{
+		{Method: "user.create", Params: map[string]interface{}{"nosuchparam": 1}},
+		{Method: "usergroup.get", Params: map[string]interface{}{"output": "extend"}, Result: &groups},
+		{Method: "user.get", Params: map[string]interface{}{"output": []string{"userid"}, "userids": []string{"1"}}, Result: &users},
+	}
+	if err := client.CallBatch(context.Background(), calls); err != nil {
+		t.Fatal(err)
+	}
+	if got := zabbix.ClassifyError(calls[0].Err).Code; got != models.CodeInvalidParams {
+		t.Errorf("第 1 条错误分类 = %s，期望 %s (%v)", got, models.CodeInvalidParams, calls[0].Err)
+	}
+	if got := zabbix.ClassifyError(calls[1].Err).Code; got != models.CodeNotFound {
+		t.Errorf("第 2 条错误分类 = %s，期望 %s (%v)", got, models.CodeNotFound, calls[1].Err)
+	}
+	if groups != nil {
+		t.Errorf("失败条目不应写入结果: %v", groups)
+	}
+	if calls[2].Err != nil || len(users) != 1 || users[0]["userid"] != "1" {
+		t.Errorf("第 3 条 = %v, %v", users, calls[2].Err)
+	}
+}
+
+// TestCallBatchRelogin 会话失效时（与认证方式被拒绝无法区分，先换一种认证方式重试）重新登录一次，
+// 之后只重发失败的条目
+func TestCallBatchRelogin(t *testing.T) {
+	srv := zabbixtest.NewServer(zabbixtest.Options{Version: "7.0.0", ReverseBatch: true})
+	defer srv.Close()
+	client, err := zabbix.NewZabbixClientFromConfig(srv.ClientConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.ExpireSessions()
+	srv.ResetCalls()
+
+	var users, groups []map[string]interface{}
+	calls := []zabbix.BatchCall{
+		{Method: "user.get", Params: map[string]interface{}{"output": []string{"userid"}}, Result: &users},
+		{Method: "usergroup.get", Params: map[string]interface{}{"output": []string{"usrgrpid"}}, Result: &groups},
+	}
+	if err := client.CallBatch(context.Background(), calls); err != nil {
+		t.Fatal(err)
+	}
+	if calls[0].Err != nil || calls[1].Err != nil || len(users) == 0 || len(groups) == 0 {
+		t.Fatalf("重新登录后结果 = %v %v, 错误 %v %v", users, groups, calls[0].Err, calls[1].Err)
+	}
+	methods := srv.Methods()
+	logins := 0
+	for _, m := range methods {
+		if m == "user.login" {
+			logins++
+		}
+	}
+	if want := []string{"user.login", "user.get", "usergroup.get"}; logins != 1 || !reflect.DeepEqual(methods[len(methods)-3:], want) {
+		t.Errorf("服务器收到的调用 = %v，期望只登录一次并在之后重发两个条目", methods)
+	}
+}
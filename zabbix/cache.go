@@ -0,0 +1,238 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-28 15:20:06
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-28 15:20:06
+ * @FilePath: \zabbix-mcp-go\zabbix\cache.go
+ * @Description: 读多写少的元数据响应缓存：按方法与规范化参数缓存 *.get 结果，经本服务的变更调用会使相关缓存失效
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+package zabbix
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+)
+
+// versionMethod 版本信息同样存放在响应缓存中，且永不过期
+const versionMethod = "apiinfo.version"
+
+// CacheConfig 响应缓存配置。Methods 为缓存的方法及其有效期，有效期为 0 时使用 TTL；
+// 未配置时只缓存 apiinfo.version
+type CacheConfig struct {
+	TTL        time.Duration
+	MaxEntries int // 每个实例最多缓存的响应数，0 表示不限制
+	Methods    map[string]time.Duration
+}
+
+// cacheDependents 对象发生变更时，结果中可能内嵌该对象的其他对象（如 hostgroup.get 的 selectHosts），
+// 这些对象的缓存一并失效；不在表中的对象变更时清空整个缓存（版本信息除外）
+var cacheDependents = map[string][]string{
+	"host":          {"host", "hostgroup", "template", "item", "trigger"},
+	"hostgroup":     {"hostgroup", "host", "template"},
+	"template":      {"template", "templategroup", "host", "hostgroup"},
+	"templategroup": {"templategroup", "template"},
+	"item":          {"item", "host", "template", "trigger"},
+	"trigger":       {"trigger", "host", "template", "item"},
+	"user":          {"user", "usergroup", "role", "mediatype"},
+	"usergroup":     {"usergroup", "user"},
+	"role":          {"role", "user"},
+	"mediatype":     {"mediatype", "user"},
+	"valuemap":      {"valuemap", "item"},
+}
+
+// readOnlyMethods 不以 .get 结尾、但也不修改数据的方法
+var readOnlyMethods = map[string]bool{
+	versionMethod:                 true,
+	"user.login":                  true,
+	"user.logout":                 true,
+	"user.checkAuthentication":    true,
+	"configuration.export":        true,
+	"configuration.importcompare": true,
+}
+
+// 缓存查找结果，用于指标与链路追踪
+const (
+	cacheHit    = "hit"
+	cacheMiss   = "miss"
+	cacheBypass = "bypass"
+)
+
+type cacheEntry struct {
+	method  string
+	payload json.RawMessage
+	stored  time.Time
+	expires time.Time // 零值表示永不过期
+}
+
+// responseCache 单个实例的响应缓存，键为方法名加规范化后的参数
+type responseCache struct {
+	mu      sync.Mutex
+	ttls    map[string]time.Duration // 方法 -> 有效期，0 表示永不过期
+	max     int
+	entries map[string]cacheEntry
+}
+
+func newResponseCache(cfg *CacheConfig) *responseCache {
+	c := &responseCache{
+		ttls:    map[string]time.Duration{versionMethod: 0},
+		entries: make(map[string]cacheEntry),
+	}
+	if cfg == nil {
+		return c
+	}
+	c.max = cfg.MaxEntries
+	for method, ttl := range cfg.Methods {
+		if ttl <= 0 {
+			ttl = cfg.TTL
+		}
+		if ttl > 0 && strings.HasSuffix(method, ".get") {
+			c.ttls[method] = ttl
+		}
+	}
+	return c
+}
+
+// key 返回方法与参数对应的缓存键；方法未配置缓存或参数无法序列化时 ok 为 false。
+// map 序列化时按键排序，nil、[] 与 {} 视为同一组空参数
+func (c *responseCache) key(method string, params interface{}) (key string, ok bool) {
+	if c == nil {
+		return "", false
+	}
+	if _, ok := c.ttls[method]; !ok {
+		return "", false
+	}
+	data, err := json.Marshal(params)
+	if err != nil {
+		return "", false
+	}
+	switch string(data) {
+	case "null", "[]", "{}":
+		return method, true
+	}
+	return method + "\x00" + string(data), true
+}
+
+func (c *responseCache) get(key string) (json.RawMessage, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if !e.expires.IsZero() && time.Now().After(e.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return e.payload, true
+}
+
+func (c *responseCache) put(method, key string, payload json.RawMessage) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	e := cacheEntry{method: method, payload: payload, stored: now}
+	if ttl := c.ttls[method]; ttl > 0 {
+		e.expires = now.Add(ttl)
+	}
+	if _, exists := c.entries[key]; !exists && c.max > 0 && len(c.entries) >= c.max {
+		c.evict(now)
+	}
+	c.entries[key] = e
+}
+
+func (c *responseCache) remove(key string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+}
+
+// evict 腾出一个位置：先清理已过期的条目，仍然满时淘汰最早写入的条目（版本信息不淘汰）
+func (c *responseCache) evict(now time.Time) {
+	for k, e := range c.entries {
+		if !e.expires.IsZero() && now.After(e.expires) {
+			delete(c.entries, k)
+		}
+	}
+	if len(c.entries) < c.max {
+		return
+	}
+	oldest := ""
+	var at time.Time
+	for k, e := range c.entries {
+		if e.method == versionMethod {
+			continue
+		}
+		if oldest == "" || e.stored.Before(at) {
+			oldest, at = k, e.stored
+		}
+	}
+	if oldest != "" {
+		delete(c.entries, oldest)
+	}
+}
+
+// invalidate 成功执行变更方法后清除相关对象的缓存，返回清除的条目数
+func (c *responseCache) invalidate(method string) int {
+	if c == nil || !isMutation(method) {
+		return 0
+	}
+	object, _, _ := strings.Cut(method, ".")
+	related, known := cacheDependents[object]
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := 0
+	for k, e := range c.entries {
+		if e.method == versionMethod {
+			continue
+		}
+		if known {
+			entryObject, _, _ := strings.Cut(e.method, ".")
+			if !containsString(related, entryObject) {
+				continue
+			}
+		}
+		delete(c.entries, k)
+		n++
+	}
+	return n
+}
+
+// isMutation 判断方法是否会修改 Zabbix 中的数据
+func isMutation(method string) bool {
+	return !strings.HasSuffix(method, ".get") && !readOnlyMethods[method]
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+type cacheBypassKey struct{}
+
+// WithCacheBypass 标记本次调用不读取响应缓存，直接请求 Zabbix；取得的结果仍会刷新缓存
+func WithCacheBypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheBypassKey{}, true)
+}
+
+// IsCacheBypassed 判断是否跳过响应缓存
+func IsCacheBypassed(ctx context.Context) bool {
+	bypass, _ := ctx.Value(cacheBypassKey{}).(bool)
+	return bypass
+}
@@ -0,0 +1,156 @@
+package zabbix
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func newTestCache(max int, methods ...string) *responseCache {
+	cfg := &CacheConfig{TTL: time.Minute, MaxEntries: max, Methods: map[string]time.Duration{}}
+	for _, m := range methods {
+		cfg.Methods[m] = 0
+	}
+	return newResponseCache(cfg)
+}
+
+func TestCacheKey(t *testing.T) {
+	c := newTestCache(0, "user.get", "user.create")
+
+	a, ok := c.key("user.get", map[string]interface{}{"output": "extend", "userids": []string{"1"}})
+	b, _ := c.key("user.get", map[string]interface{}{"userids": []string{"1"}, "output": "extend"})
+	if !ok || a != b {
+		t.Errorf("参数顺序不同的键 = %q / %q，应相同", a, b)
+	}
+	if other, _ := c.key("user.get", map[string]interface{}{"output": "extend", "userids": []string{"2"}}); other == a {
+		t.Error("参数不同的键应不同")
+	}
+	if other, _ := c.key("usergroup.get", map[string]interface{}{"output": "extend", "userids": []string{"1"}}); other == a {
+		t.Error("方法不同的键应不同")
+	}
+
+	var empty []string
+	for _, params := range []interface{}{nil, map[string]interface{}{}, []interface{}{}, empty} {
+		if k, ok := c.key("user.get", params); !ok || k != "user.get" {
+			t.Errorf("空参数 %#v 的键 = %q, %v，期望 user.get", params, k, ok)
+		}
+	}
+
+	for method, params := range map[string]interface{}{
+		"host.get":    map[string]interface{}{},               // 未配置缓存
+		"user.create": map[string]interface{}{},               // 只缓存 *.get
+		"user.get":    map[string]interface{}{"x": func() {}}, // 无法序列化
+	} {
+		if _, ok := c.key(method, params); ok {
+			t.Errorf("%s 不应缓存", method)
+		}
+	}
+	if _, ok := newResponseCache(nil).key(versionMethod, nil); !ok {
+		t.Error("未配置缓存时仍应缓存版本信息")
+	}
+	var nilCache *responseCache
+	if _, ok := nilCache.key("user.get", nil); ok {
+		t.Error("nil 缓存不应缓存")
+	}
+}
+
+func TestCacheTTL(t *testing.T) {
+	c := newResponseCache(&CacheConfig{TTL: time.Hour, Methods: map[string]time.Duration{"user.get": 20 * time.Millisecond, "role.get": 0}})
+	c.put("user.get", "user.get", json.RawMessage(`[1]`))
+	c.put("role.get", "role.get", json.RawMessage(`[2]`))
+	c.put(versionMethod, versionMethod, json.RawMessage(`"7.0.0"`))
+	if got, ok := c.get("user.get"); !ok || string(got) != "[1]" {
+		t.Fatalf("写入后应命中: %s %v", got, ok)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if _, ok := c.get("user.get"); ok {
+		t.Error("过期的条目不应命中")
+	}
+	if _, ok := c.entries["user.get"]; ok {
+		t.Error("过期的条目应在读取时删除")
+	}
+	if _, ok := c.get("role.get"); !ok {
+		t.Error("有效期为 0 的方法应使用默认 TTL")
+	}
+	if e := c.entries["role.get"]; e.expires.Sub(e.stored) != time.Hour {
+		t.Errorf("role.get 有效期 = %v，期望 1h", e.expires.Sub(e.stored))
+	}
+	if e := c.entries[versionMethod]; !e.expires.IsZero() {
+		t.Error("版本信息不应过期")
+	}
+}
+
+func TestCacheEviction(t *testing.T) {
+	c := newTestCache(3, "user.get", "host.get")
+	c.put(versionMethod, versionMethod, json.RawMessage(`"7.0.0"`))
+	c.put("user.get", "u1", json.RawMessage(`1`))
+	time.Sleep(time.Millisecond)
+	c.put("user.get", "u2", json.RawMessage(`2`))
+	time.Sleep(time.Millisecond)
+
+	// 覆盖已有条目不淘汰
+	c.put("user.get", "u1", json.RawMessage(`11`))
+	if len(c.entries) != 3 {
+		t.Fatalf("覆盖已有条目后条目数 = %d，期望 3", len(c.entries))
+	}
+
+	// 已满时淘汰最早写入的条目，版本信息不淘汰
+	c.put("host.get", "h1", json.RawMessage(`3`))
+	if _, ok := c.get("u2"); ok {
+		t.Error("最早写入的 u2 应被淘汰")
+	}
+	for _, k := range []string{versionMethod, "u1", "h1"} {
+		if _, ok := c.get(k); !ok {
+			t.Errorf("%s 不应被淘汰", k)
+		}
+	}
+
+	// 优先清理已过期的条目
+	c.entries["u1"] = cacheEntry{method: "user.get", stored: time.Now(), expires: time.Now().Add(-time.Second)}
+	c.put("host.get", "h2", json.RawMessage(`4`))
+	for _, k := range []string{versionMethod, "h1", "h2"} {
+		if _, ok := c.get(k); !ok {
+			t.Errorf("清理过期条目后 %s 应保留", k)
+		}
+	}
+}
+
+// TestCacheInvalidate 变更方法按 cacheDependents 清除相关对象的缓存
+func TestCacheInvalidate(t *testing.T) {
+	methods := []string{"user.get", "usergroup.get", "role.get", "mediatype.get", "host.get", "hostgroup.get", "item.get"}
+	cases := []struct {
+		method  string
+		dropped []string
+	}{
+		{"user.update", []string{"user.get", "usergroup.get", "role.get", "mediatype.get"}},
+		{"usergroup.create", []string{"usergroup.get", "user.get"}},
+		{"host.delete", []string{"host.get", "hostgroup.get", "item.get"}},
+		{"hostgroup.massadd", []string{"hostgroup.get", "host.get"}},
+		{"maintenance.create", methods}, // 不在表中的对象变更时清空
+		{"user.get", nil},
+		{"user.login", nil},
+		{"configuration.export", nil},
+	}
+	for _, c := range cases {
+		t.Run(c.method, func(t *testing.T) {
+			cache := newTestCache(0, methods...)
+			cache.put(versionMethod, versionMethod, json.RawMessage(`"7.0.0"`))
+			for _, m := range methods {
+				cache.put(m, m, json.RawMessage(`[]`))
+			}
+			if n := cache.invalidate(c.method); n != len(c.dropped) {
+				t.Errorf("清除条目数 = %d，期望 %d", n, len(c.dropped))
+			}
+			for _, m := range methods {
+				_, ok := cache.get(m)
+				if dropped := containsString(c.dropped, m); ok == dropped {
+					t.Errorf("%s 后 %s 缓存仍存在 = %v，期望 %v", c.method, m, ok, !dropped)
+				}
+			}
+			if _, ok := cache.get(versionMethod); !ok {
+				t.Error("版本信息不应失效")
+			}
+		})
+	}
+}
@@ -22,8 +22,16 @@ import (
 	"sync"
 	"time"
 
+	"zabbixMcp/audit"
 	"zabbixMcp/logger"
+	"zabbixMcp/metrics"
 	"zabbixMcp/models"
+	"zabbixMcp/tracing"
+	"zabbixMcp/utils"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
 )
 
 type ZabbixClient struct {
@@ -38,9 +46,10 @@ type ZabbixClient struct {
 	HTTPClient       *http.Client
 	mu               sync.Mutex
 	preferHeaderAuth bool
-	// 缓存检测到的版本（防止频繁请求）
-	cachedVersion *VersionInfo
-	cacheLock     sync.RWMutex
+	// 响应缓存，检测到的版本也存放在这里（防止频繁请求）
+	cache *responseCache
+	// 非空时录制每次请求/响应（见 fixture.go）
+	recorder *fixtureRecorder
 }
 
 // NewZabbixClient 创建新的Zabbix客户端
@@ -64,6 +73,7 @@ func NewZabbixClient(name, rawURL, user, pass string, timeout int) (*ZabbixClien
 		HTTPClient: &http.Client{
 			Timeout: HTTPTimeout,
 		},
+		cache: newResponseCache(nil),
 	}, nil
 }
 
@@ -77,6 +87,12 @@ type ClientConfig struct {
 	AuthType string // "password" 或 "token"
 	Timeout  int    // HTTP 超时（秒），0 表示使用默认值
 	ServerTZ string // 可选，设置服务器时区，空则保持默认
+	// RecordDir 非空时把脱敏后的请求/响应录制到该目录，用于生成回放 fixture
+	RecordDir string
+	// Transport 可选，替换 HTTP 传输层（例如 zabbixtest.ReplayTransport）
+	Transport http.RoundTripper
+	// Cache 可选，*.get 响应缓存；为 nil 时只缓存版本信息
+	Cache *CacheConfig
 }
 
 // NewZabbixClientFromConfig 根据 ClientConfig 创建并初始化一个 *ZabbixClient。
@@ -92,6 +108,15 @@ func NewZabbixClientFromConfig(cfg ClientConfig) (*ZabbixClient, error) {
 	if cfg.Token != "" {
 		cli.SetAuthToken(cfg.Token)
 	}
+	if cfg.Transport != nil {
+		cli.HTTPClient.Transport = cfg.Transport
+	}
+	if cfg.Cache != nil {
+		cli.cache = newResponseCache(cfg.Cache)
+	}
+	if cfg.RecordDir != "" {
+		cli.recorder = newFixtureRecorder(cfg.RecordDir, cfg.Instance)
+	}
 	// 时区使用配置中的值，如果为空则使用本地时区
 	cli.SetServerTimezone(cfg.ServerTZ)
 	if err := cli.Login(context.Background()); err != nil {
@@ -106,55 +131,106 @@ func NewZabbixClientFromConfig(cfg ClientConfig) (*ZabbixClient, error) {
 	return cli, nil
 }
 
-// SetServerTimezone 设置服务器时区
+// SetServerTimezone 设置服务器时区，用于解析工具参数中的相对时间与本地化结果中的时间；
+// 无法识别的时区记录警告并使用本地时区
 func (c *ZabbixClient) SetServerTimezone(tz string) {
 	if tz == "" {
 		tz = time.Local.String()
 	}
+	if _, err := utils.LoadLocation(tz); err != nil {
+		logger.L().Warnf("实例 %s 的时区 %q 无效，使用本地时区: %v", c.Instance, tz, err)
+		tz = time.Local.String()
+	}
 	c.ServerTZ = tz
 }
 
-// 获取客户端缓存的版本（返回拷贝以防外部修改）
+// Location 服务器时区
+func (c *ZabbixClient) Location() *time.Location {
+	loc, err := utils.LoadLocation(c.ServerTZ)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+// 获取客户端缓存的版本：即响应缓存中 apiinfo.version 的结果，每次解析出新的拷贝
 func (c *ZabbixClient) GetCachedVersion() *VersionInfo {
-	c.cacheLock.RLock()
-	defer c.cacheLock.RUnlock()
-	if c.cachedVersion == nil {
+	payload, ok := c.cache.get(versionMethod)
+	if !ok {
+		return nil
+	}
+	var full string
+	if err := json.Unmarshal(payload, &full); err != nil {
+		return nil
+	}
+	v, err := NewVersionDetector(c).parseVersion(full)
+	if err != nil {
 		return nil
 	}
-	v := *c.cachedVersion
-	return &v
+	v.Full = full
+	return v
 }
 
-// 设置客户端缓存版本（存储拷贝）
+// 设置客户端缓存版本（写入响应缓存，永不过期）
 func (c *ZabbixClient) SetCachedVersion(v *VersionInfo) {
-	c.cacheLock.Lock()
-	defer c.cacheLock.Unlock()
 	if v == nil {
-		c.cachedVersion = nil
+		c.cache.remove(versionMethod)
 		return
 	}
-	vv := *v
-	c.cachedVersion = &vv
+	payload, _ := json.Marshal(v.Full)
+	c.cache.put(versionMethod, versionMethod, payload)
 }
 
 // 清除缓存的版本信息
 func (c *ZabbixClient) ClearCachedVersion() {
-	c.cacheLock.Lock()
-	defer c.cacheLock.Unlock()
-	c.cachedVersion = nil
+	c.cache.remove(versionMethod)
 }
 
-func (c *ZabbixClient) Call(ctx context.Context, method string, params interface{}, result interface{}) error {
+// Call 调用 Zabbix API。配置了缓存的 *.get 方法先查响应缓存，命中时不访问 Zabbix；
+// 变更方法成功后清除相关对象的缓存
+func (c *ZabbixClient) Call(ctx context.Context, method string, params interface{}, result interface{}) (err error) {
+	start := time.Now()
+	ctx, span := tracing.Start(ctx, "zabbix.Call "+method,
+		tracing.AttrInstance.String(c.Instance),
+		tracing.AttrMethod.String(method),
+	)
+	if v := c.GetCachedVersion(); v != nil {
+		span.SetAttributes(tracing.AttrVersion.String(v.Full))
+	}
+	var payload json.RawMessage
+	lookup := ""
+	defer func() {
+		if lookup != cacheHit {
+			metrics.ObserveAPICall(c.Instance, method, time.Since(start), err)
+		}
+		if lookup != "" {
+			span.SetAttributes(tracing.AttrCache.String(lookup))
+		}
+		span.SetAttributes(tracing.AttrResultSize.Int(len(payload)))
+		var rpcErr *models.RPCError
+		if errors.As(err, &rpcErr) {
+			span.SetAttributes(tracing.AttrErrorCode.Int(rpcErr.Code))
+		}
+		tracing.End(span, err)
+	}()
+	key, cacheable := c.cache.key(method, params)
+	if cacheable {
+		payload, lookup = c.cacheLookup(ctx, method, key)
+		if lookup == cacheHit {
+			return c.decode(ctx, method, payload, result)
+		}
+	}
 	authToken, err := c.ensureAuthToken(ctx)
 	if err != nil {
 		return err
 	}
-	logger.L().Infof("call method:%s, params:%v, result:%v", method, params, result)
-	payload, err := c.call(ctx, method, params, authToken)
+	logger.L().Infof("call method:%s, params:%s", method, logParams(params))
+	audit.RecordCall(ctx, c.Instance, method)
+	payload, err = c.call(ctx, method, params, authToken)
 	if err != nil {
 		logger.L().Errorf("call method: %s Failed, err: %v", method, err)
 		if isAuthError(err) && c.getAuthType() != "token" {
-			if err := c.Login(ctx); err != nil {
+			if err := c.relogin(ctx); err != nil {
 				return err
 			}
 			if payload, err = c.call(ctx, method, params, c.getAuthToken()); err != nil {
@@ -164,6 +240,43 @@ func (c *ZabbixClient) Call(ctx context.Context, method string, params interface
 			return err
 		}
 	}
+	// 缓存保存未归一化的原始结果，raw 与归一化两种读取方式都能复用
+	if cacheable {
+		c.cache.put(method, key, payload)
+	} else {
+		c.invalidateCache(method)
+	}
+	return c.decode(ctx, method, payload, result)
+}
+
+// cacheLookup 查找响应缓存，返回命中的结果与查找结果（hit、miss 或 bypass）
+func (c *ZabbixClient) cacheLookup(ctx context.Context, method, key string) (json.RawMessage, string) {
+	lookup := cacheMiss
+	var payload json.RawMessage
+	if IsCacheBypassed(ctx) {
+		lookup = cacheBypass
+	} else if hit, ok := c.cache.get(key); ok {
+		lookup, payload = cacheHit, hit
+	}
+	metrics.ObserveCache(c.Instance, method, lookup)
+	return payload, lookup
+}
+
+// invalidateCache 变更方法成功后清除相关缓存
+func (c *ZabbixClient) invalidateCache(method string) {
+	if n := c.cache.invalidate(method); n > 0 {
+		metrics.ObserveCacheInvalidation(c.Instance, method, n)
+		logger.L().Debugf("%s 执行 %s 后清除 %d 条缓存", c.Instance, method, n)
+	}
+}
+
+// decode 按实例版本归一化响应（raw 模式除外）并解码到 result
+func (c *ZabbixClient) decode(ctx context.Context, method string, payload json.RawMessage, result interface{}) error {
+	if !IsRawResponse(ctx) {
+		if v, verr := NewVersionDetector(c).DetectVersion(ctx); verr == nil {
+			payload = NormalizeResponse(method, v, payload)
+		}
+	}
 	if result == nil {
 		return nil
 	}
@@ -195,9 +308,10 @@ func (c *ZabbixClient) call(ctx context.Context, method string, params interface
 
 	if payload, err := first(ctx, method, params, auth); err == nil {
 		return payload, nil
-	} else if _, ok := err.(*models.RPCError); ok && second != nil {
+	} else if rpcErr, ok := err.(*models.RPCError); ok && second != nil && authMethodRejected(rpcErr) {
 		if altPayload, altErr := second(ctx, method, params, auth); altErr == nil {
 			c.setHeaderPreference(!primaryHeader)
+			metrics.ObserveAuthFallback(c.Instance, !primaryHeader)
 			return altPayload, nil
 		}
 		return nil, err
@@ -255,20 +369,14 @@ func (c *ZabbixClient) callWithHeaderAuth(ctx context.Context, method string, pa
 }
 
 func (c *ZabbixClient) doRequest(req *http.Request) (json.RawMessage, error) {
-	resp, err := c.HTTPClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("HTTP请求失败: %w", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
+	body, status, err := c.roundTrip(req)
 	if err != nil {
-		return nil, fmt.Errorf("读取响应失败: %w", err)
+		return nil, err
 	}
 
 	var response models.JSONRPCResponse
 	if err := json.Unmarshal(body, &response); err != nil {
-		return nil, fmt.Errorf("解析响应失败: %w", err)
+		return nil, fmt.Errorf("%w: 解析响应失败(HTTP %d): %w", ErrInstanceUnavailable, status, err)
 	}
 
 	if response.Error != nil {
@@ -280,6 +388,41 @@ func (c *ZabbixClient) doRequest(req *http.Request) (json.RawMessage, error) {
 	return response.Result, nil
 }
 
+// roundTrip 发送 HTTP 请求并读取响应体，单个请求与批量请求共用
+func (c *ZabbixClient) roundTrip(req *http.Request) (body []byte, status int, err error) {
+	ctx, span := tracing.Start(req.Context(), "zabbix.doRequest",
+		tracing.AttrInstance.String(c.Instance),
+		attribute.String("http.request.method", req.Method),
+		attribute.String("url.full", req.URL.String()),
+	)
+	defer func() { tracing.End(span, err) }()
+	req = req.WithContext(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	var requestData []byte
+	if c.recorder != nil && req.GetBody != nil {
+		if rc, err := req.GetBody(); err == nil {
+			requestData, _ = io.ReadAll(rc)
+			rc.Close()
+		}
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: HTTP请求失败: %w", ErrInstanceUnavailable, err)
+	}
+	defer resp.Body.Close()
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+
+	body, err = io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, resp.StatusCode, fmt.Errorf("%w: 读取响应失败: %w", ErrInstanceUnavailable, err)
+	}
+	if c.recorder != nil {
+		c.recorder.record(requestData, body)
+	}
+	return body, resp.StatusCode, nil
+}
+
 func (c *ZabbixClient) prefersHeaderAuth() bool {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -311,14 +454,13 @@ func (c *ZabbixClient) IsConnected() bool {
 	return c.AuthToken != ""
 }
 
+// isAuthError 会话失效时才需要重新登录；参数错误、权限不足同样使用 -32602/-32500，不能只看错误码
 func isAuthError(err error) bool {
-	if err == nil {
+	var rpcErr *models.RPCError
+	if !errors.As(err, &rpcErr) {
 		return false
 	}
-	if rpcErr, ok := err.(*models.RPCError); ok {
-		return rpcErr.Code == -32602 || rpcErr.Code == -32500
-	}
-	return false
+	return isSessionError(rpcErr)
 }
 
 func (c *ZabbixClient) GetDetailedVersionFeatures() map[string]interface{} {
@@ -329,6 +471,10 @@ func (c *ZabbixClient) AdaptAPIParams(method string, spec models.ParamSpec) map[
 	return NewVersionDetector(c).AdaptAPIParams(method, spec)
 }
 
+func (c *ZabbixClient) CompatReport(method string, all bool) (*CompatReport, error) {
+	return NewVersionDetector(c).CompatReport(method, all)
+}
+
 func buildAPIEndpoint(raw string) (string, error) {
 	trimmed := strings.TrimSpace(raw)
 	if trimmed == "" {
@@ -339,3 +485,12 @@ func buildAPIEndpoint(raw string) (string, error) {
 	}
 	return strings.TrimRight(trimmed, "/") + "/api_jsonrpc.php", nil
 }
+
+// logParams 写入日志的请求参数，密码等敏感字段已替换为掩码
+func logParams(params interface{}) string {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Sprintf("<%T>", params)
+	}
+	return string(ScrubParams(raw))
+}
@@ -0,0 +1,184 @@
+package zabbix_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"zabbixMcp/models"
+	"zabbixMcp/zabbix"
+	"zabbixMcp/zabbix/zabbixtest"
+)
+
+// authAttempt 一次 user.get 请求的认证位置与结果
+type authAttempt struct {
+	header bool
+	failed bool
+}
+
+func userGetAttempts(srv *zabbixtest.Server) []authAttempt {
+	var out []authAttempt
+	for _, c := range srv.Calls() {
+		if c.Method == "user.get" {
+			out = append(out, authAttempt{header: c.AuthInHeader && !c.AuthInBody, failed: c.Error != nil})
+		}
+	}
+	return out
+}
+
+// TestCallAuthMethodFallback 首选的认证位置被拒绝时换另一种重试，成功后记住新的认证位置
+func TestCallAuthMethodFallback(t *testing.T) {
+	cases := []struct {
+		name    string
+		version string
+		auth    zabbixtest.AuthMode
+		want    []authAttempt
+	}{
+		// 7.0 起首选 Authorization 头，反向代理去掉该头时服务器报未认证
+		{"7.0 头被去掉", "7.0.0", zabbixtest.AuthBodyOnly, []authAttempt{{header: true, failed: true}, {header: false}, {header: false}}},
+		// 7.0 之前首选请求体 auth，只接受 Authorization 头的服务器报意外参数 auth
+		{"6.4 只接受头", "6.4.0", zabbixtest.AuthHeaderOnly, []authAttempt{{header: false, failed: true}, {header: true}, {header: true}}},
+		{"7.2 按版本", "7.2.0", zabbixtest.AuthDefault, []authAttempt{{header: true}, {header: true}}},
+		{"6.0 按版本", "6.0.0", zabbixtest.AuthDefault, []authAttempt{{header: false}, {header: false}}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			srv := zabbixtest.NewServer(zabbixtest.Options{Version: c.version, Auth: c.auth})
+			defer srv.Close()
+			client, err := zabbix.NewZabbixClientFromConfig(srv.ClientConfig())
+			if err != nil {
+				t.Fatal(err)
+			}
+			srv.ResetCalls()
+			ctx := zabbix.WithCacheBypass(context.Background())
+			for i := 0; i < 2; i++ {
+				var users []map[string]interface{}
+				if err := client.Call(ctx, "user.get", map[string]interface{}{"output": []string{"userid"}}, &users); err != nil || len(users) == 0 {
+					t.Fatalf("第 %d 次 user.get = %v, %v", i+1, users, err)
+				}
+			}
+			if got := userGetAttempts(srv); !slices.Equal(got, c.want) {
+				t.Errorf("user.get 请求 = %+v，期望 %+v", got, c.want)
+			}
+			if methods := srv.Methods(); len(methods) != len(c.want) {
+				t.Errorf("换认证位置不应重新登录: %v", methods)
+			}
+		})
+	}
+}
+
+// TestCallNoFallbackOnOtherErrors 权限、参数等错误不换认证位置重试，也不重新登录
+func TestCallNoFallbackOnOtherErrors(t *testing.T) {
+	srv := zabbixtest.NewServer(zabbixtest.Options{Version: "7.0.0", Auth: zabbixtest.AuthBoth})
+	defer srv.Close()
+	client, err := zabbix.NewZabbixClientFromConfig(srv.ClientConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.ResetCalls()
+	srv.FailNext("user.get", -32500, "Application error.", "You do not have permission to perform this operation.")
+
+	err = client.Call(zabbix.WithCacheBypass(context.Background()), "user.get", map[string]interface{}{}, nil)
+	var rpcErr *models.RPCError
+	if !errors.As(err, &rpcErr) || zabbix.ClassifyError(err).Code != models.CodePermissionDenied {
+		t.Fatalf("err = %v，期望权限错误", err)
+	}
+	if methods := srv.Methods(); len(methods) != 1 {
+		t.Errorf("服务器收到的调用 = %v，期望只有一次 user.get", methods)
+	}
+}
+
+// TestCallReloginAfterFallback 会话失效与认证位置被拒绝无法区分：先换认证位置重试，仍失败才重新登录，
+// 重新登录后继续使用原来的认证位置
+func TestCallReloginAfterFallback(t *testing.T) {
+	for _, c := range []struct {
+		version string
+		header  bool
+	}{
+		{"6.4.0", false},
+		{"7.0.0", true},
+	} {
+		t.Run(c.version, func(t *testing.T) {
+			srv := zabbixtest.NewServer(zabbixtest.Options{Version: c.version})
+			defer srv.Close()
+			client, err := zabbix.NewZabbixClientFromConfig(srv.ClientConfig())
+			if err != nil {
+				t.Fatal(err)
+			}
+			srv.ExpireSessions()
+			srv.ResetCalls()
+			ctx := zabbix.WithCacheBypass(context.Background())
+			for i := 0; i < 2; i++ {
+				if err := client.Call(ctx, "user.get", map[string]interface{}{"output": []string{"userid"}}, nil); err != nil {
+					t.Fatalf("第 %d 次 user.get: %v", i+1, err)
+				}
+			}
+
+			want := []authAttempt{{header: c.header, failed: true}, {header: !c.header, failed: true}, {header: c.header}, {header: c.header}}
+			if got := userGetAttempts(srv); !slices.Equal(got, want) {
+				t.Errorf("user.get 请求 = %+v，期望 %+v", got, want)
+			}
+			if methods := srv.Methods(); len(methods) != 5 || methods[2] != "user.login" {
+				t.Errorf("服务器收到的调用 = %v，期望两次失败的 user.get 之后登录一次", methods)
+			}
+		})
+	}
+}
+
+// TestCallCache 命中缓存时不访问 Zabbix，bypass 直接请求并刷新缓存，变更方法成功后清除相关对象的缓存
+func TestCallCache(t *testing.T) {
+	srv := zabbixtest.NewServer(zabbixtest.Options{Version: "7.0.0"})
+	defer srv.Close()
+	cfg := srv.ClientConfig()
+	cfg.Cache = &zabbix.CacheConfig{TTL: time.Minute, Methods: map[string]time.Duration{"user.get": 0, "usergroup.get": 0, "host.get": 0}}
+	client, err := zabbix.NewZabbixClientFromConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	userParams := map[string]interface{}{"output": []string{"userid", "name"}, "userids": []string{"1"}}
+	get := func(ctx context.Context, method string, params map[string]interface{}) []map[string]interface{} {
+		t.Helper()
+		var out []map[string]interface{}
+		if err := client.Call(ctx, method, params, &out); err != nil {
+			t.Fatalf("%s: %v", method, err)
+		}
+		return out
+	}
+	served := func() []string {
+		defer srv.ResetCalls()
+		return srv.Methods()
+	}
+
+	get(ctx, "user.get", userParams)
+	get(ctx, "usergroup.get", map[string]interface{}{"output": "extend"})
+	get(ctx, "host.get", nil)
+	served()
+	// 参数顺序不同、空参数写法不同都命中同一条缓存
+	get(ctx, "user.get", map[string]interface{}{"userids": []string{"1"}, "output": []string{"userid", "name"}})
+	get(ctx, "host.get", map[string]interface{}{})
+	if methods := served(); len(methods) != 0 {
+		t.Fatalf("命中缓存时不应访问 Zabbix: %v", methods)
+	}
+
+	get(zabbix.WithCacheBypass(ctx), "user.get", userParams)
+	if methods := served(); !slices.Equal(methods, []string{"user.get"}) {
+		t.Errorf("bypass 时服务器收到的调用 = %v，期望 [user.get]", methods)
+	}
+
+	if err := client.Call(ctx, "user.update", map[string]interface{}{"userid": "1", "name": "Renamed"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	served()
+	users := get(ctx, "user.get", userParams)
+	get(ctx, "usergroup.get", map[string]interface{}{"output": "extend"})
+	get(ctx, "host.get", nil)
+	if methods := served(); !slices.Equal(methods, []string{"user.get", "usergroup.get"}) {
+		t.Errorf("user.update 之后服务器收到的调用 = %v，期望重新请求 user.get 与 usergroup.get，host.get 仍命中缓存", methods)
+	}
+	if len(users) != 1 || users[0]["name"] != "Renamed" {
+		t.Errorf("user.update 之后 user.get = %v，期望新的姓名", users)
+	}
+}
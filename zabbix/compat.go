@@ -0,0 +1,402 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-26 09:31:15
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-26 09:31:15
+ * @FilePath: \zabbix-mcp-go\zabbix\compat.go
+ * @Description: 声明式的版本兼容规则（规则表见 compat.yaml）
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+package zabbix
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 兼容规则支持的动作
+const (
+	CompatDrop        = "drop"
+	CompatRename      = "rename"
+	CompatDropValue   = "drop_value"
+	CompatRenameValue = "rename_value"
+	CompatTransform   = "transform"
+)
+
+// 规则作用方向
+const (
+	CompatRequest  = "request"  // 适配请求参数（AdaptAPIParams）
+	CompatResponse = "response" // 归一化响应（Call 之后）
+)
+
+//go:embed compat.yaml
+var compatYAML []byte
+
+// compatRules 内置规则表（请求与响应规则），包初始化时从 compat.yaml 加载
+var compatRules = mustLoadCompatRules(compatYAML)
+
+// CompatRule 一条版本兼容规则
+type CompatRule struct {
+	Method    string `yaml:"method" json:"method"`
+	Path      string `yaml:"path" json:"path"`
+	Action    string `yaml:"action" json:"action"`
+	To        string `yaml:"to,omitempty" json:"to,omitempty"`
+	Value     string `yaml:"value,omitempty" json:"value,omitempty"`
+	Transform string `yaml:"transform,omitempty" json:"transform,omitempty"`
+	Since     string `yaml:"since,omitempty" json:"since,omitempty"` // 起始版本（含）
+	Until     string `yaml:"until,omitempty" json:"until,omitempty"` // 截止版本（不含）
+	Reason    string `yaml:"reason,omitempty" json:"reason,omitempty"`
+	Direction string `yaml:"-" json:"direction"` // request / response，由所在的 YAML 段落决定
+
+	since, until int // major*1000+minor，0 表示不限
+}
+
+// CompatRuleStatus 规则及其在某个实例上的适用情况
+type CompatRuleStatus struct {
+	CompatRule
+	Applies bool `json:"applies"`
+}
+
+// CompatReport get_api_compat 工具的输出
+type CompatReport struct {
+	Instance string             `json:"instance"`
+	Version  string             `json:"version"`
+	Method   string             `json:"method,omitempty"`
+	Rules    []CompatRuleStatus `json:"rules"`
+}
+
+// compatTransforms transform 动作可用的转换函数；返回 false 表示删除该参数
+var compatTransforms = map[string]func(v interface{}) (interface{}, bool){
+	// 5.2 之前没有角色，按内置角色换算为用户类型：1 User role / 4 Guest role → 1，2 Admin role → 2，3 Super admin role → 3
+	"roleid_to_type": func(v interface{}) (interface{}, bool) {
+		switch fmt.Sprint(v) {
+		case "1", "4":
+			return "1", true
+		case "2":
+			return "2", true
+		case "3":
+			return "3", true
+		}
+		return nil, false
+	},
+	// 5.2 之前的用户类型按内置角色换算为 roleid，与 roleid_to_type 互逆（类型 1 统一视为 User role）
+	"type_to_roleid": func(v interface{}) (interface{}, bool) {
+		switch fmt.Sprint(v) {
+		case "1", "2", "3":
+			return fmt.Sprint(v), true
+		}
+		return nil, false
+	},
+}
+
+// LoadCompatRules 解析并校验 YAML 规则表：rules 段为请求规则，responses 段为响应规则
+func LoadCompatRules(data []byte) ([]CompatRule, error) {
+	var doc struct {
+		Rules     []CompatRule `yaml:"rules"`
+		Responses []CompatRule `yaml:"responses"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("解析兼容规则失败: %w", err)
+	}
+	for i := range doc.Rules {
+		doc.Rules[i].Direction = CompatRequest
+	}
+	for i := range doc.Responses {
+		doc.Responses[i].Direction = CompatResponse
+	}
+	rules := append(doc.Rules, doc.Responses...)
+	for i := range rules {
+		r := &rules[i]
+		if err := r.init(); err != nil {
+			return nil, fmt.Errorf("%s 规则 (%s %s) 无效: %w", r.Direction, r.Method, r.Path, err)
+		}
+	}
+	return rules, nil
+}
+
+func mustLoadCompatRules(data []byte) []CompatRule {
+	rules, err := LoadCompatRules(data)
+	if err != nil {
+		panic(err)
+	}
+	return rules
+}
+
+// init 校验规则并解析版本区间
+func (r *CompatRule) init() error {
+	if r.Method == "" || r.Path == "" {
+		return fmt.Errorf("method 与 path 不能为空")
+	}
+	switch r.Action {
+	case CompatDrop:
+	case CompatRename:
+		if r.To == "" {
+			return fmt.Errorf("rename 需要 to")
+		}
+	case CompatDropValue:
+		if r.Value == "" {
+			return fmt.Errorf("drop_value 需要 value")
+		}
+	case CompatRenameValue:
+		if r.Value == "" || r.To == "" {
+			return fmt.Errorf("rename_value 需要 value 与 to")
+		}
+	case CompatTransform:
+		if _, ok := compatTransforms[r.Transform]; !ok {
+			return fmt.Errorf("未知的 transform %q", r.Transform)
+		}
+	default:
+		return fmt.Errorf("未知的 action %q", r.Action)
+	}
+	var err error
+	if r.since, err = parseMajorMinor(r.Since); err != nil {
+		return err
+	}
+	if r.until, err = parseMajorMinor(r.Until); err != nil {
+		return err
+	}
+	if r.since > 0 && r.until > 0 && r.since >= r.until {
+		return fmt.Errorf("since %s 必须小于 until %s", r.Since, r.Until)
+	}
+	return nil
+}
+
+// AppliesTo 判断规则是否适用于给定版本
+func (r CompatRule) AppliesTo(v *VersionInfo) bool {
+	if v == nil {
+		return false
+	}
+	n := v.Major*1000 + v.Minor
+	return (r.since == 0 || n >= r.since) && (r.until == 0 || n < r.until)
+}
+
+// CompatRulesFor 返回某方法（method 为空时为全部）的规则及其对版本 v 的适用情况
+func CompatRulesFor(method string, v *VersionInfo) []CompatRuleStatus {
+	out := []CompatRuleStatus{}
+	for _, r := range compatRules {
+		if method != "" && r.Method != method {
+			continue
+		}
+		out = append(out, CompatRuleStatus{CompatRule: r, Applies: r.AppliesTo(v)})
+	}
+	return out
+}
+
+// ApplyCompatRules 对参数的深拷贝应用所有命中的请求规则，不修改传入的 params
+func ApplyCompatRules(method string, v *VersionInfo, params map[string]interface{}) map[string]interface{} {
+	adapted, _ := deepCopyValue(params).(map[string]interface{})
+	if adapted == nil {
+		adapted = map[string]interface{}{}
+	}
+	for _, r := range compatRules {
+		if r.Direction == CompatRequest && r.Method == method && r.AppliesTo(v) {
+			r.apply(adapted)
+		}
+	}
+	return adapted
+}
+
+// NormalizeResponse 将响应统一为最新版本的结构：应用命中的响应规则，并把数字统一为字符串
+// （Zabbix 绝大多数字段以字符串返回，个别版本/方法返回数字）。解析失败时原样返回。
+func NormalizeResponse(method string, v *VersionInfo, payload json.RawMessage) json.RawMessage {
+	var data interface{}
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+	if err := decoder.Decode(&data); err != nil {
+		return payload
+	}
+	for _, r := range compatRules {
+		if r.Direction == CompatResponse && r.Method == method && r.AppliesTo(v) {
+			r.apply(data)
+		}
+	}
+	out, err := json.Marshal(stringifyNumbers(data))
+	if err != nil {
+		return payload
+	}
+	return out
+}
+
+// stringifyNumbers 把对象字段中的数字转换为字符串；顶层标量（如 countOutput 的结果）同样转换
+func stringifyNumbers(v interface{}) interface{} {
+	switch val := v.(type) {
+	case json.Number:
+		return val.String()
+	case map[string]interface{}:
+		for k, item := range val {
+			val[k] = stringifyNumbers(item)
+		}
+	case []interface{}:
+		for i, item := range val {
+			val[i] = stringifyNumbers(item)
+		}
+	}
+	return v
+}
+
+// apply 在已拷贝的数据上执行规则；路径途经数组时对每个元素分别执行
+func (r CompatRule) apply(node interface{}) {
+	r.applyAt(node, strings.Split(r.Path, "."))
+}
+
+func (r CompatRule) applyAt(node interface{}, keys []string) {
+	switch val := node.(type) {
+	case []interface{}:
+		for _, item := range val {
+			r.applyAt(item, keys)
+		}
+		return
+	case []map[string]interface{}:
+		for _, item := range val {
+			r.applyAt(item, keys)
+		}
+		return
+	}
+	parent, ok := node.(map[string]interface{})
+	if !ok {
+		return
+	}
+	if len(keys) > 1 {
+		if next, exists := parent[keys[0]]; exists {
+			r.applyAt(next, keys[1:])
+		}
+		return
+	}
+	key := keys[0]
+	value, ok := parent[key]
+	if !ok {
+		return
+	}
+
+	switch r.Action {
+	case CompatDrop:
+		delete(parent, key)
+	case CompatRename:
+		delete(parent, key)
+		if _, exists := parent[r.To]; !exists {
+			parent[r.To] = value
+		}
+	case CompatDropValue, CompatRenameValue:
+		fields, isList := stringSlice(value)
+		if !isList {
+			return
+		}
+		out := make([]string, 0, len(fields))
+		seen := map[string]bool{}
+		for _, f := range fields {
+			if f == r.Value {
+				if r.Action == CompatDropValue {
+					continue
+				}
+				f = r.To
+			}
+			if !seen[f] {
+				seen[f] = true
+				out = append(out, f)
+			}
+		}
+		if len(out) == 0 {
+			delete(parent, key)
+			return
+		}
+		parent[key] = out
+	case CompatTransform:
+		converted, keep := compatTransforms[r.Transform](value)
+		delete(parent, key)
+		if !keep {
+			return
+		}
+		target := key
+		if r.To != "" {
+			target = r.To
+		}
+		if _, exists := parent[target]; !exists || target == key {
+			parent[target] = converted
+		}
+	}
+}
+
+// stringSlice 识别 []string 与全部为字符串的 []interface{}
+func stringSlice(v interface{}) ([]string, bool) {
+	switch list := v.(type) {
+	case []string:
+		return list, true
+	case []interface{}:
+		out := make([]string, 0, len(list))
+		for _, item := range list {
+			s, ok := item.(string)
+			if !ok {
+				return nil, false
+			}
+			out = append(out, s)
+		}
+		return out, true
+	}
+	return nil, false
+}
+
+// deepCopyValue 拷贝参数中的 map 与切片，保证规则不会改动调用方的数据
+func deepCopyValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			out[k] = deepCopyValue(item)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, item := range val {
+			out[i] = deepCopyValue(item)
+		}
+		return out
+	case []string:
+		return append([]string(nil), val...)
+	case []map[string]interface{}:
+		out := make([]map[string]interface{}, len(val))
+		for i, item := range val {
+			out[i], _ = deepCopyValue(item).(map[string]interface{})
+		}
+		return out
+	}
+	return v
+}
+
+// parseMajorMinor 解析 "5.4" 形式的版本，空串返回 0
+func parseMajorMinor(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+	parts := strings.SplitN(s, ".", 3)
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, fmt.Errorf("版本号 %q 格式错误", s)
+	}
+	minor := 0
+	if len(parts) > 1 {
+		if minor, err = strconv.Atoi(parts[1]); err != nil {
+			return 0, fmt.Errorf("版本号 %q 格式错误", s)
+		}
+	}
+	return major*1000 + minor, nil
+}
+
+type rawResponseKey struct{}
+
+// WithRawResponse 标记本次调用跳过响应归一化，返回 Zabbix 原始结构（调试用）
+func WithRawResponse(ctx context.Context, raw bool) context.Context {
+	return context.WithValue(ctx, rawResponseKey{}, raw)
+}
+
+// IsRawResponse 判断是否跳过响应归一化
+func IsRawResponse(ctx context.Context) bool {
+	raw, _ := ctx.Value(rawResponseKey{}).(bool)
+	return raw
+}
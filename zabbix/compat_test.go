@@ -0,0 +1,463 @@
+package zabbix_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"zabbixMcp/models"
+	"zabbixMcp/zabbix"
+	"zabbixMcp/zabbix/zabbixtest"
+)
+
+// releases Zabbix 发布过的 major.minor，用于取每个分界版本之前的上一个版本
+var releases = []string{"3.0", "3.2", "3.4", "4.0", "4.2", "4.4", "5.0", "5.2", "5.4", "6.0", "6.2", "6.4", "7.0", "7.2"}
+
+func prevRelease(t *testing.T, v string) string {
+	t.Helper()
+	for i, r := range releases {
+		if r == v {
+			if i == 0 {
+				t.Fatalf("%s 之前没有已知版本", v)
+			}
+			return releases[i-1]
+		}
+	}
+	t.Fatalf("未知的分界版本 %s，请补充到 releases", v)
+	return ""
+}
+
+func versionInfo(t *testing.T, v string) *zabbix.VersionInfo {
+	t.Helper()
+	parts := strings.Split(v, ".")
+	major, err1 := strconv.Atoi(parts[0])
+	minor, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil {
+		t.Fatalf("版本 %q 格式错误", v)
+	}
+	return &zabbix.VersionInfo{Major: major, Minor: minor, Full: v + ".0"}
+}
+
+// clientAt 返回已缓存版本的客户端，AdaptAPIParams 不会访问网络
+func clientAt(t *testing.T, v string) *zabbix.ZabbixClient {
+	t.Helper()
+	client, err := zabbix.NewZabbixClient("compat", "http://127.0.0.1:1", "Admin", "zabbix", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetCachedVersion(versionInfo(t, v))
+	return client
+}
+
+// cutoffs 按规则的版本区间返回分界版本及其上一个版本：since 规则在 at 生效、below 不生效，until 规则相反；
+// 不限版本的规则在两端的版本都生效
+func cutoffs(t *testing.T, since, until string) (below, at string) {
+	t.Helper()
+	switch {
+	case since != "":
+		return prevRelease(t, since), since
+	case until != "":
+		return prevRelease(t, until), until
+	}
+	return "4.0", "7.0"
+}
+
+// ruleID 唯一标识 compat.yaml 中的一条规则
+type ruleID struct {
+	direction, method, path, action, value, since, until string
+}
+
+func idOf(r zabbix.CompatRule) ruleID {
+	return ruleID{r.Direction, r.Method, r.Path, r.Action, r.Value, r.Since, r.Until}
+}
+
+type obj = map[string]interface{}
+
+// requestCases 每条请求规则一个用例：applied 为规则生效时的结果，notApplied 为不生效时的结果（为空表示与输入相同）
+var requestCases = []struct {
+	rule       ruleID
+	in         obj
+	applied    obj
+	notApplied obj
+}{
+	// user.get
+	{ruleID{"request", "user.get", "filter.alias", "rename", "", "5.4", ""},
+		obj{"filter": obj{"alias": "Admin"}}, obj{"filter": obj{"username": "Admin"}}, nil},
+	{ruleID{"request", "user.get", "filter.username", "rename", "", "", "5.4"},
+		obj{"filter": obj{"username": "Admin"}}, obj{"filter": obj{"alias": "Admin"}}, nil},
+	{ruleID{"request", "user.get", "search.alias", "rename", "", "5.4", ""},
+		obj{"search": obj{"alias": "adm"}}, obj{"search": obj{"username": "adm"}}, nil},
+	{ruleID{"request", "user.get", "search.username", "rename", "", "", "5.4"},
+		obj{"search": obj{"username": "adm"}}, obj{"search": obj{"alias": "adm"}}, nil},
+	{ruleID{"request", "user.get", "output", "rename_value", "alias", "5.4", ""},
+		obj{"output": []string{"userid", "alias"}}, obj{"output": []string{"userid", "username"}}, nil},
+	{ruleID{"request", "user.get", "output", "rename_value", "username", "", "5.4"},
+		obj{"output": []string{"userid", "username"}}, obj{"output": []string{"userid", "alias"}}, nil},
+	{ruleID{"request", "user.get", "output", "rename_value", "roleid", "", "5.2"},
+		obj{"output": []string{"userid", "roleid"}}, obj{"output": []string{"userid", "type"}}, nil},
+	{ruleID{"request", "user.get", "selectRole", "drop", "", "", "5.2"},
+		obj{"output": "extend", "selectRole": "extend"}, obj{"output": "extend"}, nil},
+
+	// user.create
+	{ruleID{"request", "user.create", "alias", "rename", "", "5.4", ""},
+		obj{"alias": "jdoe"}, obj{"username": "jdoe"}, nil},
+	{ruleID{"request", "user.create", "username", "rename", "", "", "5.4"},
+		obj{"username": "jdoe"}, obj{"alias": "jdoe"}, nil},
+	{ruleID{"request", "user.create", "roleid", "transform", "", "", "5.2"},
+		obj{"roleid": "2"}, obj{"type": "2"}, nil},
+	{ruleID{"request", "user.create", "currentpasswd", "drop", "", "", ""},
+		obj{"passwd": "new", "currentpasswd": "old"}, obj{"passwd": "new"}, obj{"passwd": "new"}},
+	{ruleID{"request", "user.create", "medias", "rename", "", "", "5.2"},
+		obj{"medias": []interface{}{obj{"mediatypeid": "1", "sendto": []string{"a@example.com"}}}},
+		obj{"user_medias": []interface{}{obj{"mediatypeid": "1", "sendto": []string{"a@example.com"}}}}, nil},
+
+	// user.update
+	{ruleID{"request", "user.update", "alias", "rename", "", "5.4", ""},
+		obj{"userid": "5", "alias": "jdoe"}, obj{"userid": "5", "username": "jdoe"}, nil},
+	{ruleID{"request", "user.update", "username", "rename", "", "", "5.4"},
+		obj{"userid": "5", "username": "jdoe"}, obj{"userid": "5", "alias": "jdoe"}, nil},
+	{ruleID{"request", "user.update", "roleid", "transform", "", "", "5.2"},
+		obj{"userid": "5", "roleid": "3"}, obj{"userid": "5", "type": "3"}, nil},
+	// currentpasswd 在 6.4 前后分别由 drop 与 rename 两条规则处理
+	{ruleID{"request", "user.update", "currentpasswd", "rename", "", "6.4", ""},
+		obj{"userid": "5", "currentpasswd": "old"}, obj{"userid": "5", "current_passwd": "old"}, obj{"userid": "5"}},
+	{ruleID{"request", "user.update", "currentpasswd", "drop", "", "", "6.4"},
+		obj{"userid": "5", "currentpasswd": "old"}, obj{"userid": "5"}, obj{"userid": "5", "current_passwd": "old"}},
+	{ruleID{"request", "user.update", "medias", "rename", "", "", "5.2"},
+		obj{"userid": "5", "medias": []interface{}{}}, obj{"userid": "5", "user_medias": []interface{}{}}, nil},
+
+	// usergroup.get
+	{ruleID{"request", "usergroup.get", "selectUsers", "rename_value", "alias", "5.4", ""},
+		obj{"selectUsers": []string{"userid", "alias"}}, obj{"selectUsers": []string{"userid", "username"}}, nil},
+	{ruleID{"request", "usergroup.get", "selectUsers", "rename_value", "username", "", "5.4"},
+		obj{"selectUsers": []string{"userid", "username"}}, obj{"selectUsers": []string{"userid", "alias"}}, nil},
+
+	// mediatype.get
+	{ruleID{"request", "mediatype.get", "filter.name", "rename", "", "", "4.4"},
+		obj{"filter": obj{"name": "Email"}}, obj{"filter": obj{"description": "Email"}}, nil},
+	{ruleID{"request", "mediatype.get", "search.name", "rename", "", "", "4.4"},
+		obj{"search": obj{"name": "Mail"}}, obj{"search": obj{"description": "Mail"}}, nil},
+	{ruleID{"request", "mediatype.get", "output", "rename_value", "name", "", "4.4"},
+		obj{"output": []string{"mediatypeid", "name"}}, obj{"output": []string{"mediatypeid", "description"}}, nil},
+
+	// host.get
+	{ruleID{"request", "host.get", "selectTags", "drop", "", "", "4.2"},
+		obj{"selectTags": "extend"}, obj{}, nil},
+	{ruleID{"request", "host.get", "selectGroups", "rename", "", "6.2", ""},
+		obj{"selectGroups": "extend"}, obj{"selectHostGroups": "extend"}, nil},
+	{ruleID{"request", "host.get", "selectHostGroups", "rename", "", "", "6.2"},
+		obj{"selectHostGroups": "extend"}, obj{"selectGroups": "extend"}, nil},
+	{ruleID{"request", "host.get", "output", "rename_value", "proxy_hostid", "7.0", ""},
+		obj{"output": []string{"hostid", "proxy_hostid"}}, obj{"output": []string{"hostid", "proxyid"}}, nil},
+	{ruleID{"request", "host.get", "output", "rename_value", "proxyid", "", "7.0"},
+		obj{"output": []string{"hostid", "proxyid"}}, obj{"output": []string{"hostid", "proxy_hostid"}}, nil},
+
+	// item / trigger / template
+	{ruleID{"request", "item.get", "selectTags", "drop", "", "", "5.4"},
+		obj{"selectTags": "extend"}, obj{}, nil},
+	{ruleID{"request", "item.get", "selectPreprocessing", "drop", "", "", "3.4"},
+		obj{"selectPreprocessing": "extend"}, obj{}, nil},
+	{ruleID{"request", "trigger.get", "selectTags", "drop", "", "", "3.2"},
+		obj{"selectTags": "extend"}, obj{}, nil},
+	{ruleID{"request", "trigger.get", "selectDependencies", "drop", "", "", "4.0"},
+		obj{"selectDependencies": "extend"}, obj{}, nil},
+	{ruleID{"request", "template.get", "selectTags", "drop", "", "", "4.2"},
+		obj{"selectTags": "extend"}, obj{}, nil},
+}
+
+// responseCases 每条响应规则一个用例，输入与结果为 JSON
+var responseCases = []struct {
+	rule       ruleID
+	in         string
+	applied    string
+	notApplied string
+}{
+	{ruleID{"response", "user.get", "alias", "rename", "", "", "5.4"},
+		`[{"userid":"1","alias":"Admin"}]`, `[{"userid":"1","username":"Admin"}]`, ""},
+	{ruleID{"response", "user.get", "type", "transform", "", "", "5.2"},
+		`[{"userid":"1","type":"3"}]`, `[{"userid":"1","roleid":"3"}]`, ""},
+	{ruleID{"response", "usergroup.get", "users.alias", "rename", "", "", "5.4"},
+		`[{"usrgrpid":"7","users":[{"userid":"1","alias":"Admin"}]}]`, `[{"usrgrpid":"7","users":[{"userid":"1","username":"Admin"}]}]`, ""},
+	{ruleID{"response", "usergroup.get", "users.type", "transform", "", "", "5.2"},
+		`[{"usrgrpid":"7","users":[{"userid":"1","type":"2"}]}]`, `[{"usrgrpid":"7","users":[{"userid":"1","roleid":"2"}]}]`, ""},
+	{ruleID{"response", "mediatype.get", "description", "rename", "", "", "4.4"},
+		`[{"mediatypeid":"1","description":"Email"}]`, `[{"mediatypeid":"1","name":"Email"}]`, ""},
+	{ruleID{"response", "host.get", "groups", "rename", "", "", ""},
+		`[{"hostid":"10084","groups":[{"groupid":"4"}]}]`, `[{"hostid":"10084","hostgroups":[{"groupid":"4"}]}]`, `[{"hostid":"10084","hostgroups":[{"groupid":"4"}]}]`},
+	{ruleID{"response", "host.get", "proxy_hostid", "rename", "", "", "7.0"},
+		`[{"hostid":"10084","proxy_hostid":"0"}]`, `[{"hostid":"10084","proxyid":"0"}]`, ""},
+	{ruleID{"response", "hostgroup.get", "hosts.proxy_hostid", "rename", "", "", "7.0"},
+		`[{"groupid":"4","hosts":[{"hostid":"10084","proxy_hostid":"0"}]}]`, `[{"groupid":"4","hosts":[{"hostid":"10084","proxyid":"0"}]}]`, ""},
+}
+
+// TestCompatRulesCovered compat.yaml 中的每条规则都必须有对应用例，新增规则时同时补充用例
+func TestCompatRulesCovered(t *testing.T) {
+	covered := map[ruleID]bool{}
+	for _, c := range requestCases {
+		covered[c.rule] = true
+	}
+	for _, c := range responseCases {
+		covered[c.rule] = true
+	}
+	seen := map[ruleID]bool{}
+	for _, r := range zabbix.CompatRulesFor("", nil) {
+		id := idOf(r.CompatRule)
+		seen[id] = true
+		if !covered[id] {
+			t.Errorf("规则没有测试用例: %+v", id)
+		}
+	}
+	for id := range covered {
+		if !seen[id] {
+			t.Errorf("用例对应的规则不存在: %+v", id)
+		}
+	}
+}
+
+// TestCompatRuleCutoffs 每条规则在分界版本与其前一个次版本号上的适用情况
+func TestCompatRuleCutoffs(t *testing.T) {
+	for _, r := range zabbix.CompatRulesFor("", nil) {
+		applies := func(v string) bool { return r.AppliesTo(versionInfo(t, v)) }
+		if r.Since != "" {
+			prev := versionInfo(t, r.Since)
+			prev.Minor--
+			if !applies(r.Since) || r.AppliesTo(prev) {
+				t.Errorf("%+v: 应从 %s 起生效", idOf(r.CompatRule), r.Since)
+			}
+		}
+		if r.Until != "" {
+			prev := versionInfo(t, r.Until)
+			prev.Minor--
+			if applies(r.Until) || !r.AppliesTo(prev) {
+				t.Errorf("%+v: 应在 %s 之前生效", idOf(r.CompatRule), r.Until)
+			}
+		}
+	}
+	if (zabbix.CompatRule{}).AppliesTo(nil) {
+		t.Error("版本未知时规则不应生效")
+	}
+}
+
+func TestAdaptAPIParamsRules(t *testing.T) {
+	for _, c := range requestCases {
+		below, at := cutoffs(t, c.rule.since, c.rule.until)
+		appliedAt, notAppliedAt := at, below
+		if c.rule.until != "" {
+			appliedAt, notAppliedAt = below, at
+		}
+		if c.rule.since == "" && c.rule.until == "" {
+			notAppliedAt = ""
+		}
+		name := fmt.Sprintf("%s %s %s %s", c.rule.method, c.rule.path, c.rule.action, c.rule.value)
+		t.Run(name, func(t *testing.T) {
+			check := func(v string, want obj) {
+				t.Helper()
+				got := clientAt(t, v).AdaptAPIParams(c.rule.method, models.MapParams(c.in))
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("%s: AdaptAPIParams(%v) = %v, want %v", v, c.in, got, want)
+				}
+			}
+			check(appliedAt, c.applied)
+			if c.rule.since == "" && c.rule.until == "" {
+				// 不限版本的规则在两端都生效
+				check(below, c.applied)
+				return
+			}
+			want := c.notApplied
+			if want == nil {
+				want = c.in
+			}
+			check(notAppliedAt, want)
+		})
+	}
+}
+
+func TestNormalizeResponseRules(t *testing.T) {
+	for _, c := range responseCases {
+		below, at := cutoffs(t, c.rule.since, c.rule.until)
+		name := fmt.Sprintf("%s %s %s", c.rule.method, c.rule.path, c.rule.action)
+		t.Run(name, func(t *testing.T) {
+			check := func(v, want string) {
+				t.Helper()
+				got := zabbix.NormalizeResponse(c.rule.method, versionInfo(t, v), json.RawMessage(c.in))
+				if !jsonEqual(t, got, want) {
+					t.Errorf("%s: NormalizeResponse(%s) = %s, want %s", v, c.in, got, want)
+				}
+			}
+			switch {
+			case c.rule.until != "":
+				check(below, c.applied)
+				want := c.notApplied
+				if want == "" {
+					want = c.in
+				}
+				check(at, want)
+			default:
+				check(below, c.applied)
+				check(at, c.notApplied)
+			}
+		})
+	}
+}
+
+// TestRoleIDTransform roleid_to_type 对每个内置角色的换算，未知角色直接删除
+func TestRoleIDTransform(t *testing.T) {
+	cases := []struct {
+		roleid string
+		want   obj
+	}{
+		{"1", obj{"type": "1"}},
+		{"2", obj{"type": "2"}},
+		{"3", obj{"type": "3"}},
+		{"4", obj{"type": "1"}},
+		{"17", obj{}},
+	}
+	for _, method := range []string{"user.create", "user.update"} {
+		for _, c := range cases {
+			got := clientAt(t, "5.0").AdaptAPIParams(method, models.MapParams{"roleid": c.roleid})
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("%s roleid %s: got %v, want %v", method, c.roleid, got, c.want)
+			}
+		}
+	}
+}
+
+func TestAdaptAPIParamsUnknownMethod(t *testing.T) {
+	in := obj{
+		"output": []string{"userid", "username", "roleid"},
+		"filter": obj{"username": "Admin"},
+		"medias": []interface{}{},
+	}
+	for _, v := range []string{"4.0", "5.0", "7.0"} {
+		for _, method := range []string{"nosuch.get", "user.nosuchverb", "userdirectory.get"} {
+			got := clientAt(t, v).AdaptAPIParams(method, models.MapParams(in))
+			if !reflect.DeepEqual(got, in) {
+				t.Errorf("%s %s: 没有规则的方法应原样返回参数, got %v", v, method, got)
+			}
+		}
+	}
+	payload := `[{"alias":"Admin","type":3}]`
+	got := zabbix.NormalizeResponse("nosuch.get", versionInfo(t, "5.0"), json.RawMessage(payload))
+	if !jsonEqual(t, got, `[{"alias":"Admin","type":"3"}]`) {
+		t.Errorf("没有规则的方法只统一数字类型, got %s", got)
+	}
+}
+
+func TestAdaptAPIParamsDoesNotMutateCaller(t *testing.T) {
+	output := []string{"userid", "username", "roleid"}
+	filter := obj{"username": []interface{}{"Admin"}}
+	medias := []interface{}{obj{"mediatypeid": "1"}}
+	params := obj{"output": output, "filter": filter, "selectRole": "extend", "medias": medias}
+	for _, method := range []string{"user.get", "user.create"} {
+		got := zabbix.ApplyCompatRules(method, versionInfo(t, "5.0"), params)
+		got2 := clientAt(t, "5.0").AdaptAPIParams(method, models.MapParams(params))
+		if !reflect.DeepEqual(got, got2) {
+			t.Fatalf("ApplyCompatRules 与 AdaptAPIParams 结果不同: %v / %v", got, got2)
+		}
+	}
+	want := obj{
+		"output":     []string{"userid", "username", "roleid"},
+		"filter":     obj{"username": []interface{}{"Admin"}},
+		"selectRole": "extend",
+		"medias":     []interface{}{obj{"mediatypeid": "1"}},
+	}
+	if !reflect.DeepEqual(params, want) {
+		t.Fatalf("调用方的参数被修改: %v", params)
+	}
+	if output[1] != "username" || output[2] != "roleid" {
+		t.Fatalf("调用方的 output 切片被修改: %v", output)
+	}
+}
+
+func jsonEqual(t *testing.T, got json.RawMessage, want string) bool {
+	t.Helper()
+	var a, b interface{}
+	if err := json.Unmarshal(got, &a); err != nil {
+		t.Fatalf("解析 %s 失败: %v", got, err)
+	}
+	if err := json.Unmarshal([]byte(want), &b); err != nil {
+		t.Fatalf("解析 %s 失败: %v", want, err)
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// serverVersions 模拟服务器覆盖的版本，包含每个响应归一化规则的分界点
+var serverVersions = []string{"4.0.0", "5.0.0", "5.2.0", "5.4.0", "6.0.0", "6.2.0", "6.4.0", "7.0.0", "7.2.0"}
+
+// TestNormalizeResponseAgainstServer 对模拟服务器的真实响应归一化：各版本都得到 username、roleid 与 hostgroups，
+// raw 模式保留服务器原样返回的字段
+func TestNormalizeResponseAgainstServer(t *testing.T) {
+	for _, v := range serverVersions {
+		t.Run(v, func(t *testing.T) {
+			srv := zabbixtest.NewServer(zabbixtest.Options{Version: v})
+			defer srv.Close()
+			client, err := zabbix.NewZabbixClientFromConfig(srv.ClientConfig())
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx := context.Background()
+			pre52, pre54 := v < "5.2", v < "5.4"
+
+			userParams := map[string]interface{}{"output": "extend", "userids": []string{"1"}}
+			var users []map[string]interface{}
+			if err := client.Call(ctx, "user.get", userParams, &users); err != nil {
+				t.Fatal(err)
+			}
+			if len(users) != 1 || users[0]["username"] != "Admin" || users[0]["roleid"] != "3" {
+				t.Fatalf("user.get = %v，期望 username=Admin roleid=3", users)
+			}
+			for _, old := range []string{"alias", "type"} {
+				if _, ok := users[0][old]; ok {
+					t.Errorf("归一化后仍有 %s: %v", old, users[0])
+				}
+			}
+			var raw []map[string]interface{}
+			if err := client.Call(zabbix.WithRawResponse(ctx, true), "user.get", userParams, &raw); err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := raw[0]["alias"]; ok != pre54 {
+				t.Errorf("raw user.get 的 alias 字段存在 = %v，期望 %v: %v", ok, pre54, raw[0])
+			}
+			if _, ok := raw[0]["type"]; ok != pre52 {
+				t.Errorf("raw user.get 的 type 字段存在 = %v，期望 %v: %v", ok, pre52, raw[0])
+			}
+
+			var groups []map[string]interface{}
+			groupParams := map[string]interface{}{"output": []string{"usrgrpid"}, "usrgrpids": []string{"7"}, "selectUsers": "extend"}
+			if err := client.Call(ctx, "usergroup.get", groupParams, &groups); err != nil {
+				t.Fatal(err)
+			}
+			members, _ := groups[0]["users"].([]interface{})
+			if len(members) != 1 {
+				t.Fatalf("usergroup.get users = %v", groups[0]["users"])
+			}
+			if m, _ := members[0].(map[string]interface{}); m["username"] != "Admin" || m["roleid"] != "3" || m["alias"] != nil || m["type"] != nil {
+				t.Errorf("usergroup.get 成员 = %v，期望 username=Admin roleid=3", m)
+			}
+
+			// 按版本适配后的 selectHostGroups 与旧名 selectGroups（7.0 之前仍可用）都统一输出 hostgroups
+			hostParams := []map[string]interface{}{
+				client.AdaptAPIParams("host.get", models.MapParams{"output": []string{"hostid"}, "hostids": []string{"10084"}, "selectHostGroups": []string{"groupid"}}),
+			}
+			if v < "7.0" {
+				hostParams = append(hostParams, map[string]interface{}{"output": []string{"hostid"}, "hostids": []string{"10084"}, "selectGroups": []string{"groupid"}})
+			}
+			for _, params := range hostParams {
+				var hosts []map[string]interface{}
+				if err := client.Call(ctx, "host.get", params, &hosts); err != nil {
+					t.Fatalf("host.get %v: %v", params, err)
+				}
+				if len(hosts) != 1 || hosts[0]["groups"] != nil {
+					t.Fatalf("host.get %v = %v，不应再有 groups", params, hosts)
+				}
+				if hg, _ := hosts[0]["hostgroups"].([]interface{}); len(hg) != 1 || hg[0].(map[string]interface{})["groupid"] != "4" {
+					t.Errorf("host.get %v hostgroups = %v，期望 [{groupid:4}]", params, hosts[0]["hostgroups"])
+				}
+			}
+		})
+	}
+}
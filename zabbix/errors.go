@@ -0,0 +1,125 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-27 10:40:18
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-27 10:40:18
+ * @FilePath: \zabbix-mcp-go\zabbix\errors.go
+ * @Description: 将 Zabbix API 错误与传输错误归类为 models.Error
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+package zabbix
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+
+	"zabbixMcp/models"
+)
+
+var (
+	// ErrInstanceNotFound 配置中没有该实例
+	ErrInstanceNotFound = errors.New("instance not found")
+	// ErrInstanceUnavailable 请求未能得到有效的 JSON-RPC 响应（网络错误、非 JSON 响应等）
+	ErrInstanceUnavailable = errors.New("instance unavailable")
+)
+
+// defaultHints 各分类的默认修正建议
+var defaultHints = map[models.ErrorCode]string{
+	models.CodeInstanceNotFound:    "调用 get_instances_info 查看可用的实例名称后重试",
+	models.CodeInstanceUnavailable: "实例暂时无法访问，稍后重试或通过 get_instances_info 检查实例状态",
+	models.CodeAuthFailed:          "检查该实例在配置中的用户名密码或 API Token 是否有效",
+	models.CodePermissionDenied:    "当前账号角色无权执行该操作，请改用有权限的实例账号或联系管理员",
+	models.CodeInvalidParams:       "按 params 或 message 修正参数后重试；字段因版本而异时可调用 get_api_compat 查看适配规则",
+	models.CodeNotFound:            "确认对象ID是否正确（可先用查询类工具按名称查找），Zabbix 对无权查看的对象也返回该错误",
+	models.CodeConflict:            "对象已存在，改用其他名称或查询现有对象后执行更新",
+	models.CodeVersionUnsupported:  "该实例的 Zabbix 版本不支持此方法或参数，调用 get_api_compat 查看版本差异",
+	models.CodeTimeout:             "请求超时，缩小查询范围（例如增加过滤条件、减小 limit）后重试",
+	models.CodeInternal:            "未归类的错误，请查看 message 与服务日志",
+}
+
+// ClassifyError 将任意错误归类为 models.Error；已分类的错误保持原分类，缺少的建议使用默认值
+func ClassifyError(err error) *models.Error {
+	if err == nil {
+		return nil
+	}
+	var out *models.Error
+	var classified *models.Error
+	var argsErr models.ArgsError
+	var rpcErr *models.RPCError
+	var netErr net.Error
+	switch {
+	case errors.As(err, &classified):
+		copied := *classified
+		out = &copied
+	case errors.As(err, &argsErr):
+		out = &models.Error{Code: models.CodeInvalidParams, Params: argsErr}
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		out = &models.Error{Code: models.CodeTimeout, Retryable: true}
+	case errors.Is(err, ErrInstanceNotFound):
+		out = &models.Error{Code: models.CodeInstanceNotFound}
+	case errors.Is(err, ErrInstanceUnavailable), errors.Is(err, ErrPoolClosed):
+		out = &models.Error{Code: models.CodeInstanceUnavailable, Retryable: true}
+	case errors.As(err, &rpcErr):
+		out = &models.Error{Code: classifyRPCError(rpcErr), Zabbix: rpcErr}
+	default:
+		out = &models.Error{Code: models.CodeInternal}
+	}
+	if out.Message == "" {
+		out.Message = err.Error()
+	}
+	if out.Zabbix == nil && errors.As(err, &rpcErr) {
+		out.Zabbix = rpcErr
+	}
+	if out.Hint == "" {
+		out.Hint = defaultHints[out.Code]
+	}
+	if out.Err == nil {
+		out.Err = err
+	}
+	return out
+}
+
+// classifyRPCError 按错误码与错误信息归类 Zabbix API 错误。
+// Zabbix 的 -32602/-32500 同时用于参数错误、权限不足与会话失效，只能结合 message/data 判断。
+func classifyRPCError(e *models.RPCError) models.ErrorCode {
+	text := strings.ToLower(e.Message + " " + e.Data)
+	switch {
+	case isSessionError(e), containsAny(text, "login name or password is incorrect", "incorrect user name or password",
+		"account is blocked", "api token expired", "not authorized", "not authorised"):
+		return models.CodeAuthFailed
+	case e.Code == -32601, containsAny(text, "incorrect api", "incorrect method", "method not found"):
+		return models.CodeVersionUnsupported
+	case containsAny(text, "no permissions to referred object", "does not exist", "not exist"):
+		return models.CodeNotFound
+	case containsAny(text, "no permissions", "you do not have permission", "permission denied"):
+		return models.CodePermissionDenied
+	case containsAny(text, "already exists"):
+		return models.CodeConflict
+	case e.Code == -32602, e.Code == -32600, containsAny(text, "invalid parameter", "incorrect value"):
+		return models.CodeInvalidParams
+	}
+	return models.CodeInternal
+}
+
+// isSessionError 判断是否为会话失效，仅此类错误需要重新登录
+func isSessionError(e *models.RPCError) bool {
+	text := strings.ToLower(e.Message + " " + e.Data)
+	return containsAny(text, "session terminated", "re-login", "not authorized", "not authorised")
+}
+
+// authMethodRejected 判断是否因认证方式不被接受而失败（7.2 起拒绝 body auth，6.4 之前不识别 Authorization 头），
+// 只有这种情况才值得换一种认证方式重试，其它错误重试只会重复执行
+func authMethodRejected(e *models.RPCError) bool {
+	return isSessionError(e) || strings.Contains(strings.ToLower(e.Data), `unexpected parameter "auth"`)
+}
+
+func containsAny(s string, subs ...string) bool {
+	for _, sub := range subs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+package zabbix
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"zabbixMcp/models"
+)
+
+// timeoutError 实现 net.Error 的超时错误
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+var _ net.Error = timeoutError{}
+
+func rpc(code int, message, data string) *models.RPCError {
+	return &models.RPCError{Code: code, Message: message, Data: data}
+}
+
+func TestClassifyError(t *testing.T) {
+	cases := []struct {
+		name      string
+		err       error
+		code      models.ErrorCode
+		retryable bool
+	}{
+		{"会话失效", rpc(-32602, "Invalid params.", "Session terminated, re-login, please."), models.CodeAuthFailed, false},
+		{"未认证", rpc(-32602, "Invalid params.", "Not authorised."), models.CodeAuthFailed, false},
+		{"密码错误", rpc(-32500, "Application error.", "Incorrect user name or password or account is temporarily blocked."), models.CodeAuthFailed, false},
+		{"令牌过期", rpc(-32500, "Application error.", "API token expired."), models.CodeAuthFailed, false},
+		{"未知方法", rpc(-32601, "Method not found.", "Incorrect API \"foo\"."), models.CodeVersionUnsupported, false},
+		{"对象不存在", rpc(-32500, "Application error.", "No permissions to referred object or it does not exist!"), models.CodeNotFound, false},
+		{"无权限", rpc(-32500, "Application error.", "You do not have permission to perform this operation."), models.CodePermissionDenied, false},
+		{"已存在", rpc(-32602, "Invalid params.", "User with username \"Admin\" already exists."), models.CodeConflict, false},
+		{"参数错误", rpc(-32602, "Invalid params.", "Invalid parameter \"/1\": unexpected parameter \"foo\"."), models.CodeInvalidParams, false},
+		{"无效请求", rpc(-32600, "Invalid request.", ""), models.CodeInvalidParams, false},
+		{"未归类的业务错误", rpc(-32500, "Application error.", "Something else."), models.CodeInternal, false},
+		{"包装的 RPC 错误", fmt.Errorf("调用 user.get 失败: %w", rpc(-32500, "Application error.", "You do not have permission to perform this operation.")), models.CodePermissionDenied, false},
+		{"参数校验错误", models.ArgsError{{Arg: "instance", Problem: "为必填参数"}}, models.CodeInvalidParams, false},
+		{"上下文超时", fmt.Errorf("请求失败: %w", context.DeadlineExceeded), models.CodeTimeout, true},
+		{"网络超时", &net.OpError{Op: "read", Err: timeoutError{}}, models.CodeTimeout, true},
+		{"实例不存在", fmt.Errorf("zbx: %w", ErrInstanceNotFound), models.CodeInstanceNotFound, false},
+		{"实例不可用", fmt.Errorf("zbx: %w", ErrInstanceUnavailable), models.CodeInstanceUnavailable, true},
+		{"连接池已关闭", ErrPoolClosed, models.CodeInstanceUnavailable, true},
+		{"其他错误", errors.New("boom"), models.CodeInternal, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := ClassifyError(c.err)
+			if got.Code != c.code || got.Retryable != c.retryable {
+				t.Errorf("ClassifyError(%v) = %s retryable=%v，期望 %s retryable=%v", c.err, got.Code, got.Retryable, c.code, c.retryable)
+			}
+			if got.Message != c.err.Error() || got.Hint != defaultHints[c.code] || got.Err == nil || got.Err.Error() != c.err.Error() {
+				t.Errorf("ClassifyError(%v) = %+v，应保留原错误信息并带默认建议", c.err, got)
+			}
+			var rpcErr *models.RPCError
+			if errors.As(c.err, &rpcErr) && got.Zabbix != rpcErr {
+				t.Errorf("应附带 Zabbix 原始错误: %+v", got)
+			}
+		})
+	}
+
+	if ClassifyError(nil) != nil {
+		t.Error("ClassifyError(nil) 应为 nil")
+	}
+}
+
+// TestClassifyErrorKeepsClassified 已分类的错误保持原分类与建议，不修改原错误
+func TestClassifyErrorKeepsClassified(t *testing.T) {
+	orig := models.Errorf(models.CodeConflict, "预览之后对象已变化").WithHint("重新预览")
+	got := ClassifyError(fmt.Errorf("offboard: %w", orig))
+	if got.Code != models.CodeConflict || got.Hint != "重新预览" || got.Message != "预览之后对象已变化" {
+		t.Errorf("ClassifyError = %+v", got)
+	}
+	if got == orig {
+		t.Error("应返回副本，不应修改原错误")
+	}
+
+	bare := &models.Error{Code: models.CodeNotFound, Message: "用户不存在"}
+	if got := ClassifyError(bare); got.Hint != defaultHints[models.CodeNotFound] || bare.Hint != "" {
+		t.Errorf("缺少的建议应使用默认值且不修改原错误: %+v / %+v", got, bare)
+	}
+}
+
+func TestSessionAndAuthMethodErrors(t *testing.T) {
+	cases := []struct {
+		name           string
+		err            *models.RPCError
+		session        bool
+		methodRejected bool
+	}{
+		{"会话失效", rpc(-32602, "Invalid params.", "Session terminated, re-login, please."), true, true},
+		{"6.0 起未认证", rpc(-32602, "Invalid params.", "Not authorized."), true, true},
+		{"6.0 之前未认证", rpc(-32602, "Invalid params.", "Not authorised."), true, true},
+		{"7.2 拒绝请求体 auth", rpc(-32602, "Invalid params.", `Invalid parameter "/": unexpected parameter "auth".`), false, true},
+		{"其他意外参数", rpc(-32602, "Invalid params.", `Invalid parameter "/1": unexpected parameter "foo".`), false, false},
+		{"密码错误", rpc(-32500, "Application error.", "Incorrect user name or password or account is temporarily blocked."), false, false},
+		{"无权限", rpc(-32500, "Application error.", "You do not have permission to perform this operation."), false, false},
+		{"对象不存在", rpc(-32500, "Application error.", "No permissions to referred object or it does not exist!"), false, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := isSessionError(c.err); got != c.session {
+				t.Errorf("isSessionError = %v，期望 %v", got, c.session)
+			}
+			if got := authMethodRejected(c.err); got != c.methodRejected {
+				t.Errorf("authMethodRejected = %v，期望 %v", got, c.methodRejected)
+			}
+			if got := isAuthError(fmt.Errorf("wrapped: %w", c.err)); got != c.session {
+				t.Errorf("isAuthError = %v，期望 %v", got, c.session)
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-25 15:08:22
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-25 15:08:22
+ * @FilePath: \zabbix-mcp-go\zabbix\fixture.go
+ * @Description: 录制 Zabbix API 请求/响应为 fixture，供离线回放测试
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+package zabbix
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"zabbixMcp/logger"
+	"zabbixMcp/models"
+)
+
+// Fixture 一次录制的 API 调用（已脱敏）
+type Fixture struct {
+	Instance   string           `json:"instance"`
+	Method     string           `json:"method"`
+	Params     json.RawMessage  `json:"params"`
+	Result     json.RawMessage  `json:"result,omitempty"`
+	Error      *models.RPCError `json:"error,omitempty"`
+	RecordedAt time.Time        `json:"recorded_at"`
+}
+
+// scrubbedKeys 录制时替换为掩码的字段（精确匹配，避免误伤 tokenid 之类的ID字段）
+var scrubbedKeys = map[string]bool{
+	"auth":           true,
+	"passwd":         true,
+	"password":       true,
+	"currentpasswd":  true,
+	"current_passwd": true,
+	"token":          true,
+	"sessionid":      true,
+	"secret":         true,
+}
+
+// ScrubParams 对请求参数脱敏并规范化（按键排序），录制与回放匹配都使用同一规则
+func ScrubParams(raw json.RawMessage) json.RawMessage {
+	if len(raw) == 0 {
+		return json.RawMessage("null")
+	}
+	var v interface{}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return raw
+	}
+	out, err := json.Marshal(scrubValue(v))
+	if err != nil {
+		return raw
+	}
+	return out
+}
+
+// scrubResult 对响应脱敏；user.login 的结果本身就是会话ID
+func scrubResult(method string, raw json.RawMessage) json.RawMessage {
+	if method == "user.login" {
+		var session string
+		if json.Unmarshal(raw, &session) == nil {
+			out, _ := json.Marshal(models.MaskedValue)
+			return out
+		}
+	}
+	return ScrubParams(raw)
+}
+
+func scrubValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			if scrubbedKeys[strings.ToLower(k)] {
+				out[k] = models.MaskedValue
+				continue
+			}
+			out[k] = scrubValue(item)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, item := range val {
+			out[i] = scrubValue(item)
+		}
+		return out
+	}
+	return v
+}
+
+// LoadFixtures 读取 dir/<instance>/<method>/*.json，按方法与录制顺序返回
+func LoadFixtures(dir, instance string) ([]Fixture, error) {
+	files, err := filepath.Glob(filepath.Join(dir, instance, "*", "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	fixtures := make([]Fixture, 0, len(files))
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var f Fixture
+		if err := json.Unmarshal(data, &f); err != nil {
+			return nil, fmt.Errorf("解析 fixture %s 失败: %w", file, err)
+		}
+		fixtures = append(fixtures, f)
+	}
+	return fixtures, nil
+}
+
+// fixtureRecorder 将请求/响应写入 dir/<instance>/<method>/<序号>.json
+type fixtureRecorder struct {
+	dir      string
+	instance string
+	mu       sync.Mutex
+	seq      map[string]int
+}
+
+func newFixtureRecorder(dir, instance string) *fixtureRecorder {
+	return &fixtureRecorder{dir: dir, instance: instance, seq: map[string]int{}}
+}
+
+// record 记录一次调用（批量请求按 id 拆分为多条）；录制失败只记日志，不影响正常请求
+func (r *fixtureRecorder) record(request []byte, response []byte) {
+	type rpcRequest struct {
+		Method string          `json:"method"`
+		Params json.RawMessage `json:"params"`
+		ID     int             `json:"id"`
+	}
+	request = bytes.TrimSpace(request)
+	if len(request) > 0 && request[0] == '[' {
+		var reqs []rpcRequest
+		var resps []models.JSONRPCResponse
+		if json.Unmarshal(request, &reqs) != nil || json.Unmarshal(response, &resps) != nil {
+			return
+		}
+		byID := make(map[int]models.JSONRPCResponse, len(resps))
+		for _, resp := range resps {
+			byID[resp.ID] = resp
+		}
+		for _, req := range reqs {
+			if resp, ok := byID[req.ID]; ok {
+				r.recordOne(req.Method, req.Params, resp)
+			}
+		}
+		return
+	}
+	var req rpcRequest
+	var resp models.JSONRPCResponse
+	if json.Unmarshal(request, &req) != nil || json.Unmarshal(response, &resp) != nil {
+		return
+	}
+	r.recordOne(req.Method, req.Params, resp)
+}
+
+func (r *fixtureRecorder) recordOne(method string, params json.RawMessage, resp models.JSONRPCResponse) {
+	f := Fixture{
+		Instance:   r.instance,
+		Method:     method,
+		Params:     ScrubParams(params),
+		Error:      resp.Error,
+		RecordedAt: time.Now().UTC(),
+	}
+	if resp.Result != nil {
+		f.Result = scrubResult(method, resp.Result)
+	}
+	if err := r.write(f); err != nil {
+		logger.L().Warnf("录制 fixture 失败: %v", err)
+	}
+}
+
+func (r *fixtureRecorder) write(f Fixture) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	dir := filepath.Join(r.dir, r.instance, f.Method)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	seq, ok := r.seq[f.Method]
+	if !ok {
+		// 续接目录中已有的录制，避免覆盖
+		existing, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+		seq = len(existing)
+	}
+	seq++
+	r.seq[f.Method] = seq
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, fmt.Sprintf("%04d.json", seq)), data, 0o644)
+}
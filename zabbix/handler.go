@@ -20,8 +20,10 @@ import (
 // APIClient 抽象出最小可用的 Zabbix API 客户端能力
 type APIClient interface {
 	Call(ctx context.Context, method string, params interface{}, result interface{}) error // 执行一次API调用
+	CallBatch(ctx context.Context, calls []BatchCall) error                                // 一次往返执行多个互不依赖的调用
 	GetDetailedVersionFeatures() map[string]interface{}                                    // 获取详细的版本特性
 	AdaptAPIParams(method string, spec models.ParamSpec) map[string]interface{}            // 适配API参数
+	CompatReport(method string, all bool) (*CompatReport, error)                           // 版本兼容规则说明
 }
 
 // ClientLease 表示一次安全的租借句柄，用于确保归还
@@ -6,6 +6,9 @@ import (
 	"fmt"
 	"sync"
 	"time"
+
+	"zabbixMcp/metrics"
+	"zabbixMcp/tracing"
 )
 
 // ClientInfo 描述连接池中客户端的详细信息
@@ -108,6 +111,12 @@ func (p *ClientPool) Acquire(ctx context.Context) (ClientLease, error) {
 	if ctx == nil {
 		ctx = context.Background()
 	}
+	start := time.Now()
+	ctx, span := tracing.Start(ctx, "pool.Acquire")
+	defer func() {
+		metrics.ObserveLeaseWait("", time.Since(start))
+		span.End()
+	}()
 	select {
 	case <-ctx.Done():
 		return nil, ctx.Err()
@@ -128,8 +137,14 @@ func (p *ClientPool) AcquireByInstance(ctx context.Context, instance string) (Cl
 	if ctx == nil {
 		ctx = context.Background()
 	}
+	start := time.Now()
+	ctx, span := tracing.Start(ctx, "pool.AcquireByInstance", tracing.AttrInstance.String(instance))
+	defer func() {
+		metrics.ObserveLeaseWait(instance, time.Since(start))
+		span.End()
+	}()
 	if !p.hasInstance(instance) {
-		return nil, fmt.Errorf("instance %s not found", instance)
+		return nil, fmt.Errorf("%w: %s", ErrInstanceNotFound, instance)
 	}
 	for {
 		select {
@@ -168,10 +168,8 @@ func (vd *VersionDetector) GetDetailedVersionFeatures() map[string]interface{} {
 	return features
 }
 
-// AdaptAPIParams 根据版本适配API参数
+// AdaptAPIParams 根据版本适配API参数，规则见 compat.yaml；版本探测失败时原样返回
 func (vd *VersionDetector) AdaptAPIParams(method string, spec models.ParamSpec) map[string]interface{} {
-	version, err := vd.DetectVersion(context.Background())
-	logger.L().Info(version.Full)
 	var params map[string]interface{}
 	if spec != nil {
 		params = spec.BuildParams()
@@ -179,105 +177,25 @@ func (vd *VersionDetector) AdaptAPIParams(method string, spec models.ParamSpec)
 		params = map[string]interface{}{}
 	}
 
+	version, err := vd.DetectVersion(context.Background())
 	if err != nil {
+		logger.L().Warnf("版本探测失败，跳过 %s 参数适配: %v", method, err)
 		return params
 	}
+	return ApplyCompatRules(method, version, params)
+}
 
-	adaptedParams := make(map[string]interface{}, len(params))
-	for k, v := range params {
-		adaptedParams[k] = v
+// CompatReport 列出某方法（为空时全部方法）的兼容规则及其在当前版本是否生效；all 为 false 时只返回生效的规则
+func (vd *VersionDetector) CompatReport(method string, all bool) (*CompatReport, error) {
+	version, err := vd.DetectVersion(context.Background())
+	if err != nil {
+		return nil, err
 	}
-
-	// 根据版本调整参数
-	switch method {
-	case "host.get":
-		if version.Major < 4 {
-			// 旧版本不支持某些参数
-			delete(adaptedParams, "selectTags")
-			// adaptedParams["output"] = []string{"hostid", "name"}
-		}
-	// ========================= User API =========================
-	case "user.get":
-		if version.Major > 5 {
-			if f, ok := adaptedParams["filter"].(map[string]interface{}); ok {
-				delete(f, "alias")
-				if len(f) == 0 {
-					delete(adaptedParams, "filter")
-				}
-			}
-		} else {
-			if f, ok := adaptedParams["filter"].(map[string]interface{}); ok {
-				delete(f, "username")
-				if len(f) == 0 {
-					delete(adaptedParams, "filter")
-				}
-			}
-		}
-	case "user.create":
-		// Zabbix 5.x 及更早版本不使用 `username` 字段，使用 `alias`。
-		// 对于 6.x/7.x，使用 `username`。
-		if version.Major <= 5 {
-			if un, ok := adaptedParams["username"]; ok {
-				if s, ok2 := un.(string); ok2 {
-					adaptedParams["alias"] = s
-				}
-				delete(adaptedParams, "username")
-			}
-			delete(adaptedParams, "roleid")
-		} else {
-			// >=6: 尽量保留 username；如果只有 alias 提供也不会出错
-			delete(adaptedParams, "alias")
-		}
-	case "usergroup.get":
-		if version.Major < 6 {
-			if v, ok := adaptedParams["selectUsers"]; ok {
-				if fields, ok := v.([]string); ok {
-					filtered := fields[:0] // 复用底层数组
-					for _, f := range fields {
-						if f != "username" {
-							filtered = append(filtered, f)
-						}
-					}
-					if len(filtered) == 0 {
-						delete(adaptedParams, "selectUsers")
-					} else {
-						adaptedParams["selectUsers"] = filtered
-					}
-				}
-			}
-		} else {
-			if v, ok := adaptedParams["selectUsers"]; ok {
-				if fields, ok := v.([]string); ok {
-					filtered := fields[:0] // 复用底层数组
-					for _, f := range fields {
-						if f != "alias" {
-							filtered = append(filtered, f)
-						}
-					}
-					if len(filtered) == 0 {
-						delete(adaptedParams, "selectUsers")
-					} else {
-						adaptedParams["selectUsers"] = filtered
-					}
-				}
-			}
-		}
-	// ========================= Item API =========================
-	case "item.get":
-		if version.Major < 4 {
-			delete(adaptedParams, "selectTags")
-			delete(adaptedParams, "selectPreprocessing")
-		}
-	case "trigger.get":
-		if version.Major < 4 {
-			delete(adaptedParams, "selectTags")
-			delete(adaptedParams, "selectDependencies")
-		}
-	case "template.get":
-		if version.Major < 5 {
-			delete(adaptedParams, "selectTags")
+	report := &CompatReport{Instance: vd.client.Instance, Version: version.Full, Method: method, Rules: []CompatRuleStatus{}}
+	for _, r := range CompatRulesFor(method, version) {
+		if all || r.Applies {
+			report.Rules = append(report.Rules, r)
 		}
 	}
-
-	return adaptedParams
+	return report, nil
 }
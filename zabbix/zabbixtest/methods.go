@@ -0,0 +1,995 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-25 10:02:37
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-25 10:02:37
+ * @FilePath: \zabbix-mcp-go\zabbix\zabbixtest\methods.go
+ * @Description: 模拟服务器支持的 API 方法
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+package zabbixtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"zabbixMcp/models"
+)
+
+// callContext 单次调用的认证上下文
+type callContext struct {
+	userID string
+	token  string
+}
+
+type methodFunc func(s *Server, cc *callContext, raw json.RawMessage) (interface{}, *models.RPCError)
+
+// methods 支持的 API 方法；未列出的方法返回 -32601
+var methods = map[string]methodFunc{
+	"apiinfo.version": apiInfoVersion,
+	"user.login":      userLogin,
+	"user.logout":     userLogout,
+	"user.get":        userGet,
+	"user.create":     userCreate,
+	"user.update":     userUpdate,
+	"user.delete":     userDelete,
+	"usergroup.get":   userGroupGet,
+	"role.get":        roleGet,
+	"mediatype.get":   mediaTypeGet,
+	"token.get":       tokenGet,
+	"token.delete":    tokenDelete,
+	"hostgroup.get":   hostGroupGet,
+	"host.get":        hostGet,
+	"trigger.get":     triggerGet,
+	"problem.get":     problemGet,
+	"event.get":       eventGet,
+}
+
+// ============================= apiinfo / 认证 =============================
+
+func apiInfoVersion(s *Server, _ *callContext, _ json.RawMessage) (interface{}, *models.RPCError) {
+	return s.opts.Version, nil
+}
+
+// userLogin 5.4 之前使用 user，5.4~6.2 兼容 user/username，6.4 起只接受 username
+func userLogin(s *Server, _ *callContext, raw json.RawMessage) (interface{}, *models.RPCError) {
+	params, rpcErr := decodeParams(raw)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	allowed := map[string]bool{"password": true, "userData": true}
+	if !s.atLeast(6, 4) {
+		allowed["user"] = true
+	}
+	if s.atLeast(5, 4) {
+		allowed["username"] = true
+	}
+	if rpcErr := checkKeys(params, allowed); rpcErr != nil {
+		return nil, rpcErr
+	}
+	name, _ := params["username"].(string)
+	if name == "" {
+		name, _ = params["user"].(string)
+	}
+	password, _ := params["password"].(string)
+	if name == "" {
+		field := "username"
+		if !s.atLeast(5, 4) {
+			field = "user"
+		}
+		return nil, invalidParams(fmt.Sprintf(`Invalid parameter "/": the parameter "%s" is missing.`, field))
+	}
+
+	s.store.mu.Lock()
+	var user *User
+	for _, u := range s.store.users {
+		if u.Username == name {
+			user = u
+			break
+		}
+	}
+	var ok bool
+	if user != nil {
+		_, status := s.store.accessLocked(user)
+		ok = user.Passwd == password && status == "0"
+	}
+	s.store.mu.Unlock()
+	if !ok {
+		return nil, appError("Incorrect user name or password or account is temporarily blocked.")
+	}
+
+	session := s.newSession(user.ID)
+	if boolParam(params, "userData") {
+		return object{"userid": user.ID, "sessionid": session}, nil
+	}
+	return session, nil
+}
+
+func userLogout(s *Server, cc *callContext, _ json.RawMessage) (interface{}, *models.RPCError) {
+	s.mu.Lock()
+	delete(s.sessions, cc.token)
+	s.mu.Unlock()
+	return true, nil
+}
+
+// ============================= user =============================
+
+// renderUser 按版本渲染用户字段：alias/username、type/roleid
+func (s *Server) renderUser(u *User) object {
+	obj := object{
+		"userid":         u.ID,
+		"name":           u.Name,
+		"surname":        u.Surname,
+		"url":            "",
+		"autologin":      "0",
+		"autologout":     "15m",
+		"lang":           "default",
+		"refresh":        "30s",
+		"theme":          "default",
+		"attempt_failed": "0",
+		"attempt_ip":     "",
+		"attempt_clock":  "0",
+		"rows_per_page":  "50",
+	}
+	if s.atLeast(5, 4) {
+		obj["username"] = u.Username
+	} else {
+		obj["alias"] = u.Username
+	}
+	if s.atLeast(5, 2) {
+		obj["roleid"] = u.RoleID
+		obj["timezone"] = "default"
+	} else {
+		obj["type"] = u.Type
+	}
+	return obj
+}
+
+func (s *Server) renderUserGroup(g *UserGroup) object {
+	return object{
+		"usrgrpid":     g.ID,
+		"name":         g.Name,
+		"gui_access":   g.GUIAccess,
+		"users_status": g.UsersStatus,
+		"debug_mode":   "0",
+	}
+}
+
+func (s *Server) renderMedia(m Media) object {
+	return object{
+		"mediaid":     m.MediaID,
+		"mediatypeid": m.MediaTypeID,
+		"sendto":      m.SendTo,
+		"active":      m.Active,
+		"severity":    "63",
+		"period":      "1-7,00:00-24:00",
+	}
+}
+
+func (s *Server) userFieldName() string {
+	if s.atLeast(5, 4) {
+		return "username"
+	}
+	return "alias"
+}
+
+func userGet(s *Server, _ *callContext, raw json.RawMessage) (interface{}, *models.RPCError) {
+	params, rpcErr := decodeParams(raw)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	q := newGetQuery(params, "userid", s.renderUser(&User{}))
+	if rpcErr := q.validate(); rpcErr != nil {
+		return nil, rpcErr
+	}
+	if rpcErr := validateSelect(params, "selectUsrgrps", s.renderUserGroup(&UserGroup{})); rpcErr != nil {
+		return nil, rpcErr
+	}
+	userIDs, byUser := idsParam(params, "userids")
+	groupIDs, byGroup := idsParam(params, "usrgrpids")
+	mediaTypeIDs, byMediaType := idsParam(params, "mediatypeids")
+
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+	var objs []object
+	for _, id := range sortedKeys(s.store.users) {
+		u := s.store.users[id]
+		if byUser && !userIDs[u.ID] || byGroup && !anyIn(u.GroupIDs, groupIDs) {
+			continue
+		}
+		if byMediaType {
+			found := false
+			for _, m := range u.Medias {
+				found = found || mediaTypeIDs[m.MediaTypeID]
+			}
+			if !found {
+				continue
+			}
+		}
+		obj := s.renderUser(u)
+		if !q.match(obj) {
+			continue
+		}
+		if boolParam(params, "getAccess") {
+			gui, status := s.store.accessLocked(u)
+			obj["gui_access"] = gui
+			obj["users_status"] = status
+			obj["debug_mode"] = "0"
+		}
+		if output, ok := selectOutput(params, "selectUsrgrps"); ok {
+			groups := []object{}
+			for _, gid := range u.GroupIDs {
+				if g, ok := s.store.userGroups[gid]; ok {
+					groups = append(groups, project(s.renderUserGroup(g), output, "usrgrpid"))
+				}
+			}
+			obj["usrgrps"] = groups
+		}
+		if output, ok := selectOutput(params, "selectMedias"); ok {
+			medias := []object{}
+			for _, m := range u.Medias {
+				medias = append(medias, project(s.renderMedia(m), output, "mediaid"))
+			}
+			obj["medias"] = medias
+		}
+		if _, ok := selectOutput(params, "selectRole"); ok && s.atLeast(5, 2) {
+			if r, ok := s.store.roles[u.RoleID]; ok {
+				obj["role"] = object{"roleid": r.ID, "name": r.Name, "type": r.Type, "readonly": "0"}
+			} else {
+				obj["role"] = []object{}
+			}
+		}
+		objs = append(objs, obj)
+	}
+	return q.finish(objs), nil
+}
+
+// userMutableKeys user.create/user.update 在当前版本允许的字段
+func (s *Server) userMutableKeys() map[string]bool {
+	keys := map[string]bool{
+		s.userFieldName(): true,
+		"passwd":          true, "name": true, "surname": true, "usrgrps": true,
+		"url": true, "autologin": true, "autologout": true, "lang": true,
+		"refresh": true, "theme": true, "rows_per_page": true,
+	}
+	if s.atLeast(5, 2) {
+		keys["roleid"] = true
+		keys["timezone"] = true
+		keys["medias"] = true
+	} else {
+		keys["type"] = true
+		keys["user_medias"] = true
+	}
+	return keys
+}
+
+// applyUser 将 create/update 参数写入用户对象（调用方持有 store 锁）
+func (s *Server) applyUser(u *User, params map[string]interface{}) *models.RPCError {
+	if v, ok := stringParam(params, s.userFieldName()); ok {
+		if v == "" {
+			return invalidParams(fmt.Sprintf(`Invalid parameter "/1/%s": cannot be empty.`, s.userFieldName()))
+		}
+		for _, other := range s.store.users {
+			if other.ID != u.ID && other.Username == v {
+				return invalidParams(fmt.Sprintf(`User with %s "%s" already exists.`, s.userFieldName(), v))
+			}
+		}
+		u.Username = v
+	}
+	if v, ok := stringParam(params, "passwd"); ok {
+		u.Passwd = v
+	}
+	if v, ok := stringParam(params, "name"); ok {
+		u.Name = v
+	}
+	if v, ok := stringParam(params, "surname"); ok {
+		u.Surname = v
+	}
+	if v, ok := stringParam(params, "roleid"); ok {
+		if _, exists := s.store.roles[v]; !exists {
+			return invalidParams(fmt.Sprintf(`User role with ID "%s" is not available.`, v))
+		}
+		u.RoleID = v
+	}
+	if v, ok := stringParam(params, "type"); ok {
+		u.Type = v
+	}
+	if v, ok := params["usrgrps"]; ok {
+		list, isList := v.([]interface{})
+		if !isList {
+			return invalidParams(`Invalid parameter "/1/usrgrps": an array is expected.`)
+		}
+		var ids []string
+		for i, item := range list {
+			m, isMap := item.(map[string]interface{})
+			id, hasID := stringParam(m, "usrgrpid")
+			if !isMap || !hasID {
+				return invalidParams(fmt.Sprintf(`Invalid parameter "/1/usrgrps/%d": the parameter "usrgrpid" is missing.`, i+1))
+			}
+			if _, exists := s.store.userGroups[id]; !exists {
+				return noPermissions()
+			}
+			ids = append(ids, id)
+		}
+		u.GroupIDs = ids
+	}
+	for _, key := range []string{"medias", "user_medias"} {
+		v, ok := params[key]
+		if !ok {
+			continue
+		}
+		list, isList := v.([]interface{})
+		if !isList {
+			return invalidParams(fmt.Sprintf(`Invalid parameter "/1/%s": an array is expected.`, key))
+		}
+		var medias []Media
+		for i, item := range list {
+			m, _ := item.(map[string]interface{})
+			typeID, hasType := stringParam(m, "mediatypeid")
+			if !hasType {
+				return invalidParams(fmt.Sprintf(`Invalid parameter "/1/%s/%d": the parameter "mediatypeid" is missing.`, key, i+1))
+			}
+			if _, exists := s.store.mediaTypes[typeID]; !exists {
+				return noPermissions()
+			}
+			sendTo := stringList(m["sendto"])
+			active, _ := stringParam(m, "active")
+			if active == "" {
+				active = "0"
+			}
+			media := Media{MediaID: s.store.newIDLocked(), MediaTypeID: typeID, Active: active}
+			if len(sendTo) > 0 {
+				media.SendTo = sendTo[0]
+			}
+			medias = append(medias, media)
+		}
+		u.Medias = medias
+	}
+	return nil
+}
+
+func userCreate(s *Server, _ *callContext, raw json.RawMessage) (interface{}, *models.RPCError) {
+	params, rpcErr := decodeParams(raw)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	if rpcErr := checkKeys(params, s.userMutableKeys()); rpcErr != nil {
+		return nil, rpcErr
+	}
+	field := s.userFieldName()
+	if _, ok := params[field]; !ok {
+		return nil, invalidParams(fmt.Sprintf(`Invalid parameter "/1": the parameter "%s" is missing.`, field))
+	}
+	if _, ok := params["usrgrps"]; !ok && !s.atLeast(5, 2) {
+		return nil, invalidParams(`Invalid parameter "/1": the parameter "usrgrps" is missing.`)
+	}
+
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+	u := &User{RoleID: "1", Type: "1"}
+	if rpcErr := s.applyUser(u, params); rpcErr != nil {
+		return nil, rpcErr
+	}
+	u.ID = s.store.newIDLocked()
+	s.store.users[u.ID] = u
+	return object{"userids": []string{u.ID}}, nil
+}
+
+func userUpdate(s *Server, cc *callContext, raw json.RawMessage) (interface{}, *models.RPCError) {
+	params, rpcErr := decodeParams(raw)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	allowed := s.userMutableKeys()
+	allowed["userid"] = true
+	if s.atLeast(6, 4) {
+		allowed["current_passwd"] = true
+	}
+	if rpcErr := checkKeys(params, allowed); rpcErr != nil {
+		return nil, rpcErr
+	}
+	id, ok := stringParam(params, "userid")
+	if !ok {
+		return nil, invalidParams(`Invalid parameter "/1": the parameter "userid" is missing.`)
+	}
+
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+	existing, ok := s.store.users[id]
+	if !ok {
+		return nil, noPermissions()
+	}
+	if _, changing := params["passwd"]; changing && id == cc.userID && s.atLeast(6, 4) {
+		current, _ := stringParam(params, "current_passwd")
+		if current == "" {
+			return nil, invalidParams(`Invalid parameter "/1": the parameter "current_passwd" is missing.`)
+		}
+		if current != existing.Passwd {
+			return nil, invalidParams("Incorrect current password.")
+		}
+	}
+	updated := cloneUser(existing)
+	if rpcErr := s.applyUser(&updated, params); rpcErr != nil {
+		return nil, rpcErr
+	}
+	s.store.users[id] = &updated
+	return object{"userids": []string{id}}, nil
+}
+
+func userDelete(s *Server, cc *callContext, raw json.RawMessage) (interface{}, *models.RPCError) {
+	ids, rpcErr := decodeIDs(raw)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+	for _, id := range ids {
+		if _, ok := s.store.users[id]; !ok {
+			return nil, noPermissions()
+		}
+		if id == cc.userID {
+			return nil, appError("User is not allowed to delete himself.")
+		}
+	}
+	for _, id := range ids {
+		delete(s.store.users, id)
+		for tid, t := range s.store.tokens {
+			if t.UserID == id {
+				delete(s.store.tokens, tid)
+			}
+		}
+	}
+	return object{"userids": ids}, nil
+}
+
+// ============================= usergroup / role / mediatype / token =============================
+
+func userGroupGet(s *Server, _ *callContext, raw json.RawMessage) (interface{}, *models.RPCError) {
+	params, rpcErr := decodeParams(raw)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	q := newGetQuery(params, "usrgrpid", s.renderUserGroup(&UserGroup{}))
+	if rpcErr := q.validate(); rpcErr != nil {
+		return nil, rpcErr
+	}
+	if rpcErr := validateSelect(params, "selectUsers", s.renderUser(&User{})); rpcErr != nil {
+		return nil, rpcErr
+	}
+	groupIDs, byGroup := idsParam(params, "usrgrpids")
+	userIDs, byUser := idsParam(params, "userids")
+
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+	var objs []object
+	for _, id := range sortedKeys(s.store.userGroups) {
+		g := s.store.userGroups[id]
+		if byGroup && !groupIDs[g.ID] {
+			continue
+		}
+		var members []*User
+		for _, uid := range sortedKeys(s.store.users) {
+			if u := s.store.users[uid]; containsString(u.GroupIDs, g.ID) {
+				members = append(members, u)
+			}
+		}
+		if byUser {
+			found := false
+			for _, u := range members {
+				found = found || userIDs[u.ID]
+			}
+			if !found {
+				continue
+			}
+		}
+		obj := s.renderUserGroup(g)
+		if !q.match(obj) {
+			continue
+		}
+		if output, ok := selectOutput(params, "selectUsers"); ok {
+			users := []object{}
+			for _, u := range members {
+				users = append(users, project(s.renderUser(u), output, "userid"))
+			}
+			obj["users"] = users
+		}
+		objs = append(objs, obj)
+	}
+	return q.finish(objs), nil
+}
+
+func roleGet(s *Server, _ *callContext, raw json.RawMessage) (interface{}, *models.RPCError) {
+	if !s.atLeast(5, 2) {
+		return nil, &models.RPCError{Code: CodeMethodNotFound, Message: "Method not found.", Data: `Incorrect API "role".`}
+	}
+	params, rpcErr := decodeParams(raw)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	render := func(r *Role) object {
+		return object{"roleid": r.ID, "name": r.Name, "type": r.Type, "readonly": "0"}
+	}
+	q := newGetQuery(params, "roleid", render(&Role{}))
+	if rpcErr := q.validate(); rpcErr != nil {
+		return nil, rpcErr
+	}
+	roleIDs, byRole := idsParam(params, "roleids")
+
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+	var objs []object
+	for _, id := range sortedKeys(s.store.roles) {
+		if byRole && !roleIDs[id] {
+			continue
+		}
+		if obj := render(s.store.roles[id]); q.match(obj) {
+			objs = append(objs, obj)
+		}
+	}
+	return q.finish(objs), nil
+}
+
+func mediaTypeGet(s *Server, _ *callContext, raw json.RawMessage) (interface{}, *models.RPCError) {
+	params, rpcErr := decodeParams(raw)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	// 4.4 之前媒介类型名称字段为 description
+	nameField := "name"
+	if !s.atLeast(4, 4) {
+		nameField = "description"
+	}
+	render := func(m *MediaType) object {
+		return object{"mediatypeid": m.ID, nameField: m.Name, "type": m.Type, "status": m.Status,
+			"smtp_server": m.SMTPServer, "smtp_port": m.SMTPPort, "smtp_helo": "", "smtp_email": m.SMTPEmail,
+			"smtp_security": "0", "smtp_verify_peer": "0", "smtp_verify_host": "0", "smtp_authentication": "0", "username": ""}
+	}
+	q := newGetQuery(params, "mediatypeid", render(&MediaType{}))
+	if rpcErr := q.validate(); rpcErr != nil {
+		return nil, rpcErr
+	}
+	typeIDs, byType := idsParam(params, "mediatypeids")
+
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+	var objs []object
+	for _, id := range sortedKeys(s.store.mediaTypes) {
+		if byType && !typeIDs[id] {
+			continue
+		}
+		if obj := render(s.store.mediaTypes[id]); q.match(obj) {
+			objs = append(objs, obj)
+		}
+	}
+	return q.finish(objs), nil
+}
+
+func tokenGet(s *Server, _ *callContext, raw json.RawMessage) (interface{}, *models.RPCError) {
+	if !s.atLeast(5, 4) {
+		return nil, &models.RPCError{Code: CodeMethodNotFound, Message: "Method not found.", Data: `Incorrect API "token".`}
+	}
+	params, rpcErr := decodeParams(raw)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	render := func(t *APIToken) object {
+		return object{"tokenid": t.ID, "name": t.Name, "userid": t.UserID, "status": t.Status, "description": "", "expires_at": "0"}
+	}
+	q := newGetQuery(params, "tokenid", render(&APIToken{}))
+	if rpcErr := q.validate(); rpcErr != nil {
+		return nil, rpcErr
+	}
+	tokenIDs, byToken := idsParam(params, "tokenids")
+	userIDs, byUser := idsParam(params, "userids")
+
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+	var objs []object
+	for _, id := range sortedKeys(s.store.tokens) {
+		t := s.store.tokens[id]
+		if byToken && !tokenIDs[id] || byUser && !userIDs[t.UserID] {
+			continue
+		}
+		if obj := render(t); q.match(obj) {
+			objs = append(objs, obj)
+		}
+	}
+	return q.finish(objs), nil
+}
+
+func tokenDelete(s *Server, _ *callContext, raw json.RawMessage) (interface{}, *models.RPCError) {
+	if !s.atLeast(5, 4) {
+		return nil, &models.RPCError{Code: CodeMethodNotFound, Message: "Method not found.", Data: `Incorrect API "token".`}
+	}
+	ids, rpcErr := decodeIDs(raw)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+	for _, id := range ids {
+		if _, ok := s.store.tokens[id]; !ok {
+			return nil, noPermissions()
+		}
+	}
+	for _, id := range ids {
+		delete(s.store.tokens, id)
+	}
+	return object{"tokenids": ids}, nil
+}
+
+// ============================= hostgroup / host =============================
+
+func (s *Server) renderHostGroup(g *HostGroup) object {
+	return object{"groupid": g.ID, "name": g.Name, "flags": "0", "internal": "0"}
+}
+
+// renderHost 按版本渲染主机字段：proxy_hostid/proxyid
+func (s *Server) renderHost(h *Host) object {
+	obj := object{
+		"hostid":      h.ID,
+		"host":        h.Host,
+		"name":        h.Name,
+		"status":      h.Status,
+		"description": "",
+		"flags":       "0",
+	}
+	if s.atLeast(7, 0) {
+		obj["proxyid"] = h.ProxyID
+	} else {
+		obj["proxy_hostid"] = h.ProxyID
+	}
+	return obj
+}
+
+func hostGroupGet(s *Server, _ *callContext, raw json.RawMessage) (interface{}, *models.RPCError) {
+	params, rpcErr := decodeParams(raw)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	q := newGetQuery(params, "groupid", s.renderHostGroup(&HostGroup{}))
+	if rpcErr := q.validate(); rpcErr != nil {
+		return nil, rpcErr
+	}
+	groupIDs, byGroup := idsParam(params, "groupids")
+	hostIDs, byHost := idsParam(params, "hostids")
+
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+	var objs []object
+	for _, id := range sortedKeys(s.store.hostGroups) {
+		if byGroup && !groupIDs[id] {
+			continue
+		}
+		if byHost {
+			found := false
+			for hid := range hostIDs {
+				if h, ok := s.store.hosts[hid]; ok && containsString(h.GroupIDs, id) {
+					found = true
+				}
+			}
+			if !found {
+				continue
+			}
+		}
+		if obj := s.renderHostGroup(s.store.hostGroups[id]); q.match(obj) {
+			objs = append(objs, obj)
+		}
+	}
+	return q.finish(objs), nil
+}
+
+// hostGet 6.2 起 selectGroups 改为 selectHostGroups、输出 hostgroups；7.0 起移除 selectGroups
+func hostGet(s *Server, _ *callContext, raw json.RawMessage) (interface{}, *models.RPCError) {
+	params, rpcErr := decodeParams(raw)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	q := newGetQuery(params, "hostid", s.renderHost(&Host{}))
+	if rpcErr := q.validate(); rpcErr != nil {
+		return nil, rpcErr
+	}
+	if _, ok := params["selectGroups"]; ok && s.atLeast(7, 0) {
+		return nil, invalidParams(`Invalid parameter "/": unexpected parameter "selectGroups".`)
+	}
+	if _, ok := params["selectHostGroups"]; ok && !s.atLeast(6, 2) {
+		return nil, invalidParams(`Invalid parameter "/": unexpected parameter "selectHostGroups".`)
+	}
+	hostIDs, byHost := idsParam(params, "hostids")
+	groupIDs, byGroup := idsParam(params, "groupids")
+
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+	var objs []object
+	for _, id := range sortedKeys(s.store.hosts) {
+		h := s.store.hosts[id]
+		if h.Status == "3" || byHost && !hostIDs[id] || byGroup && !anyIn(h.GroupIDs, groupIDs) {
+			continue
+		}
+		obj := s.renderHost(h)
+		if !q.match(obj) {
+			continue
+		}
+		for key, field := range map[string]string{"selectGroups": "groups", "selectHostGroups": "hostgroups"} {
+			output, ok := selectOutput(params, key)
+			if !ok {
+				continue
+			}
+			groups := []object{}
+			for _, gid := range h.GroupIDs {
+				if g, ok := s.store.hostGroups[gid]; ok {
+					groups = append(groups, project(s.renderHostGroup(g), output, "groupid"))
+				}
+			}
+			obj[field] = groups
+		}
+		objs = append(objs, obj)
+	}
+	return q.finish(objs), nil
+}
+
+// ============================= trigger =============================
+
+func renderTrigger(t *Trigger) object {
+	return object{
+		"triggerid":   t.ID,
+		"templateid":  t.TemplateID,
+		"description": t.Description,
+		"expression":  t.Expression,
+		"comments":    "",
+		"url":         "",
+		"priority":    t.Priority,
+		"status":      t.Status,
+		"value":       "0",
+		"state":       "0",
+		"error":       "",
+		"lastchange":  "0",
+		"flags":       "0",
+	}
+}
+
+// triggerGet 支持 triggerids/hostids/templateids、filter 与 selectHosts/selectTags；
+// 与 API 一致，selectHosts 对模板触发器返回模板（status 3）
+func triggerGet(s *Server, _ *callContext, raw json.RawMessage) (interface{}, *models.RPCError) {
+	params, rpcErr := decodeParams(raw)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	q := newGetQuery(params, "triggerid", renderTrigger(&Trigger{}))
+	if rpcErr := q.validate(); rpcErr != nil {
+		return nil, rpcErr
+	}
+	triggerIDs, byTrigger := idsParam(params, "triggerids")
+	hostIDs, byHost := idsParam(params, "hostids")
+	templateIDs, byTemplate := idsParam(params, "templateids")
+
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+	var objs []object
+	for _, id := range sortedKeys(s.store.triggers) {
+		t := s.store.triggers[id]
+		if byTrigger && !triggerIDs[id] || byHost && !hostIDs[t.HostID] || byTemplate && !templateIDs[t.HostID] {
+			continue
+		}
+		obj := renderTrigger(t)
+		if !q.match(obj) {
+			continue
+		}
+		if output, ok := selectOutput(params, "selectHosts"); ok {
+			hosts := []object{}
+			if h, ok := s.store.hosts[t.HostID]; ok {
+				hosts = append(hosts, project(s.renderHost(h), output, "hostid"))
+			}
+			obj["hosts"] = hosts
+		}
+		if _, ok := selectOutput(params, "selectTags"); ok {
+			obj["tags"] = renderTags(t.Tags)
+		}
+		objs = append(objs, obj)
+	}
+	return q.finish(objs), nil
+}
+
+// ============================= problem / event =============================
+
+func renderEvent(p *Problem, recovery bool) object {
+	obj := object{
+		"eventid":       p.EventID,
+		"source":        "0",
+		"object":        "0",
+		"objectid":      p.TriggerID,
+		"clock":         strconv.FormatInt(p.Clock, 10),
+		"ns":            "0",
+		"r_eventid":     p.REventID,
+		"r_clock":       strconv.FormatInt(p.RClock, 10),
+		"r_ns":          "0",
+		"correlationid": "0",
+		"userid":        "0",
+		"name":          p.Name,
+		"acknowledged":  p.Acknowledged,
+		"severity":      p.Severity,
+		"opdata":        "",
+		"suppressed":    "0",
+	}
+	if recovery {
+		obj["eventid"] = p.REventID
+		obj["clock"] = strconv.FormatInt(p.RClock, 10)
+		obj["r_eventid"] = "0"
+		obj["r_clock"] = "0"
+	}
+	return obj
+}
+
+func renderTags(tags []Tag) []object {
+	out := make([]object, 0, len(tags))
+	for _, t := range tags {
+		out = append(out, object{"tag": t.Tag, "value": t.Value})
+	}
+	return out
+}
+
+// eventFilter problem.get/event.get 共用的过滤参数
+type eventFilter struct {
+	eventIDs, hostIDs, objectIDs   map[string]bool
+	byEvent, byHost, byObject      bool
+	eventFrom, eventTill           int64
+	timeFrom, timeTill             int64
+	hasFrom, hasTill, hasTF, hasTT bool
+	severities                     map[string]bool
+	bySeverity                     bool
+}
+
+func newEventFilter(params map[string]interface{}) eventFilter {
+	var f eventFilter
+	f.eventIDs, f.byEvent = idsParam(params, "eventids")
+	f.hostIDs, f.byHost = idsParam(params, "hostids")
+	f.objectIDs, f.byObject = idsParam(params, "objectids")
+	f.severities, f.bySeverity = idsParam(params, "severities")
+	f.eventFrom, f.hasFrom = int64Param(params, "eventid_from")
+	f.eventTill, f.hasTill = int64Param(params, "eventid_till")
+	f.timeFrom, f.hasTF = int64Param(params, "time_from")
+	f.timeTill, f.hasTT = int64Param(params, "time_till")
+	return f
+}
+
+func (f eventFilter) match(p *Problem, eventID string, clock int64) bool {
+	id, _ := strconv.ParseInt(eventID, 10, 64)
+	switch {
+	case f.byEvent && !f.eventIDs[eventID],
+		f.byHost && !f.hostIDs[p.HostID],
+		f.byObject && !f.objectIDs[p.TriggerID],
+		f.bySeverity && !f.severities[p.Severity],
+		f.hasFrom && id < f.eventFrom,
+		f.hasTill && id > f.eventTill,
+		f.hasTF && clock < f.timeFrom,
+		f.hasTT && clock > f.timeTill:
+		return false
+	}
+	return true
+}
+
+// problemGet 默认只返回未恢复的问题，recent=true 时包含最近恢复的问题
+func problemGet(s *Server, _ *callContext, raw json.RawMessage) (interface{}, *models.RPCError) {
+	params, rpcErr := decodeParams(raw)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	q := newGetQuery(params, "eventid", renderEvent(&Problem{}, false))
+	if rpcErr := q.validate(); rpcErr != nil {
+		return nil, rpcErr
+	}
+	filter := newEventFilter(params)
+	recent := boolParam(params, "recent")
+
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+	var objs []object
+	for _, id := range sortedKeys(s.store.problems) {
+		p := s.store.problems[id]
+		if p.REventID != "0" && !recent || !filter.match(p, p.EventID, p.Clock) {
+			continue
+		}
+		obj := renderEvent(p, false)
+		if !q.match(obj) {
+			continue
+		}
+		if _, ok := selectOutput(params, "selectTags"); ok {
+			obj["tags"] = renderTags(p.Tags)
+		}
+		objs = append(objs, obj)
+	}
+	return q.finish(objs), nil
+}
+
+// eventGet 每个问题对应一条问题事件（value=1），已恢复的问题额外对应一条恢复事件（value=0）
+func eventGet(s *Server, _ *callContext, raw json.RawMessage) (interface{}, *models.RPCError) {
+	params, rpcErr := decodeParams(raw)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	sample := renderEvent(&Problem{}, false)
+	sample["value"] = ""
+	q := newGetQuery(params, "eventid", sample)
+	if rpcErr := q.validate(); rpcErr != nil {
+		return nil, rpcErr
+	}
+	filter := newEventFilter(params)
+	values, byValue := idsParam(params, "value")
+
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+	var objs []object
+	add := func(p *Problem, recovery bool) {
+		eventID, clock, value := p.EventID, p.Clock, "1"
+		if recovery {
+			eventID, clock, value = p.REventID, p.RClock, "0"
+		}
+		if byValue && !values[value] || !filter.match(p, eventID, clock) {
+			return
+		}
+		obj := renderEvent(p, recovery)
+		obj["value"] = value
+		if !q.match(obj) {
+			return
+		}
+		if _, ok := selectOutput(params, "selectTags"); ok {
+			obj["tags"] = renderTags(p.Tags)
+		}
+		if _, ok := selectOutput(params, "selectHosts"); ok {
+			hosts := []object{}
+			if h, ok := s.store.hosts[p.HostID]; ok {
+				hosts = append(hosts, object{"hostid": h.ID, "host": h.Host, "name": h.Name})
+			}
+			obj["hosts"] = hosts
+		}
+		objs = append(objs, obj)
+	}
+	for _, id := range sortedKeys(s.store.problems) {
+		p := s.store.problems[id]
+		add(p, false)
+		if p.REventID != "0" {
+			add(p, true)
+		}
+	}
+	sort.SliceStable(objs, func(i, j int) bool {
+		return idLess(objs[i]["eventid"].(string), objs[j]["eventid"].(string))
+	})
+	return q.finish(objs), nil
+}
+
+// ============================= 工具函数 =============================
+
+// checkKeys 拒绝当前版本不支持的参数，模拟 API 的严格参数校验
+func checkKeys(params map[string]interface{}, allowed map[string]bool) *models.RPCError {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if !allowed[k] {
+			return invalidParams(fmt.Sprintf(`Invalid parameter "/": unexpected parameter "%s".`, k))
+		}
+	}
+	return nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return idLess(keys[i], keys[j]) })
+	return keys
+}
+
+func anyIn(ids []string, set map[string]bool) bool {
+	for _, id := range ids {
+		if set[id] {
+			return true
+		}
+	}
+	return false
+}
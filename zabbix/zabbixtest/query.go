@@ -0,0 +1,341 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-25 10:26:41
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-25 10:26:41
+ * @FilePath: \zabbix-mcp-go\zabbix\zabbixtest\query.go
+ * @Description: *.get 通用参数处理：output/filter/search/sortfield/limit/countOutput
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+package zabbixtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"zabbixMcp/models"
+)
+
+// object 渲染后的 API 对象，值与真实 Zabbix 一致均为字符串（子查询除外）
+type object = map[string]interface{}
+
+// getQuery *.get 请求的通用部分
+type getQuery struct {
+	params  map[string]interface{}
+	idField string
+	fields  map[string]bool // 当前版本对象允许的字段
+}
+
+// decodeParams 解析请求参数；apiinfo.version 等方法允许空数组
+func decodeParams(raw json.RawMessage) (map[string]interface{}, *models.RPCError) {
+	var params map[string]interface{}
+	if err := json.Unmarshal(raw, &params); err != nil {
+		var arr []interface{}
+		if json.Unmarshal(raw, &arr) == nil && len(arr) == 0 {
+			return map[string]interface{}{}, nil
+		}
+		return nil, invalidParams(`Invalid parameter "/": an array is expected.`)
+	}
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+	return params, nil
+}
+
+// decodeIDs 解析 *.delete 的ID数组参数
+func decodeIDs(raw json.RawMessage) ([]string, *models.RPCError) {
+	var arr []interface{}
+	if err := json.Unmarshal(raw, &arr); err != nil {
+		return nil, invalidParams(`Invalid parameter "/": an array is expected.`)
+	}
+	if len(arr) == 0 {
+		return nil, invalidParams(`Invalid parameter "/": cannot be empty.`)
+	}
+	ids := stringList(arr)
+	seen := map[string]bool{}
+	for i, id := range ids {
+		if seen[id] {
+			return nil, invalidParams(fmt.Sprintf(`Invalid parameter "/%d": value (%s) already exists.`, i+1, id))
+		}
+		seen[id] = true
+	}
+	return ids, nil
+}
+
+func newGetQuery(params map[string]interface{}, idField string, sample object) getQuery {
+	fields := make(map[string]bool, len(sample))
+	for k, v := range sample {
+		if _, isString := v.(string); isString {
+			fields[k] = true
+		}
+	}
+	return getQuery{params: params, idField: idField, fields: fields}
+}
+
+// validate 校验 output/filter/search/sortfield 中的字段名，模拟新版 API 的严格校验
+func (q getQuery) validate() *models.RPCError {
+	if list, ok := q.params["output"].([]interface{}); ok {
+		for i, f := range stringList(list) {
+			if !q.fields[f] {
+				return invalidParams(fmt.Sprintf(`Invalid parameter "/output/%d": value must be one of %s.`, i+1, q.fieldList()))
+			}
+		}
+	}
+	for _, key := range []string{"filter", "search"} {
+		if m, ok := q.params[key].(map[string]interface{}); ok {
+			for f := range m {
+				if !q.fields[f] {
+					return invalidParams(fmt.Sprintf(`Invalid parameter "/%s": unexpected parameter "%s".`, key, f))
+				}
+			}
+		}
+	}
+	for i, f := range stringList(q.params["sortfield"]) {
+		if !q.fields[f] {
+			return invalidParams(fmt.Sprintf(`Invalid parameter "/sortfield/%d": value must be one of %s.`, i+1, q.fieldList()))
+		}
+	}
+	return nil
+}
+
+func (q getQuery) fieldList() string {
+	names := make([]string, 0, len(q.fields))
+	for f := range q.fields {
+		names = append(names, `"`+f+`"`)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// match 判断对象是否满足 filter 与 search
+func (q getQuery) match(obj object) bool {
+	if filter, ok := q.params["filter"].(map[string]interface{}); ok {
+		for k, want := range filter {
+			if !containsString(stringList(want), fmt.Sprint(obj[k])) {
+				return false
+			}
+		}
+	}
+	search, ok := q.params["search"].(map[string]interface{})
+	if !ok || len(search) == 0 {
+		return true
+	}
+	byAny := boolParam(q.params, "searchByAny")
+	start := boolParam(q.params, "startSearch")
+	wildcards := boolParam(q.params, "searchWildcardsEnabled")
+	matched := 0
+	for k, want := range search {
+		if matchSearch(fmt.Sprint(obj[k]), stringList(want), start, wildcards) {
+			matched++
+		}
+	}
+	if byAny {
+		return matched > 0
+	}
+	return matched == len(search)
+}
+
+func matchSearch(value string, patterns []string, start, wildcards bool) bool {
+	value = strings.ToLower(value)
+	for _, p := range patterns {
+		p = strings.ToLower(p)
+		if wildcards {
+			if !start && !strings.HasPrefix(p, "*") {
+				p = "*" + p
+			}
+			if !strings.HasSuffix(p, "*") {
+				p += "*"
+			}
+			if ok, _ := path.Match(p, value); ok {
+				return true
+			}
+			continue
+		}
+		if start && strings.HasPrefix(value, p) || !start && strings.Contains(value, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// finish 依次执行排序、limit、countOutput 与 output 投影
+func (q getQuery) finish(objs []object) interface{} {
+	if sortFields := stringList(q.params["sortfield"]); len(sortFields) > 0 {
+		desc := strings.EqualFold(fmt.Sprint(q.params["sortorder"]), "DESC")
+		sort.SliceStable(objs, func(i, j int) bool {
+			for _, f := range sortFields {
+				a, b := fmt.Sprint(objs[i][f]), fmt.Sprint(objs[j][f])
+				if a == b {
+					continue
+				}
+				if desc {
+					return idLess(b, a)
+				}
+				return idLess(a, b)
+			}
+			return false
+		})
+	}
+	if limit, ok := intParam(q.params, "limit"); ok && limit >= 0 && limit < len(objs) {
+		objs = objs[:limit]
+	}
+	if boolParam(q.params, "countOutput") {
+		return strconv.Itoa(len(objs))
+	}
+	out := make([]object, 0, len(objs))
+	for _, obj := range objs {
+		out = append(out, project(obj, q.params["output"], q.idField))
+	}
+	return out
+}
+
+// project 按 output 参数裁剪字段；子查询结果（非字符串值）始终保留
+func project(obj object, output interface{}, idField string) object {
+	switch v := output.(type) {
+	case nil:
+		return obj
+	case string:
+		if v == "extend" {
+			return obj
+		}
+		// shortdata / refer 等只返回ID
+		return keepFields(obj, []string{idField})
+	case []interface{}:
+		return keepFields(obj, stringList(v))
+	}
+	return obj
+}
+
+func keepFields(obj object, fields []string) object {
+	out := object{}
+	for _, f := range fields {
+		if v, ok := obj[f]; ok {
+			out[f] = v
+		}
+	}
+	for k, v := range obj {
+		if _, isString := v.(string); !isString {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// selectOutput 处理 selectXxx 参数：true/"extend" 返回全部字段，数组返回指定字段，"count" 返回数量
+func selectOutput(params map[string]interface{}, key string) (interface{}, bool) {
+	v, ok := params[key]
+	if !ok || v == nil || v == false {
+		return nil, false
+	}
+	if v == true {
+		return "extend", true
+	}
+	return v, true
+}
+
+// validateSelect 校验 selectXxx 中的字段名
+func validateSelect(params map[string]interface{}, key string, sample object) *models.RPCError {
+	list, ok := params[key].([]interface{})
+	if !ok {
+		return nil
+	}
+	for i, f := range stringList(list) {
+		if _, ok := sample[f]; !ok {
+			return invalidParams(fmt.Sprintf(`Invalid parameter "/%s/%d": value must be one of %s.`, key, i+1, newGetQuery(nil, "", sample).fieldList()))
+		}
+	}
+	return nil
+}
+
+// stringList 将字符串、数字或数组统一转换为字符串切片
+func stringList(v interface{}) []string {
+	switch t := v.(type) {
+	case nil:
+		return nil
+	case string:
+		return []string{t}
+	case float64:
+		return []string{strconv.FormatFloat(t, 'f', -1, 64)}
+	case []interface{}:
+		out := make([]string, 0, len(t))
+		for _, item := range t {
+			out = append(out, stringList(item)...)
+		}
+		return out
+	case []string:
+		return t
+	}
+	return []string{fmt.Sprint(v)}
+}
+
+// idsParam 读取 xxxids 参数；第二个返回值表示是否提供了该参数
+func idsParam(params map[string]interface{}, key string) (map[string]bool, bool) {
+	v, ok := params[key]
+	if !ok || v == nil {
+		return nil, false
+	}
+	set := map[string]bool{}
+	for _, id := range stringList(v) {
+		set[id] = true
+	}
+	return set, true
+}
+
+func boolParam(params map[string]interface{}, key string) bool {
+	switch v := params[key].(type) {
+	case bool:
+		return v
+	case string:
+		return v == "1" || strings.EqualFold(v, "true")
+	case float64:
+		return v != 0
+	}
+	return false
+}
+
+func intParam(params map[string]interface{}, key string) (int, bool) {
+	switch v := params[key].(type) {
+	case float64:
+		return int(v), true
+	case string:
+		n, err := strconv.Atoi(v)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+func int64Param(params map[string]interface{}, key string) (int64, bool) {
+	switch v := params[key].(type) {
+	case float64:
+		return int64(v), true
+	case string:
+		n, err := strconv.ParseInt(v, 10, 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+func stringParam(params map[string]interface{}, key string) (string, bool) {
+	v, ok := params[key]
+	if !ok || v == nil {
+		return "", false
+	}
+	list := stringList(v)
+	if len(list) != 1 {
+		return "", false
+	}
+	return list[0], true
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,183 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-25 15:40:51
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-25 15:40:51
+ * @FilePath: \zabbix-mcp-go\zabbix\zabbixtest\replay.go
+ * @Description: 回放录制的 fixture 的 http.RoundTripper
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+package zabbixtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"zabbixMcp/models"
+	"zabbixMcp/zabbix"
+)
+
+// replayURL 回放模式下客户端使用的占位地址，请求不会真正发出
+const replayURL = "http://replay.zabbixtest.invalid"
+
+// ReplayTransport 按 方法+脱敏后的参数 匹配录制的 fixture 并返回其响应（user.login 不比较参数），批量请求逐条匹配。
+// 相同请求录制了多次时按录制顺序依次返回，用完后重复最后一条；
+// 找不到匹配时返回 -32603 错误，便于在测试中发现参数适配的变化。
+type ReplayTransport struct {
+	mu      sync.Mutex
+	entries map[string][]*replayEntry // method -> 录制顺序
+}
+
+type replayEntry struct {
+	fixture zabbix.Fixture
+	params  string
+	served  int
+}
+
+// NewReplayTransport 加载 dir/<instance> 下的 fixture
+func NewReplayTransport(dir, instance string) (*ReplayTransport, error) {
+	fixtures, err := zabbix.LoadFixtures(dir, instance)
+	if err != nil {
+		return nil, err
+	}
+	if len(fixtures) == 0 {
+		return nil, fmt.Errorf("目录 %s 中没有实例 %s 的 fixture", dir, instance)
+	}
+	t := &ReplayTransport{entries: map[string][]*replayEntry{}}
+	for _, f := range fixtures {
+		t.entries[f.Method] = append(t.entries[f.Method], &replayEntry{
+			fixture: f,
+			params:  string(zabbix.ScrubParams(f.Params)),
+		})
+	}
+	return t, nil
+}
+
+// ReplayConfig 返回使用回放传输的客户端配置，可直接传给 zabbix.NewClientProviderFromConfigs
+func ReplayConfig(dir, instance string) (zabbix.ClientConfig, error) {
+	t, err := NewReplayTransport(dir, instance)
+	if err != nil {
+		return zabbix.ClientConfig{}, err
+	}
+	return zabbix.ClientConfig{
+		Instance:  instance,
+		URL:       replayURL,
+		User:      "replay",
+		Pass:      "replay",
+		AuthType:  "password",
+		Transport: t,
+	}, nil
+}
+
+// Unused 返回从未被请求过的 fixture，可用于断言调用序列没有遗漏
+func (t *ReplayTransport) Unused() []zabbix.Fixture {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var out []zabbix.Fixture
+	for _, list := range t.entries {
+		for _, e := range list {
+			if e.served == 0 {
+				out = append(out, e.fixture)
+			}
+		}
+	}
+	return out
+}
+
+// RoundTrip 实现 http.RoundTripper
+func (t *ReplayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		data, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		body = data
+	}
+	type replayRequest struct {
+		Method string          `json:"method"`
+		Params json.RawMessage `json:"params"`
+		ID     interface{}     `json:"id"`
+	}
+	var resp interface{}
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		var batch []replayRequest
+		if err := json.Unmarshal(trimmed, &batch); err != nil {
+			return nil, fmt.Errorf("解析回放请求失败: %w", err)
+		}
+		responses := make([]rpcResponse, len(batch))
+		for i, rpc := range batch {
+			responses[i] = t.respond(rpc.Method, rpc.Params, rpc.ID)
+		}
+		resp = responses
+	} else {
+		var rpc replayRequest
+		if err := json.Unmarshal(body, &rpc); err != nil {
+			return nil, fmt.Errorf("解析回放请求失败: %w", err)
+		}
+		resp = t.respond(rpc.Method, rpc.Params, rpc.ID)
+	}
+
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		StatusCode:    http.StatusOK,
+		Status:        "200 OK",
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(data)),
+		ContentLength: int64(len(data)),
+		Request:       req,
+	}, nil
+}
+
+// respond 按录制的 fixture 构造单条响应
+func (t *ReplayTransport) respond(method string, params json.RawMessage, id interface{}) rpcResponse {
+	resp := rpcResponse{JSONRPC: "2.0", ID: id}
+	if f, ok := t.match(method, string(zabbix.ScrubParams(params))); ok {
+		resp.Error = f.Error
+		if f.Error == nil {
+			resp.Result = f.Result
+			if len(f.Result) == 0 {
+				resp.Result = json.RawMessage("null")
+			}
+		}
+		return resp
+	}
+	resp.Error = &models.RPCError{
+		Code:    CodeInternal,
+		Message: "No fixture.",
+		Data:    fmt.Sprintf("没有录制 %s 的请求: %s", method, zabbix.ScrubParams(params)),
+	}
+	return resp
+}
+
+func (t *ReplayTransport) match(method, params string) (zabbix.Fixture, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var last *replayEntry
+	for _, e := range t.entries[method] {
+		if e.params != params && method != "user.login" {
+			continue
+		}
+		if e.served == 0 {
+			e.served++
+			return e.fixture, true
+		}
+		last = e
+	}
+	if last == nil {
+		return zabbix.Fixture{}, false
+	}
+	last.served++
+	return last.fixture, true
+}
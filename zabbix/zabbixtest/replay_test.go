@@ -0,0 +1,177 @@
+package zabbixtest_test
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"zabbixMcp/models"
+	"zabbixMcp/server"
+	"zabbixMcp/zabbix"
+	"zabbixMcp/zabbix/zabbixtest"
+)
+
+// update 重新录制 testdata/fixtures：go test ./zabbix/zabbixtest -run TestReplay50 -update
+var update = flag.Bool("update", false, "重新从模拟的 5.0 服务器录制 testdata/fixtures")
+
+const (
+	fixtureDir      = "testdata/fixtures"
+	fixtureInstance = "zabbix-5.0"
+)
+
+// replayScenario 录制与回放共用的调用序列，断言在两种模式下都必须成立
+func replayScenario(t *testing.T, provider zabbix.ClientProvider) {
+	t.Helper()
+	ctx := context.Background()
+	lease, err := provider.AcquireByInstance(ctx, fixtureInstance)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := lease.Client()
+
+	// 请求适配：username/roleid 换成 5.0 的 alias/type
+	adapted := client.AdaptAPIParams("user.get", models.MapParams{
+		"output": []string{"userid", "username", "roleid"},
+		"filter": map[string]interface{}{"username": []string{"Admin"}},
+	})
+	want := map[string]interface{}{
+		"output": []string{"userid", "alias", "type"},
+		"filter": map[string]interface{}{"alias": []string{"Admin"}},
+	}
+	if !reflect.DeepEqual(adapted, want) {
+		t.Fatalf("AdaptAPIParams = %#v, want %#v", adapted, want)
+	}
+
+	// 响应统一：alias/type 换回 username/roleid
+	var users []map[string]interface{}
+	err = client.Call(ctx, "user.get", adapted, &users)
+	lease.Release(err)
+	if err != nil {
+		t.Fatalf("user.get: %v", err)
+	}
+	if len(users) != 1 || users[0]["username"] != "Admin" || users[0]["roleid"] != "3" {
+		t.Fatalf("user.get 未统一为新版本结构: %v", users)
+	}
+	if _, ok := users[0]["alias"]; ok {
+		t.Fatalf("统一后的结果不应保留 alias: %v", users[0])
+	}
+
+	// 业务层：selectHostGroups 适配为 selectGroups，响应中的 groups 统一为 hostgroups
+	hosts, err := server.GetHosts(ctx, provider, models.MapParams{"output": "extend", "selectHostGroups": "extend"}, fixtureInstance)
+	if err != nil {
+		t.Fatalf("GetHosts: %v", err)
+	}
+	if len(hosts) != 1 || hosts[0].Host != "Zabbix server" {
+		t.Fatalf("GetHosts = %+v", hosts)
+	}
+	if len(hosts[0].HostGroups) != 1 || hosts[0].HostGroups[0].Name != "Zabbix servers" {
+		t.Fatalf("主机组未统一为 hostgroups: %+v", hosts[0])
+	}
+}
+
+func TestReplay50(t *testing.T) {
+	if *update {
+		recordFixtures(t)
+	}
+
+	cfg, err := zabbixtest.ReplayConfig(fixtureDir, fixtureInstance)
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider, err := zabbix.NewClientProviderFromConfigs([]zabbix.ClientConfig{cfg})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer provider.Close()
+
+	replayScenario(t, provider)
+	if unused := cfg.Transport.(*zabbixtest.ReplayTransport).Unused(); len(unused) > 0 {
+		for _, f := range unused {
+			t.Errorf("录制的 %s 没有被请求: %s", f.Method, f.Params)
+		}
+	}
+}
+
+// TestReplay50Fixtures 直接对录制的原始响应做统一，确认 fixture 保留的是 5.0 的结构
+func TestReplay50Fixtures(t *testing.T) {
+	fixtures, err := zabbix.LoadFixtures(fixtureDir, fixtureInstance)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := &zabbix.VersionInfo{Major: 5, Minor: 0, Full: "5.0.0"}
+	checked := map[string]bool{}
+	for _, f := range fixtures {
+		switch f.Method {
+		case "apiinfo.version":
+			var version string
+			if err := json.Unmarshal(f.Result, &version); err != nil || version != "5.0.0" {
+				t.Fatalf("录制的版本 = %s", f.Result)
+			}
+		case "user.login":
+			if string(f.Result) != `"`+models.MaskedValue+`"` {
+				t.Fatalf("user.login 的会话ID没有脱敏: %s", f.Result)
+			}
+		case "user.get":
+			var raw, normalized []map[string]interface{}
+			if err := json.Unmarshal(f.Result, &raw); err != nil {
+				t.Fatal(err)
+			}
+			if raw[0]["alias"] != "Admin" || raw[0]["type"] != "3" {
+				t.Fatalf("录制的 user.get 不是 5.0 的结构: %s", f.Result)
+			}
+			if err := json.Unmarshal(zabbix.NormalizeResponse(f.Method, v, f.Result), &normalized); err != nil {
+				t.Fatal(err)
+			}
+			if normalized[0]["username"] != "Admin" || normalized[0]["roleid"] != "3" {
+				t.Fatalf("NormalizeResponse = %v", normalized)
+			}
+		case "host.get":
+			var raw, normalized []map[string]interface{}
+			if err := json.Unmarshal(f.Result, &raw); err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := raw[0]["groups"]; !ok {
+				t.Fatalf("录制的 host.get 不是 5.0 的结构: %s", f.Result)
+			}
+			if err := json.Unmarshal(zabbix.NormalizeResponse(f.Method, v, f.Result), &normalized); err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := normalized[0]["hostgroups"]; !ok {
+				t.Fatalf("NormalizeResponse = %v", normalized)
+			}
+			if _, ok := normalized[0]["proxyid"]; !ok {
+				t.Fatalf("NormalizeResponse = %v", normalized)
+			}
+		default:
+			continue
+		}
+		checked[f.Method] = true
+	}
+	for _, m := range []string{"apiinfo.version", "user.login", "user.get", "host.get"} {
+		if !checked[m] {
+			t.Errorf("fixture 中缺少 %s", m)
+		}
+	}
+}
+
+// recordFixtures 对模拟的 5.0 服务器执行同一调用序列并覆盖 testdata/fixtures
+func recordFixtures(t *testing.T) {
+	t.Helper()
+	srv := zabbixtest.NewServer(zabbixtest.Options{Version: "5.0.0", Instance: fixtureInstance})
+	defer srv.Close()
+	if err := os.RemoveAll(filepath.Join(fixtureDir, fixtureInstance)); err != nil {
+		t.Fatal(err)
+	}
+	cfg := srv.ClientConfig()
+	cfg.RecordDir = fixtureDir
+	provider, err := zabbix.NewClientProviderFromConfigs([]zabbix.ClientConfig{cfg})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer provider.Close()
+	replayScenario(t, provider)
+}
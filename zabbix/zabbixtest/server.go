@@ -0,0 +1,435 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-25 09:12:03
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-25 09:12:03
+ * @FilePath: \zabbix-mcp-go\zabbix\zabbixtest\server.go
+ * @Description: 基于 httptest 的 Zabbix JSON-RPC 模拟服务器，便于离线测试客户端、连接池与 MCP 工具
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+
+// Package zabbixtest 提供进程内的 Zabbix API 模拟服务器。
+//
+// 服务器按 Options.Version 模拟不同版本的差异：
+//   - 认证位置：6.4 之前只接受请求体 auth 字段，6.4 起支持 Authorization 头，7.2 起不再接受请求体 auth；
+//   - user.login 参数：5.4 之前为 user，5.4 起为 username，6.4 起不再接受 user；
+//   - 用户名字段：5.4 之前为 alias，之后为 username；5.2 之前使用 type，之后使用 roleid；
+//   - 主机字段：6.2 起 groups 改为 hostgroups，7.0 起 proxy_hostid 改为 proxyid；
+//   - 错误码：参数错误与未认证返回 -32602，业务错误返回 -32500，未知方法返回 -32601；
+//   - 批量请求（JSON 数组）逐条处理，每条独立认证与报错；ReverseBatch 时响应按逆序返回。
+package zabbixtest
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"zabbixMcp/models"
+	"zabbixMcp/zabbix"
+)
+
+// AuthMode 控制服务器接受的认证位置
+type AuthMode int
+
+const (
+	AuthDefault    AuthMode = iota // 按版本决定
+	AuthBodyOnly                   // 仅请求体 auth 字段
+	AuthHeaderOnly                 // 仅 Authorization: Bearer 头
+	AuthBoth                       // 两者均可
+)
+
+// 常见 JSON-RPC 错误码
+const (
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternal       = -32603
+	CodeApplication    = -32500
+)
+
+// Options 模拟服务器配置
+type Options struct {
+	Instance string   // 实例名，默认 zabbixtest
+	Version  string   // 模拟的 Zabbix 版本，例如 4.0.0 / 5.0.0 / 5.4.0 / 6.0.0 / 6.4.0 / 7.0.0，默认 6.0.0
+	Username string   // 管理员用户名，默认 Admin
+	Password string   // 管理员密码，默认 zabbix
+	APIToken string   // 预置的 API 令牌（可用于 token 认证）
+	Auth     AuthMode // 认证位置，默认按版本决定
+	// ReverseBatch 批量请求的响应按请求的逆序返回（JSON-RPC 允许任意顺序），用于测试按 id 关联响应
+	ReverseBatch bool
+}
+
+// Call 服务器收到的一次 API 调用
+type Call struct {
+	Method       string
+	Params       json.RawMessage
+	AuthInBody   bool
+	AuthInHeader bool
+	Traceparent  string // 请求携带的 W3C traceparent 头
+	Error        *models.RPCError
+}
+
+// Server 模拟的 Zabbix API 服务器
+type Server struct {
+	*httptest.Server
+	opts  Options
+	major int
+	minor int
+	store *Store
+
+	mu       sync.Mutex
+	sessions map[string]string // session -> userid
+	failures map[string][]*models.RPCError
+	calls    []Call
+}
+
+// NewServer 启动一个模拟服务器，使用完毕后需调用 Close
+func NewServer(opts Options) *Server {
+	if opts.Instance == "" {
+		opts.Instance = "zabbixtest"
+	}
+	if opts.Version == "" {
+		opts.Version = "6.0.0"
+	}
+	if opts.Username == "" {
+		opts.Username = "Admin"
+	}
+	if opts.Password == "" {
+		opts.Password = "zabbix"
+	}
+	s := &Server{
+		opts:     opts,
+		store:    newStore(opts.Username, opts.Password),
+		sessions: map[string]string{},
+		failures: map[string][]*models.RPCError{},
+	}
+	s.major, s.minor = parseVersion(opts.Version)
+	if opts.APIToken != "" {
+		s.store.AddAPIToken(APIToken{Name: "preset", UserID: "1", Token: opts.APIToken})
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Version 返回模拟的版本号
+func (s *Server) Version() string {
+	return s.opts.Version
+}
+
+// APIURL 返回 api_jsonrpc.php 的完整地址
+func (s *Server) APIURL() string {
+	return s.URL + "/api_jsonrpc.php"
+}
+
+// Store 返回内存存储，便于预置数据或断言结果
+func (s *Server) Store() *Store {
+	return s.store
+}
+
+// ClientConfig 返回连接该服务器的客户端配置；配置了 APIToken 时使用 token 认证
+func (s *Server) ClientConfig() zabbix.ClientConfig {
+	cfg := zabbix.ClientConfig{
+		Instance: s.opts.Instance,
+		URL:      s.APIURL(),
+		User:     s.opts.Username,
+		Pass:     s.opts.Password,
+		AuthType: "password",
+		Timeout:  5,
+	}
+	if s.opts.APIToken != "" {
+		cfg.AuthType = "token"
+		cfg.Token = s.opts.APIToken
+	}
+	return cfg
+}
+
+// NewProvider 为一组模拟服务器创建 ClientProvider，每个服务器对应一个实例
+func NewProvider(servers ...*Server) (zabbix.ClientProvider, error) {
+	cfgs := make([]zabbix.ClientConfig, 0, len(servers))
+	for _, s := range servers {
+		cfgs = append(cfgs, s.ClientConfig())
+	}
+	return zabbix.NewClientProviderFromConfigs(cfgs)
+}
+
+// FailNext 让下一次调用 method 时返回指定错误（可多次调用排队）
+func (s *Server) FailNext(method string, code int, message, data string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[method] = append(s.failures[method], &models.RPCError{Code: code, Message: message, Data: data})
+}
+
+// ExpireSessions 使所有登录会话失效，用于测试重新登录逻辑
+func (s *Server) ExpireSessions() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions = map[string]string{}
+}
+
+// Calls 返回收到的全部调用
+func (s *Server) Calls() []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Call(nil), s.calls...)
+}
+
+// Methods 返回收到的调用方法序列
+func (s *Server) Methods() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]string, 0, len(s.calls))
+	for _, c := range s.calls {
+		out = append(out, c.Method)
+	}
+	return out
+}
+
+// ResetCalls 清空调用记录
+func (s *Server) ResetCalls() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = nil
+}
+
+// atLeast 判断模拟版本是否 >= major.minor
+func (s *Server) atLeast(major, minor int) bool {
+	return s.major > major || (s.major == major && s.minor >= minor)
+}
+
+func (s *Server) bodyAuthAllowed() bool {
+	switch s.opts.Auth {
+	case AuthBodyOnly, AuthBoth:
+		return true
+	case AuthHeaderOnly:
+		return false
+	}
+	return !s.atLeast(7, 2)
+}
+
+func (s *Server) headerAuthAllowed() bool {
+	switch s.opts.Auth {
+	case AuthHeaderOnly, AuthBoth:
+		return true
+	case AuthBodyOnly:
+		return false
+	}
+	return s.atLeast(6, 4)
+}
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+	ID      interface{}     `json:"id"`
+	Auth    *string         `json:"auth"`
+}
+
+type rpcResponse struct {
+	JSONRPC string           `json:"jsonrpc"`
+	Result  interface{}      `json:"result,omitempty"`
+	Error   *models.RPCError `json:"error,omitempty"`
+	ID      interface{}      `json:"id"`
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	header := ""
+	if v := r.Header.Get("Authorization"); strings.HasPrefix(v, "Bearer ") {
+		header = strings.TrimPrefix(v, "Bearer ")
+	}
+	traceparent := r.Header.Get("traceparent")
+
+	parseError := rpcResponse{JSONRPC: "2.0", Error: &models.RPCError{Code: -32700, Message: "Parse error.", Data: "Invalid JSON. An error occurred on the server while parsing the JSON text."}}
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		// 批量请求：逐条处理，按请求顺序返回响应数组；空数组按 JSON-RPC 规范返回单个错误
+		var batch []rpcRequest
+		if err := json.Unmarshal(trimmed, &batch); err != nil {
+			writeJSON(w, parseError)
+			return
+		}
+		if len(batch) == 0 {
+			writeJSON(w, rpcResponse{JSONRPC: "2.0", Error: &models.RPCError{Code: CodeInvalidRequest, Message: "Invalid request.", Data: "Invalid parameter \"/\": cannot be empty."}})
+			return
+		}
+		responses := make([]rpcResponse, len(batch))
+		for i, req := range batch {
+			responses[i] = s.serve(req, header, traceparent)
+		}
+		if s.opts.ReverseBatch {
+			slices.Reverse(responses)
+		}
+		writeJSON(w, responses)
+		return
+	}
+	var req rpcRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeJSON(w, parseError)
+		return
+	}
+	writeJSON(w, s.serve(req, header, traceparent))
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// serve 处理单个 JSON-RPC 请求
+func (s *Server) serve(req rpcRequest, header, traceparent string) rpcResponse {
+	resp := rpcResponse{JSONRPC: "2.0", ID: req.ID}
+	call := Call{Method: req.Method, Params: req.Params, AuthInBody: req.Auth != nil && *req.Auth != "", AuthInHeader: header != "", Traceparent: traceparent}
+	defer func() {
+		call.Error = resp.Error
+		s.mu.Lock()
+		s.calls = append(s.calls, call)
+		s.mu.Unlock()
+	}()
+
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		resp.Error = &models.RPCError{Code: CodeInvalidRequest, Message: "Invalid request.", Data: `Invalid parameter "/jsonrpc": value must be "2.0".`}
+		return resp
+	}
+	if rpcErr := s.popFailure(req.Method); rpcErr != nil {
+		resp.Error = rpcErr
+		return resp
+	}
+
+	handler, ok := methods[req.Method]
+	if !ok {
+		resp.Error = &models.RPCError{Code: CodeMethodNotFound, Message: "Method not found.", Data: `Incorrect API "` + apiName(req.Method) + `".`}
+		return resp
+	}
+
+	cc := &callContext{}
+	if req.Method == "apiinfo.version" || req.Method == "user.login" {
+		if call.AuthInBody {
+			resp.Error = invalidParams(`The "` + req.Method + `" method must be called without the "auth" parameter.`)
+			return resp
+		}
+	} else {
+		var rpcErr *models.RPCError
+		if cc, rpcErr = s.authenticate(req, header); rpcErr != nil {
+			resp.Error = rpcErr
+			return resp
+		}
+	}
+
+	params := req.Params
+	if len(params) == 0 || string(params) == "null" {
+		params = json.RawMessage("{}")
+	}
+	result, rpcErr := handler(s, cc, params)
+	if rpcErr != nil {
+		resp.Error = rpcErr
+		return resp
+	}
+	resp.Result = result
+	return resp
+}
+
+func (s *Server) popFailure(method string) *models.RPCError {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	queue := s.failures[method]
+	if len(queue) == 0 {
+		return nil
+	}
+	s.failures[method] = queue[1:]
+	return queue[0]
+}
+
+// authenticate 按版本允许的位置读取会话/令牌，返回调用上下文
+func (s *Server) authenticate(req rpcRequest, header string) (*callContext, *models.RPCError) {
+	var token string
+	if req.Auth != nil && *req.Auth != "" {
+		if !s.bodyAuthAllowed() {
+			return nil, invalidParams(`Invalid parameter "/": unexpected parameter "auth".`)
+		}
+		token = *req.Auth
+	}
+	if token == "" && header != "" && s.headerAuthAllowed() {
+		token = header
+	}
+	if token == "" {
+		return nil, invalidParams(s.notAuthorised())
+	}
+
+	s.mu.Lock()
+	userID, ok := s.sessions[token]
+	s.mu.Unlock()
+	if ok {
+		return &callContext{userID: userID, token: token}, nil
+	}
+	if s.atLeast(5, 4) {
+		s.store.mu.Lock()
+		defer s.store.mu.Unlock()
+		for _, t := range s.store.tokens {
+			if t.Token == token && t.Status == "0" {
+				return &callContext{userID: t.UserID, token: token}, nil
+			}
+		}
+	}
+	return nil, invalidParams("Session terminated, re-login, please.")
+}
+
+func (s *Server) notAuthorised() string {
+	if s.atLeast(6, 0) {
+		return "Not authorized."
+	}
+	return "Not authorised."
+}
+
+func (s *Server) newSession(userID string) string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	session := hex.EncodeToString(buf)
+	s.mu.Lock()
+	s.sessions[session] = userID
+	s.mu.Unlock()
+	return session
+}
+
+func invalidParams(data string) *models.RPCError {
+	return &models.RPCError{Code: CodeInvalidParams, Message: "Invalid params.", Data: data}
+}
+
+func appError(data string) *models.RPCError {
+	return &models.RPCError{Code: CodeApplication, Message: "Application error.", Data: data}
+}
+
+func noPermissions() *models.RPCError {
+	return appError("No permissions to referred object or it does not exist!")
+}
+
+func apiName(method string) string {
+	if i := strings.Index(method, "."); i > 0 {
+		return method[:i]
+	}
+	return method
+}
+
+func parseVersion(v string) (int, int) {
+	parts := strings.Split(strings.TrimPrefix(v, "v"), ".")
+	major, _ := strconv.Atoi(parts[0])
+	minor := 0
+	if len(parts) > 1 {
+		minor, _ = strconv.Atoi(parts[1])
+	}
+	return major, minor
+}
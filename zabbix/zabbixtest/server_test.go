@@ -0,0 +1,288 @@
+package zabbixtest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"zabbixMcp/models"
+	"zabbixMcp/zabbix"
+	"zabbixMcp/zabbix/zabbixtest"
+)
+
+// versions 模拟服务器覆盖的全部版本，包含每个行为分界点
+var versions = []string{"4.0.0", "5.0.0", "5.2.0", "5.4.0", "6.0.0", "6.2.0", "6.4.0", "7.0.0", "7.2.0"}
+
+func newClient(t *testing.T, srv *zabbixtest.Server) *zabbix.ZabbixClient {
+	t.Helper()
+	client, err := zabbix.NewZabbixClientFromConfig(srv.ClientConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestClientLoginAndCall(t *testing.T) {
+	for _, v := range versions {
+		t.Run(v, func(t *testing.T) {
+			srv := zabbixtest.NewServer(zabbixtest.Options{Version: v})
+			defer srv.Close()
+			groupID := srv.Store().AddHostGroup(zabbixtest.HostGroup{Name: "Linux servers"})
+			srv.Store().AddHost(zabbixtest.Host{Host: "web01", Name: "web01", Status: "0", ProxyID: "0", GroupIDs: []string{groupID}})
+			client := newClient(t, srv)
+			ctx := context.Background()
+
+			// 创建客户端时即登录，显式 Login 会换发新的会话
+			first := client.GetAuthToken()
+			if first == "" {
+				t.Fatal("创建客户端后没有会话令牌")
+			}
+			if err := client.Login(ctx); err != nil {
+				t.Fatalf("登录失败: %v", err)
+			}
+			if token := client.GetAuthToken(); token == "" || token == first {
+				t.Fatalf("重新登录后的令牌 = %q", token)
+			}
+
+			var users []map[string]interface{}
+			if err := client.Call(ctx, "user.get", map[string]interface{}{"output": "extend"}, &users); err != nil {
+				t.Fatalf("user.get: %v", err)
+			}
+			var admin map[string]interface{}
+			for _, u := range users {
+				if u["userid"] == "1" {
+					admin = u
+				}
+			}
+			// 响应统一为新版本结构：alias→username，type→roleid
+			if admin == nil || admin["username"] != "Admin" || admin["roleid"] != "3" {
+				t.Fatalf("user.get 结果未统一: %v", admin)
+			}
+			if _, ok := admin["alias"]; ok {
+				t.Fatalf("统一后的结果不应保留 alias: %v", admin)
+			}
+
+			var hosts []map[string]interface{}
+			params := map[string]interface{}{"output": "extend"}
+			if v < "6.2" {
+				params["selectGroups"] = "extend"
+			} else {
+				params["selectHostGroups"] = "extend"
+			}
+			if err := client.Call(ctx, "host.get", params, &hosts); err != nil {
+				t.Fatalf("host.get: %v", err)
+			}
+			var web map[string]interface{}
+			for _, h := range hosts {
+				if h["host"] == "web01" {
+					web = h
+				}
+			}
+			if web == nil {
+				t.Fatalf("host.get 缺少 web01: %v", hosts)
+			}
+			if groups, ok := web["hostgroups"].([]interface{}); !ok || len(groups) != 1 {
+				t.Fatalf("groups 未统一为 hostgroups: %v", web)
+			}
+			if _, ok := web["proxyid"]; !ok {
+				t.Fatalf("proxy_hostid 未统一为 proxyid: %v", web)
+			}
+
+			// 认证位置：6.4 之前只在请求体，7.2 起只在请求头
+			for _, call := range srv.Calls() {
+				if call.Method != "user.get" {
+					continue
+				}
+				if v < "6.4" && (!call.AuthInBody || call.AuthInHeader) {
+					t.Fatalf("%s 应只在请求体携带 auth: %+v", v, call)
+				}
+				if v >= "7.2" && (call.AuthInBody || !call.AuthInHeader) {
+					t.Fatalf("%s 应只在 Authorization 头携带令牌: %+v", v, call)
+				}
+			}
+		})
+	}
+}
+
+func TestClientReloginAfterSessionExpired(t *testing.T) {
+	for _, v := range versions {
+		t.Run(v, func(t *testing.T) {
+			srv := zabbixtest.NewServer(zabbixtest.Options{Version: v})
+			defer srv.Close()
+			client := newClient(t, srv)
+			ctx := context.Background()
+			srv.ExpireSessions()
+			srv.ResetCalls()
+			var users []map[string]interface{}
+			if err := client.Call(ctx, "user.get", map[string]interface{}{"output": []string{"userid"}}, &users); err != nil {
+				t.Fatalf("会话失效后应自动重新登录: %v", err)
+			}
+			logins := 0
+			for _, m := range srv.Methods() {
+				if m == "user.login" {
+					logins++
+				}
+			}
+			if logins != 1 {
+				t.Fatalf("user.login 次数 = %d, want 1: %v", logins, srv.Methods())
+			}
+		})
+	}
+}
+
+func TestClientCallErrors(t *testing.T) {
+	srv := zabbixtest.NewServer(zabbixtest.Options{Version: "6.0.0"})
+	defer srv.Close()
+	client := newClient(t, srv)
+	ctx := context.Background()
+
+	err := client.Call(ctx, "nosuch.method", map[string]interface{}{}, nil)
+	var rpcErr *models.RPCError
+	if !errors.As(err, &rpcErr) || rpcErr.Code != zabbixtest.CodeMethodNotFound {
+		t.Fatalf("未知方法应返回 -32601: %v", err)
+	}
+
+	srv.FailNext("user.get", -32500, "Application error.", "You do not have permission to perform this operation.")
+	err = client.Call(ctx, "user.get", map[string]interface{}{}, nil)
+	if got := zabbix.ClassifyError(err).Code; got != models.CodePermissionDenied {
+		t.Fatalf("ClassifyError = %s, want %s (%v)", got, models.CodePermissionDenied, err)
+	}
+
+	err = client.Call(ctx, "user.create", map[string]interface{}{"nosuchparam": 1}, nil)
+	if got := zabbix.ClassifyError(err).Code; got != models.CodeInvalidParams {
+		t.Fatalf("ClassifyError = %s, want %s (%v)", got, models.CodeInvalidParams, err)
+	}
+}
+
+func TestClientCallBatch(t *testing.T) {
+	for _, v := range versions {
+		t.Run(v, func(t *testing.T) {
+			srv := zabbixtest.NewServer(zabbixtest.Options{Version: v})
+			defer srv.Close()
+			client := newClient(t, srv)
+			ctx := context.Background()
+			srv.ResetCalls()
+
+			var users, groups []map[string]interface{}
+			calls := []zabbix.BatchCall{
+				{Method: "user.get", Params: map[string]interface{}{"output": "extend", "userids": []string{"1"}}, Result: &users},
+				{Method: "usergroup.get", Params: map[string]interface{}{"output": "extend"}, Result: &groups},
+				{Method: "nosuch.method", Params: map[string]interface{}{}},
+			}
+			if err := client.CallBatch(ctx, calls); err != nil {
+				t.Fatalf("CallBatch: %v", err)
+			}
+			if calls[0].Err != nil || len(users) != 1 || users[0]["username"] != "Admin" {
+				t.Fatalf("user.get 条目 = %v, %v", users, calls[0].Err)
+			}
+			if calls[1].Err != nil || len(groups) == 0 {
+				t.Fatalf("usergroup.get 条目 = %v, %v", groups, calls[1].Err)
+			}
+			var rpcErr *models.RPCError
+			if !errors.As(calls[2].Err, &rpcErr) || rpcErr.Code != zabbixtest.CodeMethodNotFound {
+				t.Fatalf("未知方法条目应单独报错: %v", calls[2].Err)
+			}
+			// 三个条目在一次 HTTP 往返中完成
+			if got := len(srv.Calls()); got != 3 {
+				t.Fatalf("服务器收到 %d 个调用, want 3", got)
+			}
+		})
+	}
+}
+
+func TestClientPoolAcquireRelease(t *testing.T) {
+	for _, v := range versions {
+		t.Run(v, func(t *testing.T) {
+			a := zabbixtest.NewServer(zabbixtest.Options{Version: v, Instance: "a"})
+			defer a.Close()
+			b := zabbixtest.NewServer(zabbixtest.Options{Version: v, Instance: "b"})
+			defer b.Close()
+			provider, err := zabbixtest.NewProvider(a, b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer provider.Close()
+			ctx := context.Background()
+
+			lease, err := provider.AcquireByInstance(ctx, "b")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !inUse(provider, "b") || inUse(provider, "a") {
+				t.Fatalf("租借后 InUse 状态错误: %+v", provider.Info(""))
+			}
+			var out interface{}
+			if err := lease.Client().Call(ctx, "user.get", map[string]interface{}{"output": []string{"userid"}}, &out); err != nil {
+				t.Fatal(err)
+			}
+			for _, m := range a.Methods() {
+				if m == "user.get" {
+					t.Fatal("AcquireByInstance(b) 的调用落到了实例 a")
+				}
+			}
+
+			// 实例 b 被占用时再次租借会等待，超时返回 ctx 错误
+			waitCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+			_, err = provider.AcquireByInstance(waitCtx, "b")
+			cancel()
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("实例繁忙时应等待至超时: %v", err)
+			}
+
+			// 归还后可以立即再次租借，重复归还不会把客户端放回两次
+			lease.Release(nil)
+			lease.Release(nil)
+			if inUse(provider, "b") {
+				t.Fatal("归还后仍为 InUse")
+			}
+			again, err := provider.AcquireByInstance(ctx, "b")
+			if err != nil {
+				t.Fatal(err)
+			}
+			other, err := provider.Acquire(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := other.Client(); got == again.Client() {
+				t.Fatal("同一客户端被租借了两次")
+			}
+			again.Release(nil)
+			other.Release(nil)
+
+			if _, err := provider.AcquireByInstance(ctx, "missing"); !errors.Is(err, zabbix.ErrInstanceNotFound) {
+				t.Fatalf("未配置的实例应返回 ErrInstanceNotFound: %v", err)
+			}
+		})
+	}
+}
+
+// TestClientPoolAcquireNilContext 未传 ctx 时按 context.Background 处理
+func TestClientPoolAcquireNilContext(t *testing.T) {
+	srv := zabbixtest.NewServer(zabbixtest.Options{})
+	defer srv.Close()
+	provider, err := zabbixtest.NewProvider(srv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer provider.Close()
+	for name, acquire := range map[string]func() (zabbix.ClientLease, error){
+		"Acquire":           func() (zabbix.ClientLease, error) { return provider.Acquire(nil) },
+		"AcquireByInstance": func() (zabbix.ClientLease, error) { return provider.AcquireByInstance(nil, "zabbixtest") },
+	} {
+		lease, err := acquire()
+		if err != nil {
+			t.Fatalf("%s(nil): %v", name, err)
+		}
+		lease.Release(nil)
+	}
+}
+
+func inUse(provider zabbix.ClientProvider, instance string) bool {
+	for _, info := range provider.Info(instance) {
+		if info.InUse {
+			return true
+		}
+	}
+	return false
+}
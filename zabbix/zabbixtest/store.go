@@ -0,0 +1,414 @@
+/*
+ * @Author: fengzhilaoling fengzhilaoling@gmail.com
+ * @Date: 2025-12-25 09:40:18
+ * @LastEditors: fengzhilaoling
+ * @LastEditTime: 2025-12-25 09:40:18
+ * @FilePath: \zabbix-mcp-go\zabbix\zabbixtest\store.go
+ * @Description: 模拟 Zabbix 的内存对象存储
+ * @Copyright: Copyright (c) 2025 by fengzhilaoling@gmail.com, All Rights Reserved.
+ */
+package zabbixtest
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Media 用户的告警媒介
+type Media struct {
+	MediaID     string
+	MediaTypeID string
+	SendTo      string
+	Active      string // 0 启用 1 禁用
+}
+
+// User 用户（与版本无关的内部表示，输出时再按版本渲染 alias/username、type/roleid）
+type User struct {
+	ID       string
+	Username string
+	Name     string
+	Surname  string
+	Passwd   string
+	RoleID   string // 5.2+ 使用
+	Type     string // 5.2 之前使用：1 用户 2 管理员 3 超级管理员
+	GroupIDs []string
+	Medias   []Media
+}
+
+// UserGroup 用户组
+type UserGroup struct {
+	ID          string
+	Name        string
+	GUIAccess   string // 0 默认 1 内部 2 LDAP 3 禁止访问前端
+	UsersStatus string // 0 启用 1 禁用
+}
+
+// Role 用户角色（5.2+）
+type Role struct {
+	ID   string
+	Name string
+	Type string
+}
+
+// MediaType 媒介类型
+type MediaType struct {
+	ID     string
+	Name   string
+	Type   string // 0 Email
+	Status string
+	// 邮件媒介的 SMTP 设置
+	SMTPServer string
+	SMTPPort   string
+	SMTPEmail  string
+}
+
+// HostGroup 主机组
+type HostGroup struct {
+	ID   string
+	Name string
+}
+
+// Host 主机
+type Host struct {
+	ID       string
+	Host     string
+	Name     string
+	Status   string // 0 监控中 1 未监控 3 模板
+	ProxyID  string // 7.0 之前输出为 proxy_hostid
+	GroupIDs []string
+}
+
+// Trigger 触发器；继承的触发器需要分别添加，TemplateID 指向上一级模板触发器
+type Trigger struct {
+	ID          string
+	HostID      string // 所属主机或模板
+	TemplateID  string // "0" 表示不是继承的
+	Description string
+	Expression  string
+	Priority    string // 0-5
+	Status      string // 0 启用 1 禁用
+	Tags        []Tag
+}
+
+// Tag 事件/问题标签
+type Tag struct {
+	Tag   string
+	Value string
+}
+
+// Problem 问题（同时对应一条 value=1 的问题事件）
+type Problem struct {
+	EventID      string
+	TriggerID    string
+	HostID       string
+	Name         string
+	Severity     string // 0-5
+	Clock        int64
+	Acknowledged string
+	REventID     string // 恢复事件ID，"0" 表示未恢复
+	RClock       int64
+	Tags         []Tag
+}
+
+// APIToken API 令牌（5.4+）
+type APIToken struct {
+	ID     string
+	Name   string
+	UserID string
+	Token  string
+	Status string // 0 启用 1 禁用
+}
+
+// Store 模拟 Zabbix 数据库，所有方法并发安全
+type Store struct {
+	mu         sync.Mutex
+	nextID     int
+	users      map[string]*User
+	userGroups map[string]*UserGroup
+	roles      map[string]*Role
+	mediaTypes map[string]*MediaType
+	hostGroups map[string]*HostGroup
+	hosts      map[string]*Host
+	triggers   map[string]*Trigger
+	problems   map[string]*Problem
+	tokens     map[string]*APIToken
+}
+
+// newStore 创建带有 Zabbix 默认数据的存储（Admin 用户、内置用户组、角色等）
+func newStore(adminUser, adminPass string) *Store {
+	s := &Store{
+		nextID:     1000,
+		users:      map[string]*User{},
+		userGroups: map[string]*UserGroup{},
+		roles:      map[string]*Role{},
+		mediaTypes: map[string]*MediaType{},
+		hostGroups: map[string]*HostGroup{},
+		hosts:      map[string]*Host{},
+		triggers:   map[string]*Trigger{},
+		problems:   map[string]*Problem{},
+		tokens:     map[string]*APIToken{},
+	}
+	for _, g := range []*UserGroup{
+		{ID: "7", Name: "Zabbix administrators", GUIAccess: "0", UsersStatus: "0"},
+		{ID: "8", Name: "Guests", GUIAccess: "0", UsersStatus: "0"},
+		{ID: "9", Name: "No access to the frontend", GUIAccess: "3", UsersStatus: "0"},
+		{ID: "11", Name: "Enabled debug mode", GUIAccess: "0", UsersStatus: "0"},
+		{ID: "12", Name: "Disabled", GUIAccess: "0", UsersStatus: "1"},
+	} {
+		s.userGroups[g.ID] = g
+	}
+	for _, r := range []*Role{
+		{ID: "1", Name: "User role", Type: "1"},
+		{ID: "2", Name: "Admin role", Type: "2"},
+		{ID: "3", Name: "Super admin role", Type: "3"},
+		{ID: "4", Name: "Guest role", Type: "1"},
+	} {
+		s.roles[r.ID] = r
+	}
+	s.mediaTypes["1"] = &MediaType{ID: "1", Name: "Email", Type: "0", Status: "0", SMTPServer: "mail.example.com", SMTPPort: "25", SMTPEmail: "zabbix@example.com"}
+	s.users["1"] = &User{ID: "1", Username: adminUser, Name: "Zabbix", Surname: "Administrator", Passwd: adminPass, RoleID: "3", Type: "3", GroupIDs: []string{"7"}}
+	s.users["2"] = &User{ID: "2", Username: "guest", Passwd: "", RoleID: "4", Type: "1", GroupIDs: []string{"8"}}
+	s.hostGroups["2"] = &HostGroup{ID: "2", Name: "Linux servers"}
+	s.hostGroups["4"] = &HostGroup{ID: "4", Name: "Zabbix servers"}
+	s.hosts["10084"] = &Host{ID: "10084", Host: "Zabbix server", Name: "Zabbix server", Status: "0", ProxyID: "0", GroupIDs: []string{"4"}}
+	return s
+}
+
+func (s *Store) newIDLocked() string {
+	s.nextID++
+	return strconv.Itoa(s.nextID)
+}
+
+// AddUser 新增用户，ID 为空时自动分配，返回用户ID
+func (s *Store) AddUser(u User) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if u.ID == "" {
+		u.ID = s.newIDLocked()
+	}
+	if u.RoleID == "" {
+		u.RoleID = "1"
+	}
+	if u.Type == "" {
+		u.Type = "1"
+	}
+	for i := range u.Medias {
+		if u.Medias[i].MediaID == "" {
+			u.Medias[i].MediaID = s.newIDLocked()
+		}
+		if u.Medias[i].Active == "" {
+			u.Medias[i].Active = "0"
+		}
+	}
+	s.users[u.ID] = &u
+	return u.ID
+}
+
+// User 返回用户快照
+func (s *Store) User(id string) (User, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[id]
+	if !ok {
+		return User{}, false
+	}
+	return cloneUser(u), true
+}
+
+// Users 返回全部用户快照（按ID排序）
+func (s *Store) Users() []User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]User, 0, len(s.users))
+	for _, u := range s.users {
+		out = append(out, cloneUser(u))
+	}
+	sort.Slice(out, func(i, j int) bool { return idLess(out[i].ID, out[j].ID) })
+	return out
+}
+
+// AddUserGroup 新增用户组，返回ID
+func (s *Store) AddUserGroup(g UserGroup) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if g.ID == "" {
+		g.ID = s.newIDLocked()
+	}
+	if g.GUIAccess == "" {
+		g.GUIAccess = "0"
+	}
+	if g.UsersStatus == "" {
+		g.UsersStatus = "0"
+	}
+	s.userGroups[g.ID] = &g
+	return g.ID
+}
+
+// AddRole 新增角色，返回ID
+func (s *Store) AddRole(r Role) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r.ID == "" {
+		r.ID = s.newIDLocked()
+	}
+	s.roles[r.ID] = &r
+	return r.ID
+}
+
+// AddMediaType 新增媒介类型，返回ID
+func (s *Store) AddMediaType(m MediaType) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if m.ID == "" {
+		m.ID = s.newIDLocked()
+	}
+	if m.Status == "" {
+		m.Status = "0"
+	}
+	s.mediaTypes[m.ID] = &m
+	return m.ID
+}
+
+// AddHostGroup 新增主机组，返回ID
+func (s *Store) AddHostGroup(g HostGroup) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if g.ID == "" {
+		g.ID = s.newIDLocked()
+	}
+	s.hostGroups[g.ID] = &g
+	return g.ID
+}
+
+// AddHost 新增主机，返回ID
+func (s *Store) AddHost(h Host) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if h.ID == "" {
+		h.ID = s.newIDLocked()
+	}
+	if h.Name == "" {
+		h.Name = h.Host
+	}
+	if h.Status == "" {
+		h.Status = "0"
+	}
+	if h.ProxyID == "" {
+		h.ProxyID = "0"
+	}
+	s.hosts[h.ID] = &h
+	return h.ID
+}
+
+// AddTemplate 新增模板（status 为 3 的主机，host.get 不返回），返回ID
+func (s *Store) AddTemplate(name string) string {
+	return s.AddHost(Host{Host: name, Status: "3"})
+}
+
+// AddTrigger 新增触发器，返回ID
+func (s *Store) AddTrigger(t Trigger) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if t.ID == "" {
+		t.ID = s.newIDLocked()
+	}
+	if t.TemplateID == "" {
+		t.TemplateID = "0"
+	}
+	if t.Priority == "" {
+		t.Priority = "0"
+	}
+	if t.Status == "" {
+		t.Status = "0"
+	}
+	s.triggers[t.ID] = &t
+	return t.ID
+}
+
+// AddProblem 新增一个未恢复的问题，返回事件ID
+func (s *Store) AddProblem(p Problem) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p.EventID == "" {
+		p.EventID = s.newIDLocked()
+	}
+	if p.TriggerID == "" {
+		p.TriggerID = s.newIDLocked()
+	}
+	if p.Clock == 0 {
+		p.Clock = time.Now().Unix()
+	}
+	if p.Severity == "" {
+		p.Severity = "0"
+	}
+	if p.Acknowledged == "" {
+		p.Acknowledged = "0"
+	}
+	p.REventID = "0"
+	s.problems[p.EventID] = &p
+	return p.EventID
+}
+
+// ResolveProblem 恢复问题，生成恢复事件并返回其ID
+func (s *Store) ResolveProblem(eventID string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.problems[eventID]
+	if !ok || p.REventID != "0" {
+		return "", false
+	}
+	p.REventID = s.newIDLocked()
+	p.RClock = time.Now().Unix()
+	return p.REventID, true
+}
+
+// AddAPIToken 新增 API 令牌，返回ID
+func (s *Store) AddAPIToken(t APIToken) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if t.ID == "" {
+		t.ID = s.newIDLocked()
+	}
+	if t.Status == "" {
+		t.Status = "0"
+	}
+	s.tokens[t.ID] = &t
+	return t.ID
+}
+
+func cloneUser(u *User) User {
+	c := *u
+	c.GroupIDs = append([]string(nil), u.GroupIDs...)
+	c.Medias = append([]Media(nil), u.Medias...)
+	return c
+}
+
+// idLess 按数值比较 Zabbix 的字符串ID
+func idLess(a, b string) bool {
+	ai, errA := strconv.ParseInt(a, 10, 64)
+	bi, errB := strconv.ParseInt(b, 10, 64)
+	if errA == nil && errB == nil {
+		return ai < bi
+	}
+	return a < b
+}
+
+// accessLocked 根据用户所属用户组计算前端访问方式与启用状态（调用方持有锁）
+func (s *Store) accessLocked(u *User) (guiAccess, usersStatus string) {
+	guiAccess, usersStatus = "0", "0"
+	for _, gid := range u.GroupIDs {
+		g, ok := s.userGroups[gid]
+		if !ok {
+			continue
+		}
+		if g.GUIAccess > guiAccess {
+			guiAccess = g.GUIAccess
+		}
+		if g.UsersStatus == "1" {
+			usersStatus = "1"
+		}
+	}
+	return guiAccess, usersStatus
+}